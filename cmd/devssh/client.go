@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+	"devssh/pkg/secrets"
+	"devssh/pkg/ssh"
+	"devssh/pkg/tracing"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/term"
+)
+
+// sshFlags 各命令共用的SSH连接参数
+type sshFlags struct {
+	user     string
+	port     string
+	keyPath  string
+	password string
+	// passwordStdin 从stdin读取密码
+	passwordStdin bool
+	// passwordSecret 密钥存储中的密码ID，避免在命令行中明文传递
+	passwordSecret string
+	timeout        int
+	// startInstance 云主机已停止时先启动
+	startInstance bool
+	// transfer 上传文件的调优参数，transferBuffer以KiB为单位
+	compress       bool
+	transferBuffer int
+	sftpRequests   int
+	parallel       int
+	// sendEnv 额外发送到远程会话的本地环境变量模式
+	sendEnv []string
+	// profile 连接配置档，为空时使用配置文件或$DEVSSH_PROFILE中的设置
+	profile string
+	// cmd 注册参数的命令，用于判断参数是否显式指定
+	cmd *cobra.Command
+}
+
+// register 注册SSH连接相关的命令行参数
+func (f *sshFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.user, "user", "u", "", "SSH username")
+	cmd.Flags().StringVarP(&f.port, "port", "p", "22", "SSH port")
+	cmd.Flags().StringVar(&f.keyPath, "key", "", "SSH private key path")
+	cmd.Flags().StringVar(&f.password, "password", "", "SSH password")
+	cmd.Flags().MarkDeprecated("password", "it exposes the password in shell history and process lists; use --password-stdin, $"+config.EnvPassword+", --password-secret or the interactive prompt")
+	cmd.Flags().BoolVar(&f.passwordStdin, "password-stdin", false, "Read the SSH password from stdin")
+	cmd.Flags().StringVar(&f.passwordSecret, "password-secret", "", "ID of the stored secret holding the SSH password")
+	cmd.Flags().BoolVar(&f.startInstance, "start", false, "Start the cloud instance first if it is stopped")
+	cmd.Flags().IntVar(&f.timeout, "timeout", config.EnvTimeoutSeconds(config.EnvTimeout, 30), "SSH connection timeout in seconds ($DEVSSH_TIMEOUT)")
+	cmd.Flags().BoolVar(&f.compress, "compress", false, "Compress uploads of files that are not already compressed (helps on slow links)")
+	cmd.Flags().IntVar(&f.transferBuffer, "transfer-buffer", ssh.DefaultBufferSize/1024, "Upload buffer size in KiB")
+	cmd.Flags().IntVar(&f.sftpRequests, "sftp-requests", 0, "Concurrent SFTP requests per file when syncing (0 uses the default)")
+	cmd.Flags().IntVar(&f.parallel, "parallel-transfer", 1, "Split uploads of 64 MiB or more across this many SSH channels, verified by checksum")
+	cmd.Flags().StringSliceVar(&f.sendEnv, "send-env", []string{}, "Local environment variables to send to the remote host, as names or patterns like MY_* (TERM, LANG and LC_* are sent by default)")
+	cmd.Flags().StringVar(&f.profile, "profile", "", "Connection profile for the link quality: "+strings.Join(config.ProfileNames(), ", ")+" ($DEVSSH_PROFILE)")
+	f.cmd = cmd
+}
+
+// changed 判断参数是否在命令行中显式指定
+func (f *sshFlags) changed(name string) bool {
+	return f.cmd != nil && f.cmd.Flags().Changed(name)
+}
+
+// connectionProfile 返回生效的连接配置档：--profile，其次为配置文件和环境变量；都未设置时返回nil
+func (f *sshFlags) connectionProfile(cfg *config.Config, host string) (*config.ConnectionProfile, error) {
+	name := f.profile
+	if name == "" && cfg != nil {
+		name = cfg.ResolveDefaults(host).Profile
+	}
+	if name == "" {
+		name = os.Getenv(config.EnvProfile)
+	}
+	if name == "" {
+		return nil, nil
+	}
+	profile, err := config.LookupProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// withProfile 返回未显式指定的超时和传输参数换成配置档取值后的副本
+func (f sshFlags) withProfile(profile *config.ConnectionProfile) sshFlags {
+	if profile == nil {
+		return f
+	}
+	if !f.changed("timeout") && os.Getenv(config.EnvTimeout) == "" {
+		f.timeout = int(profile.Timeout.Seconds())
+	}
+	if !f.changed("compress") {
+		f.compress = profile.Compress
+	}
+	if !f.changed("parallel-transfer") {
+		f.parallel = profile.Parallel
+	}
+	return f
+}
+
+// transferOptions 返回上传参数
+func (f *sshFlags) transferOptions() ssh.TransferOptions {
+	return ssh.TransferOptions{
+		Compress:    f.compress,
+		BufferSize:  f.transferBuffer * 1024,
+		MaxRequests: f.sftpRequests,
+		Parallel:    f.parallel,
+	}
+}
+
+// newSSHClient 根据主机参数创建SSH客户端，主机的查找顺序见 config.ResolveHost
+func newSSHClient(ctx context.Context, host string, f *sshFlags, logger log.Logger) (*ssh.Client, error) {
+	_, name, _, err := config.ParseHostArg(host)
+	if err != nil {
+		return nil, err
+	}
+	password, passphrase, err := resolveCredentials(name, f, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Debugf("Failed to load config, configured hosts are not used: %v", err)
+		cfg = nil
+	}
+	profile, err := f.connectionProfile(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	flags := f.withProfile(profile)
+
+	override := ssh.Config{
+		Username:   flags.user,
+		KeyPath:    flags.keyPath,
+		Password:   password,
+		Passphrase: passphrase,
+		Timeout:    time.Duration(flags.timeout) * time.Second,
+	}
+	if profile != nil {
+		override.KeepAlive = profile.KeepAlive
+	}
+	if cfg != nil {
+		defaults := cfg.ResolveHostDefaults(name)
+		override.StrictHostKeyChecking = defaults.StrictHostKeyChecking
+		override.KnownHostsFile = defaults.KnownHostsFile
+		override.SendEnv = defaults.SendEnv
+		override.SetEnv = defaults.SetEnv
+	}
+	override.SendEnv = append(override.SendEnv, flags.sendEnv...)
+	// 只有当用户显式提供了-p参数时才覆盖端口
+	if flags.port != "22" {
+		override.Port = flags.port
+	}
+
+	resolved, err := config.ResolveHost(cfg, host, override)
+	if err != nil {
+		return nil, err
+	}
+
+	// 云主机的地址可能变化，连接前刷新
+	if resolved.Host != nil && resolved.Host.Cloud != nil {
+		hostConfig, err := refreshCloudHost(ctx, *resolved.Host, f.startInstance, logger)
+		if err != nil {
+			return nil, err
+		}
+		if err := resolved.UpdateHostConfig(hostConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	client := ssh.NewClientWithLogger(&resolved.SSH, logger)
+	client.SetTransferOptions(flags.transferOptions())
+	if resolved.SSH.Password == "" && term.IsTerminal(int(os.Stdin.Fd())) {
+		client.SetPasswordPrompt(func() (string, error) {
+			value, err := readSecretValue(fmt.Sprintf("%s@%s's password: ", resolved.SSH.Username, resolved.SSH.Host))
+			if err == nil {
+				// 同一命令中再次连接时不重复询问
+				f.password = value
+			}
+			return value, err
+		})
+	}
+	return client, nil
+}
+
+// readPasswordStdin 指定--password-stdin时从stdin读取密码；stdin只能读取一次，之后的连接使用读到的密码。
+// 并发连接多台主机时须在启动worker之前调用，否则各worker会同时读取stdin
+func (f *sshFlags) readPasswordStdin() error {
+	if f.password != "" || !f.passwordStdin {
+		return nil
+	}
+	value, err := readSecretValue("SSH password: ")
+	if err != nil {
+		return err
+	}
+	logging.RegisterSecret(value)
+	f.password = value
+	return nil
+}
+
+// resolveCredentials 确定密码和私钥口令：命令行参数优先，其次为devssh配置中主机引用的密钥
+func resolveCredentials(host string, f *sshFlags, logger log.Logger) (string, string, error) {
+	var store secrets.Store
+
+	logging.RegisterSecret(f.password)
+	if err := f.readPasswordStdin(); err != nil {
+		return "", "", err
+	}
+	password := f.password
+	if password == "" {
+		password = os.Getenv(config.EnvPassword)
+		logging.RegisterSecret(password)
+	}
+	if password == "" && f.passwordSecret != "" {
+		value, err := resolveSecret(&store, f.passwordSecret)
+		if err != nil {
+			return "", "", err
+		}
+		password = value
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Debugf("Failed to load config, stored secrets are not used: %v", err)
+		return password, "", nil
+	}
+	hostConfig, exists := cfg.GetHost(host)
+	if !exists {
+		return password, "", nil
+	}
+
+	if password == "" && hostConfig.PasswordSecret != "" {
+		value, err := resolveSecret(&store, hostConfig.PasswordSecret)
+		if err != nil {
+			return "", "", err
+		}
+		password = value
+	}
+
+	passphrase := ""
+	if hostConfig.PassphraseSecret != "" {
+		value, err := resolveSecret(&store, hostConfig.PassphraseSecret)
+		if err != nil {
+			return "", "", err
+		}
+		passphrase = value
+	}
+
+	return password, passphrase, nil
+}
+
+// connectSSH 创建SSH客户端并建立连接，连接过程记录为ctx下的ssh.connect span
+func connectSSH(ctx context.Context, host string, f *sshFlags, logger log.Logger) (client *ssh.Client, retErr error) {
+	logging.SetContextField("host", host)
+
+	ctx, span := tracing.Start(ctx, "ssh.connect", attribute.String("devssh.host", host))
+	defer func() { tracing.End(span, retErr) }()
+
+	client, err := newSSHClient(ctx, host, f, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	sshConfig := client.GetConfig()
+	logger.Infof("Connecting to %s@%s...", sshConfig.Username, net.JoinHostPort(sshConfig.Host, sshConfig.Port))
+	if err := client.ConnectContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	logger.Infof("Connected successfully")
+
+	return client, nil
+}
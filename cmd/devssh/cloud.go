@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"devssh/pkg/cloud"
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+	"devssh/pkg/tracing"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// cloudFlags 云主机命令共用的参数
+type cloudFlags struct {
+	provider string
+	tags     []string
+	region   string
+	profile  string
+	project  string
+	userTag  string
+}
+
+// register 注册云厂商相关的命令行参数
+func (f *cloudFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.provider, "provider", cloud.ProviderAWS, "Cloud provider (aws, gcp)")
+	cmd.Flags().StringSliceVar(&f.tags, "tag", []string{}, "Only instances with this tag (GCP: label), e.g. team=ml")
+	cmd.Flags().StringVar(&f.region, "region", "", "AWS region (defaults to the aws CLI configuration)")
+	cmd.Flags().StringVar(&f.profile, "profile", "", "AWS profile (defaults to the aws CLI configuration)")
+	cmd.Flags().StringVar(&f.project, "project", "", "GCP project (defaults to the gcloud configuration)")
+	cmd.Flags().StringVar(&f.userTag, "user-tag", cloud.DefaultUserTag, "Tag (GCP: label) holding the SSH username")
+}
+
+// list 列出符合标签条件的实例，按名称排序
+func (f *cloudFlags) list(ctx context.Context) ([]cloud.Instance, error) {
+	tags, err := cloud.ParseTags(f.tags)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := cloud.NewProvider(f.provider, cloud.Options{
+		Region:  f.region,
+		Profile: f.profile,
+		Project: f.project,
+		UserTag: f.userTag,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := provider.List(ctx, tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s instances: %w", f.provider, err)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Name < instances[j].Name
+	})
+	return instances, nil
+}
+
+func newCloudCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cloud",
+		Short: "Discover AWS EC2 and GCP instances and use them as hosts",
+		Long: `Discover cloud instances through the aws and gcloud CLIs, using their existing
+credentials and default region/project.
+
+Imported hosts remember their instance: before connecting, devssh refreshes the
+address, and with --start (or auto_start in the host config) starts a stopped
+instance first.`,
+	}
+
+	cmd.AddCommand(
+		newCloudListCmd(),
+		newCloudImportCmd(),
+	)
+
+	return cmd
+}
+
+func newCloudListCmd() *cobra.Command {
+	var flags cloudFlags
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List instances matching the given tags",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			instances, err := flags.list(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if format != outputTable {
+				return printStructured(format, instances)
+			}
+
+			if len(instances) == 0 {
+				logger.Infof("No %s instances found", flags.provider)
+				return nil
+			}
+
+			logger.Infof("%s instances:", flags.provider)
+			for _, instance := range instances {
+				logger.Infof("  %s (%s): %s, public %s, private %s, user %s, zone %s",
+					instance.Name, instance.ID, instance.State,
+					valueOrDash(instance.PublicIP), valueOrDash(instance.PrivateIP),
+					valueOrDash(instance.User), valueOrDash(instance.Zone))
+			}
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+
+	return cmd
+}
+
+func newCloudImportCmd() *cobra.Command {
+	var (
+		flags       cloudFlags
+		defaultUser string
+		privateIP   bool
+		autoStart   bool
+		overwrite   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Add instances matching the given tags as hosts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			instances, err := flags.list(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			names := make(map[string]int)
+			for _, instance := range instances {
+				names[instance.Name]++
+			}
+
+			var hosts []config.HostConfig
+			for _, instance := range instances {
+				host := config.HostConfig{
+					Name:     instance.Name,
+					Host:     instance.Address(privateIP),
+					Port:     "22",
+					Username: instance.User,
+					Cloud: &config.CloudSource{
+						Provider:  flags.provider,
+						Instance:  instance.ID,
+						Zone:      instance.Zone,
+						Region:    flags.region,
+						Project:   flags.project,
+						Profile:   flags.profile,
+						PrivateIP: privateIP,
+						AutoStart: autoStart,
+					},
+				}
+				// 同名实例以ID区分
+				if names[instance.Name] > 1 {
+					host.Name = instance.Name + "-" + instance.ID
+				}
+				if host.Username == "" {
+					host.Username = defaultUser
+				}
+
+				// 同一实例再次导入时只刷新地址和实例信息，保留用户的其他设置
+				if existing, exists := cfg.GetHost(host.Name); exists {
+					sameInstance := existing.Cloud != nil &&
+						existing.Cloud.Provider == host.Cloud.Provider &&
+						existing.Cloud.Instance == host.Cloud.Instance
+					if !sameInstance && !overwrite {
+						logger.Warnf("Skipping %s: a host with this name already exists (use --overwrite to replace it)", host.Name)
+						continue
+					}
+					if sameInstance {
+						if host.Username == "" {
+							host.Username = existing.Username
+						}
+						host.Port = existing.Port
+						host.KeyPath = existing.KeyPath
+						host.Defaults = existing.Defaults
+						host.PasswordSecret = existing.PasswordSecret
+						host.PassphraseSecret = existing.PassphraseSecret
+					}
+				}
+
+				hosts = append(hosts, host)
+				logger.Debugf("Importing %s: %s@%s (%s, %s)", host.Name, host.Username, host.Host, instance.ID, instance.State)
+			}
+
+			imported, err := cfg.ImportHosts(hosts, true)
+			if err != nil {
+				return err
+			}
+			logger.Infof("Imported %d of %d %s instance(s)", imported, len(instances), flags.provider)
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	cmd.Flags().StringVarP(&defaultUser, "user", "u", "", "SSH username for instances without the user tag")
+	cmd.Flags().BoolVar(&privateIP, "private-ip", false, "Connect through the private IP address")
+	cmd.Flags().BoolVar(&autoStart, "auto-start", false, "Start stopped instances automatically before connecting")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace existing hosts with the same name")
+
+	return cmd
+}
+
+// refreshCloudHost 查询云实例的当前状态和地址，需要时先启动实例，地址变化时更新配置
+func refreshCloudHost(ctx context.Context, host config.HostConfig, start bool, logger log.Logger) (_ config.HostConfig, retErr error) {
+	source := host.Cloud
+	_, span := tracing.Start(ctx, "cloud.refresh",
+		attribute.String("cloud.provider", source.Provider),
+		attribute.String("cloud.instance", source.Instance))
+	defer func() { tracing.End(span, retErr) }()
+
+	provider, err := cloud.NewProvider(source.Provider, cloud.Options{
+		Region:  source.Region,
+		Profile: source.Profile,
+		Project: source.Project,
+	})
+	if err != nil {
+		return host, err
+	}
+
+	instance, err := provider.Get(ctx, source.Instance, source.Zone)
+	if err != nil {
+		return host, fmt.Errorf("failed to query instance %s: %w", source.Instance, err)
+	}
+
+	if !instance.Running() {
+		if !start && !source.AutoStart {
+			return host, fmt.Errorf("instance %s of host %s is %s; use --start to start it", source.Instance, host.Name, instance.State)
+		}
+
+		logger.Infof("Starting %s instance %s (%s)...", source.Provider, source.Instance, instance.State)
+		if err := provider.Start(ctx, source.Instance, source.Zone); err != nil {
+			return host, fmt.Errorf("failed to start instance %s: %w", source.Instance, err)
+		}
+		if instance, err = provider.Get(ctx, source.Instance, source.Zone); err != nil {
+			return host, fmt.Errorf("failed to query instance %s: %w", source.Instance, err)
+		}
+		span.SetAttributes(attribute.Bool("cloud.started", true))
+	}
+
+	address := instance.Address(source.PrivateIP)
+	if address == "" {
+		return host, fmt.Errorf("instance %s has no IP address", source.Instance)
+	}
+	if address == host.Host {
+		return host, nil
+	}
+
+	// 停止再启动后公网IP通常会变化，写回配置
+	logger.Infof("Address of %s changed from %s to %s", host.Name, valueOrDash(host.Host), address)
+	host.Host = address
+	cfg, err := config.Load()
+	if err == nil {
+		err = cfg.Update(func(latest *config.Config) error {
+			if current, exists := latest.Hosts[host.Name]; exists {
+				current.Host = address
+				latest.Hosts[host.Name] = current
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		logger.Warnf("Failed to save new address of %s: %v", host.Name, err)
+	} else {
+		syncManagedSSHConfig(cfg, logger)
+	}
+
+	return host, nil
+}
+
+// valueOrDash 空值显示为 -
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+	"devssh/pkg/ssh"
+
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and manage the devssh configuration",
+	}
+
+	cmd.AddCommand(
+		newConfigGetCmd(),
+		newConfigSetCmd(),
+		newConfigEditCmd(),
+		newConfigPathCmd(),
+		newConfigValidateCmd(),
+	)
+
+	return cmd
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [file]",
+		Short: "Validate the config file (unknown keys, wrong types)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			path := ""
+			if len(args) > 0 {
+				path = args[0]
+			} else {
+				var err error
+				if path, err = config.GetConfigPath(); err != nil {
+					return err
+				}
+			}
+
+			issues, err := config.ValidateFile(path)
+			if err != nil {
+				return err
+			}
+
+			if len(issues) == 0 {
+				logger.Infof("%s is valid", path)
+				return nil
+			}
+
+			for _, issue := range issues {
+				logger.Errorf("  %s", issue)
+			}
+			return fmt.Errorf("%s has %d problem(s)", path, len(issues))
+		},
+	}
+
+	return cmd
+}
+
+func newConfigGetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get [key]",
+		Short: "Print a config value by dotted path (e.g. defaults.ide, hosts.gpu01.port)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			value, err := cfg.GetValue(args[0])
+			if err != nil {
+				return err
+			}
+
+			// 标量直接输出，对象和列表以结构化格式输出
+			switch v := value.(type) {
+			case nil:
+				return nil
+			case string, bool, float64:
+				if format == outputTable {
+					fmt.Println(v)
+					return nil
+				}
+			}
+			if format == outputTable {
+				format = outputYAML
+			}
+			return printStructured(format, value)
+		},
+	}
+
+	return cmd
+}
+
+func newConfigSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set [key] [value]",
+		Short: "Set a config value by dotted path (lists accept a,b,c or YAML)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			if err := cfg.SetValue(args[0], args[1]); err != nil {
+				return fmt.Errorf("failed to set %s: %w", args[0], err)
+			}
+
+			logger.Infof("Set %s", args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newConfigEditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Open the config file in $EDITOR and validate it before saving",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			path, err := config.GetConfigPath()
+			if err != nil {
+				return err
+			}
+
+			// 配置文件不存在时先创建
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				if err := config.NewConfig().Save(); err != nil {
+					return fmt.Errorf("failed to create config: %w", err)
+				}
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read config file: %w", err)
+			}
+
+			// 在临时副本上编辑，校验通过后再替换原文件
+			tmp, err := os.CreateTemp(filepath.Dir(path), "config-edit-*"+filepath.Ext(path))
+			if err != nil {
+				return fmt.Errorf("failed to create temp file: %w", err)
+			}
+			tmpPath := tmp.Name()
+			_, err = tmp.Write(data)
+			tmp.Close()
+			if err != nil {
+				os.Remove(tmpPath)
+				return fmt.Errorf("failed to write temp file: %w", err)
+			}
+
+			if err := runEditor(tmpPath); err != nil {
+				os.Remove(tmpPath)
+				return err
+			}
+
+			issues, err := config.ValidateFile(tmpPath)
+			if err != nil {
+				logger.Errorf("Your changes were kept in %s", tmpPath)
+				return err
+			}
+			if len(issues) > 0 {
+				for _, issue := range issues {
+					logger.Errorf("  %s", issue)
+				}
+				logger.Errorf("Your changes were kept in %s", tmpPath)
+				return fmt.Errorf("edited config has %d problem(s), not saved", len(issues))
+			}
+
+			if err := config.WithLock(func() error { return os.Rename(tmpPath, path) }); err != nil {
+				os.Remove(tmpPath)
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+
+			logger.Infof("Saved %s", path)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// runEditor 使用 $VISUAL/$EDITOR 打开文件，默认vi
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// 编辑器可能带参数，如 "code -w"
+	parts := strings.Fields(editor)
+	editorCmd := exec.Command(parts[0], append(parts[1:], path)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+	return nil
+}
+
+func newConfigPathCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print the locations of the config files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			configPath, err := config.GetConfigPath()
+			if err != nil {
+				return err
+			}
+			configDir, err := config.GetConfigDir()
+			if err != nil {
+				return err
+			}
+			cacheDir, err := config.GetCacheDir()
+			if err != nil {
+				return err
+			}
+			stateDir, err := config.GetStateDir()
+			if err != nil {
+				return err
+			}
+			sshConfigPath := ssh.NewSSHConfigParser().ConfigPath()
+
+			if format != outputTable {
+				return printStructured(format, map[string]string{
+					"config":     configPath,
+					"config_dir": configDir,
+					"cache_dir":  cacheDir,
+					"state_dir":  stateDir,
+					"ssh_config": sshConfigPath,
+				})
+			}
+
+			fmt.Printf("config:     %s\n", configPath)
+			fmt.Printf("config dir: %s\n", configDir)
+			fmt.Printf("cache dir:  %s\n", cacheDir)
+			fmt.Printf("state dir:  %s\n", stateDir)
+			fmt.Printf("ssh config: %s\n", sshConfigPath)
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"devssh/pkg/tunnel"
+
+	"github.com/loft-sh/log"
+	"golang.org/x/term"
+)
+
+// consoleHelp 前台会话中可用的交互命令
+const consoleHelp = `Commands:
+  a [NAME=][BIND:]LOCAL:[HOST:]REMOTE  forward a port (e.g. "a 3000", "a 8080:80/fail" or "a db=5432:dbhost:5432")
+  r PORT|NAME                          stop a forward
+  l                                    list forwards
+  ?                                    show this help
+
+Named forwards are remembered and restored the next time you connect to this host.`
+
+// startForwardConsole 标准输入为终端时，在前台会话中读取命令增删转发。
+// 控制台运行期间，端口冲突的确认也从这里读取回答，避免与命令争抢标准输入
+func startForwardConsole(ctx context.Context, controller *tunnel.Controller, manager *tunnel.TunnelManager, logger log.Logger) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return
+	}
+
+	logger.Infof(`Type "a PORT" to forward another port, "?" for more commands`)
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	prompts := make(chan chan string)
+	done := make(chan struct{})
+	manager.OnConflict(func(port int, owner tunnel.PortOwner) bool {
+		return confirmPortKill(port, owner, func() (string, bool) {
+			reply := make(chan string, 1)
+			select {
+			case prompts <- reply:
+			case <-done:
+				return "", false
+			}
+			select {
+			case answer := <-reply:
+				return answer, true
+			case <-done:
+				return "", false
+			}
+		})
+	})
+
+	go func() {
+		defer close(done)
+
+		// 等待回答的确认，下一行输入交给它而不是当作命令
+		var pending chan string
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case reply := <-prompts:
+				pending = reply
+			case line, ok := <-lines:
+				if !ok {
+					return
+				}
+				if pending != nil {
+					pending <- line
+					pending = nil
+					continue
+				}
+				// 命令可能等待确认，不阻塞输入循环
+				go runConsoleCommand(controller, line, logger)
+			}
+		}
+	}()
+}
+
+// runConsoleCommand 执行一行交互命令
+func runConsoleCommand(controller *tunnel.Controller, line string, logger log.Logger) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+
+	switch command, args := fields[0], fields[1:]; command {
+	case "a", "add":
+		if len(args) == 0 {
+			logger.Warnf("Usage: a [NAME=][BIND:]LOCAL:[HOST:]REMOTE...")
+			return
+		}
+		for _, arg := range args {
+			forward, err := tunnel.ForwardFromSpec(arg)
+			if err == nil {
+				_, err = controller.Add(forward)
+			}
+			if err != nil {
+				logger.Warnf("Failed to forward %s: %v", arg, err)
+			}
+		}
+	case "r", "rm", "remove":
+		if len(args) == 0 {
+			logger.Warnf("Usage: r PORT|NAME...")
+			return
+		}
+		for _, arg := range args {
+			if _, err := controller.Remove(arg, false); err != nil {
+				logger.Warnf("Failed to remove %s: %v", arg, err)
+			}
+		}
+	case "l", "ls", "list":
+		printForwards(controller.List(), logger)
+	case "?", "h", "help":
+		logger.Info(consoleHelp)
+	default:
+		logger.Warnf("Unknown command %q, type ? for help", command)
+	}
+}
+
+// printForwards 以表格形式输出转发列表
+func printForwards(forwards []tunnel.ForwardStatus, logger log.Logger) {
+	if len(forwards) == 0 {
+		logger.Infof("No active port forwards")
+		return
+	}
+
+	logger.Infof("Active port forwards:")
+	for _, forward := range forwards {
+		logger.Infof("  %s: %s (%s)", forward.Name, forward, tunnel.FormatStats(forward.TunnelStats))
+	}
+}
+
+// confirmKillPortOwner 本地端口被占用且策略为kill时从标准输入询问是否结束占用进程，非终端时拒绝
+func confirmKillPortOwner(logger log.Logger) func(port int, owner tunnel.PortOwner) bool {
+	return func(port int, owner tunnel.PortOwner) bool {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			logger.Warnf("Local port %d is used by %s; not stopping it without a terminal to confirm", port, owner)
+			return false
+		}
+
+		return confirmPortKill(port, owner, func() (string, bool) {
+			answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			return answer, err == nil
+		})
+	}
+}
+
+// confirmPortKill 询问是否结束占用端口的进程，readLine返回用户输入的一行
+func confirmPortKill(port int, owner tunnel.PortOwner, readLine func() (string, bool)) bool {
+	fmt.Fprintf(os.Stderr, "Local port %d is used by %s. Stop it? [y/N] ", port, owner)
+	answer, ok := readLine()
+	if !ok {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"devssh/pkg/config"
+	"devssh/pkg/gitsetup"
+	"devssh/pkg/ide"
+	"devssh/pkg/logging"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+)
+
+func newDownCmd() *cobra.Command {
+	var (
+		flags   sshFlags
+		runAs   runAsFlags
+		ideType string
+		idePort int
+		session string
+		purge   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "down [host]",
+		Short: "Stop the remote development environment and clear its local state",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+			host := args[0]
+
+			if err := runAs.loadDefaults(cmd, host); err != nil {
+				return err
+			}
+
+			client, err := connectSSH(cmd.Context(), host, &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ideConn, err := ideClient(client, runAs, logger)
+			if err != nil {
+				return err
+			}
+			ideInstaller := ide.NewInstallerWithOptions(ideConn, ide.IDE(ideType), nil, logger)
+
+			// 先停止本地会话，关闭端口转发
+			sessionPorts, err := stopLocalSessions(logger, session, host, client.GetConfig().Host)
+			if err != nil {
+				if session != "" {
+					return err
+				}
+				logger.Warnf("Failed to clear local session state: %v", err)
+			}
+
+			// 未指定端口时停止这些会话的IDE，只停一个会话时不影响同一主机上的其他会话
+			ports := []int{idePort}
+			if idePort == 0 {
+				ports = sessionPorts
+				if session == "" && !slices.Contains(ports, ideInstaller.GetDefaultPort()) {
+					ports = append(ports, ideInstaller.GetDefaultPort())
+				}
+			}
+
+			// 停止远程IDE（包括常驻服务）
+			for _, port := range ports {
+				if err := ideInstaller.RemoveService(port); err != nil {
+					logger.Warnf("Failed to remove %s service on port %d: %v", ideType, port, err)
+				}
+				if err := ideInstaller.Stop(port); err != nil {
+					return fmt.Errorf("failed to stop %s on port %d: %w", ideType, port, err)
+				}
+			}
+
+			if purge {
+				logger.Infof("Purging devssh files on remote host...")
+				if err := ideInstaller.Uninstall(true); err != nil {
+					return fmt.Errorf("failed to uninstall %s: %w", ideType, err)
+				}
+				if output, err := client.RunCommand("rm -rf ~/.devssh"); err != nil {
+					return fmt.Errorf("failed to remove ~/.devssh: %w, output: %s", err, output)
+				}
+				if err := gitsetup.NewBootstrapper(client, logger).RemoveToken(); err != nil {
+					return err
+				}
+			}
+
+			logger.Infof("Environment on %s is down", host)
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	runAs.register(cmd)
+	cmd.Flags().StringVar(&ideType, "ide", "vscode", "Web IDE type (vscode, code-server, or an IDE plugin name)")
+	cmd.Flags().IntVar(&idePort, "ide-port", 0, "Remote IDE port (defaults to the ports of the stopped sessions)")
+	cmd.Flags().StringVar(&session, "session", "", "Only stop this session, leaving other sessions on the host running")
+	cmd.Flags().BoolVar(&purge, "purge", false, "Also remove ~/.devssh, ~/.openvscode-server and the devssh git token on the remote host")
+
+	return cmd
+}
+
+// stopLocalSessions 结束指向该主机的本地devssh进程并删除对应的连接记录，id不为空时只处理该会话；
+// 返回这些会话的远程IDE端口
+func stopLocalSessions(logger log.Logger, id string, hosts ...string) ([]int, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if id != "" {
+		conn, exists := cfg.GetConnection(id)
+		if !exists || !slices.Contains(hosts, conn.Host) {
+			return nil, fmt.Errorf("no session %s for host %s", id, hosts[0])
+		}
+	}
+
+	var ports []int
+	for _, conn := range cfg.ListConnections() {
+		if !slices.Contains(hosts, conn.Host) || (id != "" && conn.ID != id) {
+			continue
+		}
+		if conn.RemotePort != 0 && !slices.Contains(ports, conn.RemotePort) {
+			ports = append(ports, conn.RemotePort)
+		}
+
+		if conn.IsAlive() {
+			if err := conn.Terminate(); err == nil {
+				logger.Infof("Stopped local session %s (PID %d)", conn.ID, conn.PID)
+			}
+		}
+
+		if err := cfg.RemoveConnection(conn.ID); err != nil {
+			return ports, err
+		}
+	}
+
+	return ports, nil
+}
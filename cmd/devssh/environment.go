@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"devssh/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// environmentFlags 远程IDE进程的环境参数，叠加在配置的环境之上
+type environmentFlags struct {
+	loginShell bool
+	env        []string
+	envFiles   []string
+	tz         string
+	locale     string
+	// defaults 配置中主机和工作区的环境
+	defaults *config.IDEEnvironment
+}
+
+// register 注册IDE环境相关的命令行参数
+func (f *environmentFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.loginShell, "login-shell", false, "Start the remote IDE from your login shell so PATH, conda and nvm setup apply")
+	cmd.Flags().StringArrayVar(&f.env, "env", []string{}, "Environment variable for the remote IDE as KEY=VALUE (repeatable)")
+	cmd.Flags().StringArrayVar(&f.envFiles, "env-file", []string{}, "Env file on the remote host to load before starting the IDE (repeatable)")
+	cmd.Flags().StringVar(&f.tz, "tz", "", "Time zone for the remote IDE and its terminals (e.g. Europe/Berlin, or local for this machine's)")
+	cmd.Flags().StringVar(&f.locale, "locale", "", "Locale (LANG) for the remote IDE and its terminals (e.g. de_DE.UTF-8, or local for this machine's)")
+}
+
+// loadDefaults 为不读取其他默认值的命令从配置加载主机的IDE环境
+func (f *environmentFlags) loadDefaults(host string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	f.defaults = cfg.ResolveHostDefaults(host).Environment
+	return nil
+}
+
+// environment 返回配置的环境叠加命令行参数后的结果
+func (f *environmentFlags) environment() (config.IDEEnvironment, error) {
+	flags, err := f.parse()
+	if err != nil {
+		return config.IDEEnvironment{}, err
+	}
+	if merged := config.MergeEnvironment(f.defaults, flags); merged != nil {
+		return *merged, nil
+	}
+	return config.IDEEnvironment{}, nil
+}
+
+// parse 把命令行参数转换为IDE环境，没有设置时返回nil
+func (f *environmentFlags) parse() (*config.IDEEnvironment, error) {
+	result := &config.IDEEnvironment{LoginShell: f.loginShell, EnvFiles: f.envFiles, TZ: f.tz, Locale: f.locale}
+	for _, item := range f.env {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --env %q: expected KEY=VALUE", item)
+		}
+		if result.Env == nil {
+			result.Env = make(map[string]string)
+		}
+		result.Env[name] = value
+	}
+	if result.IsZero() {
+		return nil, nil
+	}
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"devssh/pkg/config"
+	"devssh/pkg/ide"
+	"devssh/pkg/preflight"
+	"devssh/pkg/ssh"
+	"devssh/pkg/tunnel"
+)
+
+// 进程退出码，按失败原因区分，在根命令的帮助中列出
+const (
+	exitError        = 1
+	exitAuth         = 3
+	exitNetwork      = 4
+	exitInstall      = 5
+	exitPortConflict = 6
+	// exitInterrupted 与shell中被SIGINT结束的进程一致
+	exitInterrupted = 130
+)
+
+// errorClass 一类失败对应的退出码和给用户的提示
+type errorClass struct {
+	errs []error
+	code int
+	// reason -o json结果对象中的失败分类
+	reason string
+	hint   string
+}
+
+// errorClasses 按顺序匹配，安装中途断线等同时属于多类的错误取第一类；用户中断优先
+var errorClasses = []errorClass{
+	{
+		errs:   []error{context.Canceled},
+		code:   exitInterrupted,
+		reason: "interrupted",
+	},
+	{
+		errs:   []error{ssh.ErrHostKeyRejected},
+		code:   exitAuth,
+		reason: "host_key",
+	},
+	{
+		errs:   []error{ssh.ErrAuthFailed},
+		code:   exitAuth,
+		reason: "auth",
+		hint:   fmt.Sprintf("check the user and key; for password login run in a terminal, or use --password-stdin or $%s", config.EnvPassword),
+	},
+	{
+		errs:   []error{ssh.ErrSudoPassword},
+		code:   exitAuth,
+		reason: "auth",
+	},
+	{
+		errs:   []error{ssh.ErrHostUnreachable, ssh.ErrNotConnected, ssh.ErrConnectionLost},
+		code:   exitNetwork,
+		reason: "network",
+		hint:   "check that the host is running and reachable from this machine; on slow links try --profile slow",
+	},
+	{
+		errs:   []error{tunnel.ErrPortInUse},
+		code:   exitPortConflict,
+		reason: "port_conflict",
+		hint:   "free the local port or forward to another one; without /fail and --strict-ports the next free port is used",
+	},
+	{
+		errs:   []error{ide.ErrIDENotInstalled},
+		code:   exitInstall,
+		reason: "not_installed",
+		hint:   "install it with: devssh install HOST",
+	},
+	{
+		errs:   []error{preflight.ErrRequirementsNotMet},
+		code:   exitInstall,
+		reason: "requirements",
+	},
+	{
+		errs:   []error{ide.ErrInstallFailed},
+		code:   exitInstall,
+		reason: "install",
+		hint:   "if the remote host cannot download the IDE, pass a pre-downloaded archive with --local-tar",
+	},
+	{
+		errs:   []error{exec.ErrNotFound},
+		code:   exitError,
+		reason: "missing_program",
+		hint:   "install the missing program or add it to PATH",
+	},
+}
+
+// classifyError 返回err所属的分类，没有匹配时返回nil
+func classifyError(err error) *errorClass {
+	for i := range errorClasses {
+		for _, target := range errorClasses[i].errs {
+			if errors.Is(err, target) {
+				return &errorClasses[i]
+			}
+		}
+	}
+	return nil
+}
+
+// exitCode 返回命令失败时的退出码
+func exitCode(err error) int {
+	if class := classifyError(err); class != nil {
+		return class.code
+	}
+	return exitError
+}
+
+// errorHint 返回失败后给用户的提示，没有时为空
+func errorHint(err error) string {
+	if class := classifyError(err); class != nil {
+		return class.hint
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+	"devssh/pkg/tunnel"
+
+	"github.com/spf13/cobra"
+)
+
+// addForwardControlCmds 为forward命令添加管理运行中会话的子命令
+func addForwardControlCmds(cmd *cobra.Command) {
+	cmd.AddCommand(
+		newForwardAddCmd(),
+		newForwardRemoveCmd(),
+		newForwardListCmd(),
+	)
+}
+
+func newForwardAddCmd() *cobra.Command {
+	var session string
+
+	cmd := &cobra.Command{
+		Use:   "add [NAME=][BIND:]LOCAL:[HOST:]REMOTE[/POLICY]...",
+		Short: "Forward more ports in a running session",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			socketPath, err := sessionSocket(session)
+			if err != nil {
+				return err
+			}
+
+			for _, arg := range args {
+				forward, err := tunnel.ForwardFromSpec(arg)
+				if err != nil {
+					return err
+				}
+				forwards, err := tunnel.SendControl(socketPath, tunnel.ControlRequest{
+					Action:     tunnel.ControlAdd,
+					Name:       forward.Name,
+					LocalHost:  forward.LocalHost,
+					LocalPort:  forward.LocalPort,
+					RemoteHost: forward.RemoteHost,
+					RemotePort: forward.RemotePort,
+					Conflict:   forward.Conflict,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to forward %s: %w", arg, err)
+				}
+				for _, forward := range forwards {
+					logger.Infof("Forwarding %s (%s)", forward, forward.Name)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&session, "session", "", "Session ID (defaults to the only running session)")
+
+	return cmd
+}
+
+func newForwardRemoveCmd() *cobra.Command {
+	var (
+		session string
+		forget  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "remove PORT|NAME...",
+		Aliases: []string{"rm"},
+		Short:   "Stop forwards in a running session",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			socketPath, err := sessionSocket(session)
+			if err != nil {
+				return err
+			}
+
+			for _, arg := range args {
+				forwards, err := tunnel.SendControl(socketPath, tunnel.ControlRequest{
+					Action: tunnel.ControlRemove,
+					Target: arg,
+					Forget: forget,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to remove %s: %w", arg, err)
+				}
+				for _, forward := range forwards {
+					logger.Infof("Stopped %s (%s)", forward, forward.Name)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&session, "session", "", "Session ID (defaults to the only running session)")
+	cmd.Flags().BoolVar(&forget, "forget", false, "Also stop restoring this named forward in future sessions")
+
+	return cmd
+}
+
+func newForwardListCmd() *cobra.Command {
+	var session string
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List forwards of a running session",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			socketPath, err := sessionSocket(session)
+			if err != nil {
+				return err
+			}
+
+			forwards, err := tunnel.SendControl(socketPath, tunnel.ControlRequest{Action: tunnel.ControlList})
+			if err != nil {
+				return err
+			}
+
+			if format != outputTable {
+				if forwards == nil {
+					forwards = []tunnel.ForwardStatus{}
+				}
+				return printStructured(format, forwards)
+			}
+			printForwards(forwards, logger)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&session, "session", "", "Session ID (defaults to the only running session)")
+
+	return cmd
+}
+
+// sessionSocket 返回会话的控制socket路径，未指定会话时使用唯一运行中的会话
+func sessionSocket(id string) (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var running []config.ConnectionConfig
+	for _, conn := range cfg.ListConnections() {
+		if conn.IsAlive() {
+			running = append(running, conn)
+		}
+	}
+
+	if id == "" {
+		switch len(running) {
+		case 0:
+			return "", fmt.Errorf("no running sessions")
+		case 1:
+			id = running[0].ID
+		default:
+			ids := make([]string, 0, len(running))
+			for _, conn := range running {
+				ids = append(ids, fmt.Sprintf("%s (%s)", conn.ID, conn.Host))
+			}
+			sort.Strings(ids)
+			return "", fmt.Errorf("multiple sessions are running, choose one with --session: %s", strings.Join(ids, ", "))
+		}
+	} else {
+		conn, exists := cfg.GetConnection(id)
+		if !exists || !conn.IsAlive() {
+			return "", fmt.Errorf("session %s is not running", id)
+		}
+	}
+
+	return config.SessionSocketPath(id)
+}
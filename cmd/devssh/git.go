@@ -0,0 +1,66 @@
+package main
+
+import (
+	"devssh/pkg/config"
+	"devssh/pkg/gitsetup"
+	"devssh/pkg/secrets"
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+)
+
+// gitFlags 连接后写入远程的git配置
+type gitFlags struct {
+	identity    bool
+	tokenSecret string
+	tokenHost   string
+	tokenUser   string
+}
+
+// register 注册git引导相关的命令行参数
+func (f *gitFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.identity, "git-identity", false, "Copy the local git user.name and user.email to the remote host")
+	cmd.Flags().StringVar(&f.tokenSecret, "git-token-secret", "", "ID of the stored secret holding a git access token to install on the remote host (the local git credential helper is not forwarded; down --purge and ide uninstall remove the token)")
+	cmd.Flags().StringVar(&f.tokenHost, "git-token-host", gitsetup.DefaultTokenHost, "Git server the token is used for")
+	cmd.Flags().StringVar(&f.tokenUser, "git-token-user", gitsetup.DefaultTokenUser, "Username stored with the git token")
+}
+
+// applyDefaults 未显式指定的参数使用配置中的 defaults.git
+func (f *gitFlags) applyDefaults(changed func(string) bool, defaults config.GitDefaults) {
+	if !changed("git-identity") {
+		f.identity = defaults.Identity
+	}
+	if !changed("git-token-secret") && defaults.TokenSecret != "" {
+		f.tokenSecret = defaults.TokenSecret
+	}
+	if !changed("git-token-host") && defaults.TokenHost != "" {
+		f.tokenHost = defaults.TokenHost
+	}
+	if !changed("git-token-user") && defaults.TokenUser != "" {
+		f.tokenUser = defaults.TokenUser
+	}
+}
+
+// bootstrapGit 按参数复制提交者信息并安装令牌，什么都没有启用时直接返回
+func bootstrapGit(client *ssh.Client, f *gitFlags, logger log.Logger) error {
+	if !f.identity && f.tokenSecret == "" {
+		return nil
+	}
+
+	opts := gitsetup.Options{
+		Identity:  f.identity,
+		TokenHost: f.tokenHost,
+		TokenUser: f.tokenUser,
+	}
+	if f.tokenSecret != "" {
+		var store secrets.Store
+		token, err := resolveSecret(&store, f.tokenSecret)
+		if err != nil {
+			return err
+		}
+		opts.Token = token
+	}
+
+	return gitsetup.NewBootstrapper(client, logger).Run(opts)
+}
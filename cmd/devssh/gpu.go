@@ -0,0 +1,54 @@
+package main
+
+import (
+	"devssh/pkg/preflight"
+	"devssh/pkg/ssh"
+	"devssh/pkg/tunnel"
+
+	"github.com/loft-sh/log"
+)
+
+// mlServices --ml-ports 转发的常用机器学习服务端口，远程服务未启动时连接会失败，启动后即可访问
+var mlServices = []struct {
+	name string
+	port int
+}{
+	{"tensorboard", 6006},
+	{"mlflow", 5000},
+	{"ray-dashboard", 8265},
+}
+
+// reportGPU 连接后检测远程GPU环境并输出概况，检测失败不影响会话
+func reportGPU(client *ssh.Client, logger log.Logger) {
+	gpu, err := preflight.CollectGPU(client)
+	if err != nil {
+		logger.Debugf("%v", err)
+		return
+	}
+	if gpu != nil {
+		logger.Infof("GPU: %s", gpu.Summary())
+	}
+}
+
+// mlForwards 返回常用机器学习服务的转发，跳过已由其他转发占用的远程端口
+func mlForwards(existing []tunnel.ForwardConfig) []tunnel.ForwardConfig {
+	taken := make(map[int]bool)
+	for _, forward := range existing {
+		if forward.RemoteHost == "" {
+			taken[forward.RemotePort] = true
+		}
+	}
+
+	var forwards []tunnel.ForwardConfig
+	for _, service := range mlServices {
+		if taken[service.port] {
+			continue
+		}
+		forwards = append(forwards, tunnel.ForwardConfig{
+			Name:       service.name,
+			LocalPort:  service.port,
+			RemotePort: service.port,
+		})
+	}
+	return forwards
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+
+	"devssh/pkg/config"
+	"devssh/pkg/gitsetup"
+	"devssh/pkg/ide"
+	"devssh/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+func newIDECmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ide",
+		Short: "Manage the web IDE installed on a remote host",
+	}
+
+	cmd.AddCommand(
+		newIDEUpgradeCmd(),
+		newIDEUninstallCmd(),
+		newIDEPluginsCmd(),
+	)
+
+	return cmd
+}
+
+func newIDEUpgradeCmd() *cobra.Command {
+	var (
+		flags   sshFlags
+		runAs   runAsFlags
+		limits  limitFlags
+		ideType string
+		version string
+		idePort int
+		force   bool
+		archive string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upgrade [host]",
+		Short: "Upgrade the remote IDE, keeping extensions and settings and rolling back on failure",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			if err := ide.ValidateResourceLimits(limits.limits); err != nil {
+				return err
+			}
+			if err := ide.ValidateLocalArchive(archive); err != nil {
+				return err
+			}
+
+			if err := runAs.loadDefaults(cmd, args[0]); err != nil {
+				return err
+			}
+
+			client, err := connectSSH(cmd.Context(), args[0], &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ideConn, err := ideClient(client, runAs, logger)
+			if err != nil {
+				return err
+			}
+			ideInstaller := ide.NewInstallerWithOptions(ideConn, ide.IDE(ideType), nil, logger)
+			ideInstaller.SetVersion(version)
+			ideInstaller.SetContext(cmd.Context())
+			if err := ideInstaller.SetResourceLimits(limits.limits); err != nil {
+				return err
+			}
+			if err := ideInstaller.SetLocalArchive(archive); err != nil {
+				return err
+			}
+			// 重启时保留配置的IDE环境
+			if cfg, err := config.Load(); err == nil {
+				if env := cfg.ResolveHostDefaults(args[0]).Environment; env != nil {
+					if err := ideInstaller.SetEnvironment(*env); err != nil {
+						return err
+					}
+				}
+			}
+			if idePort == 0 {
+				idePort = ideInstaller.GetDefaultPort()
+			}
+
+			result, err := ideInstaller.Upgrade(idePort, force)
+			if err != nil {
+				return fmt.Errorf("failed to upgrade IDE: %w", err)
+			}
+
+			if format != outputTable {
+				return printStructured(format, result)
+			}
+			if result.Restarted {
+				logger.Infof("%s %s is running on port %d", ideType, result.Version, idePort)
+			}
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	runAs.register(cmd)
+	limits.register(cmd)
+	cmd.Flags().StringVar(&ideType, "ide", "vscode", "Web IDE type (vscode or code-server)")
+	cmd.Flags().StringVar(&version, "version", "", "IDE version to upgrade to (e.g. v1.105.1; defaults to the built-in version)")
+	cmd.Flags().IntVar(&idePort, "ide-port", 0, "Remote IDE port to restart (defaults to the IDE's default port)")
+	cmd.Flags().BoolVar(&force, "force", false, "Reinstall even if the requested version is already installed")
+	cmd.Flags().StringVar(&archive, "local-tar", "", "Upgrade to the IDE in this pre-downloaded archive instead of downloading it (set --version to the archive's version)")
+
+	return cmd
+}
+
+func newIDEUninstallCmd() *cobra.Command {
+	var (
+		flags     sshFlags
+		runAs     runAsFlags
+		ideType   string
+		purgeData bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "uninstall [host]",
+		Short: "Stop the remote IDE and remove its installation, services, logs and PID files",
+		Long: `Stop every IDE instance devssh started on the host, remove its systemd or
+cron services, logs, PID files and the installation itself, as well as the
+git token installed by up --git-token-secret.
+
+Installed extensions and settings are kept so a later install picks them up
+again; pass --purge-data to remove them as well.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+			host := args[0]
+
+			if err := runAs.loadDefaults(cmd, host); err != nil {
+				return err
+			}
+
+			client, err := connectSSH(cmd.Context(), host, &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			// 本地会话的IDE即将不存在，先结束它们
+			if _, err := stopLocalSessions(logger, "", host, client.GetConfig().Host); err != nil {
+				logger.Warnf("Failed to clear local session state: %v", err)
+			}
+
+			ideConn, err := ideClient(client, runAs, logger)
+			if err != nil {
+				return err
+			}
+			ideInstaller := ide.NewInstallerWithOptions(ideConn, ide.IDE(ideType), nil, logger)
+			if err := ideInstaller.Uninstall(purgeData); err != nil {
+				return fmt.Errorf("failed to uninstall %s: %w", ideType, err)
+			}
+			// up --git-token-secret 写入的令牌随IDE一起删除
+			return gitsetup.NewBootstrapper(client, logger).RemoveToken()
+		},
+	}
+
+	flags.register(cmd)
+	runAs.register(cmd)
+	cmd.Flags().StringVar(&ideType, "ide", "vscode", "Web IDE type (vscode, code-server, or an IDE plugin name)")
+	cmd.Flags().BoolVar(&purgeData, "purge-data", false, "Also remove installed extensions and settings")
+
+	return cmd
+}
+
+func newIDEPluginsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugins",
+		Short: "List IDE provider plugins usable with --ide",
+		Long: fmt.Sprintf(`List IDE provider plugins usable with --ide.
+
+A plugin is an executable named %[1]sNAME in the plugins directory of the
+devssh config directory; --ide NAME then uses it. devssh runs it with the action
+as its argument and a JSON request on stdin, e.g. {"action":"start","port":8080}.
+The plugin answers with JSON on stdout: {"script":"..."} holds a bash script
+that devssh runs on the remote host, and "describe" returns {"default_port":N}.
+Scripts for "installed" and "running" print true or false, "pid" prints the PID.
+Actions: describe, install, start, stop, uninstall, installed, running, pid,
+extensions, settings. Plugins cannot be used with service, logs or ide upgrade.`, ide.PluginPrefix),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			plugins, err := ide.ListPlugins()
+			if err != nil {
+				return err
+			}
+			if format != outputTable {
+				return printStructured(format, plugins)
+			}
+
+			if len(plugins) == 0 {
+				dir, _ := ide.PluginDir()
+				fmt.Printf("No IDE plugins in %s\n", dir)
+				return nil
+			}
+			for _, plugin := range plugins {
+				fmt.Printf("%s\t%s\n", plugin.Name, plugin.Path)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path"
+
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+	"devssh/pkg/ui"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+)
+
+// 主机导入来源
+const (
+	importFromSSH     = "ssh"
+	importFromAnsible = "ansible"
+	importFromHosts   = "hosts"
+)
+
+func newImportCmd() *cobra.Command {
+	var (
+		from        string
+		overwrite   bool
+		refresh     bool
+		dryRun      bool
+		interactive bool
+		match       []string
+		exclude     []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import hosts from the SSH config, an Ansible inventory or a hosts file",
+		Long: `Import hosts into the devssh config.
+
+Sources:
+  ssh      ~/.ssh/config (default, takes no file)
+  ansible  Ansible inventory in YAML or INI format; ansible_host, ansible_port,
+           ansible_user and ansible_ssh_private_key_file are read from host and
+           group variables
+  hosts    /etc/hosts-style file ("IP name [aliases...]") or one [user@]host[:port] per line
+
+Hosts can be narrowed with --match/--exclude glob patterns and picked by hand
+with --interactive. Existing hosts are kept unless --refresh (update address,
+port, user and key, keeping other settings) or --overwrite (replace) is given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			if overwrite && refresh {
+				return fmt.Errorf("--overwrite and --refresh cannot be used together")
+			}
+
+			var (
+				hosts  []config.HostConfig
+				source string
+				err    error
+			)
+			switch from {
+			case importFromSSH:
+				if len(args) > 0 {
+					return fmt.Errorf("--from ssh reads ~/.ssh/config and does not take a file")
+				}
+				source = "SSH config"
+				hosts, err = config.SSHHosts()
+			case importFromAnsible:
+				if len(args) == 0 {
+					return fmt.Errorf("--from ansible requires an inventory file")
+				}
+				source = args[0]
+				hosts, err = config.ParseAnsibleInventory(args[0])
+			case importFromHosts:
+				if len(args) == 0 {
+					return fmt.Errorf("--from hosts requires a hosts file")
+				}
+				source = args[0]
+				hosts, err = config.ParseHostsFile(args[0])
+			default:
+				return fmt.Errorf("unsupported import source %q (use ssh, ansible or hosts)", from)
+			}
+			if err != nil {
+				return err
+			}
+
+			if hosts, err = filterHosts(hosts, match, exclude); err != nil {
+				return err
+			}
+			if len(hosts) == 0 {
+				logger.Infof("No matching hosts found in %s", source)
+				return nil
+			}
+
+			if interactive {
+				if hosts, err = selectHosts(hosts); err != nil {
+					return err
+				}
+				if len(hosts) == 0 {
+					logger.Infof("No hosts selected")
+					return nil
+				}
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			if dryRun {
+				previewImport(cfg, hosts, overwrite, refresh, logger)
+				return nil
+			}
+
+			if refresh {
+				added, updated, err := cfg.RefreshHosts(hosts)
+				if err != nil {
+					return err
+				}
+				logger.Infof("Imported %d new and refreshed %d existing host(s) from %s", added, updated, source)
+				return nil
+			}
+
+			imported, err := cfg.ImportHosts(hosts, overwrite)
+			if err != nil {
+				return err
+			}
+
+			logger.Infof("Imported %d of %d host(s) from %s", imported, len(hosts), source)
+			if skipped := len(hosts) - imported; skipped > 0 {
+				logger.Infof("Skipped %d existing host(s), use --refresh or --overwrite to update them", skipped)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", importFromSSH, "Import source (ssh, ansible, hosts)")
+	cmd.Flags().StringSliceVar(&match, "match", []string{}, "Only import hosts matching this glob (e.g., 'prod-*'); can be repeated")
+	cmd.Flags().StringSliceVar(&exclude, "exclude", []string{}, "Skip hosts matching this glob; can be repeated")
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Choose the hosts to import from a list")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be imported without changing the config")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "Update address, port, user and key of existing hosts")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace hosts that already exist in the config")
+
+	return cmd
+}
+
+// filterHosts 按主机名过滤：匹配任一match（未指定时全部匹配）且不匹配任何exclude
+func filterHosts(hosts []config.HostConfig, match, exclude []string) ([]config.HostConfig, error) {
+	matchAny := func(patterns []string, name string) (bool, error) {
+		for _, pattern := range patterns {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	var filtered []config.HostConfig
+	for _, host := range hosts {
+		if len(match) > 0 {
+			ok, err := matchAny(match, host.Name)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		excluded, err := matchAny(exclude, host.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !excluded {
+			filtered = append(filtered, host)
+		}
+	}
+	return filtered, nil
+}
+
+// selectHosts 在终端中多选要导入的主机
+func selectHosts(hosts []config.HostConfig) ([]config.HostConfig, error) {
+	items := make([]string, len(hosts))
+	for i, host := range hosts {
+		items[i] = fmt.Sprintf("%-24s %s", host.Name, hostAddress(host))
+	}
+
+	indexes, err := ui.NewMultiSelect("Select hosts to import", items).Run()
+	if err != nil {
+		if errors.Is(err, ui.ErrCanceled) {
+			return nil, fmt.Errorf("import canceled")
+		}
+		return nil, err
+	}
+
+	selected := make([]config.HostConfig, 0, len(indexes))
+	for _, i := range indexes {
+		selected = append(selected, hosts[i])
+	}
+	return selected, nil
+}
+
+// previewImport 列出每个主机将执行的操作
+func previewImport(cfg *config.Config, hosts []config.HostConfig, overwrite, refresh bool, logger log.Logger) {
+	logger.Infof("Dry run, the config is not changed:")
+	for _, host := range hosts {
+		action := "add"
+		if existing, exists := cfg.GetHost(host.Name); exists {
+			switch {
+			case overwrite:
+				action = "replace"
+			case refresh && hostAddress(existing) != hostAddress(host) || refresh && existing.KeyPath != host.KeyPath:
+				action = "refresh"
+			case refresh:
+				action = "unchanged"
+			default:
+				action = "skip (exists)"
+			}
+		}
+		logger.Infof("  %-14s %-24s %s", action, host.Name, hostAddress(host))
+	}
+}
+
+// hostAddress 以 user@host:port 形式显示主机，IPv6地址带端口时加方括号
+func hostAddress(host config.HostConfig) string {
+	address := host.Host
+	if host.Port != "" && host.Port != "22" {
+		address = net.JoinHostPort(address, host.Port)
+	}
+	if host.Username != "" {
+		address = host.Username + "@" + address
+	}
+	return address
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"devssh/pkg/config"
+	"devssh/pkg/hooks"
+	"devssh/pkg/ide"
+	"devssh/pkg/logging"
+	"devssh/pkg/progress"
+	"devssh/pkg/tracing"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func newInstallCmd() *cobra.Command {
+	var (
+		opts  upOptions
+		hosts []string
+		all   bool
+		jobs  int
+	)
+
+	cmd := &cobra.Command{
+		Use:         "install [host...]",
+		Short:       "Install the web IDE on one or more hosts without starting it",
+		Annotations: map[string]string{resultAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			targets, err := resolveTargetHosts(args, hosts, all)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			if err := ide.ValidateLocalArchive(opts.localArchive); err != nil {
+				return err
+			}
+			opts.progress = progressReporter(cmd)
+			if err := opts.ssh.readPasswordStdin(); err != nil {
+				return err
+			}
+			logger.Infof("Installing on %d host(s) with up to %d in parallel...", len(targets), jobs)
+			results := runParallel(cmd.Context(), targets, jobs, logger, func(ctx context.Context, host string, hostLogger log.Logger, _ func()) error {
+				hostOpts := opts
+				hostOpts.host = host
+				hostOpts.applyDefaults(cmd, cfg)
+				return runInstall(ctx, &hostOpts, hostLogger)
+			})
+
+			return reportResults(cmd, results, logger)
+		},
+	}
+
+	opts.ssh.register(cmd)
+	opts.runAs.register(cmd)
+	cmd.Flags().StringVar(&opts.ideType, "ide", "vscode", "Web IDE type (vscode, code-server, or an IDE plugin name)")
+	cmd.Flags().StringVar(&opts.version, "version", "", "IDE version to install (defaults to the built-in version)")
+	cmd.Flags().StringSliceVar(&opts.extensions, "extension", []string{}, "IDE extensions to install (e.g., golang.go)")
+	cmd.Flags().StringSliceVar(&hosts, "hosts", []string{}, "Hosts to install on (e.g., host1,host2)")
+	cmd.Flags().BoolVar(&all, "all", false, "Install on every host from the devssh and SSH config files")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", defaultJobs, "Maximum number of hosts installed at the same time")
+	cmd.Flags().BoolVar(&opts.noHooks, "no-hooks", false, "Do not run the lifecycle hooks from the config")
+	cmd.Flags().BoolVar(&opts.skipPreflight, "skip-preflight", false, "Install the IDE even if the remote host does not meet the disk, memory or glibc requirements")
+	cmd.Flags().StringVar(&opts.localArchive, "local-tar", "", "Install the IDE from this pre-downloaded archive instead of downloading it (for hosts without internet access)")
+
+	return cmd
+}
+
+// runInstall 连接主机并安装IDE及其扩展和设置
+func runInstall(ctx context.Context, opts *upOptions, logger log.Logger) (retErr error) {
+	ctx, span := tracing.Start(ctx, "devssh.install",
+		attribute.String("devssh.host", opts.host),
+		attribute.String("devssh.ide", opts.ideType))
+	defer func() { tracing.End(span, retErr) }()
+
+	hookRunner := hooks.NewRunner(opts.hooks, logger)
+	hookEnv := hooks.Env{Host: opts.host, IDE: opts.ideType}
+	if err := hookRunner.Run(hooks.PreConnect, hookEnv); err != nil {
+		return err
+	}
+
+	tracker := newTracker(opts, logger, progress.PhaseConnect, progress.PhaseInstall)
+	step := tracker.Start(progress.PhaseConnect)
+	client, err := connectSSH(ctx, opts.host, &opts.ssh, logger)
+	step.End(err)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	hookRunner.SetClient(client)
+	if err := hookRunner.Run(hooks.PostConnect, hookEnv); err != nil {
+		return err
+	}
+
+	step = tracker.Start(progress.PhaseInstall)
+	_, freshInstall, err := prepareIDE(ctx, client, opts, logger)
+	step.End(err)
+	if err != nil {
+		return err
+	}
+	if freshInstall {
+		return hookRunner.Run(hooks.PostInstall, hookEnv)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"devssh/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// limitFlags 远程IDE进程的资源限制参数
+type limitFlags struct {
+	limits config.ResourceLimits
+}
+
+// register 注册资源限制相关的命令行参数
+func (f *limitFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&f.limits.Nice, "nice", 0, "Run the remote IDE with this nice value (-20 to 19, e.g. 10)")
+	cmd.Flags().StringVar(&f.limits.IOClass, "ionice", "", "I/O scheduling class for the remote IDE (idle, best-effort)")
+	cmd.Flags().StringVar(&f.limits.MemoryMax, "memory-max", "", "Memory cap for the remote IDE via systemd-run (e.g. 4G, 50%)")
+	cmd.Flags().StringVar(&f.limits.CPUQuota, "cpu-quota", "", "CPU cap for the remote IDE via systemd-run (e.g. 200% for two cores)")
+}
+
+// applyDefaults 未显式指定的参数使用配置中的 defaults.limits
+func (f *limitFlags) applyDefaults(changed func(string) bool, defaults config.ResourceLimits) {
+	if !changed("nice") {
+		f.limits.Nice = defaults.Nice
+	}
+	if !changed("ionice") {
+		f.limits.IOClass = defaults.IOClass
+	}
+	if !changed("memory-max") {
+		f.limits.MemoryMax = defaults.MemoryMax
+	}
+	if !changed("cpu-quota") {
+		f.limits.CPUQuota = defaults.CPUQuota
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+
+	"github.com/loft-sh/log"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// autoLogFile --log-file 不带值时使用状态目录下按会话命名的日志文件
+const autoLogFile = "auto"
+
+// transcriptPath 本次运行写入的日志文件，出错时提示用户
+var transcriptPath string
+
+// registerLogFlags 注册全局日志参数
+func registerLogFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output (debug level)")
+	cmd.PersistentFlags().BoolP("quiet", "q", false, "Quiet mode (only errors displayed)")
+	cmd.PersistentFlags().String("log-level", "", "Log level (debug, info, warn, error); overrides -v/-q and $DEVSSH_LOG_LEVEL")
+	cmd.PersistentFlags().String("log-file", config.EnvString(config.EnvLogFile, ""), "Also write a full debug transcript to this file (no value: per-session file in the state dir; $DEVSSH_LOG_FILE)")
+	cmd.PersistentFlags().Lookup("log-file").NoOptDefVal = autoLogFile
+	cmd.PersistentFlags().String("log-format", config.EnvString(config.EnvLogFormat, logging.FormatText), "Log format (text, json; $DEVSSH_LOG_FORMAT)")
+}
+
+// resolveLogFile 确定日志文件路径，auto时在状态目录的logs下按命令和时间命名
+func resolveLogFile(cmd *cobra.Command) (string, error) {
+	value, _ := cmd.Flags().GetString("log-file")
+	if value != autoLogFile {
+		return value, nil
+	}
+
+	stateDir, err := config.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s-%d.log",
+		strings.ReplaceAll(cmd.CommandPath(), " ", "-"),
+		time.Now().Format("20060102-150405"),
+		os.Getpid())
+	return filepath.Join(stateDir, "logs", name), nil
+}
+
+// resolveLogLevel 确定日志级别：--log-level > -v/-q > DEVSSH_LOG_LEVEL > info
+func resolveLogLevel(cmd *cobra.Command) (logrus.Level, error) {
+	if value, _ := cmd.Flags().GetString("log-level"); value != "" {
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --log-level: %w", err)
+		}
+		return level, nil
+	}
+
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	if verbose && quiet {
+		return 0, fmt.Errorf("--verbose and --quiet cannot be used together")
+	}
+	if verbose {
+		return logrus.DebugLevel, nil
+	}
+	if quiet {
+		return logrus.ErrorLevel, nil
+	}
+
+	if value := os.Getenv(config.EnvLogLevel); value != "" {
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %w", config.EnvLogLevel, err)
+		}
+		return level, nil
+	}
+
+	return logrus.InfoLevel, nil
+}
+
+// setupLogging 根据全局参数创建logger
+func setupLogging(cmd *cobra.Command) (log.Logger, error) {
+	level, err := resolveLogLevel(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// 结构化输出时日志改写到stderr，避免污染stdout
+	var out io.Writer = os.Stdout
+	if format, err := getOutputFormat(cmd); err == nil && format != outputTable {
+		out = os.Stderr
+	}
+
+	logFile, err := resolveLogFile(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	format, _ := cmd.Flags().GetString("log-format")
+
+	// 只显示错误时不输出源代码位置
+	logger, err := logging.InitWithOptions(logging.Options{
+		Out:          out,
+		Level:        level,
+		EnableCaller: level > logrus.ErrorLevel,
+		LogFile:      logFile,
+		Format:       format,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up logging: %w", err)
+	}
+
+	if logFile != "" {
+		transcriptPath = logFile
+		logger.Debugf("devssh %s: %s %s (started %s)", version, cmd.CommandPath(), strings.Join(cmd.Flags().Args(), " "), time.Now().Format(time.RFC3339))
+	}
+
+	return logger, nil
+}
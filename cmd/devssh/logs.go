@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"devssh/pkg/ide"
+	"devssh/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+func newLogsCmd() *cobra.Command {
+	var (
+		flags   sshFlags
+		runAs   runAsFlags
+		ideType string
+		idePort int
+		lines   int
+		follow  bool
+		since   time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs [host]",
+		Short: "Show remote IDE logs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			if err := runAs.loadDefaults(cmd, args[0]); err != nil {
+				return err
+			}
+
+			client, err := connectSSH(cmd.Context(), args[0], &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ideConn, err := ideClient(client, runAs, logger)
+			if err != nil {
+				return err
+			}
+			ideInstaller := ide.NewInstallerWithOptions(ideConn, ide.IDE(ideType), nil, logger)
+			ideInstaller.SetContext(cmd.Context())
+			if idePort == 0 {
+				idePort = ideInstaller.GetDefaultPort()
+			}
+
+			var out io.Writer = os.Stdout
+			if since > 0 {
+				filter := newSinceFilter(os.Stdout, time.Now().Add(-since), follow)
+				defer filter.Flush()
+				out = filter
+				// 按时间过滤需要从日志开头读取，--lines不再限制范围
+				lines = -1
+			}
+
+			err = ideInstaller.StreamLogs(idePort, lines, follow, out, os.Stderr)
+			if follow && errors.Is(err, context.Canceled) {
+				// -f 只能用Ctrl+C结束，属于正常退出
+				return nil
+			}
+			return err
+		},
+	}
+
+	flags.register(cmd)
+	runAs.register(cmd)
+	cmd.Flags().StringVar(&ideType, "ide", "vscode", "Web IDE type (vscode or code-server)")
+	cmd.Flags().IntVar(&idePort, "ide-port", 0, "Remote IDE port (defaults to the IDE's default port)")
+	cmd.Flags().IntVarP(&lines, "lines", "n", 100, "Number of lines to show (ignored with --since)")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
+	cmd.Flags().DurationVar(&since, "since", 0, "Only show lines newer than this duration (e.g. 10m, 2h)")
+
+	return cmd
+}
+
+// 日志行中的时间戳，例如 2025-01-02T15:04:05.123Z 或 2025-01-02 15:04:05，时区可选
+var logTimestampPattern = regexp.MustCompile(`(\d{4}-\d{2}-\d{2})[T ](\d{2}:\d{2}:\d{2})(?:[.,]\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+
+// maxPendingLines 第一个时间戳之前最多缓存的行数，超过时认为日志没有时间戳，不再过滤
+const maxPendingLines = 200
+
+// pendingFlushDelay -f 时第一个时间戳之前的行最多缓存的时间，日志没有时间戳时不必等满maxPendingLines行
+const pendingFlushDelay = 500 * time.Millisecond
+
+// sinceFilter 按行过滤早于指定时间的日志
+// 没有时间戳的行沿用上一行的判断结果（例如多行堆栈）；第一个时间戳之前的行由该时间戳决定
+type sinceFilter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	since   time.Time
+	buf     []byte
+	include bool
+	// decided 已经遇到时间戳，之前的行缓存在pending中
+	decided bool
+	pending [][]byte
+	// flushDelay 大于0时缓存的行在该时间后按没有时间戳输出
+	flushDelay time.Duration
+	timer      *time.Timer
+	// err 定时输出缓存行时的写入错误，由下一次Write返回
+	err error
+}
+
+// newSinceFilter 创建过滤器，follow时日志持续输出，缓存的行在pendingFlushDelay后输出
+func newSinceFilter(out io.Writer, since time.Time, follow bool) *sinceFilter {
+	f := &sinceFilter{out: out, since: since}
+	if follow {
+		f.flushDelay = pendingFlushDelay
+	}
+	return f
+}
+
+func (f *sinceFilter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	f.buf = append(f.buf, p...)
+	for {
+		idx := bytes.IndexByte(f.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		if err := f.writeLine(f.buf[:idx+1]); err != nil {
+			return 0, err
+		}
+		f.buf = f.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush 输出缓冲区中剩余的不完整行；整个日志没有时间戳时输出全部
+func (f *sinceFilter) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+
+	if len(f.buf) > 0 {
+		if err := f.writeLine(f.buf); err != nil {
+			return err
+		}
+		f.buf = nil
+	}
+	if !f.decided {
+		return f.decide(true)
+	}
+	return nil
+}
+
+func (f *sinceFilter) writeLine(line []byte) error {
+	prefix := line
+	if len(prefix) > 64 {
+		prefix = prefix[:64]
+	}
+
+	if ts, ok := parseLogTimestamp(prefix); ok {
+		if err := f.decide(!ts.Before(f.since)); err != nil {
+			return err
+		}
+	} else if !f.decided {
+		f.pending = append(f.pending, append([]byte(nil), line...))
+		if len(f.pending) > maxPendingLines {
+			return f.decide(true)
+		}
+		if f.flushDelay > 0 && f.timer == nil {
+			f.timer = time.AfterFunc(f.flushDelay, f.flushPending)
+		}
+		return nil
+	}
+
+	if !f.include {
+		return nil
+	}
+	return f.write(line)
+}
+
+// flushPending 到时仍没有遇到时间戳，认为日志没有时间戳，输出缓存的行
+func (f *sinceFilter) flushPending() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.decided {
+		f.err = f.decide(true)
+	}
+}
+
+// decide 设置之后的行是否输出，第一次调用时按同样的结果处理缓存的行
+func (f *sinceFilter) decide(include bool) error {
+	f.include = include
+	if f.decided {
+		return nil
+	}
+	f.decided = true
+	pending := f.pending
+	f.pending = nil
+	if !include {
+		return nil
+	}
+	for _, line := range pending {
+		if err := f.write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *sinceFilter) write(line []byte) error {
+	if _, err := f.out.Write(line); err != nil {
+		return fmt.Errorf("failed to write log line: %w", err)
+	}
+	return nil
+}
+
+// parseLogTimestamp 解析行首的时间戳，带时区（如openvscode的Z）时按该时区，否则按本地时间
+func parseLogTimestamp(prefix []byte) (time.Time, bool) {
+	match := logTimestampPattern.FindSubmatch(prefix)
+	if match == nil {
+		return time.Time{}, false
+	}
+	value := string(match[1]) + "T" + string(match[2])
+	zone := string(match[3])
+	if zone == "" {
+		ts, err := time.ParseInLocation("2006-01-02T15:04:05", value, time.Local)
+		return ts, err == nil
+	}
+	if zone != "Z" && !strings.Contains(zone, ":") {
+		zone = zone[:3] + ":" + zone[3:]
+	}
+	ts, err := time.Parse(time.RFC3339, value+zone)
+	return ts, err == nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseLogTimestamp(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		line  string
+		want  time.Time
+		found bool
+	}{
+		{"utc", "2025-01-02T15:04:05.123Z [info] started", time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"offset with colon", "2025-01-02T15:04:05+08:00 started", time.Date(2025, 1, 2, 7, 4, 5, 0, time.UTC), true},
+		{"offset without colon", "2025-01-02T15:04:05-0500 started", time.Date(2025, 1, 2, 20, 4, 5, 0, time.UTC), true},
+		{"space and comma", "2025-01-02 15:04:05,123 INFO started", time.Date(2025, 1, 2, 15, 4, 5, 0, time.Local), true},
+		{"bracketed", "[2025-01-02 15:04:05] started", time.Date(2025, 1, 2, 15, 4, 5, 0, time.Local), true},
+		{"no timestamp", "    at Object.<anonymous> (server.js:1:1)", time.Time{}, false},
+		{"date only", "2025-01-02 started", time.Time{}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, found := parseLogTimestamp([]byte(tc.line))
+			if found != tc.found {
+				t.Fatalf("found = %v, want %v", found, tc.found)
+			}
+			if found && !got.Equal(tc.want) {
+				t.Errorf("timestamp = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSinceFilter(t *testing.T) {
+	since := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			"drops older lines",
+			"2025-01-02T11:00:00Z old\n2025-01-02T12:30:00Z new\n",
+			"2025-01-02T12:30:00Z new\n",
+		},
+		{
+			"continuation lines follow the previous line",
+			"2025-01-02T11:00:00Z old\n  at old.js\n2025-01-02T12:30:00Z new\n  at new.js\n",
+			"2025-01-02T12:30:00Z new\n  at new.js\n",
+		},
+		{
+			"lines before the first timestamp follow it",
+			"banner\n2025-01-02T11:00:00Z old\n",
+			"",
+		},
+		{
+			"log without timestamps",
+			"first\nsecond\nlast without newline",
+			"first\nsecond\nlast without newline",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			filter := newSinceFilter(&out, since, false)
+			// 按小块写入，行可能被拆到多次Write中
+			for _, chunk := range chunks(tc.input, 7) {
+				if _, err := filter.Write([]byte(chunk)); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+			if err := filter.Flush(); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+			if out.String() != tc.want {
+				t.Errorf("output = %q, want %q", out.String(), tc.want)
+			}
+		})
+	}
+}
+
+// syncBuilder 可以在定时器的goroutine中写入的strings.Builder
+type syncBuilder struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.String()
+}
+
+func TestSinceFilterFollowWithoutTimestamps(t *testing.T) {
+	var out syncBuilder
+	filter := newSinceFilter(&out, time.Now().Add(-time.Hour), true)
+	defer filter.Flush()
+
+	if _, err := filter.Write([]byte("listening on 3000\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// -f 时不等满maxPendingLines行，也不等Flush
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("pending lines were not written while following")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := filter.Write([]byte("connection accepted\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := out.String(); got != "listening on 3000\nconnection accepted\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+// chunks 把s拆成最长n字节的片段
+func chunks(s string, n int) []string {
+	var parts []string
+	for len(s) > n {
+		parts = append(parts, s[:n])
+		s = s[n:]
+	}
+	if s != "" {
+		parts = append(parts, s)
+	}
+	return parts
+}
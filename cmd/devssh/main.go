@@ -5,24 +5,31 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"strconv"
-	"strings"
+	"os/signal"
+	"sort"
+	"syscall"
 	"time"
 
-	"devssh/pkg/ide"
+	"devssh/pkg/config"
 	"devssh/pkg/logging"
+	"devssh/pkg/notify"
 	"devssh/pkg/ssh"
+	"devssh/pkg/tracing"
 	"devssh/pkg/tunnel"
 
 	"github.com/loft-sh/log"
+	"github.com/skratchdot/open-golang/open"
 	"github.com/spf13/cobra"
 )
 
 var (
 	version = "0.1.2"
 	logger  log.Logger
+	// shutdownTracing 退出前刷新并关闭追踪导出器
+	shutdownTracing = func(context.Context) error { return nil }
 )
 
 func main() {
@@ -33,463 +40,417 @@ func main() {
 	cobra.EnableCommandSorting = false
 
 	rootCmd := &cobra.Command{
-		Use:     "devssh",
-		Short:   "DevSSH - SSH-based remote development environment setup",
+		Use:   "devssh",
+		Short: "DevSSH - SSH-based remote development environment setup",
+		Long: `DevSSH - SSH-based remote development environment setup
+
+Exit codes:
+  0    success
+  1    other errors
+  3    authentication failed (SSH login, host key verification or sudo password)
+  4    network error (host unreachable, connection timed out or lost)
+  5    IDE installation failed, the IDE is not installed, or the host does not meet its requirements
+  6    a local port for a forward is in use
+  130  interrupted
+
+With --output json, up, install, status and workspace up print a final JSON
+line after any progress events:
+  {"type":"result","command":"up","success":false,"exit_code":4,"reason":"network","error":"...","hint":"...","result":{...}}
+reason names the failure class (auth, host_key, network, port_conflict, install,
+not_installed, requirements, missing_program, interrupted) and result holds the
+command's output, such as the IDE URL for up or the host status for status.`,
 		Version: version,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			// 处理全局标志
-			verbose, _ := cmd.Flags().GetBool("verbose")
-			quiet, _ := cmd.Flags().GetBool("quiet")
-
-			// 根据标志设置日志级别
-			if verbose {
-				logger = logging.InitDebug()
-			} else if quiet {
-				logger = logging.InitQuiet()
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// 命令行传入的密码在任何输出前登记隐藏
+			if flag := cmd.Flags().Lookup("password"); flag != nil {
+				logging.RegisterSecret(flag.Value.String())
 			}
 
-			// 设置全局logger
+			configured, err := setupLogging(cmd)
+			if err != nil {
+				return err
+			}
+			logger = configured
+
+			// 设置全局logger，各子系统通过它获取日志配置
 			logging.SetGlobalLogger(logger)
+
+			if config.EnvBool(config.EnvSSHConfigCache, false) {
+				if err := config.PersistSSHConfigCache(); err != nil {
+					logger.Debugf("SSH config cache is not persisted: %v", err)
+				}
+			}
+
+			// 设置了OTEL_EXPORTER_OTLP_ENDPOINT时导出连接和安装过程的追踪数据
+			shutdown, err := tracing.Init(cmd.Context(), version)
+			if err != nil {
+				logger.Warnf("Tracing disabled: %v", err)
+				return nil
+			}
+			shutdownTracing = shutdown
+			return nil
 		},
 	}
 
 	// 添加全局标志
-	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output (debug level)")
-	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Quiet mode (only errors displayed)")
+	registerLogFlags(rootCmd)
+	rootCmd.PersistentFlags().StringP("output", "o", config.EnvString(config.EnvOutput, "table"), "Output format for list/status commands; json also streams up/install progress events and ends up/install/status with a result object (table, json, yaml; $DEVSSH_OUTPUT)")
+	// cobra自身输出的错误同样隐藏密钥
+	rootCmd.SetErr(logging.NewRedactWriter(os.Stderr))
 	// 禁用自动生成的completion命令
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
 	rootCmd.AddCommand(
 		newUpCmd(),
+		newInstallCmd(),
+		newIDECmd(),
+		newWorkspaceCmd(),
 		newForwardCmd(),
+		newSyncCmd(),
+		newMountCmd(),
+		newDownCmd(),
 		newListCmd(),
+		newStatusCmd(),
+		newPingCmd(),
+		newUICmd(),
+		newServiceCmd(),
+		newLogsCmd(),
+		newImportCmd(),
+		newCloudCmd(),
+		newSSHConfigCmd(),
+		newConfigCmd(),
+		newSecretCmd(),
 	)
 
-	if err := rootCmd.Execute(); err != nil {
+	// 捕获SIGINT/SIGTERM，通过context通知各命令有序退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// 第一次信号后恢复默认处理，清理卡住时再按一次Ctrl+C可直接结束进程
+		<-ctx.Done()
+		stop()
+	}()
+
+	executed, err := rootCmd.ExecuteContextC(ctx)
+	flushTracing()
+	printResult(executed, err)
+	if err != nil {
 		logger.Errorf("%v", err)
-		os.Exit(1)
+		if hint := errorHint(err); hint != "" {
+			logger.Infof("Hint: %s", hint)
+		}
+		if transcriptPath != "" {
+			logger.Errorf("Full log written to %s", transcriptPath)
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// flushTracing 导出尚未发送的span，收集端不可达时最多等待5秒
+func flushTracing() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Debugf("Failed to flush traces: %v", err)
 	}
 }
 
-func newUpCmd() *cobra.Command {
+func newForwardCmd() *cobra.Command {
 	var (
-		user     string
-		port     string
-		keyPath  string
-		password string
-		ideType  string
-		forwards []string
-		auto     bool
-		timeout  int
+		flags         sshFlags
+		forwards      []string
+		auto          bool
+		watch         bool
+		watchInterval time.Duration
+		idleTimeout   time.Duration
+		strictPorts   bool
+		proxyOpts     proxyFlags
+		notifyDesktop bool
 	)
 
 	cmd := &cobra.Command{
-		Use:   "up [host]",
-		Short: "Connect to remote host and setup development environment",
-		Args:  cobra.ExactArgs(1),
+		Use:   "forward [host]",
+		Short: "Forward ports from remote host to local machine",
+		Long: `Forward ports from a remote host to the local machine.
+
+Each forward is written like ssh -L: [NAME=][BIND:]LOCAL:[HOST:]REMOTE. BIND is the
+local listen address (default 127.0.0.1, use 0.0.0.0 to share the forward on
+your network) and HOST is reached from the remote host (default the remote
+host itself). IPv6 addresses go in brackets, e.g. [::1]:8080:80.
+
+Named forwards such as db=5432:dbhost:5432 are saved for the host and
+restored the next time you connect to it; "devssh forward remove --forget"
+drops them.
+
+When a local port is busy the next free port is used. Append /fail to a
+forward (or pass --strict-ports for all of them) to stop with an error
+instead, or /kill to be asked whether to stop the process holding the port.
+
+--auto and --watch forward common web ports. Add ports or ranges with
+"devssh config set defaults.auto_detect.include 9000-9100" and keep ports
+from ever being auto-forwarded with defaults.auto_detect.exclude (also
+settable per host under hosts.NAME.defaults).
+
+While a session (forward or up) is running, forwards can be changed without
+restarting it: type "a PORT" in the session, or use "devssh forward add",
+"devssh forward remove" and "devssh forward list" from another terminal.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// 获取logger
 			logger := logging.GetGlobalLogger()
-			host := args[0]
-
-			var client *ssh.Client
-			var err error
-
-			// 检查是否是SSH配置文件中的主机
-			parser := ssh.NewSSHConfigParser()
-			_, sshErr := parser.GetHost(host)
-			if sshErr == nil {
-				// 从SSH配置文件创建客户端，使用命令行参数覆盖
-				overrideConfig := &ssh.Config{
-					Host: host,
-
-					Username: user,
-					KeyPath:  keyPath,
-					Password: password,
-					Timeout:  time.Duration(timeout) * time.Second,
-				}
-				// 只有当用户显式提供了-p参数时才覆盖端口
-				if port != "22" {
-					overrideConfig.Port = port
-				}
-				client, err = ssh.NewClientFromSSHConfigWithLogger(host, overrideConfig, logger)
-				if err != nil {
-					return fmt.Errorf("failed to create client from SSH config: %w", err)
-				}
-			} else {
-				// 检查是否是特殊主机模式的错误
-				if strings.Contains(sshErr.Error(), "is a special pattern") {
-					return fmt.Errorf("cannot connect to %s: %v", host, sshErr)
-				}
-
-				// 如果不是SSH配置文件中的主机，使用传统方式
-				// Parse host if it contains user@host format
-				if strings.Contains(host, "@") {
-					parts := strings.Split(host, "@")
-					if len(parts) == 2 {
-						user = parts[0]
-						host = parts[1]
-					}
-				}
-
-				// 检查必需参数
-				if user == "" {
-					return fmt.Errorf("username is required when host is not in SSH config file. Use -u flag or user@host format")
-				}
-
-				// Create SSH config
-				sshConfig := &ssh.Config{
-					Host:     host,
-					Port:     port,
-					Username: user,
-					KeyPath:  keyPath,
-					Password: password,
-					Timeout:  time.Duration(timeout) * time.Second,
-				}
-
-				client = ssh.NewClientWithLogger(sshConfig, logger)
-			}
-
-			// 获取SSH配置信息
-			sshConfig := client.GetConfig()
-			logger.Infof("Connecting to %s@%s:%s...", sshConfig.Username, sshConfig.Host, sshConfig.Port)
-			if err := client.Connect(); err != nil {
-				return fmt.Errorf("failed to connect: %w", err)
+			// 主机默认值和保存的转发按去掉用户名的主机名查找
+			_, host, _, err := config.ParseHostArg(args[0])
+			if err != nil {
+				return err
 			}
-			defer client.Close()
-			logger.Infof("Connected successfully")
-
-			// Create IDE installer with logger
-			ideInstaller := ide.NewInstallerWithOptions(client, ide.IDE(ideType), nil, logger)
 
-			// Check if IDE is installed
-			logger.Infof("Checking if %s is installed...", ideType)
-			installed, err := ideInstaller.IsInstalled()
+			// 配置的自动检测端口增减
+			cfg, err := config.Load()
 			if err != nil {
-				return fmt.Errorf("failed to check IDE installation: %w", err)
+				return fmt.Errorf("failed to load config: %w", err)
 			}
-
-			// Install IDE if not installed
-			if !installed {
-				logger.Infof("%s is not installed. Installing...", ideType)
-				if err := ideInstaller.Install(); err != nil {
-					return fmt.Errorf("failed to install IDE: %w", err)
-				}
-				logger.Infof("%s installed successfully", ideType)
-			} else {
-				logger.Infof("%s is already installed", ideType)
+			portFilter, err := autoDetectFilter(cfg.ResolveDefaults(host).AutoDetect)
+			if err != nil {
+				return err
 			}
 
-			// Start IDE
-			defaultPort := ideInstaller.GetDefaultPort()
-			logger.Infof("Starting %s on port %d...", ideType, defaultPort)
-			if err := ideInstaller.Start(defaultPort); err != nil {
-				return fmt.Errorf("failed to start IDE: %w", err)
+			client, err := connectSSH(cmd.Context(), args[0], &flags, logger)
+			if err != nil {
+				return err
 			}
-			logger.Infof("%s started on port %d", ideType, defaultPort)
+			defer client.Close()
 
 			// Create tunnel manager
 			tunnelManager := tunnel.NewTunnelManagerWithLogger(logger)
+			defer stopTunnels(tunnelManager, logger)
+			defer tunnelManager.Follow(client)()
+			if strictPorts {
+				tunnelManager.SetConflictPolicy(tunnel.ConflictFail)
+			}
+			tunnelManager.OnConflict(confirmKillPortOwner(logger))
+			tunnelManager.SetPortFilter(portFilter)
 
 			// Parse forward ports
-			var forwardConfigs []tunnel.ForwardConfig
+			var forwardConfigs, named []tunnel.ForwardConfig
 			if auto {
 				forwardConfigs = append(forwardConfigs, tunnel.ForwardConfig{AutoDetect: true})
 			} else {
-				for _, forward := range forwards {
-					parts := strings.Split(forward, ":")
-					if len(parts) == 1 {
-						// Single port: forward remote port to same local port
-						port, err := strconv.Atoi(parts[0])
-						if err != nil {
-							return fmt.Errorf("invalid port: %s", parts[0])
-						}
-						forwardConfigs = append(forwardConfigs, tunnel.ForwardConfig{
-							LocalPort:  port,
-							RemotePort: port,
-						})
-					} else if len(parts) == 2 {
-						// Local:Remote port mapping
-						localPort, err := strconv.Atoi(parts[0])
-						if err != nil {
-							return fmt.Errorf("invalid local port: %s", parts[0])
-						}
-						remotePort, err := strconv.Atoi(parts[1])
-						if err != nil {
-							return fmt.Errorf("invalid remote port: %s", parts[1])
-						}
-						forwardConfigs = append(forwardConfigs, tunnel.ForwardConfig{
-							LocalPort:  localPort,
-							RemotePort: remotePort,
-						})
-					}
+				named, err = parseForwards(forwards)
+				if err != nil {
+					return err
 				}
-
-				// Always forward IDE port
-				forwardConfigs = append(forwardConfigs, tunnel.ForwardConfig{
-					LocalPort:  defaultPort,
-					RemotePort: defaultPort,
-				})
+				forwardConfigs = named
 			}
+			forwardConfigs = withSavedTunnels(host, forwardConfigs, logger)
 
 			// Create port forwards
 			portResults, err := tunnel.CreatePortForwards(client, forwardConfigs, tunnelManager)
 			if err != nil {
 				return fmt.Errorf("failed to create port forwards: %w", err)
 			}
+			saveNamedForwards(host, named, logger)
 
 			// List active tunnels
 			tunnels := tunnelManager.ListTunnels()
 			logger.Infof("Active port forwards:")
 			for name, info := range tunnels {
-				logger.Infof("  %s: localhost:%d -> remote:%d", name, info.LocalPort, info.RemotePort)
+				logger.Infof("  %s: %s", name, info)
 			}
 
-			// 查找IDE端口的实际转发端口
-			actualIDEPort := defaultPort
-			foundInResults := false
+			// 本地反向代理
+			proxy, err := startProxy(client, proxyOpts, logger)
+			if err != nil {
+				return err
+			}
+			if proxy != nil {
+				defer proxy.Close()
+				registerProxyForwards(proxy, portResults, logger)
+			}
 
-			// 首先从portResults中查找
-			for _, result := range portResults {
-				if result.RemotePort == defaultPort {
-					actualIDEPort = result.ActualPort
-					foundInResults = true
-					break
-				}
+			controller := tunnel.NewController(client, tunnelManager, logger)
+			controller.SetStore(hostTunnelStore{host: host})
+			defer recordSession(client, tunnelManager, sessionInfo{host: host, controller: controller}, logger)()
+
+			// 关闭长时间没有流量的转发
+			if idleTimeout > 0 {
+				go tunnelManager.RunIdleReaper(cmd.Context(), idleTimeout, nil)
 			}
 
-			// 如果没有在portResults中找到，从隧道管理器中查找
-			if !foundInResults {
-				tunnels := tunnelManager.ListTunnels()
-				for _, info := range tunnels {
-					// 查找转发到IDE远程端口的隧道
-					if info.RemotePort == defaultPort {
-						actualIDEPort = info.LocalPort
-						break
-					}
-				}
+			// 持续监视远程端口，自动转发新出现的Web服务
+			if watch {
+				notifier := notify.NewNotifier(notifyDesktop, logger)
+				go watchPorts(cmd.Context(), client, tunnelManager, watchInterval, notifier, proxy, logger)
 			}
 
-			logger.Infof("%s is now accessible at http://localhost:%d", ideType, actualIDEPort)
 			logger.Infof("Press Ctrl+C to stop...")
+			startForwardConsole(cmd.Context(), controller, tunnelManager, logger)
 
-			// Wait for interrupt
+			// Wait for interrupt or connection loss
 			select {
 			case <-cmd.Context().Done():
 				logger.Infof("Stopping...")
+			case <-waitConnectionLost(client):
+				return fmt.Errorf("%w: %s", ssh.ErrConnectionLost, host)
 			}
 
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVarP(&user, "user", "u", "", "SSH username")
-	cmd.Flags().StringVarP(&port, "port", "p", "22", "SSH port")
-	cmd.Flags().StringVar(&keyPath, "key", "", "SSH private key path")
-	cmd.Flags().StringVar(&password, "password", "", "SSH password")
-	cmd.Flags().StringVar(&ideType, "ide", "vscode", "Web IDE type (vscode, code-server)")
-	cmd.Flags().StringSliceVar(&forwards, "forward", []string{}, "Ports to forward (e.g., 3000, 8080:80)")
+	flags.register(cmd)
+	cmd.Flags().StringSliceVar(&forwards, "ports", []string{}, "Ports to forward as [name=][bind:]local:[host:]remote[/increment|fail|kill] (e.g., 3000, 8080:80/fail, db=0.0.0.0:5432:dbhost:5432)")
 	cmd.Flags().BoolVar(&auto, "auto", false, "Auto-detect and forward web service ports")
-	cmd.Flags().IntVar(&timeout, "timeout", 30, "SSH connection timeout in seconds")
+	cmd.Flags().BoolVar(&watch, "watch", false, "Keep watching remote ports and forward new web services as they appear")
+	cmd.Flags().DurationVar(&watchInterval, "watch-interval", tunnel.DefaultWatchInterval, "How often --watch scans remote ports")
+	cmd.Flags().DurationVar(&idleTimeout, "idle-timeout", 0, "Close port forwards without traffic for this long (e.g. 30m; 0 disables)")
+	cmd.Flags().BoolVar(&strictPorts, "strict-ports", false, "Fail instead of picking another local port when one is busy (per forward: PORT/fail, PORT/kill)")
+	proxyOpts.register(cmd)
+	cmd.Flags().BoolVar(&notifyDesktop, "notify", false, "Send desktop notifications when forwards are added or removed")
+
+	addForwardControlCmds(cmd)
 
 	return cmd
 }
 
-func newForwardCmd() *cobra.Command {
-	var (
-		user     string
-		port     string
-		keyPath  string
-		password string
-		forwards []string
-		auto     bool
-		timeout  int
-	)
-
+func newListCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "forward [host]",
-		Short: "Forward ports from remote host to local machine",
-		Args:  cobra.ExactArgs(1),
+		Use:   "list",
+		Short: "List hosts from SSH config file and active sessions",
+		Long: `List hosts from the SSH config file and active sessions.
+
+A session is listed while its local devssh process is running. The remote host
+is not contacted, so a session whose remote IDE has exited is still shown;
+use "devssh status <host>" to check the remote side.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// 获取logger
 			logger := logging.GetGlobalLogger()
-			host := args[0]
-
-			// Parse host if it contains user@host format
-			if strings.Contains(host, "@") {
-				parts := strings.Split(host, "@")
-				if len(parts) == 2 {
-					user = parts[0]
-					host = parts[1]
-				}
-			}
 
-			var client *ssh.Client
-			var err error
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
 
-			// 检查是否是SSH配置文件中的主机
 			parser := ssh.NewSSHConfigParser()
-			_, sshErr := parser.GetHost(host)
-			if sshErr == nil {
-				// 从SSH配置文件创建客户端，使用命令行参数覆盖
-				overrideConfig := &ssh.Config{
-					Host: host,
-
-					Username: user,
-					KeyPath:  keyPath,
-					Password: password,
-					Timeout:  time.Duration(timeout) * time.Second,
-				}
-				// 只有当用户显式提供了-p参数时才覆盖端口
-				if port != "22" {
-					overrideConfig.Port = port
-				}
-				client, err = ssh.NewClientFromSSHConfigWithLogger(host, overrideConfig, logger)
-				if err != nil {
-					return fmt.Errorf("failed to create client from SSH config: %w", err)
-				}
-			} else {
-				// 检查是否是特殊主机模式的错误
-				if strings.Contains(sshErr.Error(), "is a special pattern") {
-					return fmt.Errorf("cannot connect to %s: %v", host, sshErr)
-				}
-
-				// 如果不是SSH配置文件中的主机，使用传统方式
-				// Parse host if it contains user@host format
-				if strings.Contains(host, "@") {
-					parts := strings.Split(host, "@")
-					if len(parts) == 2 {
-						user = parts[0]
-						host = parts[1]
-					}
-				}
-
-				// 检查必需参数
-				if user == "" {
-					return fmt.Errorf("username is required when host is not in SSH config file. Use -u flag or user@host format")
-				}
-
-				// Create SSH config
-				sshConfig := &ssh.Config{
-					Host:     host,
-					Port:     port,
-					Username: user,
-					KeyPath:  keyPath,
-					Password: password,
-					Timeout:  time.Duration(timeout) * time.Second,
-				}
+			hosts, err := parser.ListHosts()
+			if err != nil {
+				return fmt.Errorf("failed to list SSH hosts: %w", err)
+			}
+			sort.Strings(hosts)
 
-				client = ssh.NewClientWithLogger(sshConfig, logger)
+			// 列出会话前清理本地进程已退出的记录
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
 			}
-			sshConfig := client.GetConfig()
-			logger.Infof("Connecting to %s@%s:%s...", sshConfig.Username, sshConfig.Host, sshConfig.Port)
-			if err := client.Connect(); err != nil {
-				return fmt.Errorf("failed to connect: %w", err)
+			stale, err := cfg.PruneStaleConnections()
+			if err != nil {
+				logger.Warnf("%v", err)
+			}
+			for _, conn := range stale {
+				logger.Debugf("Removed stale session %s (%s, PID %d)", conn.ID, conn.Host, conn.PID)
 			}
-			defer client.Close()
-			logger.Infof("Connected successfully")
 
-			// Create tunnel manager
-			tunnelManager := tunnel.NewTunnelManagerWithLogger(logger)
+			sessions := cfg.ListConnections()
+			sort.Slice(sessions, func(i, j int) bool {
+				return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+			})
+
+			if format != outputTable {
+				return printStructured(format, struct {
+					Hosts    []string                  `json:"hosts"`
+					Sessions []config.ConnectionConfig `json:"sessions"`
+				}{
+					Hosts:    hosts,
+					Sessions: sessions,
+				})
+			}
 
-			// Parse forward ports
-			var forwardConfigs []tunnel.ForwardConfig
-			if auto {
-				forwardConfigs = append(forwardConfigs, tunnel.ForwardConfig{AutoDetect: true})
+			if len(hosts) == 0 {
+				logger.Infof("No hosts found in SSH config file")
 			} else {
-				for _, forward := range forwards {
-					parts := strings.Split(forward, ":")
-					if len(parts) == 1 {
-						// Single port: forward remote port to same local port
-						port, err := strconv.Atoi(parts[0])
-						if err != nil {
-							return fmt.Errorf("invalid port: %s", parts[0])
-						}
-						forwardConfigs = append(forwardConfigs, tunnel.ForwardConfig{
-							LocalPort:  port,
-							RemotePort: port,
-						})
-					} else if len(parts) == 2 {
-						// Local:Remote port mapping
-						localPort, err := strconv.Atoi(parts[0])
-						if err != nil {
-							return fmt.Errorf("invalid local port: %s", parts[0])
-						}
-						remotePort, err := strconv.Atoi(parts[1])
-						if err != nil {
-							return fmt.Errorf("invalid remote port: %s", parts[1])
-						}
-						forwardConfigs = append(forwardConfigs, tunnel.ForwardConfig{
-							LocalPort:  localPort,
-							RemotePort: remotePort,
-						})
-					}
+				logger.Infof("Hosts from SSH config file:")
+				for _, host := range hosts {
+					logger.Infof("  %s", host)
 				}
 			}
 
-			// Create port forwards
-			_, err = tunnel.CreatePortForwards(client, forwardConfigs, tunnelManager)
-			if err != nil {
-				return fmt.Errorf("failed to create port forwards: %w", err)
-			}
-
-			// List active tunnels
-			tunnels := tunnelManager.ListTunnels()
-			logger.Infof("Active port forwards:")
-			for name, info := range tunnels {
-				logger.Infof("  %s: localhost:%d -> remote:%d", name, info.LocalPort, info.RemotePort)
+			if len(sessions) == 0 {
+				logger.Infof("No active sessions")
+				return nil
 			}
 
-			logger.Infof("Press Ctrl+C to stop...")
-
-			// Wait for interrupt
-			select {
-			case <-cmd.Context().Done():
-				logger.Infof("Stopping...")
+			logger.Infof("Active sessions:")
+			for _, conn := range sessions {
+				logger.Infof("  %s: %s@%s (PID %d, started %s)", conn.ID, conn.Username, conn.Host, conn.PID, conn.StartedAt.Format(time.DateTime))
+				if conn.IDE != "" {
+					logger.Infof("    %s: http://localhost:%d -> remote:%d (remote PID %d)", conn.IDE, conn.LocalPort, conn.RemotePort, conn.RemotePID)
+				}
+				for _, forward := range conn.Forwards {
+					logger.Infof("    %s: %s", forward.Name, tunnel.FormatForward(forward.LocalHost, forward.LocalPort, forward.RemoteHost, forward.RemotePort))
+				}
 			}
 
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVarP(&user, "user", "u", "", "SSH username")
-	cmd.Flags().StringVarP(&port, "port", "p", "22", "SSH port")
-	cmd.Flags().StringVar(&keyPath, "key", "", "SSH private key path")
-	cmd.Flags().StringVar(&password, "password", "", "SSH password")
-	cmd.Flags().StringSliceVar(&forwards, "ports", []string{}, "Ports to forward (e.g., 3000, 8080:80)")
-	cmd.Flags().BoolVar(&auto, "auto", false, "Auto-detect and forward web service ports")
-	cmd.Flags().IntVar(&timeout, "timeout", 30, "SSH connection timeout in seconds")
-
 	return cmd
 }
 
-func newListCmd() *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "list",
-		Short: "List hosts from SSH config file",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// 获取logger
-			logger := logging.GetGlobalLogger()
-
-			parser := ssh.NewSSHConfigParser()
-			hosts, err := parser.ListHosts()
-			if err != nil {
-				return fmt.Errorf("failed to list SSH hosts: %w", err)
-			}
-
-			if len(hosts) == 0 {
-				logger.Infof("No hosts found in SSH config file")
-				return nil
-			}
+// stopTunnels 关闭所有端口转发
+func stopTunnels(manager *tunnel.TunnelManager, logger log.Logger) {
+	if err := manager.StopAllTunnels(); err != nil {
+		logger.Warnf("Failed to stop port forwards: %v", err)
+		return
+	}
+	logger.Debugf("All port forwards stopped")
+}
 
-			logger.Infof("Hosts from SSH config file:")
-			for _, host := range hosts {
-				logger.Infof("  %s", host)
-			}
+// openWhenReady 等待IDE响应后用系统默认浏览器打开
+func openWhenReady(ctx context.Context, url string, logger log.Logger) {
+	if err := tunnel.WaitForHTTP(ctx, url, 2*time.Minute); err != nil {
+		logger.Warnf("IDE did not become ready: %v", err)
+		return
+	}
 
-			return nil
-		},
+	logger.Infof("Opening %s in browser...", url)
+	if err := open.Start(url); err != nil {
+		logger.Warnf("Failed to open browser: %v", err)
 	}
+}
 
-	return cmd
+// waitConnectionLost 返回在SSH连接断开时关闭的channel
+func waitConnectionLost(client *ssh.Client) <-chan struct{} {
+	lost := make(chan struct{})
+	go func() {
+		client.GetClient().Wait()
+		close(lost)
+	}()
+	return lost
+}
+
+// watchPorts 持续监视远程端口，自动转发新出现的Web服务并移除消失的转发
+func watchPorts(ctx context.Context, client *ssh.Client, manager *tunnel.TunnelManager, interval time.Duration, notifier *notify.Notifier, proxy *tunnel.ReverseProxy, logger log.Logger) {
+	watcher := tunnel.NewPortWatcher(client, manager, logger)
+	watcher.SetInterval(interval)
+	watcher.OnEvent(func(event tunnel.PortEvent) {
+		logger.Debugf("Port watcher: %s", event)
+		switch event.Type {
+		case tunnel.PortForwarded, tunnel.PortRemoved:
+			notifier.Notify(notify.EventPorts, event.String())
+		}
+
+		// 自动检测到的服务同时注册到反向代理
+		if proxy == nil {
+			return
+		}
+		switch event.Type {
+		case tunnel.PortForwarded:
+			name := proxy.Register(tunnel.ProxyName(event.Info), event.RemotePort)
+			logger.Infof("%s is also available at %s", event.Service, proxy.URL(name))
+		case tunnel.PortRemoved:
+			proxy.Unregister(event.RemotePort)
+		}
+	})
+	watcher.Run(ctx)
 }
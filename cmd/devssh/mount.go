@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"devssh/pkg/logging"
+	"devssh/pkg/remotefs"
+	"devssh/pkg/ssh"
+
+	"github.com/spf13/cobra"
+)
+
+func newMountCmd() *cobra.Command {
+	var (
+		flags sshFlags
+		opts  remotefs.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "mount <host>:<remote-dir> <local-dir>",
+		Short: "Mount a remote directory locally over SFTP",
+		Long: `Mount a remote directory on a local directory over SFTP.
+
+The mount uses FUSE (libfuse/fusermount on Linux, macFUSE on macOS) and lasts
+as long as this command runs: it is unmounted on Ctrl+C or when the SSH
+connection is lost. Mounting is not available on other platforms; use
+"devssh sync" instead.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			host, remoteDir, ok := strings.Cut(args[0], ":")
+			if !ok || host == "" {
+				return fmt.Errorf("invalid source %q, expected <host>:<remote-dir>", args[0])
+			}
+			localDir := args[1]
+			if err := os.MkdirAll(localDir, 0755); err != nil {
+				return fmt.Errorf("failed to create mount point %s: %w", localDir, err)
+			}
+
+			client, err := connectSSH(cmd.Context(), host, &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			sftpClient, err := client.NewSFTPClient()
+			if err != nil {
+				return err
+			}
+			defer sftpClient.Close()
+
+			mount, err := remotefs.NewMount(sftpClient, remoteDir, localDir, opts, logger)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := mount.Unmount(); err != nil {
+					logger.Warnf("%v (close programs using it, then run: %s)", err, unmountHint(localDir))
+				}
+			}()
+
+			logger.Infof("Mounted %s on %s", args[0], localDir)
+			logger.Infof("Press Ctrl+C to unmount...")
+
+			select {
+			case <-cmd.Context().Done():
+				logger.Infof("Unmounting %s...", localDir)
+			case <-mount.Done():
+				logger.Infof("%s was unmounted", localDir)
+			case <-waitConnectionLost(client):
+				return fmt.Errorf("%w: %s", ssh.ErrConnectionLost, host)
+			}
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	cmd.Flags().BoolVar(&opts.ReadOnly, "read-only", false, "Mount the remote directory read-only")
+	cmd.Flags().DurationVar(&opts.CacheTimeout, "cache-timeout", remotefs.DefaultCacheTimeout, "How long file attributes and directory entries are cached locally")
+
+	return cmd
+}
+
+// unmountHint 手动卸载挂载点的命令
+func unmountHint(dir string) string {
+	for _, tool := range []string{"fusermount3", "fusermount"} {
+		if _, err := exec.LookPath(tool); err == nil {
+			return tool + " -u " + dir
+		}
+	}
+	return "umount " + dir
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+)
+
+// defaultJobs 多主机操作默认的并发数
+const defaultJobs = 4
+
+// hostResult 单个主机的执行结果
+type hostResult struct {
+	Host    string `json:"host"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Reason 失败的分类，见 errorClasses
+	Reason   string `json:"reason,omitempty"`
+	Duration string `json:"duration"`
+	// err 原始错误，用于确定退出码
+	err error
+}
+
+// hostsFailedError 部分主机失败，errors.Is按各主机的错误匹配
+type hostsFailedError struct {
+	failed, total int
+	errs          []error
+}
+
+func (e *hostsFailedError) Error() string {
+	return fmt.Sprintf("%d of %d hosts failed", e.failed, e.total)
+}
+
+func (e *hostsFailedError) Unwrap() []error {
+	return e.errs
+}
+
+// hostTask 在单个主机上执行的操作；release 可提前归还并发名额（如up完成准备阶段后）
+type hostTask func(ctx context.Context, host string, logger log.Logger, release func()) error
+
+// runParallel 以最多jobs个并发在各主机上执行task，结果顺序与hosts一致
+func runParallel(ctx context.Context, hosts []string, jobs int, logger log.Logger, task hostTask) []hostResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	results := make([]hostResult, len(hosts))
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			results[i].Host = host
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results[i].Error = ctx.Err().Error()
+				results[i].err = ctx.Err()
+				return
+			}
+			var once sync.Once
+			release := func() { once.Do(func() { <-slots }) }
+			defer release()
+
+			start := time.Now()
+			err := task(ctx, host, logging.WithHost(logger, host), release)
+			results[i].Duration = time.Since(start).Round(time.Millisecond).String()
+			if err != nil {
+				results[i].Error = err.Error()
+				results[i].err = err
+				if class := classifyError(err); class != nil {
+					results[i].Reason = class.reason
+				}
+				return
+			}
+			results[i].Success = true
+		}(i, host)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// reportResults 输出汇总结果，有主机失败时返回错误
+func reportResults(cmd *cobra.Command, results []hostResult, logger log.Logger) error {
+	format, err := getOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, result := range results {
+		if !result.Success {
+			errs = append(errs, result.err)
+		}
+	}
+	failed := len(errs)
+
+	if reportsResult(cmd) {
+		setResult(results)
+	} else if format != outputTable {
+		if err := printStructured(format, results); err != nil {
+			return err
+		}
+	} else {
+		logger.Infof("Summary: %d succeeded, %d failed", len(results)-failed, failed)
+		for _, result := range results {
+			if result.Success {
+				logger.Donef("  %s (%s)", result.Host, result.Duration)
+			} else {
+				logger.Errorf("  %s: %s", result.Host, result.Error)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return &hostsFailedError{failed: failed, total: len(results), errs: errs}
+	}
+	return nil
+}
+
+// resolveTargetHosts 合并参数和 --hosts 指定的主机；all为true时使用devssh配置和SSH配置中的全部主机
+func resolveTargetHosts(args, hosts []string, all bool) ([]string, error) {
+	seen := make(map[string]bool)
+	var targets []string
+	add := func(host string) {
+		host = strings.TrimSpace(host)
+		if host != "" && !seen[host] {
+			seen[host] = true
+			targets = append(targets, host)
+		}
+	}
+
+	for _, host := range args {
+		add(host)
+	}
+	for _, host := range hosts {
+		add(host)
+	}
+
+	if all {
+		var configured []string
+
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		for name := range cfg.Hosts {
+			configured = append(configured, name)
+		}
+
+		sshHosts, err := ssh.NewSSHConfigParser().ListHosts()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list SSH hosts: %w", err)
+		}
+		configured = append(configured, sshHosts...)
+
+		sort.Strings(configured)
+		for _, host := range configured {
+			add(host)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no hosts specified")
+	}
+	return targets, nil
+}
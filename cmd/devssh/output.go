@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"devssh/pkg/logging"
+	"devssh/pkg/progress"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+)
+
+// outputFormat 命令输出格式
+type outputFormat string
+
+const (
+	outputTable outputFormat = "table"
+	outputJSON  outputFormat = "json"
+	outputYAML  outputFormat = "yaml"
+)
+
+// getOutputFormat 读取全局 -o/--output 参数
+func getOutputFormat(cmd *cobra.Command) (outputFormat, error) {
+	value, _ := cmd.Flags().GetString("output")
+
+	switch format := outputFormat(value); format {
+	case outputTable, outputJSON, outputYAML:
+		return format, nil
+	case "":
+		return outputTable, nil
+	default:
+		return "", fmt.Errorf("unsupported output format %q (use table, json or yaml)", value)
+	}
+}
+
+// printStructured 以JSON或YAML格式输出到stdout
+func printStructured(format outputFormat, v interface{}) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch format {
+	case outputJSON:
+		data, err = json.MarshalIndent(v, "", "  ")
+		data = append(data, '\n')
+	case outputYAML:
+		data, err = yaml.Marshal(v)
+	default:
+		return fmt.Errorf("format %s is not a structured output format", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// progressReporter -o json时进度事件逐行以JSON写入stdout，供编辑器插件等程序读取；
+// 其他格式返回nil，进度以步骤行输出到日志
+func progressReporter(cmd *cobra.Command) progress.Reporter {
+	if format, err := getOutputFormat(cmd); err == nil && format == outputJSON {
+		return progress.NewJSONReporter(os.Stdout)
+	}
+	return nil
+}
+
+// resultAnnotation 标记 -o json 时结束后输出结果对象的命令
+const resultAnnotation = "devssh/result"
+
+// commandResult -o json时命令结束后写入stdout的最后一行，位于进度事件之后；
+// type固定为result，用于和进度事件区分
+type commandResult struct {
+	Type     string `json:"type"`
+	Command  string `json:"command"`
+	Success  bool   `json:"success"`
+	ExitCode int    `json:"exit_code"`
+	// Reason 失败的分类，与退出码对应，见 errorClasses
+	Reason string      `json:"reason,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	Hint   string      `json:"hint,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+// resultData 命令设置的结果内容，多主机的up在各主机的goroutine中执行
+var resultData struct {
+	sync.Mutex
+	value interface{}
+}
+
+// setResult 设置结果对象中的result
+func setResult(v interface{}) {
+	resultData.Lock()
+	defer resultData.Unlock()
+	resultData.value = v
+}
+
+// reportsResult 判断命令是否以结果对象代替结构化输出
+func reportsResult(cmd *cobra.Command) bool {
+	if cmd == nil || cmd.Annotations[resultAnnotation] == "" {
+		return false
+	}
+	format, err := getOutputFormat(cmd)
+	return err == nil && format == outputJSON
+}
+
+// printResult 输出命令的结果对象，err为命令返回的错误
+func printResult(cmd *cobra.Command, err error) {
+	if !reportsResult(cmd) {
+		return
+	}
+
+	resultData.Lock()
+	result := commandResult{
+		Type:    "result",
+		Command: strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "),
+		Success: err == nil,
+		Result:  resultData.value,
+	}
+	resultData.Unlock()
+	if err != nil {
+		result.ExitCode = exitCode(err)
+		result.Error = logging.Redact(err.Error())
+		if class := classifyError(err); class != nil {
+			result.Reason = class.reason
+			result.Hint = class.hint
+		}
+	}
+
+	line, marshalErr := json.Marshal(result)
+	if marshalErr != nil {
+		return
+	}
+	os.Stdout.Write(append(line, '\n'))
+}
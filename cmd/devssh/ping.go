@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net"
+	"time"
+
+	"devssh/pkg/logging"
+	"devssh/pkg/ssh"
+
+	"github.com/spf13/cobra"
+)
+
+// ideArchiveSize openvscode-server压缩包的大致大小，用于估算安装时的上传时间
+const ideArchiveSize = 80 * 1024 * 1024
+
+// pingReport ping命令的输出
+type pingReport struct {
+	Host string `json:"host"`
+	// TCP 建立TCP连接的时间，约等于一次网络往返
+	TCP time.Duration `json:"tcp"`
+	// Connect 解析主机、TCP连接、SSH握手和认证的总时间
+	Connect time.Duration `json:"connect"`
+	*ssh.PingResult
+	// IDEUpload 按上传速度估算的IDE安装包上传时间
+	IDEUpload time.Duration `json:"ide_upload,omitempty"`
+	Hints     []string      `json:"hints,omitempty"`
+}
+
+func newPingCmd() *cobra.Command {
+	var (
+		flags sshFlags
+		count int
+		size  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "ping [host]",
+		Short: "Measure SSH latency, authentication time and throughput to a host",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			start := time.Now()
+			client, err := connectSSH(cmd.Context(), args[0], &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+			report := pingReport{Host: args[0], Connect: time.Since(start)}
+
+			address := net.JoinHostPort(client.GetConfig().Host, client.GetConfig().Port)
+			start = time.Now()
+			if conn, err := net.DialTimeout("tcp", address, time.Duration(flags.timeout)*time.Second); err == nil {
+				report.TCP = time.Since(start)
+				conn.Close()
+			} else {
+				// 经过代理或跳板机的主机不能直接连接
+				logger.Debugf("Failed to measure TCP connect time to %s: %v", address, err)
+			}
+
+			if size > 0 {
+				logger.Infof("Measuring latency and transferring %d MiB each way...", size)
+			}
+			report.PingResult, err = client.Ping(count, int64(size)*1024*1024)
+			if err != nil {
+				return err
+			}
+			report.Hints = pingHints(&report)
+
+			if format != outputTable {
+				return printStructured(format, report)
+			}
+			printPingReport(report)
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	cmd.Flags().IntVarP(&count, "count", "c", 5, "Number of round trips to measure")
+	cmd.Flags().IntVar(&size, "size", 4, "MiB to upload and download for the throughput test (0 skips it)")
+
+	return cmd
+}
+
+// pingHints 根据测量结果给出安装和传输方式的建议
+func pingHints(report *pingReport) []string {
+	if report.Upload == 0 {
+		return nil
+	}
+	report.IDEUpload = time.Duration(ideArchiveSize / report.Upload * float64(time.Second)).Round(time.Second)
+
+	var hints []string
+	// 单个SSH通道的吞吐量受窗口大小和往返时间限制
+	if report.RTTAvg > 100*time.Millisecond && report.Upload < 5*1024*1024 {
+		hints = append(hints, "High latency limits a single SSH channel; try --parallel-transfer 4 for large uploads")
+	}
+	if report.IDEUpload > 2*time.Minute {
+		hints = append(hints, "Uploading the IDE will be slow; run devssh from a machine closer to the host or pass --local-tar with an archive already on a nearby machine")
+	}
+	return hints
+}
+
+func printPingReport(report pingReport) {
+	logger := logging.GetGlobalLogger()
+
+	logger.Infof("Host: %s", report.Host)
+	if report.TCP > 0 {
+		logger.Infof("TCP connect: %s", report.TCP.Round(100*time.Microsecond))
+	}
+	logger.Infof("SSH connect and auth: %s", report.Connect.Round(time.Millisecond))
+	logger.Infof("Round trip: min %s, avg %s, max %s",
+		report.RTTMin.Round(100*time.Microsecond), report.RTTAvg.Round(100*time.Microsecond), report.RTTMax.Round(100*time.Microsecond))
+	logger.Infof("Command overhead: %s", report.Exec.Round(time.Millisecond))
+	if report.Upload > 0 {
+		logger.Infof("Upload: %s", ssh.FormatThroughput(report.Upload))
+		logger.Infof("Download: %s", ssh.FormatThroughput(report.Download))
+		logger.Infof("Estimated IDE upload: %s", report.IDEUpload)
+	}
+	for _, hint := range report.Hints {
+		logger.Warnf("%s", hint)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"devssh/pkg/ssh"
+	"devssh/pkg/tunnel"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+)
+
+// proxyFlags 本地反向代理的参数，up和forward共用
+type proxyFlags struct {
+	port   int
+	routes []string
+}
+
+func (f *proxyFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&f.port, "proxy-port", 0, fmt.Sprintf("Serve forwarded web services on one local port as http://NAME.localhost:PORT or /NAME/ (e.g. %d; 0 disables)", tunnel.DefaultProxyPort))
+	cmd.Flags().StringSliceVar(&f.routes, "proxy-route", []string{}, "Extra reverse proxy route as NAME=REMOTE_PORT (e.g. api=8000)")
+}
+
+// startProxy 启用时启动本地反向代理并注册--proxy-route指定的路由，未启用时返回nil
+func startProxy(client *ssh.Client, flags proxyFlags, logger log.Logger) (*tunnel.ReverseProxy, error) {
+	if flags.port == 0 {
+		if len(flags.routes) > 0 {
+			return nil, fmt.Errorf("--proxy-route requires --proxy-port")
+		}
+		return nil, nil
+	}
+
+	proxy := tunnel.NewReverseProxy(client, logger)
+	for _, route := range flags.routes {
+		name, portValue, ok := strings.Cut(route, "=")
+		port, err := strconv.Atoi(portValue)
+		if !ok || err != nil || name == "" {
+			return nil, fmt.Errorf("invalid proxy route %q, expected NAME=REMOTE_PORT", route)
+		}
+		proxy.Register(name, port)
+	}
+
+	if err := proxy.Start(net.JoinHostPort(ssh.DefaultTunnelHost, strconv.Itoa(flags.port))); err != nil {
+		return nil, fmt.Errorf("failed to start reverse proxy: %w", err)
+	}
+	logger.Infof("Reverse proxy listening on http://localhost:%d", proxy.Port())
+	for _, route := range proxy.Routes() {
+		logger.Infof("  %s -> remote:%d", proxy.URL(route.Name), route.RemotePort)
+	}
+	return proxy, nil
+}
+
+// registerProxyForwards 为创建的转发注册代理路由：自动检测的服务按应用名，手动转发按远程端口号
+func registerProxyForwards(proxy *tunnel.ReverseProxy, results []tunnel.PortForwardResult, logger log.Logger) {
+	if proxy == nil {
+		return
+	}
+
+	for _, result := range results {
+		name := strconv.Itoa(result.RemotePort)
+		if result.Service.Port != 0 {
+			name = tunnel.ProxyName(result.Service)
+		}
+		name = proxy.Register(name, result.RemotePort)
+		logger.Infof("  %s -> remote:%d", proxy.URL(name), result.RemotePort)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"devssh/pkg/config"
+	"devssh/pkg/secrets"
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// runAsFlags 以其他远程用户安装和运行IDE的参数
+type runAsFlags struct {
+	user           string
+	sudo           bool
+	passwordSecret string
+}
+
+// register 注册远程用户相关的命令行参数
+func (f *runAsFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.user, "remote-user", "", "Install and run the IDE as this remote user via sudo -u")
+	cmd.Flags().BoolVar(&f.sudo, "sudo", false, "Install and run the IDE as root via sudo")
+	cmd.Flags().StringVar(&f.passwordSecret, "sudo-password-secret", "", "ID of the stored secret holding the remote sudo password (otherwise $"+config.EnvSudoPassword+", or prompted for when needed)")
+}
+
+// applyDefaults 未显式指定的参数使用配置中的默认值
+func (f *runAsFlags) applyDefaults(changed func(string) bool, defaults config.HostDefaults) {
+	if !changed("remote-user") && defaults.RemoteUser != "" {
+		f.user = defaults.RemoteUser
+	}
+	if !changed("sudo") && defaults.Sudo {
+		f.sudo = true
+	}
+	if !changed("sudo-password-secret") && defaults.SudoPasswordSecret != "" {
+		f.passwordSecret = defaults.SudoPasswordSecret
+	}
+}
+
+// loadDefaults 为不读取其他默认值的命令从配置加载主机的远程用户设置
+func (f *runAsFlags) loadDefaults(cmd *cobra.Command, host string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	changed := func(name string) bool {
+		flag := cmd.Flags().Lookup(name)
+		return flag != nil && flag.Changed
+	}
+	f.applyDefaults(changed, cfg.ResolveHostDefaults(host))
+	return nil
+}
+
+// sudoPromptAttempts 终端输入sudo密码的次数
+const sudoPromptAttempts = 3
+
+// ideClient 返回安装和运行IDE使用的连接；需要切换用户时检查sudo，需要密码时依次使用
+// 密钥存储、$DEVSSH_SUDO_PASSWORD，或在终端提示输入
+func ideClient(client *ssh.Client, f runAsFlags, logger log.Logger) (*ssh.Client, error) {
+	if f.user == "" && !f.sudo {
+		return client, nil
+	}
+
+	runAs := ssh.RunAs{User: f.user}
+	if f.passwordSecret != "" {
+		var store secrets.Store
+		password, err := resolveSecret(&store, f.passwordSecret)
+		if err != nil {
+			return nil, err
+		}
+		runAs.Password = password
+	} else {
+		runAs.Password = os.Getenv(config.EnvSudoPassword)
+	}
+
+	userClient := client.AsUser(runAs)
+	err := userClient.CheckSudo()
+	// 提供的密码被拒绝时不再提示，免得覆盖配置错误
+	if errors.Is(err, ssh.ErrSudoPassword) && runAs.Password == "" && term.IsTerminal(int(os.Stdin.Fd())) {
+		prompt := fmt.Sprintf("[sudo] password for %s@%s: ", client.GetConfig().Username, client.GetConfig().Host)
+		for attempt := 1; attempt <= sudoPromptAttempts && errors.Is(err, ssh.ErrSudoPassword); attempt++ {
+			if attempt > 1 {
+				fmt.Fprintln(os.Stderr, "Sorry, try again.")
+			}
+			password, readErr := readSecretValue(prompt)
+			if readErr != nil {
+				return nil, readErr
+			}
+			runAs.Password = password
+			userClient = client.AsUser(runAs)
+			err = userClient.CheckSudo()
+		}
+	}
+	if errors.Is(err, ssh.ErrSudoPassword) {
+		if runAs.Password != "" {
+			return nil, fmt.Errorf("%w: the remote host rejected the given password", ssh.ErrSudoPassword)
+		}
+		return nil, fmt.Errorf("%w on the remote host; run in a terminal, set $%s, pass --sudo-password-secret, or allow NOPASSWD sudo", ssh.ErrSudoPassword, config.EnvSudoPassword)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot run commands as %s via sudo: %w", userClient.RunAsUser(), err)
+	}
+
+	logger.Infof("IDE commands run as %s via sudo", userClient.RunAsUser())
+	return userClient, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+	"devssh/pkg/secrets"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// masterPassphraseEnv 非交互环境下提供加密文件主口令的环境变量
+const masterPassphraseEnv = "DEVSSH_MASTER_PASSPHRASE"
+
+func newSecretCmd() *cobra.Command {
+	var backend string
+
+	cmd := &cobra.Command{
+		Use:   "secret",
+		Short: "Manage passwords and passphrases in the OS keychain or an encrypted file",
+		Long: `Manage passwords and passphrases in the OS keychain or an encrypted file.
+
+The keychain backend uses the macOS login keychain (security) or the Secret
+Service on Linux (secret-tool). Windows has no keychain backend: secrets are
+always kept in the encrypted file, protected by a master passphrase that is
+asked for (twice when the file is created) or read from $DEVSSH_MASTER_PASSPHRASE.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&backend, "backend", "", "Secrets backend (auto, keychain, file; Windows always uses file); defaults to defaults.secrets_backend or $DEVSSH_SECRETS_BACKEND")
+
+	cmd.AddCommand(
+		newSecretSetCmd(&backend),
+		newSecretGetCmd(&backend),
+		newSecretRemoveCmd(&backend),
+		newSecretListCmd(&backend),
+	)
+
+	return cmd
+}
+
+func newSecretSetCmd(backend *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set [id]",
+		Short: "Store a secret (read from the terminal or stdin)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			store, err := openSecretStore(*backend)
+			if err != nil {
+				return err
+			}
+
+			value, err := readSecretValue(fmt.Sprintf("Value for %s: ", args[0]))
+			if err != nil {
+				return err
+			}
+			if value == "" {
+				return fmt.Errorf("secret value must not be empty")
+			}
+			logging.RegisterSecret(value)
+
+			if err := store.Set(args[0], value); err != nil {
+				return err
+			}
+
+			logger.Infof("Secret %s stored in %s backend", args[0], store.Backend())
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newSecretGetCmd(backend *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get [id]",
+		Short: "Print a stored secret to stdout",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openSecretStore(*backend)
+			if err != nil {
+				return err
+			}
+
+			value, err := store.Get(args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(value)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newSecretRemoveCmd(backend *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove [id]",
+		Short: "Remove a stored secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			store, err := openSecretStore(*backend)
+			if err != nil {
+				return err
+			}
+
+			if err := store.Delete(args[0]); err != nil {
+				return err
+			}
+
+			logger.Infof("Secret %s removed", args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newSecretListCmd(backend *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List secret IDs (encrypted file backend only)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			store, err := openSecretStore(*backend)
+			if err != nil {
+				return err
+			}
+
+			// 系统钥匙串不支持按服务列出条目
+			lister, ok := store.(interface{ List() ([]string, error) })
+			if !ok {
+				return fmt.Errorf("listing is not supported by the %s backend", store.Backend())
+			}
+
+			ids, err := lister.List()
+			if err != nil {
+				return err
+			}
+
+			if format != outputTable {
+				return printStructured(format, ids)
+			}
+			for _, id := range ids {
+				fmt.Println(id)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// openSecretStore 按参数或配置文件中的后端打开密钥存储
+func openSecretStore(backend string) (secrets.Store, error) {
+	if backend == "" {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		backend = cfg.ResolveDefaults("").SecretsBackend
+	}
+
+	return secrets.NewStore(secrets.Backend(backend), promptMasterPassphrase)
+}
+
+// resolveSecret 读取密钥存储中的值
+func resolveSecret(store *secrets.Store, id string) (string, error) {
+	if *store == nil {
+		s, err := openSecretStore("")
+		if err != nil {
+			return "", err
+		}
+		*store = s
+	}
+
+	value, err := (*store).Get(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", id, err)
+	}
+	logging.RegisterSecret(value)
+	return value, nil
+}
+
+// promptMasterPassphrase 从环境变量或终端读取加密文件的主口令，创建加密文件时在终端输入两次确认
+func promptMasterPassphrase(create bool) (string, error) {
+	if passphrase := os.Getenv(masterPassphraseEnv); passphrase != "" {
+		logging.RegisterSecret(passphrase)
+		return passphrase, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("no terminal to prompt for the master passphrase; set %s", masterPassphraseEnv)
+	}
+
+	if !create {
+		return readSecretValue("Master passphrase: ")
+	}
+	passphrase, err := readSecretValue("New master passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	confirm, err := readSecretValue("Confirm master passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != confirm {
+		return "", fmt.Errorf("master passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// readSecretValue 终端下不回显读取，否则从stdin读取全部内容
+func readSecretValue(prompt string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprint(os.Stderr, prompt)
+		value, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+		return string(value), nil
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+
+	"devssh/pkg/ide"
+	"devssh/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+func newServiceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Manage the remote IDE as a persistent user service",
+	}
+
+	cmd.AddCommand(
+		newServiceInstallCmd(),
+		newServiceStatusCmd(),
+		newServiceRemoveCmd(),
+	)
+
+	return cmd
+}
+
+func newServiceInstallCmd() *cobra.Command {
+	var (
+		flags   sshFlags
+		runAs   runAsFlags
+		limits  limitFlags
+		env     environmentFlags
+		ideType string
+		idePort int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "install [host]",
+		Short: "Install the remote IDE as a systemd user service (crontab fallback)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			if err := ide.ValidateResourceLimits(limits.limits); err != nil {
+				return err
+			}
+
+			if err := runAs.loadDefaults(cmd, args[0]); err != nil {
+				return err
+			}
+			if err := env.loadDefaults(args[0]); err != nil {
+				return err
+			}
+			environment, err := env.environment()
+			if err != nil {
+				return err
+			}
+
+			client, err := connectSSH(cmd.Context(), args[0], &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ideConn, err := ideClient(client, runAs, logger)
+			if err != nil {
+				return err
+			}
+			ideInstaller := ide.NewInstallerWithOptions(ideConn, ide.IDE(ideType), nil, logger)
+			if err := ideInstaller.SetResourceLimits(limits.limits); err != nil {
+				return err
+			}
+			// 服务由systemd或cron启动，IDE环境写入启动脚本
+			if err := ideInstaller.SetEnvironment(environment); err != nil {
+				return err
+			}
+			if idePort == 0 {
+				idePort = ideInstaller.GetDefaultPort()
+			}
+
+			manager, err := ideInstaller.InstallService(idePort)
+			if err != nil {
+				return fmt.Errorf("failed to install service: %w", err)
+			}
+
+			logger.Infof("%s on port %d installed as %s service", ideType, idePort, manager)
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	runAs.register(cmd)
+	limits.register(cmd)
+	env.register(cmd)
+	cmd.Flags().StringVar(&ideType, "ide", "vscode", "Web IDE type (vscode or code-server)")
+	cmd.Flags().IntVar(&idePort, "ide-port", 0, "Remote IDE port (defaults to the IDE's default port)")
+
+	return cmd
+}
+
+func newServiceStatusCmd() *cobra.Command {
+	var (
+		flags   sshFlags
+		runAs   runAsFlags
+		ideType string
+		idePort int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "status [host]",
+		Short: "Show the status of the remote IDE service",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			if err := runAs.loadDefaults(cmd, args[0]); err != nil {
+				return err
+			}
+
+			client, err := connectSSH(cmd.Context(), args[0], &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ideConn, err := ideClient(client, runAs, logger)
+			if err != nil {
+				return err
+			}
+			ideInstaller := ide.NewInstallerWithOptions(ideConn, ide.IDE(ideType), nil, logger)
+			if idePort == 0 {
+				idePort = ideInstaller.GetDefaultPort()
+			}
+
+			status, err := ideInstaller.GetServiceStatus(idePort)
+			if err != nil {
+				return fmt.Errorf("failed to get service status: %w", err)
+			}
+
+			if format != outputTable {
+				return printStructured(format, status)
+			}
+
+			logger.Infof("Service manager: %s", status.Manager)
+			if status.Manager == ide.ServiceSystemd {
+				logger.Infof("Unit: %s", status.Unit)
+			}
+			logger.Infof("Enabled: %t", status.Enabled)
+			logger.Infof("Active: %t", status.Active)
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	runAs.register(cmd)
+	cmd.Flags().StringVar(&ideType, "ide", "vscode", "Web IDE type (vscode or code-server)")
+	cmd.Flags().IntVar(&idePort, "ide-port", 0, "Remote IDE port (defaults to the IDE's default port)")
+
+	return cmd
+}
+
+func newServiceRemoveCmd() *cobra.Command {
+	var (
+		flags   sshFlags
+		runAs   runAsFlags
+		ideType string
+		idePort int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "remove [host]",
+		Short: "Stop and remove the remote IDE service",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			if err := runAs.loadDefaults(cmd, args[0]); err != nil {
+				return err
+			}
+
+			client, err := connectSSH(cmd.Context(), args[0], &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ideConn, err := ideClient(client, runAs, logger)
+			if err != nil {
+				return err
+			}
+			ideInstaller := ide.NewInstallerWithOptions(ideConn, ide.IDE(ideType), nil, logger)
+			if idePort == 0 {
+				idePort = ideInstaller.GetDefaultPort()
+			}
+
+			return ideInstaller.RemoveService(idePort)
+		},
+	}
+
+	flags.register(cmd)
+	runAs.register(cmd)
+	cmd.Flags().StringVar(&ideType, "ide", "vscode", "Web IDE type (vscode or code-server)")
+	cmd.Flags().IntVar(&idePort, "ide-port", 0, "Remote IDE port (defaults to the IDE's default port)")
+
+	return cmd
+}
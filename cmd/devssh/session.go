@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+	"devssh/pkg/ssh"
+	"devssh/pkg/tunnel"
+
+	"github.com/loft-sh/log"
+)
+
+// sessionInfo 记录会话时需要的信息
+type sessionInfo struct {
+	// id 会话ID，为空时自动生成
+	id         string
+	host       string
+	ide        string
+	localPort  int
+	remotePort int
+	remotePID  int
+	// controller 不为nil时通过控制socket接受 forward add/remove/list
+	controller *tunnel.Controller
+}
+
+// recordSession 将当前会话写入配置文件，返回退出时删除记录的清理函数
+func recordSession(client *ssh.Client, manager *tunnel.TunnelManager, info sessionInfo, logger log.Logger) func() {
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Warnf("Failed to load config, session will not be recorded: %v", err)
+		return func() {}
+	}
+
+	if info.id == "" {
+		info.id = config.NewSessionID()
+	}
+
+	sshConfig := client.GetConfig()
+	conn := config.ConnectionConfig{
+		ID:         info.id,
+		Host:       info.host,
+		Port:       sshConfig.Port,
+		Username:   sshConfig.Username,
+		IDE:        info.ide,
+		LocalPort:  info.localPort,
+		RemotePort: info.remotePort,
+		RemotePID:  info.remotePID,
+		Forwards:   forwardStates(manager),
+		StartedAt:  time.Now(),
+		PID:        os.Getpid(),
+	}
+
+	if err := cfg.AddConnection(conn); err != nil {
+		logger.Warnf("Failed to record session: %v", err)
+		return func() {}
+	}
+	logger.Debugf("Recorded session %s", conn.ID)
+	logging.SetContextField("session", conn.ID)
+
+	// 转发变化后同步到会话记录，供list/down显示
+	manager.OnChange(func() {
+		latest, err := config.Load()
+		if err == nil {
+			err = latest.SetConnectionForwards(conn.ID, forwardStates(manager))
+		}
+		if err != nil {
+			logger.Warnf("Failed to update session %s: %v", conn.ID, err)
+		}
+	})
+
+	var control net.Listener
+	if info.controller != nil {
+		control = serveSessionControl(conn.ID, info.controller, logger)
+	}
+
+	return func() {
+		manager.OnChange(nil)
+		if control != nil {
+			control.Close()
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			logger.Warnf("Failed to load config: %v", err)
+			return
+		}
+		if err := cfg.RemoveConnection(conn.ID); err != nil {
+			logger.Warnf("Failed to remove session %s: %v", conn.ID, err)
+		}
+	}
+}
+
+// forwardStates 将隧道管理器中的转发转换为可持久化的状态
+func forwardStates(manager *tunnel.TunnelManager) []config.ForwardState {
+	tunnels := manager.ListTunnels()
+
+	states := make([]config.ForwardState, 0, len(tunnels))
+	for name, info := range tunnels {
+		states = append(states, config.ForwardState{
+			Name:       name,
+			LocalHost:  info.LocalHost,
+			LocalPort:  info.LocalPort,
+			RemoteHost: info.RemoteHost,
+			RemotePort: info.RemotePort,
+		})
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+// serveSessionControl 在会话的控制socket上提供转发管理，失败时只记录警告
+func serveSessionControl(id string, controller *tunnel.Controller, logger log.Logger) net.Listener {
+	socketPath, err := config.SessionSocketPath(id)
+	if err == nil {
+		var listener net.Listener
+		if listener, err = controller.Listen(socketPath); err == nil {
+			logger.Debugf("Session control socket: %s", socketPath)
+			return listener
+		}
+	}
+	logger.Warnf("Live forward management is unavailable: %v", err)
+	return nil
+}
+
+// pickIDEPort 未指定端口时从默认端口开始，跳过同一主机上其他运行中会话的IDE端口，
+// 使多个会话可以各自运行一个IDE。会话是否运行只按本地进程判断（见 IsAlive），
+// 远程IDE已退出的会话端口同样跳过，多占一个端口不会导致冲突
+func pickIDEPort(requested, defaultPort int, hosts ...string) int {
+	if requested != 0 {
+		return requested
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return defaultPort
+	}
+	used := make(map[int]bool)
+	for _, conn := range cfg.ListConnections() {
+		if conn.IsAlive() && conn.RemotePort != 0 && slices.Contains(hosts, conn.Host) {
+			used[conn.RemotePort] = true
+		}
+	}
+
+	port := defaultPort
+	for used[port] {
+		port++
+	}
+	return port
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"devssh/pkg/config"
+	"devssh/pkg/logging"
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+)
+
+func newSSHConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ssh-config",
+		Short: "Manage the devssh block in ~/.ssh/config",
+	}
+
+	cmd.AddCommand(newSSHConfigExportCmd())
+
+	return cmd
+}
+
+func newSSHConfigExportCmd() *cobra.Command {
+	var (
+		dryRun bool
+		remove bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export [host...]",
+		Short: "Write devssh hosts to ~/.ssh/config so plain ssh can use them",
+		Long: `Write Host blocks for devssh hosts (all of them, or the given ones) into
+~/.ssh/config between "` + ssh.ManagedBlockBegin + `" and
+"` + ssh.ManagedBlockEnd + `" markers. Running it again replaces the block;
+the rest of the file is left untouched and a backup is kept next to it.
+
+Hosts that are already defined outside the block are skipped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+			parser := ssh.NewSSHConfigParser()
+
+			var hosts []ssh.ManagedHost
+			if remove {
+				if len(args) > 0 {
+					return fmt.Errorf("--remove does not take host arguments")
+				}
+			} else {
+				cfg, err := config.Load()
+				if err != nil {
+					return fmt.Errorf("failed to load config: %w", err)
+				}
+				if hosts, err = managedHosts(parser, cfg, args, logger); err != nil {
+					return err
+				}
+				if len(hosts) == 0 {
+					return fmt.Errorf("no hosts to export")
+				}
+			}
+
+			if dryRun {
+				return printManagedBlock(hosts)
+			}
+
+			if err := parser.WriteManagedHosts(hosts); err != nil {
+				return err
+			}
+
+			if remove {
+				logger.Infof("Removed devssh hosts from %s", parser.ConfigPath())
+			} else {
+				logger.Infof("Exported %d host(s) to %s", len(hosts), parser.ConfigPath())
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the block instead of writing it")
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the devssh block from the SSH config")
+
+	return cmd
+}
+
+// managedHosts 将devssh主机转换为SSH配置中的Host块，names为空时导出全部主机
+func managedHosts(parser *ssh.SSHConfigParser, cfg *config.Config, names []string, logger log.Logger) ([]ssh.ManagedHost, error) {
+	userHosts, err := parser.UserHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := len(names) > 0
+	if !explicit {
+		for name := range cfg.Hosts {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var hosts []ssh.ManagedHost
+	for _, name := range names {
+		host, exists := cfg.GetHost(name)
+		if !exists {
+			if explicit {
+				return nil, fmt.Errorf("host %s not found in devssh config", name)
+			}
+			continue
+		}
+		// 用户自己定义的同名主机优先，不重复写入
+		if userHosts[name] {
+			logger.Warnf("Skipping %s: already defined in %s", name, parser.ConfigPath())
+			continue
+		}
+		if host.Host == "" {
+			logger.Warnf("Skipping %s: no address configured", name)
+			continue
+		}
+
+		hosts = append(hosts, ssh.ManagedHost{
+			Alias:        name,
+			HostName:     host.Host,
+			User:         host.Username,
+			Port:         host.Port,
+			IdentityFile: host.KeyPath,
+		})
+	}
+
+	return hosts, nil
+}
+
+// printManagedBlock 输出将要写入的块
+func printManagedBlock(hosts []ssh.ManagedHost) error {
+	_, err := os.Stdout.WriteString(ssh.RenderManagedConfig("", hosts))
+	return err
+}
+
+// syncManagedSSHConfig 主机地址变化后刷新SSH配置中已导出的主机，没有导出过时不做任何事
+func syncManagedSSHConfig(cfg *config.Config, logger log.Logger) {
+	parser := ssh.NewSSHConfigParser()
+	exported, err := parser.ManagedHostNames()
+	if err != nil || len(exported) == 0 {
+		return
+	}
+
+	// 之后从devssh配置中删除的主机不再写入
+	var names []string
+	for _, name := range exported {
+		if _, exists := cfg.GetHost(name); exists {
+			names = append(names, name)
+		}
+	}
+
+	var hosts []ssh.ManagedHost
+	if len(names) > 0 {
+		hosts, err = managedHosts(parser, cfg, names, log.Discard)
+	}
+	if err == nil {
+		err = parser.WriteManagedHosts(hosts)
+	}
+	if err != nil {
+		logger.Warnf("Failed to update %s: %v", parser.ConfigPath(), err)
+		return
+	}
+	logger.Debugf("Updated devssh hosts in %s", parser.ConfigPath())
+}
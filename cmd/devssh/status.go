@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"devssh/pkg/devssh"
+	"devssh/pkg/ide"
+	"devssh/pkg/logging"
+	"devssh/pkg/preflight"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+)
+
+// hostStatus status命令的输出
+type hostStatus struct {
+	Host string `json:"host"`
+	*devssh.Status
+}
+
+func newStatusCmd() *cobra.Command {
+	var (
+		flags   sshFlags
+		runAs   runAsFlags
+		ideType string
+		idePort int
+	)
+
+	cmd := &cobra.Command{
+		Use:         "status [host]",
+		Short:       "Show the remote host's resources, GPUs, container runtimes and whether the IDE is installed and running",
+		Annotations: map[string]string{resultAnnotation: "true"},
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			if err := runAs.loadDefaults(cmd, args[0]); err != nil {
+				return err
+			}
+
+			client, err := connectSSH(cmd.Context(), args[0], &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ideConn, err := ideClient(client, runAs, logger)
+			if err != nil {
+				return err
+			}
+			// 以IDE用户收集，磁盘空间对应该用户的主目录
+			session := devssh.NewSession(ideConn, devssh.Options{Logger: logger})
+			result, err := session.Status(cmd.Context(), ide.IDE(ideType), idePort)
+			if err != nil {
+				return err
+			}
+			status := hostStatus{Host: args[0], Status: result}
+
+			if reportsResult(cmd) {
+				setResult(status)
+				return nil
+			}
+			if format != outputTable {
+				return printStructured(format, status)
+			}
+			printHostStatus(status, logger)
+			return nil
+		},
+	}
+
+	flags.register(cmd)
+	runAs.register(cmd)
+	cmd.Flags().StringVar(&ideType, "ide", "vscode", "Web IDE type (vscode, code-server, or an IDE plugin name)")
+	cmd.Flags().IntVar(&idePort, "ide-port", 0, "Remote IDE port (defaults to the IDE's default port)")
+
+	return cmd
+}
+
+// printHostStatus 以文本形式输出主机状态，未获取到的项显示为unknown
+func printHostStatus(status hostStatus, logger log.Logger) {
+	info := status.System
+	orUnknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	bytesOrUnknown := func(n int64) string {
+		if n == 0 {
+			return "unknown"
+		}
+		return preflight.FormatBytes(n)
+	}
+
+	logger.Infof("Host: %s", status.Host)
+	logger.Infof("System: %s %s (kernel %s)", orUnknown(info.OS), orUnknown(info.Arch), orUnknown(info.Kernel))
+	if info.CPUs > 0 {
+		logger.Infof("CPUs: %d", info.CPUs)
+	}
+	if len(info.Load) == 3 {
+		logger.Infof("Load average: %.2f %.2f %.2f", info.Load[0], info.Load[1], info.Load[2])
+	}
+	libc := orUnknown(info.Libc)
+	if info.GlibcVersion != "" {
+		libc += " " + info.GlibcVersion
+	}
+	logger.Infof("C library: %s", libc)
+	logger.Infof("Memory: %s available of %s", bytesOrUnknown(info.MemAvailable), bytesOrUnknown(info.MemTotal))
+	logger.Infof("Disk (home): %s free of %s", bytesOrUnknown(info.DiskFree), bytesOrUnknown(info.DiskTotal))
+	logger.Infof("GPU: %s", info.GPU.Summary())
+	runtimes := "none"
+	if len(info.ContainerRuntimes) > 0 {
+		runtimes = strings.Join(info.ContainerRuntimes, ", ")
+	}
+	logger.Infof("Container runtimes: %s", runtimes)
+
+	ideState := "not installed"
+	if status.IDERunning {
+		ideState = fmt.Sprintf("running on port %d", status.IDEPort)
+	} else if status.IDEInstalled {
+		ideState = "installed, not running"
+	}
+	logger.Infof("%s: %s", status.IDE, ideState)
+
+	for _, issue := range status.Issues {
+		if issue.Fatal && !status.IDEInstalled {
+			logger.Errorf("IDE cannot be installed: %s", issue.Message)
+		} else {
+			logger.Warnf("%s", issue.Message)
+		}
+	}
+}
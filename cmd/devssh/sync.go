@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"devssh/pkg/filesync"
+	"devssh/pkg/logging"
+	"devssh/pkg/ssh"
+	"devssh/pkg/tunnel"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+)
+
+func newSyncCmd() *cobra.Command {
+	var (
+		flags          sshFlags
+		opts           filesync.Options
+		watch          bool
+		watchInterval  time.Duration
+		deltaThreshold int64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync <local-dir> <host>:<remote-dir>",
+		Short: "Incrementally copy a local directory to a remote host",
+		Long: `Incrementally copy a local directory to a remote host.
+
+Only files whose size or modification time differ (or content, with --checksum)
+are sent, packed into a single compressed stream. Large files that already
+exist on the remote host are compared block by block and only the changed
+blocks are sent. Use --watch to keep syncing as the local directory changes.
+
+Exclude patterns without a slash match a file or directory name at any depth
+(e.g. .git, node_modules, *.pyc); patterns with a slash match from the root of
+the synced directory (e.g. build/*.o).`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			localDir := args[0]
+			info, err := os.Stat(localDir)
+			if err != nil {
+				return fmt.Errorf("failed to access %s: %w", localDir, err)
+			}
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", localDir)
+			}
+
+			host, remoteDir, ok := strings.Cut(args[1], ":")
+			if !ok || host == "" {
+				return fmt.Errorf("invalid destination %q, expected <host>:<remote-dir>", args[1])
+			}
+			if remoteDir == "" {
+				remoteDir = "~"
+			}
+
+			client, err := connectSSH(cmd.Context(), host, &flags, logger)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			opts.DeltaThreshold = deltaThreshold
+			syncer := filesync.NewSyncer(client, localDir, remoteDir, opts, logger)
+
+			if !watch {
+				result, err := syncer.Sync()
+				if err != nil {
+					return err
+				}
+				printSyncResult(result, opts.DryRun, logger)
+				return nil
+			}
+
+			logger.Infof("Watching %s, syncing to %s:%s every %v (Ctrl+C to stop)", localDir, host, remoteDir, watchInterval)
+			return syncer.Watch(cmd.Context(), watchInterval, func(result *filesync.Result, err error) {
+				if err != nil {
+					logger.Warnf("Sync failed: %v", err)
+					return
+				}
+				printSyncResult(result, opts.DryRun, logger)
+			})
+		},
+	}
+
+	flags.register(cmd)
+	cmd.Flags().BoolVarP(&opts.Checksum, "checksum", "c", false, "Compare file contents by checksum instead of size and modification time")
+	cmd.Flags().BoolVar(&opts.Delete, "delete", false, "Delete remote files that do not exist locally")
+	cmd.Flags().StringSliceVarP(&opts.Excludes, "exclude", "x", []string{}, "Paths to skip (e.g., .git,node_modules,build/*.o)")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Show what would be transferred without changing the remote directory")
+	cmd.Flags().IntVar(&opts.BlockSize, "block-size", filesync.DefaultBlockSize, "Block size in bytes for delta transfer of large files")
+	cmd.Flags().Int64Var(&deltaThreshold, "delta-threshold", filesync.DefaultDeltaThreshold, "Minimum size in bytes of files sent as block deltas (-1 always sends whole files)")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Keep watching the local directory and sync changes continuously")
+	cmd.Flags().DurationVar(&watchInterval, "watch-interval", filesync.DefaultWatchInterval, "How often --watch scans the local directory")
+
+	return cmd
+}
+
+// printSyncResult 输出一次同步传输、更新和删除的路径
+func printSyncResult(result *filesync.Result, dryRun bool, logger log.Logger) {
+	if !result.Changed() {
+		logger.Infof("Already up to date")
+		return
+	}
+
+	prefix := ""
+	if dryRun {
+		prefix = "(dry run) "
+	}
+	for _, rel := range result.Uploaded {
+		logger.Infof("%supload %s", prefix, rel)
+	}
+	for _, rel := range result.Patched {
+		logger.Infof("%spatch  %s", prefix, rel)
+	}
+	for _, rel := range result.Deleted {
+		logger.Infof("%sdelete %s", prefix, rel)
+	}
+
+	if dryRun {
+		return
+	}
+	logger.Infof("Synced %d uploaded, %d patched, %d deleted, sent %s in %v",
+		len(result.Uploaded), len(result.Patched), len(result.Deleted),
+		tunnel.FormatBytes(result.Bytes), result.Duration.Truncate(time.Millisecond))
+}
+
+// syncMount --mount 指定的一对本地和远程目录
+type syncMount struct {
+	local  string
+	remote string
+}
+
+// parseMounts 解析 --mount 参数（local:remote），以最后一个冒号分隔以兼容Windows盘符
+func parseMounts(specs []string) ([]syncMount, error) {
+	var mounts []syncMount
+	for _, spec := range specs {
+		idx := strings.LastIndex(spec, ":")
+		if idx <= 0 || idx == len(spec)-1 {
+			return nil, fmt.Errorf("invalid mount %q, expected <local-dir>:<remote-dir>", spec)
+		}
+		mount := syncMount{local: spec[:idx], remote: spec[idx+1:]}
+		info, err := os.Stat(mount.local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mount %q: %w", spec, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("invalid mount %q: %s is not a directory", spec, mount.local)
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+// followMount 把本地目录的修改实时推送到远程目录，直到ctx取消
+// 首次同步不删除远程多出的文件（如远程生成的构建产物），之后本地删除的文件同步删除
+func followMount(ctx context.Context, client *ssh.Client, mount syncMount, excludes []string, logger log.Logger) {
+	syncer := filesync.NewSyncer(client, mount.local, mount.remote, filesync.Options{Excludes: excludes}, logger)
+
+	logger.Infof("Pushing changes in %s to remote %s", mount.local, mount.remote)
+	err := syncer.Follow(ctx, func(result *filesync.Result, err error) {
+		if err != nil {
+			logger.Warnf("Failed to push %s: %v", mount.local, err)
+			return
+		}
+		if !result.Changed() {
+			return
+		}
+		logger.Infof("Pushed %s to %s: %d updated, %d deleted (%s)",
+			mount.local, mount.remote, len(result.Uploaded)+len(result.Patched), len(result.Deleted),
+			tunnel.FormatBytes(result.Bytes))
+		for _, rel := range append(append(result.Uploaded, result.Patched...), result.Deleted...) {
+			logger.Debugf("  %s", rel)
+		}
+	})
+	if err != nil {
+		logger.Warnf("Stopped pushing %s: %v", mount.local, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"devssh/pkg/config"
+	"devssh/pkg/tunnel"
+
+	"github.com/loft-sh/log"
+)
+
+// hostTunnelStore 把命名转发保存到主机配置中
+type hostTunnelStore struct {
+	host string
+}
+
+func (s hostTunnelStore) SaveTunnel(forward tunnel.ForwardConfig) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	return cfg.SaveHostTunnel(s.host, forward.Name, forward.Spec())
+}
+
+func (s hostTunnelStore) ForgetTunnel(name string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	return cfg.ForgetHostTunnel(s.host, name)
+}
+
+// withSavedTunnels 在转发列表后追加主机保存的命名转发，同名时以命令行为准
+func withSavedTunnels(host string, forwards []tunnel.ForwardConfig, logger log.Logger) []tunnel.ForwardConfig {
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Warnf("Failed to load saved forwards: %v", err)
+		return forwards
+	}
+	hostConfig, exists := cfg.GetHost(host)
+	if !exists || len(hostConfig.Tunnels) == 0 {
+		return forwards
+	}
+
+	given := make(map[string]bool)
+	for _, forward := range forwards {
+		if forward.Name != "" {
+			given[forward.Name] = true
+		}
+	}
+
+	names := make([]string, 0, len(hostConfig.Tunnels))
+	for name := range hostConfig.Tunnels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if given[name] {
+			continue
+		}
+		forward, err := tunnel.ForwardFromSpec(name + "=" + hostConfig.Tunnels[name])
+		if err != nil {
+			logger.Warnf("Ignoring saved forward %s: %v", name, err)
+			continue
+		}
+		logger.Infof("Restoring saved forward %s (%s)", name, forward)
+		forwards = append(forwards, forward)
+	}
+	return forwards
+}
+
+// saveNamedForwards 保存命令行中命名的转发，主机不在配置中时只提示一次
+func saveNamedForwards(host string, forwards []tunnel.ForwardConfig, logger log.Logger) {
+	store := hostTunnelStore{host: host}
+	for _, forward := range forwards {
+		if forward.Name == "" {
+			continue
+		}
+		if err := store.SaveTunnel(forward); err != nil {
+			logger.Warnf("Named forwards will not be restored next time: %v", err)
+			return
+		}
+	}
+}
+
+// autoDetectFilter 由配置的自动检测端口增减创建端口过滤器，未配置时返回nil
+func autoDetectFilter(ports *config.AutoDetectPorts) (*tunnel.PortFilter, error) {
+	if ports == nil {
+		return nil, nil
+	}
+	filter, err := tunnel.NewPortFilter(ports.Include, ports.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auto_detect config: %w", err)
+	}
+	return filter, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"devssh/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+func newUICmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ui",
+		Short: "Open a terminal dashboard of hosts, sessions and tunnels",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			executable, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate devssh executable: %w", err)
+			}
+
+			return ui.NewDashboard(executable).Run()
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,674 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"devssh/pkg/config"
+	"devssh/pkg/hooks"
+	"devssh/pkg/ide"
+	"devssh/pkg/logging"
+	"devssh/pkg/notify"
+	"devssh/pkg/preflight"
+	"devssh/pkg/progress"
+	"devssh/pkg/ssh"
+	"devssh/pkg/tracing"
+	"devssh/pkg/tunnel"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// upOptions up流程的全部参数，up命令和workspace up共用
+type upOptions struct {
+	host          string
+	ssh           sshFlags
+	ideType       string
+	version       string
+	folder        string
+	forwards      []string
+	extensions    []string
+	settings      string
+	auto          bool
+	watchPorts    bool
+	watchInterval time.Duration
+	idleTimeout   time.Duration
+	strictPorts   bool
+	proxy         proxyFlags
+	watchIDE      bool
+	keepRunning   bool
+	openBrowser   bool
+	notifyDesktop bool
+	// mounts 本地目录到远程目录的实时推送，格式为 local:remote
+	mounts        []string
+	mountExcludes []string
+	git           gitFlags
+	limits        limitFlags
+	env           environmentFlags
+	runAs         runAsFlags
+	// hooks 配置的生命周期钩子，--no-hooks 时为nil
+	hooks   *config.Hooks
+	noHooks bool
+	// mlPorts 转发TensorBoard、MLflow和Ray dashboard的端口
+	mlPorts bool
+	// skipPreflight 远程资源不满足安装要求时仍然安装
+	skipPreflight bool
+	// idePort 远程IDE端口，0表示默认端口，被同一主机的其他会话占用时顺延
+	idePort int
+	// localArchive 预先下载的IDE安装包，用于不能访问外网的环境
+	localArchive string
+	// autoDetect 配置的自动检测端口增减
+	autoDetect *config.AutoDetectPorts
+	// setup 工作区的远程准备任务
+	setup []config.SetupTask
+	// onReady 准备阶段完成、IDE可访问时调用，可为nil
+	onReady func()
+	// interactive 单主机前台会话，启用交互命令
+	interactive bool
+	// progress 接收各阶段的进度事件，为nil时以步骤行输出到日志
+	progress progress.Reporter
+}
+
+// registerSessionFlags 注册up与workspace up共用的会话参数
+func (o *upOptions) registerSessionFlags(cmd *cobra.Command) {
+	o.ssh.register(cmd)
+	cmd.Flags().BoolVar(&o.auto, "auto", false, "Auto-detect and forward web service ports")
+	cmd.Flags().BoolVar(&o.watchPorts, "watch", false, "Keep watching remote ports and forward new web services as they appear")
+	cmd.Flags().DurationVar(&o.watchInterval, "watch-interval", tunnel.DefaultWatchInterval, "How often --watch scans remote ports")
+	cmd.Flags().DurationVar(&o.idleTimeout, "idle-timeout", 0, "Close port forwards without traffic for this long, except the IDE (e.g. 30m; 0 disables)")
+	cmd.Flags().BoolVar(&o.strictPorts, "strict-ports", false, "Fail instead of picking another local port when one is busy (per forward: PORT/fail, PORT/kill)")
+	o.proxy.register(cmd)
+	cmd.Flags().BoolVar(&o.watchIDE, "watch-ide", true, "Restart the IDE automatically if it crashes")
+	cmd.Flags().BoolVar(&o.keepRunning, "keep-running", false, "Keep the remote IDE running after exit")
+	cmd.Flags().BoolVar(&o.openBrowser, "open", false, "Open the IDE in the browser once it is ready")
+	cmd.Flags().BoolVar(&o.notifyDesktop, "notify", false, "Send desktop notifications when the environment is ready or fails")
+	cmd.Flags().StringArrayVar(&o.mounts, "mount", []string{}, "Push local changes to the remote host as they happen, as local:remote (repeatable)")
+	cmd.Flags().StringSliceVar(&o.mountExcludes, "mount-exclude", []string{}, "Paths --mount does not push (e.g., .git,node_modules)")
+	o.git.register(cmd)
+	o.limits.register(cmd)
+	o.env.register(cmd)
+	o.runAs.register(cmd)
+	cmd.Flags().BoolVar(&o.noHooks, "no-hooks", false, "Do not run the lifecycle hooks from the config")
+	cmd.Flags().BoolVar(&o.mlPorts, "ml-ports", false, "Also forward TensorBoard (6006), MLflow (5000) and Ray dashboard (8265)")
+	cmd.Flags().IntVar(&o.idePort, "ide-port", 0, "Remote IDE port (defaults to the IDE's default port, or the next one not used by another session on the host)")
+	cmd.Flags().BoolVar(&o.skipPreflight, "skip-preflight", false, "Install the IDE even if the remote host does not meet the disk, memory or glibc requirements")
+	cmd.Flags().StringVar(&o.localArchive, "local-tar", "", "Install the IDE from this pre-downloaded archive instead of downloading it (for hosts without internet access)")
+}
+
+// applyDefaults 未显式指定的参数使用默认值（环境变量 > 主机默认值 > 全局默认值）
+func (o *upOptions) applyDefaults(cmd *cobra.Command, cfg *config.Config) {
+	changed := func(name string) bool {
+		flag := cmd.Flags().Lookup(name)
+		return flag != nil && flag.Changed
+	}
+
+	defaults := cfg.ResolveDefaults(o.host)
+	if !changed("open") {
+		o.openBrowser = defaults.OpenBrowser
+	}
+	if !changed("notify") {
+		o.notifyDesktop = defaults.Notify
+	}
+	if !changed("ide") && defaults.IDE != "" {
+		o.ideType = defaults.IDE
+	}
+	if !changed("version") && defaults.Version != "" {
+		o.version = defaults.Version
+	}
+	if !changed("folder") && defaults.Workdir != "" {
+		o.folder = defaults.Workdir
+	}
+	if !changed("forward") && len(defaults.Forwards) > 0 {
+		o.forwards = defaults.Forwards
+	}
+	if !changed("extension") && len(defaults.Extensions) > 0 {
+		o.extensions = defaults.Extensions
+	}
+	if len(defaults.Settings) > 0 {
+		o.settings = string(defaults.Settings)
+	}
+	if defaults.Git != nil {
+		o.git.applyDefaults(changed, *defaults.Git)
+	}
+	if defaults.Limits != nil {
+		o.limits.applyDefaults(changed, *defaults.Limits)
+	}
+	o.env.defaults = defaults.Environment
+	if !o.noHooks {
+		o.hooks = defaults.Hooks
+	}
+	if !changed("profile") && defaults.Profile != "" {
+		o.ssh.profile = defaults.Profile
+	}
+	o.autoDetect = defaults.AutoDetect
+	o.runAs.applyDefaults(changed, defaults.HostDefaults)
+}
+
+func newUpCmd() *cobra.Command {
+	var (
+		opts  upOptions
+		hosts []string
+		jobs  int
+	)
+
+	cmd := &cobra.Command{
+		Use:         "up [host]",
+		Short:       "Connect to remote host and setup development environment",
+		Annotations: map[string]string{resultAnnotation: "true"},
+		Args:        cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			opts.progress = progressReporter(cmd)
+			if len(hosts) == 0 {
+				if len(args) == 0 {
+					return fmt.Errorf("a host or --hosts is required")
+				}
+				opts.host = args[0]
+				opts.interactive = true
+				opts.applyDefaults(cmd, cfg)
+				return runUp(cmd.Context(), &opts, logging.GetGlobalLogger())
+			}
+
+			targets, err := resolveTargetHosts(args, hosts, false)
+			if err != nil {
+				return err
+			}
+			// 每台主机的代理都会监听同一个本地端口
+			if opts.proxy.port != 0 && len(targets) > 1 {
+				return fmt.Errorf("--proxy-port can only be used with a single host")
+			}
+			return runUpHosts(cmd, cfg, &opts, targets, jobs)
+		},
+	}
+
+	opts.registerSessionFlags(cmd)
+	cmd.Flags().StringVar(&opts.ideType, "ide", "vscode", "Web IDE type (vscode, code-server, or an IDE plugin name)")
+	cmd.Flags().StringVar(&opts.version, "version", "", "IDE version to install (defaults to the built-in version)")
+	cmd.Flags().StringVar(&opts.folder, "folder", "", "Remote folder to open in the IDE")
+	cmd.Flags().StringSliceVar(&opts.forwards, "forward", []string{}, "Ports to forward as [name=][bind:]local:[host:]remote[/increment|fail|kill] (e.g., 3000, 8080:80/fail, db=0.0.0.0:5432:dbhost:5432)")
+	cmd.Flags().StringSliceVar(&opts.extensions, "extension", []string{}, "IDE extensions to install (e.g., golang.go)")
+	cmd.Flags().StringSliceVar(&hosts, "hosts", []string{}, "Bring up several hosts concurrently (e.g., host1,host2)")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", defaultJobs, "Maximum number of hosts set up at the same time")
+
+	return cmd
+}
+
+// upResult 单个主机的up就绪后的状态，-o json时写入结果对象
+type upResult struct {
+	Host       string                `json:"host"`
+	IDE        string                `json:"ide"`
+	URL        string                `json:"url"`
+	LocalPort  int                   `json:"local_port"`
+	RemotePort int                   `json:"remote_port"`
+	Forwards   []config.ForwardState `json:"forwards"`
+}
+
+// runUpHosts 在多台主机上并发执行up，准备阶段最多jobs个并发，全部会话保持到ctx取消
+func runUpHosts(cmd *cobra.Command, cfg *config.Config, base *upOptions, hosts []string, jobs int) error {
+	logger := logging.GetGlobalLogger()
+
+	if err := base.ssh.readPasswordStdin(); err != nil {
+		return err
+	}
+	results := runParallel(cmd.Context(), hosts, jobs, logger, func(ctx context.Context, host string, hostLogger log.Logger, release func()) error {
+		opts := *base
+		opts.host = host
+		opts.applyDefaults(cmd, cfg)
+		// 准备完成后归还并发名额，会话继续保持
+		opts.onReady = release
+		return runUp(ctx, &opts, hostLogger)
+	})
+
+	return reportResults(cmd, results, logger)
+}
+
+// runUp 连接远程主机，安装并启动IDE，建立端口转发，直到ctx取消
+func runUp(ctx context.Context, opts *upOptions, logger log.Logger) (retErr error) {
+	host := opts.host
+
+	// 返回时结束看门狗、端口监视等后台任务；--hosts时其他主机的ctx仍未取消
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 失败时发送桌面通知
+	notifier := notify.NewNotifier(opts.notifyDesktop, logger)
+	defer func() {
+		if retErr != nil {
+			notifier.Notify(notify.EventFailure, fmt.Sprintf("%s: %v", host, retErr))
+		}
+	}()
+
+	// up的准备阶段（连接、安装、启动、转发）记录为一个根span，IDE可访问时结束
+	traceCtx, upSpan := tracing.Start(ctx, "devssh.up",
+		attribute.String("devssh.host", host),
+		attribute.String("devssh.ide", opts.ideType))
+	setupDone := false
+	defer func() {
+		if !setupDone {
+			tracing.End(upSpan, retErr)
+		}
+	}()
+
+	mounts, err := parseMounts(opts.mounts)
+	if err != nil {
+		return err
+	}
+	if err := ide.ValidateLocalArchive(opts.localArchive); err != nil {
+		return err
+	}
+	portFilter, err := autoDetectFilter(opts.autoDetect)
+	if err != nil {
+		return err
+	}
+	if err := ide.ValidateResourceLimits(opts.limits.limits); err != nil {
+		return err
+	}
+	if environment, err := opts.env.environment(); err != nil {
+		return err
+	} else if err := ide.ValidateEnvironment(environment); err != nil {
+		return err
+	}
+
+	sessionID := config.NewSessionID()
+	hookRunner := hooks.NewRunner(opts.hooks, logger)
+	hookEnv := hooks.Env{Host: host, IDE: opts.ideType, SessionID: sessionID}
+	if err := hookRunner.Run(hooks.PreConnect, hookEnv); err != nil {
+		return err
+	}
+
+	tracker := newTracker(opts, logger, progress.PhaseConnect, progress.PhasePrepare, progress.PhaseInstall,
+		progress.PhaseStart, progress.PhaseForward, progress.PhaseReady)
+	step := tracker.Start(progress.PhaseConnect)
+	client, err := connectSSH(traceCtx, host, &opts.ssh, logger)
+	step.End(err)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	hookRunner.SetClient(client)
+	if err := hookRunner.Run(hooks.PostConnect, hookEnv); err != nil {
+		return err
+	}
+	// post_stop在IDE和转发都停止之后执行，只在会话成功启动后执行
+	started := false
+	defer func() {
+		if !started {
+			return
+		}
+		if err := hookRunner.Run(hooks.PostStop, hookEnv); err != nil {
+			logger.Warnf("%v", err)
+		}
+	}()
+
+	// 远程git配置失败不影响IDE启动
+	step = tracker.Start(progress.PhasePrepare)
+	if err := bootstrapGit(client, &opts.git, logger); err != nil {
+		logger.Warnf("Git setup failed: %v", err)
+	}
+
+	reportGPU(client, logger)
+
+	err = runSetupTasks(client, opts.setup, logger)
+	step.End(err)
+	if err != nil {
+		return err
+	}
+
+	ideType := opts.ideType
+	step = tracker.Start(progress.PhaseInstall)
+	ideInstaller, freshInstall, err := prepareIDE(traceCtx, client, opts, logger)
+	step.End(err)
+	if err != nil {
+		return err
+	}
+	if freshInstall {
+		if err := hookRunner.Run(hooks.PostInstall, hookEnv); err != nil {
+			return err
+		}
+	}
+
+	// Start IDE，同一主机上的每个会话使用各自的端口，PID文件和日志也按端口区分
+	idePort := pickIDEPort(opts.idePort, ideInstaller.GetDefaultPort(), host, client.GetConfig().Host)
+	logger.Infof("Starting %s on port %d...", ideType, idePort)
+	step = tracker.Start(progress.PhaseStart)
+	_, span := tracing.Start(traceCtx, "ide.start", attribute.Int("ide.port", idePort))
+	err = ideInstaller.Start(idePort)
+	tracing.End(span, err)
+	step.End(err)
+	if err != nil {
+		return fmt.Errorf("failed to start IDE: %w", err)
+	}
+	logger.Infof("%s started on port %d", ideType, idePort)
+
+	// 退出时停止远程IDE，--keep-running时保留
+	if !opts.keepRunning {
+		defer func() {
+			logger.Infof("Stopping %s on remote host...", ideType)
+			// ctx在Ctrl+C时已取消，清理不能因此中止
+			if err := ideInstaller.WithContext(context.WithoutCancel(ctx)).Stop(idePort); err != nil {
+				logger.Warnf("Failed to stop %s: %v", ideType, err)
+			}
+		}()
+	}
+
+	// Create tunnel manager
+	tunnelManager := tunnel.NewTunnelManagerWithLogger(logger)
+	defer stopTunnels(tunnelManager, logger)
+	defer tunnelManager.Follow(client)()
+	if opts.strictPorts {
+		tunnelManager.SetConflictPolicy(tunnel.ConflictFail)
+	}
+	tunnelManager.OnConflict(confirmKillPortOwner(logger))
+	tunnelManager.SetPortFilter(portFilter)
+
+	// Parse forward ports
+	var forwardConfigs, named []tunnel.ForwardConfig
+	if opts.auto {
+		forwardConfigs = append(forwardConfigs, tunnel.ForwardConfig{AutoDetect: true})
+	} else {
+		named, err = parseForwards(opts.forwards)
+		if err != nil {
+			return err
+		}
+		forwardConfigs = named
+
+		// Always forward IDE port
+		forwardConfigs = append(forwardConfigs, tunnel.ForwardConfig{
+			LocalPort:  idePort,
+			RemotePort: idePort,
+		})
+	}
+
+	forwardConfigs = withSavedTunnels(host, forwardConfigs, logger)
+	if opts.mlPorts {
+		forwardConfigs = append(forwardConfigs, mlForwards(forwardConfigs)...)
+	}
+
+	// Create port forwards
+	step = tracker.Start(progress.PhaseForward)
+	_, span = tracing.Start(traceCtx, "tunnel.create", attribute.Int("tunnel.requested", len(forwardConfigs)))
+	portResults, err := tunnel.CreatePortForwards(client, forwardConfigs, tunnelManager)
+	tracing.End(span, err)
+	step.End(err)
+	if err != nil {
+		return fmt.Errorf("failed to create port forwards: %w", err)
+	}
+	saveNamedForwards(host, named, logger)
+
+	// List active tunnels
+	tunnels := tunnelManager.ListTunnels()
+	logger.Infof("Active port forwards:")
+	for name, info := range tunnels {
+		logger.Infof("  %s: %s", name, info)
+	}
+
+	// 查找IDE端口的实际转发端口
+	actualIDEPort := idePort
+	foundInResults := false
+
+	// 首先从portResults中查找
+	for _, result := range portResults {
+		if result.RemotePort == idePort {
+			actualIDEPort = result.ActualPort
+			foundInResults = true
+			break
+		}
+	}
+
+	// 如果没有在portResults中找到，从隧道管理器中查找
+	if !foundInResults {
+		for _, info := range tunnels {
+			// 查找转发到IDE远程端口的隧道
+			if info.RemotePort == idePort {
+				actualIDEPort = info.LocalPort
+				break
+			}
+		}
+	}
+
+	ideURL := fmt.Sprintf("http://localhost:%d", actualIDEPort)
+	if opts.folder != "" {
+		ideURL += "/?folder=" + url.QueryEscape(opts.folder)
+	}
+	logger.Infof("%s is now accessible at %s", ideType, ideURL)
+
+	// 本地反向代理，IDE按类型名注册
+	proxy, err := startProxy(client, opts.proxy, logger)
+	if err != nil {
+		return err
+	}
+	if proxy != nil {
+		defer proxy.Close()
+		registerProxyForwards(proxy, portResults, logger)
+		logger.Infof("%s is also available at %s", ideType, proxy.URL(proxy.Register(ideType, idePort)))
+	}
+
+	// 记录会话状态，供list/down使用
+	remotePID, _ := ideInstaller.GetPID(idePort)
+	controller := tunnel.NewController(client, tunnelManager, logger)
+	controller.SetStore(hostTunnelStore{host: host})
+	defer recordSession(client, tunnelManager, sessionInfo{
+		id:         sessionID,
+		host:       host,
+		ide:        ideType,
+		localPort:  actualIDEPort,
+		remotePort: idePort,
+		remotePID:  remotePID,
+		controller: controller,
+	}, logger)()
+
+	tracker.Start(progress.PhaseReady).End(nil)
+	notifier.Notify(notify.EventReady, fmt.Sprintf("%s on %s is accessible at %s", ideType, host, ideURL))
+
+	hookEnv.LocalPort = actualIDEPort
+	hookEnv.RemotePort = idePort
+	hookEnv.URL = ideURL
+	started = true
+	if opts.interactive {
+		setResult(upResult{
+			Host:       host,
+			IDE:        ideType,
+			URL:        ideURL,
+			LocalPort:  actualIDEPort,
+			RemotePort: idePort,
+			Forwards:   forwardStates(tunnelManager),
+		})
+	}
+	if err := hookRunner.Run(hooks.PostStart, hookEnv); err != nil {
+		logger.Warnf("%v", err)
+	}
+
+	// IDE就绪后在浏览器中打开
+	if opts.openBrowser {
+		go openWhenReady(ctx, ideURL, logger)
+	}
+
+	// 监控IDE进程，崩溃后自动重启
+	if opts.watchIDE {
+		watchdog := ide.NewWatchdog(ideInstaller, idePort, logger)
+		watchdog.OnEvent(func(event ide.WatchdogEvent) {
+			logger.Debugf("IDE watchdog: %s", event)
+			switch event.Type {
+			case ide.WatchdogIDEDown:
+				notifier.Notify(notify.EventReconnecting, event.String())
+			case ide.WatchdogIDERestarted:
+				notifier.Notify(notify.EventReady, event.String())
+			}
+		})
+		go watchdog.Run(ctx)
+	}
+
+	// 关闭长时间没有流量的转发，IDE端口除外
+	if opts.idleTimeout > 0 {
+		go tunnelManager.RunIdleReaper(ctx, opts.idleTimeout, func(name string, info tunnel.TunnelInfo) bool {
+			return info.RemotePort == idePort
+		})
+	}
+
+	// 本地修改实时推送到远程，供远程的热重载开发服务器使用
+	for _, mount := range mounts {
+		go followMount(ctx, client, mount, opts.mountExcludes, logger)
+	}
+
+	// 持续监视远程端口，自动转发新出现的Web服务
+	if opts.watchPorts {
+		go watchPorts(ctx, client, tunnelManager, opts.watchInterval, notifier, proxy, logger)
+	}
+
+	tracing.End(upSpan, nil)
+	setupDone = true
+	if opts.onReady != nil {
+		opts.onReady()
+	}
+
+	logger.Infof("Press Ctrl+C to stop...")
+	if opts.interactive {
+		startForwardConsole(ctx, controller, tunnelManager, logger)
+	}
+
+	// Wait for interrupt or connection loss
+	select {
+	case <-ctx.Done():
+		logger.Infof("Stopping...")
+	case <-waitConnectionLost(client):
+		return fmt.Errorf("%w: %s", ssh.ErrConnectionLost, host)
+	}
+
+	return nil
+}
+
+// prepareIDE 创建IDE安装器，未安装时安装IDE，已安装时补装配置的扩展和设置；返回本次是否新安装了IDE
+func prepareIDE(ctx context.Context, client *ssh.Client, opts *upOptions, logger log.Logger) (*ide.Installer, bool, error) {
+	ideType := opts.ideType
+	client, err := ideClient(client, opts.runAs, logger)
+	if err != nil {
+		return nil, false, err
+	}
+	settings, err := profileSettings(opts)
+	if err != nil {
+		return nil, false, err
+	}
+	ideInstaller := ide.NewInstallerWithOptions(client, ide.IDE(ideType), nil, logger)
+	ideInstaller.SetVersion(opts.version)
+	ideInstaller.SetOpenVSCodeExtensions(opts.extensions)
+	ideInstaller.SetOpenVSCodeSettings(settings)
+	ideInstaller.SetContext(ctx)
+	if err := ideInstaller.SetResourceLimits(opts.limits.limits); err != nil {
+		return nil, false, err
+	}
+	environment, err := opts.env.environment()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := ideInstaller.SetEnvironment(environment); err != nil {
+		return nil, false, err
+	}
+	if err := ideInstaller.SetLocalArchive(opts.localArchive); err != nil {
+		return nil, false, err
+	}
+
+	// Check if IDE is installed
+	logger.Infof("Checking if %s is installed...", ideType)
+	installed, err := ideInstaller.IsInstalled()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check IDE installation: %w", err)
+	}
+
+	// Install IDE if not installed
+	if !installed {
+		if err := runPreflight(client, opts.skipPreflight, logger); err != nil {
+			return nil, false, err
+		}
+		logger.Infof("%s is not installed. Installing...", ideType)
+		installCtx, span := tracing.Start(ctx, "ide.install", attribute.String("ide.version", opts.version))
+		ideInstaller.SetContext(installCtx)
+		err := ideInstaller.Install()
+		tracing.End(span, err)
+		ideInstaller.SetContext(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		logger.Infof("%s installed successfully", ideType)
+	} else {
+		logger.Infof("%s is already installed", ideType)
+		// 已安装时补装配置的扩展和设置
+		if len(opts.extensions) > 0 {
+			if err := ideInstaller.InstallExtensions(); err != nil {
+				logger.Warnf("Failed to install extensions: %v", err)
+			}
+		}
+		if settings != "" {
+			if err := ideInstaller.InstallSettings(); err != nil {
+				logger.Warnf("Failed to install settings: %v", err)
+			}
+		}
+	}
+
+	return ideInstaller, !installed, nil
+}
+
+// profileSettings 返回合并了连接配置档IDE设置的settings.json内容
+func profileSettings(opts *upOptions) (string, error) {
+	if opts.ssh.profile == "" {
+		return opts.settings, nil
+	}
+	profile, err := config.LookupProfile(opts.ssh.profile)
+	if err != nil {
+		return "", err
+	}
+	return profile.MergeSettings(opts.settings)
+}
+
+// runPreflight 安装IDE前检查远程磁盘、内存和glibc，不满足必要条件时拒绝安装，skip为true时只警告
+func runPreflight(client *ssh.Client, skip bool, logger log.Logger) error {
+	info, err := preflight.Collect(client)
+	if err != nil {
+		logger.Warnf("Skipping preflight checks: %v", err)
+		return nil
+	}
+
+	var fatal []string
+	for _, issue := range preflight.Check(info, preflight.DefaultRequirements()) {
+		if issue.Fatal && !skip {
+			logger.Errorf("Preflight check failed: %s", issue.Message)
+			fatal = append(fatal, issue.Check)
+		} else {
+			logger.Warnf("Preflight check: %s", issue.Message)
+		}
+	}
+	if len(fatal) > 0 {
+		return fmt.Errorf("%w (%s); use --skip-preflight to install anyway", preflight.ErrRequirementsNotMet, strings.Join(fatal, ", "))
+	}
+	return nil
+}
+
+// parseForwards 解析 --forward 参数（[name=][bind:]local:[host:]remote[/policy]）
+func parseForwards(forwards []string) ([]tunnel.ForwardConfig, error) {
+	var configs []tunnel.ForwardConfig
+	for _, forward := range forwards {
+		config, err := tunnel.ForwardFromSpec(forward)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// newTracker 创建该主机流程的进度跟踪器，未指定reporter时以步骤行输出到日志
+func newTracker(opts *upOptions, logger log.Logger, phases ...string) *progress.Tracker {
+	reporter := opts.progress
+	if reporter == nil {
+		reporter = progress.NewLogReporter(logger)
+	}
+	return progress.NewTracker(reporter, opts.host, phases...)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"devssh/pkg/config"
+	"devssh/pkg/ide"
+	"devssh/pkg/logging"
+	"devssh/pkg/provision"
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+	"github.com/spf13/cobra"
+)
+
+func newWorkspaceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workspace",
+		Short: "Manage named workspaces (host + IDE + folder + forwards + extensions)",
+	}
+
+	cmd.AddCommand(
+		newWorkspaceAddCmd(),
+		newWorkspaceListCmd(),
+		newWorkspaceRemoveCmd(),
+		newWorkspaceUpCmd(),
+	)
+
+	return cmd
+}
+
+func newWorkspaceAddCmd() *cobra.Command {
+	var (
+		workspace config.WorkspaceConfig
+		env       environmentFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add [name]",
+		Short: "Add or replace a workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// 提前校验端口转发格式
+			if _, err := parseForwards(workspace.Forwards); err != nil {
+				return err
+			}
+			workspace.Environment, err = env.parse()
+			if err != nil {
+				return err
+			}
+			if workspace.Environment != nil {
+				if err := ide.ValidateEnvironment(*workspace.Environment); err != nil {
+					return err
+				}
+			}
+
+			workspace.Name = args[0]
+			if err := cfg.AddWorkspace(workspace); err != nil {
+				return fmt.Errorf("failed to add workspace: %w", err)
+			}
+
+			logger.Infof("Workspace %s saved", workspace.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&workspace.Host, "host", "", "Remote host (SSH config alias or user@host)")
+	cmd.Flags().StringVar(&workspace.IDE, "ide", "vscode", "Web IDE type (vscode, code-server, or an IDE plugin name)")
+	cmd.Flags().StringVar(&workspace.Version, "version", "", "IDE version to install")
+	cmd.Flags().StringVar(&workspace.Folder, "folder", "", "Remote folder to open in the IDE")
+	cmd.Flags().StringSliceVar(&workspace.Forwards, "forward", []string{}, "Ports to forward as [name=][bind:]local:[host:]remote[/increment|fail|kill] (e.g., 3000, 8080:80/fail, db=0.0.0.0:5432:dbhost:5432)")
+	cmd.Flags().StringSliceVar(&workspace.Extensions, "extension", []string{}, "IDE extensions to install")
+	env.register(cmd)
+	cmd.MarkFlagRequired("host")
+
+	return cmd
+}
+
+func newWorkspaceListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List workspaces",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetGlobalLogger()
+
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			workspaces := cfg.ListWorkspaces()
+			sort.Slice(workspaces, func(i, j int) bool {
+				return workspaces[i].Name < workspaces[j].Name
+			})
+
+			if format != outputTable {
+				return printStructured(format, workspaces)
+			}
+
+			if len(workspaces) == 0 {
+				logger.Infof("No workspaces defined")
+				return nil
+			}
+
+			logger.Infof("Workspaces:")
+			for _, ws := range workspaces {
+				logger.Infof("  %s: %s (%s)", ws.Name, ws.Host, ws.IDE)
+				if ws.Folder != "" {
+					logger.Infof("    folder: %s", ws.Folder)
+				}
+				if len(ws.Forwards) > 0 {
+					logger.Infof("    forwards: %s", strings.Join(ws.Forwards, ", "))
+				}
+				if len(ws.Extensions) > 0 {
+					logger.Infof("    extensions: %s", strings.Join(ws.Extensions, ", "))
+				}
+				if len(ws.Setup) > 0 {
+					logger.Infof("    setup: %d task(s)", len(ws.Setup))
+				}
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newWorkspaceRemoveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove a workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			return cfg.RemoveWorkspace(args[0])
+		},
+	}
+
+	return cmd
+}
+
+func newWorkspaceUpCmd() *cobra.Command {
+	var (
+		opts      upOptions
+		skipSetup bool
+	)
+
+	cmd := &cobra.Command{
+		Use:         "up [name]",
+		Short:       "Bring up a workspace",
+		Annotations: map[string]string{resultAnnotation: "true"},
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			workspace, exists := cfg.GetWorkspace(args[0])
+			if !exists {
+				return fmt.Errorf("workspace %s not found", args[0])
+			}
+
+			// 先应用主机默认值，再用工作区中的设置覆盖
+			opts.host = workspace.Host
+			opts.applyDefaults(cmd, cfg)
+			if workspace.IDE != "" {
+				opts.ideType = workspace.IDE
+			}
+			if workspace.Version != "" {
+				opts.version = workspace.Version
+			}
+			if workspace.Folder != "" {
+				opts.folder = workspace.Folder
+			}
+			if len(workspace.Forwards) > 0 {
+				opts.forwards = workspace.Forwards
+			}
+			if len(workspace.Extensions) > 0 {
+				opts.extensions = workspace.Extensions
+			}
+			opts.env.defaults = config.MergeEnvironment(opts.env.defaults, workspace.Environment)
+			if opts.ideType == "" {
+				opts.ideType = "vscode"
+			}
+			if !skipSetup {
+				opts.setup = workspace.Setup
+			}
+			opts.interactive = true
+			opts.progress = progressReporter(cmd)
+
+			return runUp(cmd.Context(), &opts, logging.GetGlobalLogger())
+		},
+	}
+
+	opts.registerSessionFlags(cmd)
+	cmd.Flags().BoolVar(&skipSetup, "skip-setup", false, "Do not run the workspace setup tasks")
+
+	return cmd
+}
+
+// runSetupTasks 执行工作区的准备任务并报告每个任务的状态
+func runSetupTasks(client *ssh.Client, tasks []config.SetupTask, logger log.Logger) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	logger.Infof("Running %d setup task(s)...", len(tasks))
+	results, err := provision.NewProvisioner(client, logger).Run(tasks)
+
+	counts := make(map[provision.Status]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	logger.Infof("Setup: %d done, %d already done, %d failed, %d not run",
+		counts[provision.StatusDone], counts[provision.StatusSkipped], counts[provision.StatusFailed], counts[provision.StatusPending])
+	for _, result := range results {
+		switch result.Status {
+		case provision.StatusFailed:
+			logger.Errorf("  %s: %s", result.Name, result.Error)
+		case provision.StatusPending:
+			logger.Warnf("  %s: not run", result.Name)
+		}
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// awsProvider 通过aws CLI访问EC2
+type awsProvider struct {
+	opts Options
+}
+
+// awsDescribeOutput aws ec2 describe-instances 的输出
+type awsDescribeOutput struct {
+	Reservations []struct {
+		Instances []awsInstance `json:"Instances"`
+	} `json:"Reservations"`
+}
+
+type awsInstance struct {
+	InstanceID       string `json:"InstanceId"`
+	PublicIPAddress  string `json:"PublicIpAddress"`
+	PrivateIPAddress string `json:"PrivateIpAddress"`
+	State            struct {
+		Name string `json:"Name"`
+	} `json:"State"`
+	Placement struct {
+		AvailabilityZone string `json:"AvailabilityZone"`
+	} `json:"Placement"`
+	Tags []struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	} `json:"Tags"`
+}
+
+func (p *awsProvider) Name() string {
+	return ProviderAWS
+}
+
+// globalArgs 区域和配置文件参数
+func (p *awsProvider) globalArgs() []string {
+	args := []string{"--output", "json"}
+	if p.opts.Region != "" {
+		args = append(args, "--region", p.opts.Region)
+	}
+	if p.opts.Profile != "" {
+		args = append(args, "--profile", p.opts.Profile)
+	}
+	return args
+}
+
+func (p *awsProvider) List(ctx context.Context, tags map[string]string) ([]Instance, error) {
+	args := []string{"ec2", "describe-instances", "--filters",
+		"Name=instance-state-name,Values=pending,running,stopping,stopped"}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("Name=tag:%s,Values=%s", key, tags[key]))
+	}
+
+	return p.describe(ctx, append(args, p.globalArgs()...))
+}
+
+func (p *awsProvider) Get(ctx context.Context, id, _ string) (*Instance, error) {
+	args := append([]string{"ec2", "describe-instances", "--instance-ids", id}, p.globalArgs()...)
+	instances, err := p.describe(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("EC2 instance %s not found", id)
+	}
+	return &instances[0], nil
+}
+
+func (p *awsProvider) Start(ctx context.Context, id, _ string) error {
+	if err := runCLI(ctx, nil, "aws", append([]string{"ec2", "start-instances", "--instance-ids", id}, p.globalArgs()...)...); err != nil {
+		return err
+	}
+	// 等待实例进入running状态
+	return runCLI(ctx, nil, "aws", append([]string{"ec2", "wait", "instance-running", "--instance-ids", id}, p.globalArgs()...)...)
+}
+
+func (p *awsProvider) describe(ctx context.Context, args []string) ([]Instance, error) {
+	var output awsDescribeOutput
+	if err := runCLI(ctx, &output, "aws", args...); err != nil {
+		return nil, err
+	}
+
+	var instances []Instance
+	for _, reservation := range output.Reservations {
+		for _, raw := range reservation.Instances {
+			instance := Instance{
+				Provider:  ProviderAWS,
+				ID:        raw.InstanceID,
+				Name:      raw.InstanceID,
+				State:     raw.State.Name,
+				PublicIP:  raw.PublicIPAddress,
+				PrivateIP: raw.PrivateIPAddress,
+				Zone:      raw.Placement.AvailabilityZone,
+				Tags:      make(map[string]string),
+			}
+			for _, tag := range raw.Tags {
+				instance.Tags[tag.Key] = tag.Value
+			}
+			if name := instance.Tags["Name"]; name != "" {
+				instance.Name = name
+			}
+			instance.User = instance.Tags[p.opts.UserTag]
+			instances = append(instances, instance)
+		}
+	}
+
+	return instances, nil
+}
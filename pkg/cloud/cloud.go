@@ -0,0 +1,127 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Provider 云厂商名称
+const (
+	ProviderAWS = "aws"
+	ProviderGCP = "gcp"
+)
+
+// 统一后的实例状态，其他状态保留厂商的原始值（小写）
+const (
+	StateRunning = "running"
+	StateStopped = "stopped"
+)
+
+// DefaultUserTag 记录SSH用户名的标签（GCP为label）键名
+const DefaultUserTag = "devssh-user"
+
+// Instance 云主机实例
+type Instance struct {
+	Provider  string            `json:"provider"`
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	State     string            `json:"state"`
+	PublicIP  string            `json:"public_ip,omitempty"`
+	PrivateIP string            `json:"private_ip,omitempty"`
+	User      string            `json:"user,omitempty"`
+	Zone      string            `json:"zone,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// Running 实例是否在运行
+func (i *Instance) Running() bool {
+	return i.State == StateRunning
+}
+
+// Address 返回连接地址，优先公网IP，private为true或没有公网IP时使用内网IP
+func (i *Instance) Address(private bool) string {
+	if private || i.PublicIP == "" {
+		return i.PrivateIP
+	}
+	return i.PublicIP
+}
+
+// Options 访问云厂商的参数，空值时使用CLI自身的默认配置
+type Options struct {
+	// Region AWS区域
+	Region string
+	// Profile AWS配置文件
+	Profile string
+	// Project GCP项目
+	Project string
+	// UserTag 记录SSH用户名的标签键，默认为DefaultUserTag
+	UserTag string
+}
+
+// Provider 云主机来源，通过厂商CLI（aws、gcloud）访问，复用用户已有的登录凭据
+type Provider interface {
+	// Name 厂商名称
+	Name() string
+	// List 列出带有全部指定标签的实例（不含已销毁的实例）
+	List(ctx context.Context, tags map[string]string) ([]Instance, error)
+	// Get 获取单个实例的当前状态
+	Get(ctx context.Context, id, zone string) (*Instance, error)
+	// Start 启动已停止的实例并等待其运行
+	Start(ctx context.Context, id, zone string) error
+}
+
+// NewProvider 按名称创建云厂商实现
+func NewProvider(name string, opts Options) (Provider, error) {
+	if opts.UserTag == "" {
+		opts.UserTag = DefaultUserTag
+	}
+
+	switch name {
+	case ProviderAWS:
+		return &awsProvider{opts: opts}, nil
+	case ProviderGCP:
+		return &gcpProvider{opts: opts}, nil
+	default:
+		return nil, fmt.Errorf("unsupported cloud provider %q (use aws or gcp)", name)
+	}
+}
+
+// ParseTags 解析 key=value 形式的标签过滤条件
+func ParseTags(values []string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, value := range values {
+		key, tagValue, found := strings.Cut(value, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid tag filter %q (expected key=value)", value)
+		}
+		tags[key] = tagValue
+	}
+	return tags, nil
+}
+
+// runCLI 执行厂商CLI并将JSON输出解析到out
+func runCLI(ctx context.Context, out interface{}, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s CLI not found in PATH: %w", name, err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %v: %s", name, strings.Join(args[:min(len(args), 3)], " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(output, out); err != nil {
+		return fmt.Errorf("failed to parse %s output: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// gcpProvider 通过gcloud CLI访问Compute Engine，标签对应实例的labels
+type gcpProvider struct {
+	opts Options
+}
+
+type gcpInstance struct {
+	Name              string            `json:"name"`
+	Status            string            `json:"status"`
+	Zone              string            `json:"zone"`
+	Labels            map[string]string `json:"labels"`
+	NetworkInterfaces []struct {
+		NetworkIP     string `json:"networkIP"`
+		AccessConfigs []struct {
+			NatIP string `json:"natIP"`
+		} `json:"accessConfigs"`
+	} `json:"networkInterfaces"`
+}
+
+func (p *gcpProvider) Name() string {
+	return ProviderGCP
+}
+
+// globalArgs 项目和输出格式参数
+func (p *gcpProvider) globalArgs() []string {
+	args := []string{"--format", "json"}
+	if p.opts.Project != "" {
+		args = append(args, "--project", p.opts.Project)
+	}
+	return args
+}
+
+func (p *gcpProvider) List(ctx context.Context, tags map[string]string) ([]Instance, error) {
+	// 已删除的实例不会出现在列表中，TERMINATED即为已停止
+	args := []string{"compute", "instances", "list"}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var filters []string
+	for _, key := range keys {
+		filters = append(filters, fmt.Sprintf("labels.%s=%s", key, tags[key]))
+	}
+	if len(filters) > 0 {
+		args = append(args, "--filter", strings.Join(filters, " AND "))
+	}
+
+	var raw []gcpInstance
+	if err := runCLI(ctx, &raw, "gcloud", append(args, p.globalArgs()...)...); err != nil {
+		return nil, err
+	}
+
+	instances := make([]Instance, 0, len(raw))
+	for _, instance := range raw {
+		instances = append(instances, p.convert(instance))
+	}
+	return instances, nil
+}
+
+func (p *gcpProvider) Get(ctx context.Context, name, zone string) (*Instance, error) {
+	if zone == "" {
+		return nil, fmt.Errorf("zone is required for GCP instance %s", name)
+	}
+
+	var raw gcpInstance
+	args := append([]string{"compute", "instances", "describe", name, "--zone", zone}, p.globalArgs()...)
+	if err := runCLI(ctx, &raw, "gcloud", args...); err != nil {
+		return nil, err
+	}
+
+	instance := p.convert(raw)
+	return &instance, nil
+}
+
+func (p *gcpProvider) Start(ctx context.Context, name, zone string) error {
+	if zone == "" {
+		return fmt.Errorf("zone is required for GCP instance %s", name)
+	}
+	// gcloud默认等待操作完成
+	args := append([]string{"compute", "instances", "start", name, "--zone", zone}, p.globalArgs()...)
+	return runCLI(ctx, nil, "gcloud", args...)
+}
+
+func (p *gcpProvider) convert(raw gcpInstance) Instance {
+	instance := Instance{
+		Provider: ProviderGCP,
+		ID:       raw.Name,
+		Name:     raw.Name,
+		Zone:     path.Base(raw.Zone),
+		Tags:     raw.Labels,
+		User:     raw.Labels[p.opts.UserTag],
+	}
+
+	switch status := strings.ToLower(raw.Status); status {
+	case "terminated", "stopped", "suspended":
+		instance.State = StateStopped
+	default:
+		instance.State = status
+	}
+
+	if len(raw.NetworkInterfaces) > 0 {
+		nic := raw.NetworkInterfaces[0]
+		instance.PrivateIP = nic.NetworkIP
+		if len(nic.AccessConfigs) > 0 {
+			instance.PublicIP = nic.AccessConfigs[0].NatIP
+		}
+	}
+
+	return instance
+}
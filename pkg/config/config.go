@@ -5,44 +5,229 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"devssh/pkg/ssh"
+
+	"github.com/ghodss/yaml"
 )
 
 type HostConfig struct {
-	Name     string `json:"name"`
-	Host     string `json:"host"`
-	Port     string `json:"port"`
-	Username string `json:"username"`
-	KeyPath  string `json:"key_path,omitempty"`
+	Name     string       `json:"name"`
+	Host     string       `json:"host"`
+	Port     string       `json:"port"`
+	Username string       `json:"username"`
+	KeyPath  string       `json:"key_path,omitempty"`
+	Defaults HostDefaults `json:"defaults,omitempty"`
+	// 密码和私钥口令保存在密钥存储中，这里只记录其ID
+	PasswordSecret   string `json:"password_secret,omitempty"`
+	PassphraseSecret string `json:"passphrase_secret,omitempty"`
+	// Cloud 由云主机导入时记录实例信息，连接前据此刷新地址
+	Cloud *CloudSource `json:"cloud,omitempty"`
+	// Tunnels 保存的命名转发，名称 -> [bind:]local:[host:]remote[/policy]，连接时自动恢复
+	Tunnels map[string]string `json:"tunnels,omitempty"`
+}
+
+// CloudSource 主机对应的云实例
+type CloudSource struct {
+	Provider string `json:"provider"`
+	Instance string `json:"instance"`
+	Zone     string `json:"zone,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Project  string `json:"project,omitempty"`
+	Profile  string `json:"profile,omitempty"`
+	// PrivateIP 使用内网IP连接
+	PrivateIP bool `json:"private_ip,omitempty"`
+	// AutoStart 实例停止时连接前自动启动
+	AutoStart bool `json:"auto_start,omitempty"`
+}
+
+// HostDefaults 连接主机时使用的默认IDE设置，可全局或按主机配置
+type HostDefaults struct {
+	IDE        string          `json:"ide,omitempty"`
+	Version    string          `json:"version,omitempty"`
+	Forwards   []string        `json:"forwards,omitempty"`
+	Extensions []string        `json:"extensions,omitempty"`
+	Settings   json.RawMessage `json:"settings,omitempty"`
+	Workdir    string          `json:"workdir,omitempty"`
+	// Git 连接时写入远程的git配置
+	Git *GitDefaults `json:"git,omitempty"`
+	// Hooks 会话各阶段执行的命令
+	Hooks *Hooks `json:"hooks,omitempty"`
+	// AutoDetect 调整 --auto 和 --watch 自动转发的端口
+	AutoDetect *AutoDetectPorts `json:"auto_detect,omitempty"`
+	// Limits 远程IDE进程的资源限制
+	Limits *ResourceLimits `json:"limits,omitempty"`
+	// Environment 远程IDE进程的环境
+	Environment *IDEEnvironment `json:"environment,omitempty"`
+	// RemoteUser 通过sudo以该用户安装和运行IDE
+	RemoteUser string `json:"remote_user,omitempty"`
+	// Sudo 通过sudo以root安装和运行IDE，设置了RemoteUser时隐含
+	Sudo bool `json:"sudo,omitempty"`
+	// SudoPasswordSecret 保存sudo密码的密钥ID，未设置时需要免密sudo或在终端输入密码
+	SudoPasswordSecret string `json:"sudo_password_secret,omitempty"`
+	// Profile 连接配置档（slow、normal、fast），见 ConnectionProfile
+	Profile string `json:"profile,omitempty"`
+	// StrictHostKeyChecking 主机密钥策略（accept-new、yes、no），覆盖SSH配置文件中的设置
+	StrictHostKeyChecking string `json:"strict_host_key_checking,omitempty"`
+	// KnownHostsFile 校验主机密钥的known_hosts文件，空格分隔多个时新主机记录到第一个
+	KnownHostsFile string `json:"known_hosts_file,omitempty"`
+	// SendEnv 发送到远程会话的本地环境变量模式（如 AWS_PROFILE、MY_*），叠加在SSH配置之上
+	SendEnv []string `json:"send_env,omitempty"`
+	// SetEnv 为远程会话设置的环境变量，优先于SSH配置中的SetEnv
+	SetEnv map[string]string `json:"set_env,omitempty"`
+}
+
+// ResourceLimits 远程IDE进程的优先级和资源上限，零值表示不限制
+type ResourceLimits struct {
+	// Nice 进程优先级，-20到19，负值需要root
+	Nice int `json:"nice,omitempty"`
+	// IOClass ionice调度类，idle或best-effort
+	IOClass string `json:"io_class,omitempty"`
+	// MemoryMax 内存上限，如 4G 或 50%，通过systemd用户实例的scope生效
+	MemoryMax string `json:"memory_max,omitempty"`
+	// CPUQuota CPU配额，如 200% 表示最多使用两个核
+	CPUQuota string `json:"cpu_quota,omitempty"`
+}
+
+// IsZero 判断是否没有设置任何限制
+func (l ResourceLimits) IsZero() bool {
+	return l == ResourceLimits{}
+}
+
+// IDEEnvironment 远程IDE进程的环境，集成终端和语言服务器继承这些设置
+type IDEEnvironment struct {
+	// LoginShell 通过用户的登录shell启动IDE，加载PATH、conda、nvm等初始化
+	LoginShell bool `json:"login_shell,omitempty"`
+	// Env 额外的环境变量，值按字面设置，优先于登录shell中的设置
+	Env map[string]string `json:"env,omitempty"`
+	// EnvFiles 远程主机上的env文件（KEY=VALUE，可引用其他变量），启动前依次加载
+	EnvFiles []string `json:"env_files,omitempty"`
+	// TZ IDE进程的时区，如 Europe/Berlin；local表示本机的时区
+	TZ string `json:"tz,omitempty"`
+	// Locale IDE进程的LANG，如 de_DE.UTF-8；local表示本机的语言环境
+	Locale string `json:"locale,omitempty"`
+}
+
+// IsZero 判断是否没有设置任何环境
+func (e IDEEnvironment) IsZero() bool {
+	return !e.LoginShell && len(e.Env) == 0 && len(e.EnvFiles) == 0 && e.TZ == "" && e.Locale == ""
+}
+
+// MergeEnvironment 叠加两层环境设置：变量同名时override优先，env文件依次加载
+func MergeEnvironment(base, override *IDEEnvironment) *IDEEnvironment {
+	if base == nil {
+		return override
+	}
+	if override == nil {
+		return base
+	}
+
+	merged := IDEEnvironment{
+		LoginShell: base.LoginShell || override.LoginShell,
+		EnvFiles:   append(append([]string{}, base.EnvFiles...), override.EnvFiles...),
+		TZ:         base.TZ,
+		Locale:     base.Locale,
+	}
+	if override.TZ != "" {
+		merged.TZ = override.TZ
+	}
+	if override.Locale != "" {
+		merged.Locale = override.Locale
+	}
+	if len(base.Env) > 0 || len(override.Env) > 0 {
+		merged.Env = make(map[string]string, len(base.Env)+len(override.Env))
+		for name, value := range base.Env {
+			merged.Env[name] = value
+		}
+		for name, value := range override.Env {
+			merged.Env[name] = value
+		}
+	}
+	return &merged
+}
+
+// AutoDetectPorts 自动检测转发的端口增减，每项为端口或范围，如 9000 或 9000-9100
+type AutoDetectPorts struct {
+	// Include 在内置Web端口之外也自动转发的端口
+	Include []string `json:"include,omitempty"`
+	// Exclude 永不自动转发的端口，优先于Include和内置端口
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Hooks 会话生命周期钩子，每个阶段按顺序执行一组shell命令
+// pre_connect、post_start、post_stop 在本地执行，post_connect、post_install 在远程执行
+type Hooks struct {
+	PreConnect  []string `json:"pre_connect,omitempty"`
+	PostConnect []string `json:"post_connect,omitempty"`
+	// PostInstall 只在本次会话新安装了IDE后执行
+	PostInstall []string `json:"post_install,omitempty"`
+	PostStart   []string `json:"post_start,omitempty"`
+	PostStop    []string `json:"post_stop,omitempty"`
+}
+
+// GitDefaults 远程git引导设置，令牌保存在密钥存储中，这里只记录其ID
+type GitDefaults struct {
+	// Identity 复制本地的 user.name 和 user.email
+	Identity    bool   `json:"identity,omitempty"`
+	TokenSecret string `json:"token_secret,omitempty"`
+	// TokenHost 令牌对应的git服务器，默认为github.com
+	TokenHost string `json:"token_host,omitempty"`
+	TokenUser string `json:"token_user,omitempty"`
 }
 
 type ConnectionConfig struct {
-	ID        string    `json:"id"`
-	Host      string    `json:"host"`
-	Port      string    `json:"port"`
-	Username  string    `json:"username"`
-	IDE       string    `json:"ide"`
-	LocalPort int       `json:"local_port"`
-	StartedAt time.Time `json:"started_at"`
-	PID       int       `json:"pid,omitempty"`
+	ID         string         `json:"id"`
+	Host       string         `json:"host"`
+	Port       string         `json:"port"`
+	Username   string         `json:"username"`
+	IDE        string         `json:"ide"`
+	LocalPort  int            `json:"local_port"`
+	RemotePort int            `json:"remote_port,omitempty"`
+	RemotePID  int            `json:"remote_pid,omitempty"`
+	Forwards   []ForwardState `json:"forwards,omitempty"`
+	StartedAt  time.Time      `json:"started_at"`
+	PID        int            `json:"pid,omitempty"`
+}
+
+// DefaultsConfig 全局默认设置，命令行参数优先
+type DefaultsConfig struct {
+	HostDefaults
+	OpenBrowser    bool   `json:"open_browser,omitempty"`
+	Notify         bool   `json:"notify,omitempty"`
+	SecretsBackend string `json:"secrets_backend,omitempty"`
 }
 
 type Config struct {
+	Defaults    DefaultsConfig              `json:"defaults,omitempty"`
 	Hosts       map[string]HostConfig       `json:"hosts"`
+	Workspaces  map[string]WorkspaceConfig  `json:"workspaces,omitempty"`
 	Connections map[string]ConnectionConfig `json:"connections"`
 }
 
 func NewConfig() *Config {
 	return &Config{
 		Hosts:       make(map[string]HostConfig),
+		Workspaces:  make(map[string]WorkspaceConfig),
 		Connections: make(map[string]ConnectionConfig),
 	}
 }
 
+// Save 在配置锁内写入整个配置。修改部分内容时应使用Update，以免覆盖其他进程的修改
 func (c *Config) Save() error {
+	lock, err := lockConfig()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return c.write()
+}
+
+// write 原子地写入配置文件，调用方需持有配置锁
+func (c *Config) write() error {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return err
@@ -54,12 +239,47 @@ func (c *Config) Save() error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	var data []byte
+	if isYAMLPath(configPath) {
+		data, err = yaml.Marshal(c)
+	} else {
+		data, err = json.MarshalIndent(c, "", "  ")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	return os.WriteFile(configPath, data, 0644)
+	return WriteFileAtomic(configPath, data, 0644)
+}
+
+// WriteFileAtomic 先写入同目录下的临时文件再重命名，避免写入中断导致配置文件损坏
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
 }
 
 func (c *Config) Load() error {
@@ -77,10 +297,34 @@ func (c *Config) Load() error {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	data, err = toJSON(configPath, data)
+	if err != nil {
+		return err
+	}
+
 	if err := json.Unmarshal(data, c); err != nil {
+		// 用校验结果给出更具体的出错位置
+		if issues, verr := Validate(data); verr == nil {
+			for _, issue := range issues {
+				if !issue.UnknownKey {
+					return fmt.Errorf("invalid config %s: %w", configPath, issue)
+				}
+			}
+		}
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// 配置文件中为null或缺失的字段
+	if c.Hosts == nil {
+		c.Hosts = make(map[string]HostConfig)
+	}
+	if c.Workspaces == nil {
+		c.Workspaces = make(map[string]WorkspaceConfig)
+	}
+	if c.Connections == nil {
+		c.Connections = make(map[string]ConnectionConfig)
+	}
+
 	return nil
 }
 
@@ -89,17 +333,21 @@ func (c *Config) AddHost(host HostConfig) error {
 		return fmt.Errorf("host name is required")
 	}
 
-	c.Hosts[host.Name] = host
-	return c.Save()
+	return c.Update(func(latest *Config) error {
+		latest.Hosts[host.Name] = host
+		return nil
+	})
 }
 
 func (c *Config) RemoveHost(name string) error {
-	if _, exists := c.Hosts[name]; !exists {
-		return fmt.Errorf("host %s not found", name)
-	}
+	return c.Update(func(latest *Config) error {
+		if _, exists := latest.Hosts[name]; !exists {
+			return fmt.Errorf("host %s not found", name)
+		}
 
-	delete(c.Hosts, name)
-	return c.Save()
+		delete(latest.Hosts, name)
+		return nil
+	})
 }
 
 func (c *Config) GetHost(name string) (HostConfig, bool) {
@@ -107,6 +355,147 @@ func (c *Config) GetHost(name string) (HostConfig, bool) {
 	return host, exists
 }
 
+// SaveHostTunnel 保存主机的命名转发，同名时覆盖
+func (c *Config) SaveHostTunnel(hostName, name, spec string) error {
+	return c.Update(func(latest *Config) error {
+		host, exists := latest.Hosts[hostName]
+		if !exists {
+			return fmt.Errorf("host %s is not in the devssh config", hostName)
+		}
+
+		if host.Tunnels == nil {
+			host.Tunnels = make(map[string]string)
+		}
+		host.Tunnels[name] = spec
+		latest.Hosts[hostName] = host
+		return nil
+	})
+}
+
+// ForgetHostTunnel 删除主机保存的命名转发，不存在时不做任何事
+func (c *Config) ForgetHostTunnel(hostName, name string) error {
+	return c.Update(func(latest *Config) error {
+		host, exists := latest.Hosts[hostName]
+		if !exists {
+			return nil
+		}
+
+		delete(host.Tunnels, name)
+		if len(host.Tunnels) == 0 {
+			host.Tunnels = nil
+		}
+		latest.Hosts[hostName] = host
+		return nil
+	})
+}
+
+// ResolveHostDefaults 合并全局默认值与主机默认值，主机设置优先
+func (c *Config) ResolveHostDefaults(name string) HostDefaults {
+	resolved := c.Defaults.HostDefaults
+
+	host, exists := c.Hosts[name]
+	if !exists {
+		return resolved
+	}
+
+	d := host.Defaults
+	if d.IDE != "" {
+		resolved.IDE = d.IDE
+	}
+	if d.Version != "" {
+		resolved.Version = d.Version
+	}
+	if len(d.Forwards) > 0 {
+		resolved.Forwards = d.Forwards
+	}
+	if len(d.Extensions) > 0 {
+		resolved.Extensions = d.Extensions
+	}
+	if len(d.Settings) > 0 {
+		resolved.Settings = d.Settings
+	}
+	if d.Workdir != "" {
+		resolved.Workdir = d.Workdir
+	}
+	if d.Git != nil {
+		resolved.Git = d.Git
+	}
+	if d.Hooks != nil {
+		resolved.Hooks = mergeHooks(resolved.Hooks, d.Hooks)
+	}
+	if d.Limits != nil {
+		resolved.Limits = d.Limits
+	}
+	resolved.Environment = MergeEnvironment(resolved.Environment, d.Environment)
+	if d.RemoteUser != "" {
+		resolved.RemoteUser = d.RemoteUser
+	}
+	if d.Sudo {
+		resolved.Sudo = true
+	}
+	if d.SudoPasswordSecret != "" {
+		resolved.SudoPasswordSecret = d.SudoPasswordSecret
+	}
+	if d.Profile != "" {
+		resolved.Profile = d.Profile
+	}
+	if d.StrictHostKeyChecking != "" {
+		resolved.StrictHostKeyChecking = d.StrictHostKeyChecking
+	}
+	if d.KnownHostsFile != "" {
+		resolved.KnownHostsFile = d.KnownHostsFile
+	}
+	// SendEnv累加，SetEnv中主机的设置优先
+	resolved.SendEnv = append(append([]string{}, resolved.SendEnv...), d.SendEnv...)
+	resolved.SetEnv = ssh.MergeEnv(resolved.SetEnv, d.SetEnv)
+	if d.AutoDetect != nil {
+		resolved.AutoDetect = mergeAutoDetect(resolved.AutoDetect, d.AutoDetect)
+	}
+
+	return resolved
+}
+
+// mergeHooks 按阶段合并钩子，主机设置了的阶段替换全局设置
+func mergeHooks(global, host *Hooks) *Hooks {
+	if global == nil {
+		return host
+	}
+
+	merged := *global
+	if len(host.PreConnect) > 0 {
+		merged.PreConnect = host.PreConnect
+	}
+	if len(host.PostConnect) > 0 {
+		merged.PostConnect = host.PostConnect
+	}
+	if len(host.PostInstall) > 0 {
+		merged.PostInstall = host.PostInstall
+	}
+	if len(host.PostStart) > 0 {
+		merged.PostStart = host.PostStart
+	}
+	if len(host.PostStop) > 0 {
+		merged.PostStop = host.PostStop
+	}
+	return &merged
+}
+
+// mergeAutoDetect 主机设置了的列表替换全局设置
+func mergeAutoDetect(global, host *AutoDetectPorts) *AutoDetectPorts {
+	if global == nil {
+		return host
+	}
+
+	merged := *global
+	if len(host.Include) > 0 {
+		merged.Include = host.Include
+	}
+	if len(host.Exclude) > 0 {
+		merged.Exclude = host.Exclude
+	}
+	return &merged
+}
+
 func (c *Config) ListHosts() []HostConfig {
 	hosts := make([]HostConfig, 0, len(c.Hosts))
 	for _, host := range c.Hosts {
@@ -117,56 +506,75 @@ func (c *Config) ListHosts() []HostConfig {
 
 // ImportSSHHosts 从SSH配置文件中导入主机
 func (c *Config) ImportSSHHosts() (int, error) {
+	hosts, err := SSHHosts()
+	if err != nil {
+		return 0, err
+	}
+
+	// 已存在的主机保持不变
+	return c.ImportHosts(hosts, false)
+}
+
+// SSHHosts 将SSH配置文件中的主机（不含通配符）转换为主机配置，按名称排序
+func SSHHosts() ([]HostConfig, error) {
 	parser := ssh.NewSSHConfigParser()
 	sshHosts, err := parser.Parse()
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse SSH config: %w", err)
+		return nil, fmt.Errorf("failed to parse SSH config: %w", err)
 	}
 
-	imported := 0
+	var hosts []HostConfig
 	for hostName, sshHost := range sshHosts {
 		// 跳过通配符主机
 		if strings.Contains(hostName, "*") {
 			continue
 		}
 
-		// 检查是否已存在
-		if _, exists := c.Hosts[hostName]; !exists {
-			hostConfig := HostConfig{
-				Name:     hostName,
-				Host:     sshHost.HostName,
-				Port:     sshHost.Port,
-				Username: sshHost.User,
-				KeyPath:  sshHost.IdentityFile,
-			}
-
-			// 如果没有指定主机名，使用主机别名
-			if hostConfig.Host == "" {
-				hostConfig.Host = hostName
-			}
-
-			c.Hosts[hostName] = hostConfig
-			imported++
+		hostConfig := HostConfig{
+			Name:     hostName,
+			Host:     sshHost.HostName,
+			Port:     sshHost.Port,
+			Username: sshHost.User,
+			KeyPath:  sshHost.IdentityFile,
 		}
-	}
 
-	if imported > 0 {
-		if err := c.Save(); err != nil {
-			return imported, fmt.Errorf("imported %d hosts but failed to save config: %w", imported, err)
+		// 如果没有指定主机名，使用主机别名
+		if hostConfig.Host == "" {
+			hostConfig.Host = hostName
 		}
+
+		hosts = append(hosts, hostConfig)
 	}
 
-	return imported, nil
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].Name < hosts[j].Name
+	})
+	return hosts, nil
 }
 
 func (c *Config) AddConnection(conn ConnectionConfig) error {
-	c.Connections[conn.ID] = conn
-	return c.Save()
+	return c.Update(func(latest *Config) error {
+		latest.Connections[conn.ID] = conn
+		return nil
+	})
 }
 
 func (c *Config) RemoveConnection(id string) error {
-	delete(c.Connections, id)
-	return c.Save()
+	return c.Update(func(latest *Config) error {
+		delete(latest.Connections, id)
+		return nil
+	})
+}
+
+// SetConnectionForwards 更新会话的端口转发列表，会话已不存在时不做任何事
+func (c *Config) SetConnectionForwards(id string, forwards []ForwardState) error {
+	return c.Update(func(latest *Config) error {
+		if conn, exists := latest.Connections[id]; exists {
+			conn.Forwards = forwards
+			latest.Connections[id] = conn
+		}
+		return nil
+	})
 }
 
 func (c *Config) GetConnection(id string) (ConnectionConfig, bool) {
@@ -182,22 +590,33 @@ func (c *Config) ListConnections() []ConnectionConfig {
 	return connections
 }
 
+// configFileNames 支持的配置文件名，按优先级排列
+var configFileNames = []string{"config.yaml", "config.yml", "config.json"}
+
 func getConfigPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
 	}
 
-	return filepath.Join(homeDir, ".config", "devssh", "config.json"), nil
-}
-
-func GetConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := GetConfigDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return "", err
+	}
+
+	// 使用已存在的配置文件，都不存在时默认为config.json
+	for _, name := range configFileNames {
+		path := filepath.Join(configDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
 	}
 
-	return filepath.Join(homeDir, ".config", "devssh"), nil
+	return filepath.Join(configDir, "config.json"), nil
+}
+
+// GetConfigPath 返回当前使用的配置文件路径
+func GetConfigPath() (string, error) {
+	return getConfigPath()
 }
 
 func Load() (*Config, error) {
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+const appName = "devssh"
+
+var (
+	migrateConfigOnce sync.Once
+	migrateCacheOnce  sync.Once
+	configDirResult   string
+	cacheDirResult    string
+)
+
+// GetConfigDir 返回配置目录：$XDG_CONFIG_HOME/devssh，未设置时使用平台目录
+// （Linux ~/.config、macOS ~/Library/Application Support、Windows %AppData%）。
+// 首次调用时将旧的 ~/.config/devssh 迁移到新位置。
+func GetConfigDir() (string, error) {
+	dir, err := platformDir("XDG_CONFIG_HOME", os.UserConfigDir)
+	if err != nil {
+		return "", err
+	}
+
+	migrateConfigOnce.Do(func() {
+		configDirResult = migrateLegacyDir(filepath.Join(".config", appName), dir)
+	})
+	if configDirResult != dir {
+		return configDirResult, nil
+	}
+	return dir, nil
+}
+
+// GetCacheDir 返回本地缓存目录：$DEVSSH_CACHE_DIR、$XDG_CACHE_HOME/devssh，
+// 或平台缓存目录（Linux ~/.cache、macOS ~/Library/Caches、Windows %LocalAppData%）。
+func GetCacheDir() (string, error) {
+	if dir := os.Getenv(EnvCacheDir); dir != "" {
+		return dir, nil
+	}
+
+	dir, err := platformDir("XDG_CACHE_HOME", os.UserCacheDir)
+	if err != nil {
+		return "", err
+	}
+
+	migrateCacheOnce.Do(func() {
+		cacheDirResult = migrateLegacyDir(filepath.Join(".cache", appName), dir)
+	})
+	if cacheDirResult != dir {
+		return cacheDirResult, nil
+	}
+	return dir, nil
+}
+
+// GetStateDir 返回运行状态目录（日志等）：$XDG_STATE_HOME/devssh，未设置时
+// Linux为 ~/.local/state/devssh，macOS为配置目录，Windows为 %LocalAppData%\devssh。
+func GetStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return platformDir("", os.UserConfigDir)
+	case "windows":
+		return platformDir("", os.UserCacheDir)
+	default:
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		return filepath.Join(homeDir, ".local", "state", appName), nil
+	}
+}
+
+// platformDir XDG变量为绝对路径时优先使用，否则使用平台默认目录
+func platformDir(xdgEnv string, platform func() (string, error)) (string, error) {
+	if xdgEnv != "" {
+		if dir := os.Getenv(xdgEnv); filepath.IsAbs(dir) {
+			return filepath.Join(dir, appName), nil
+		}
+	}
+
+	base, err := platform()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user directory: %w", err)
+	}
+	return filepath.Join(base, appName), nil
+}
+
+// migrateLegacyDir 新目录不存在而旧目录（相对home）存在时移动过去；
+// 无法移动时继续使用旧目录，返回实际使用的目录
+func migrateLegacyDir(legacyRel, dir string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return dir
+	}
+
+	legacy := filepath.Join(homeDir, legacyRel)
+	if legacy == dir {
+		return dir
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return dir
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return dir
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return legacy
+	}
+	if err := os.Rename(legacy, dir); err != nil {
+		return legacy
+	}
+	fmt.Fprintf(os.Stderr, "Moved %s to %s\n", legacy, dir)
+	return dir
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 环境变量覆盖，优先级：配置文件 < 环境变量 < 命令行参数
+const (
+	EnvConfig         = "DEVSSH_CONFIG"           // 配置文件路径
+	EnvCacheDir       = "DEVSSH_CACHE_DIR"        // 本地缓存目录
+	EnvLogLevel       = "DEVSSH_LOG_LEVEL"        // 日志级别（debug、info、warn、error）
+	EnvLogFile        = "DEVSSH_LOG_FILE"         // 日志文件路径，auto为状态目录下的会话日志
+	EnvLogFormat      = "DEVSSH_LOG_FORMAT"       // 日志格式（text、json）
+	EnvOutput         = "DEVSSH_OUTPUT"           // 输出格式（table、json、yaml）
+	EnvTimeout        = "DEVSSH_TIMEOUT"          // SSH连接超时，秒数或时长（如 30s）
+	EnvIDE            = "DEVSSH_IDE"              // IDE类型
+	EnvVersion        = "DEVSSH_VERSION"          // IDE版本
+	EnvWorkdir        = "DEVSSH_WORKDIR"          // 远程工作目录
+	EnvForwards       = "DEVSSH_FORWARDS"         // 端口转发，逗号分隔
+	EnvExtensions     = "DEVSSH_EXTENSIONS"       // IDE扩展，逗号分隔
+	EnvOpenBrowser    = "DEVSSH_OPEN_BROWSER"     // 就绪后打开浏览器
+	EnvNotify         = "DEVSSH_NOTIFY"           // 桌面通知
+	EnvSecretsBackend = "DEVSSH_SECRETS_BACKEND"  // 密钥存储后端
+	EnvProfile        = "DEVSSH_PROFILE"          // 连接配置档（slow、normal、fast）
+	EnvSSHConfigCache = "DEVSSH_SSH_CONFIG_CACHE" // 跨进程缓存~/.ssh/config的解析结果
+	EnvPassword       = "DEVSSH_PASSWORD"         // SSH密码，代替 --password
+	EnvSudoPassword   = "DEVSSH_SUDO_PASSWORD"    // 远程sudo密码
+)
+
+// ResolveDefaults 返回主机的最终默认设置：全局默认值、主机默认值，再叠加环境变量
+func (c *Config) ResolveDefaults(name string) DefaultsConfig {
+	resolved := c.Defaults
+	resolved.HostDefaults = c.ResolveHostDefaults(name)
+
+	if value := os.Getenv(EnvIDE); value != "" {
+		resolved.IDE = value
+	}
+	if value := os.Getenv(EnvVersion); value != "" {
+		resolved.Version = value
+	}
+	if value := os.Getenv(EnvWorkdir); value != "" {
+		resolved.Workdir = value
+	}
+	if value := os.Getenv(EnvForwards); value != "" {
+		resolved.Forwards = splitList(value)
+	}
+	if value := os.Getenv(EnvExtensions); value != "" {
+		resolved.Extensions = splitList(value)
+	}
+	resolved.OpenBrowser = EnvBool(EnvOpenBrowser, resolved.OpenBrowser)
+	resolved.Notify = EnvBool(EnvNotify, resolved.Notify)
+	if value := os.Getenv(EnvSecretsBackend); value != "" {
+		resolved.SecretsBackend = value
+	}
+	if value := os.Getenv(EnvProfile); value != "" {
+		resolved.Profile = value
+	}
+
+	return resolved
+}
+
+// EnvString 读取字符串环境变量，未设置时返回fallback
+func EnvString(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// EnvBool 读取布尔环境变量，未设置或无法解析时返回fallback
+func EnvBool(name string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// EnvTimeoutSeconds 读取超时环境变量（秒数或时长），未设置或无法解析时返回fallback
+func EnvTimeoutSeconds(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return seconds
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= time.Second {
+		return int(duration / time.Second)
+	}
+	return fallback
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,457 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// ImportHosts 将主机写入配置，已存在的主机仅在overwrite为true时替换，返回导入数量
+func (c *Config) ImportHosts(hosts []HostConfig, overwrite bool) (int, error) {
+	imported := 0
+	err := c.Update(func(latest *Config) error {
+		imported = 0
+		for _, host := range hosts {
+			if host.Name == "" {
+				continue
+			}
+			if _, exists := latest.Hosts[host.Name]; exists && !overwrite {
+				continue
+			}
+			latest.Hosts[host.Name] = host
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save imported hosts: %w", err)
+	}
+
+	return imported, nil
+}
+
+// RefreshHosts 导入新主机，并用导入的地址、端口、用户名和私钥更新已存在的主机，
+// 保留其默认值和密钥等其他设置；返回新增和更新的数量
+func (c *Config) RefreshHosts(hosts []HostConfig) (added, updated int, err error) {
+	err = c.Update(func(latest *Config) error {
+		added, updated = 0, 0
+		for _, host := range hosts {
+			if host.Name == "" {
+				continue
+			}
+			existing, exists := latest.Hosts[host.Name]
+			if !exists {
+				latest.Hosts[host.Name] = host
+				added++
+				continue
+			}
+
+			refreshed := existing
+			refreshed.Host = host.Host
+			refreshed.Port = host.Port
+			refreshed.Username = host.Username
+			refreshed.KeyPath = host.KeyPath
+			if refreshed.Host != existing.Host || refreshed.Port != existing.Port ||
+				refreshed.Username != existing.Username || refreshed.KeyPath != existing.KeyPath {
+				latest.Hosts[host.Name] = refreshed
+				updated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to save imported hosts: %w", err)
+	}
+
+	return added, updated, nil
+}
+
+// ansibleGroup 清单中的一个组
+type ansibleGroup struct {
+	vars     map[string]interface{}
+	hosts    map[string]map[string]interface{}
+	children []string
+}
+
+// groupVars 主机从某个组继承的变量，depth越大优先级越高
+type groupVars struct {
+	depth int
+	group string
+	vars  map[string]interface{}
+}
+
+// ParseAnsibleInventory 解析Ansible清单（YAML或INI格式），组变量按层级继承，主机变量优先
+func ParseAnsibleInventory(path string) ([]HostConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	var groups map[string]*ansibleGroup
+	if isYAMLPath(path) {
+		groups, err = parseAnsibleYAML(data)
+	} else {
+		groups, err = parseAnsibleINI(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+	}
+
+	return resolveAnsibleHosts(groups)
+}
+
+// parseAnsibleYAML 解析YAML格式的清单
+func parseAnsibleYAML(data []byte) (map[string]*ansibleGroup, error) {
+	var root map[string]interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*ansibleGroup)
+	var walk func(name string, raw interface{}) error
+	walk = func(name string, raw interface{}) error {
+		group := groups[name]
+		if group == nil {
+			group = &ansibleGroup{
+				vars:  make(map[string]interface{}),
+				hosts: make(map[string]map[string]interface{}),
+			}
+			groups[name] = group
+		}
+		if raw == nil {
+			return nil
+		}
+		body, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("group %s: expected a mapping", name)
+		}
+
+		if vars, ok := body["vars"].(map[string]interface{}); ok {
+			for key, value := range vars {
+				group.vars[key] = value
+			}
+		}
+		if hosts, ok := body["hosts"].(map[string]interface{}); ok {
+			for pattern, rawVars := range hosts {
+				vars, _ := rawVars.(map[string]interface{})
+				for _, host := range expandHostPattern(pattern) {
+					group.hosts[host] = vars
+				}
+			}
+		}
+		if children, ok := body["children"].(map[string]interface{}); ok {
+			for child, rawChild := range children {
+				group.children = append(group.children, child)
+				if err := walk(child, rawChild); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for name, raw := range root {
+		if err := walk(name, raw); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// parseAnsibleINI 解析INI格式的清单，支持 [group]、[group:vars] 和 [group:children]
+func parseAnsibleINI(data []byte) (map[string]*ansibleGroup, error) {
+	groups := make(map[string]*ansibleGroup)
+	getGroup := func(name string) *ansibleGroup {
+		if groups[name] == nil {
+			groups[name] = &ansibleGroup{
+				vars:  make(map[string]interface{}),
+				hosts: make(map[string]map[string]interface{}),
+			}
+		}
+		return groups[name]
+	}
+
+	section, kind := "ungrouped", ""
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+			kind = ""
+			if name, suffix, found := strings.Cut(section, ":"); found {
+				section, kind = name, suffix
+			}
+			getGroup(section)
+			continue
+		}
+
+		group := getGroup(section)
+		switch kind {
+		case "vars":
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				return nil, fmt.Errorf("line %d: expected key=value", lineNum)
+			}
+			group.vars[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		case "children":
+			group.children = append(group.children, line)
+			getGroup(line)
+		case "":
+			fields := strings.Fields(line)
+			vars := make(map[string]interface{})
+			for _, field := range fields[1:] {
+				key, value, found := strings.Cut(field, "=")
+				if !found {
+					return nil, fmt.Errorf("line %d: invalid host variable %q", lineNum, field)
+				}
+				vars[key] = strings.Trim(value, `"'`)
+			}
+			for _, host := range expandHostPattern(fields[0]) {
+				group.hosts[host] = vars
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unsupported section type %q", lineNum, kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// resolveAnsibleHosts 从all组开始遍历，合并每个主机继承的组变量
+func resolveAnsibleHosts(groups map[string]*ansibleGroup) ([]HostConfig, error) {
+	// 未被其他组引用的组都隐式属于all
+	all := groups["all"]
+	if all == nil {
+		all = &ansibleGroup{vars: make(map[string]interface{})}
+		groups["all"] = all
+	}
+	referenced := make(map[string]bool)
+	for _, group := range groups {
+		for _, child := range group.children {
+			referenced[child] = true
+		}
+	}
+	for name := range groups {
+		if name != "all" && !referenced[name] {
+			all.children = append(all.children, name)
+		}
+	}
+	sort.Strings(all.children)
+
+	inherited := make(map[string][]groupVars)
+	hostVars := make(map[string]map[string]interface{})
+
+	// 每个组把自身变量追加到从父组继承的层级后传给子组
+	var walk func(name string, depth int, parents []groupVars, visiting map[string]bool) error
+	walk = func(name string, depth int, parents []groupVars, visiting map[string]bool) error {
+		group, ok := groups[name]
+		if !ok {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("group %s is its own descendant", name)
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		layers := append(append([]groupVars{}, parents...), groupVars{depth: depth, group: name, vars: group.vars})
+		for host, vars := range group.hosts {
+			inherited[host] = append(inherited[host], layers...)
+			if hostVars[host] == nil {
+				hostVars[host] = make(map[string]interface{})
+			}
+			for key, value := range vars {
+				hostVars[host][key] = value
+			}
+		}
+		for _, child := range group.children {
+			if err := walk(child, depth+1, layers, visiting); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk("all", 0, nil, make(map[string]bool)); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(hostVars))
+	for name := range hostVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hosts := make([]HostConfig, 0, len(names))
+	for _, name := range names {
+		layers := inherited[name]
+		sort.SliceStable(layers, func(i, j int) bool {
+			if layers[i].depth != layers[j].depth {
+				return layers[i].depth < layers[j].depth
+			}
+			return layers[i].group < layers[j].group
+		})
+
+		vars := make(map[string]interface{})
+		for _, layer := range layers {
+			for key, value := range layer.vars {
+				vars[key] = value
+			}
+		}
+		for key, value := range hostVars[name] {
+			vars[key] = value
+		}
+
+		hosts = append(hosts, ansibleHostConfig(name, vars))
+	}
+
+	return hosts, nil
+}
+
+// ansibleHostConfig 将ansible_*连接变量映射为主机配置
+func ansibleHostConfig(name string, vars map[string]interface{}) HostConfig {
+	lookup := func(keys ...string) string {
+		for _, key := range keys {
+			if value, ok := vars[key]; ok && value != nil {
+				switch v := value.(type) {
+				case float64:
+					return strconv.FormatFloat(v, 'f', -1, 64)
+				default:
+					return fmt.Sprint(v)
+				}
+			}
+		}
+		return ""
+	}
+
+	host := HostConfig{
+		Name:     name,
+		Host:     lookup("ansible_host", "ansible_ssh_host"),
+		Port:     lookup("ansible_port", "ansible_ssh_port"),
+		Username: lookup("ansible_user", "ansible_ssh_user"),
+		KeyPath:  expandHome(lookup("ansible_ssh_private_key_file", "ansible_private_key_file")),
+	}
+	if host.Host == "" {
+		host.Host = name
+	}
+	if host.Port == "" {
+		host.Port = "22"
+	}
+	return host
+}
+
+// ParseHostsFile 解析主机列表文件：/etc/hosts 格式（IP 名称 [别名...]），
+// 或每行一个 [user@]host[:port]
+func ParseHostsFile(path string) ([]HostConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hosts file: %w", err)
+	}
+	defer file.Close()
+
+	var hosts []HostConfig
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var host HostConfig
+		if ip := net.ParseIP(fields[0]); ip != nil && len(fields) > 1 {
+			// 跳过本机和组播等特殊地址
+			if ip.IsLoopback() || ip.IsMulticast() || ip.IsUnspecified() || strings.HasPrefix(fields[1], "ip6-") {
+				continue
+			}
+			host = HostConfig{Name: fields[1], Host: fields[0], Port: "22"}
+		} else {
+			host = parseHostSpec(fields[0])
+		}
+
+		if seen[host.Name] {
+			continue
+		}
+		seen[host.Name] = true
+		hosts = append(hosts, host)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading hosts file: %w", err)
+	}
+
+	return hosts, nil
+}
+
+// parseHostSpec 解析 [user@]host[:port]，主机名作为配置名
+func parseHostSpec(spec string) HostConfig {
+	host := HostConfig{Port: "22"}
+	address := spec
+	if user, rest, found := strings.Cut(spec, "@"); found {
+		host.Username = user
+		address = rest
+	}
+	if h, port, err := net.SplitHostPort(address); err == nil {
+		address = h
+		host.Port = port
+	}
+	host.Name = address
+	host.Host = address
+	return host
+}
+
+// hostRangePattern 匹配Ansible的数字范围写法，如 web[01:10].example.com
+var hostRangePattern = regexp.MustCompile(`^(.*)\[(\d+):(\d+)\](.*)$`)
+
+// expandHostPattern 展开主机名中的数字范围，保留前导零宽度
+func expandHostPattern(pattern string) []string {
+	match := hostRangePattern.FindStringSubmatch(pattern)
+	if match == nil {
+		return []string{pattern}
+	}
+
+	start, _ := strconv.Atoi(match[2])
+	end, _ := strconv.Atoi(match[3])
+	width := 0
+	if strings.HasPrefix(match[2], "0") {
+		width = len(match[2])
+	}
+
+	var hosts []string
+	for i := start; i <= end; i++ {
+		hosts = append(hosts, fmt.Sprintf("%s%0*d%s", match[1], width, i, match[4]))
+	}
+	return hosts
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
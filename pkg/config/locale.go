@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalValue 时区和语言环境取本机设置时使用的值
+const LocalValue = "local"
+
+// ResolveLocal 把取值为local的时区和语言环境替换为本机的设置，无法确定时返回错误
+func (e IDEEnvironment) ResolveLocal() (IDEEnvironment, error) {
+	if e.TZ == LocalValue {
+		tz, err := LocalTimezone()
+		if err != nil {
+			return e, err
+		}
+		e.TZ = tz
+	}
+	if e.Locale == LocalValue {
+		locale, err := LocalLocale()
+		if err != nil {
+			return e, err
+		}
+		e.Locale = locale
+	}
+	return e, nil
+}
+
+// LocalTimezone 返回本机的IANA时区名：$TZ，其次为/etc/localtime指向的zoneinfo文件
+func LocalTimezone() (string, error) {
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" {
+		return tz, nil
+	}
+	// Linux和macOS上/etc/localtime是指向 .../zoneinfo/Area/City 的链接
+	target, err := filepath.EvalSymlinks("/etc/localtime")
+	if err == nil {
+		if _, name, ok := strings.Cut(filepath.ToSlash(target), "zoneinfo/"); ok && name != "" {
+			return name, nil
+		}
+	}
+	if data, err := os.ReadFile("/etc/timezone"); err == nil {
+		if tz := strings.TrimSpace(string(data)); tz != "" {
+			return tz, nil
+		}
+	}
+	return "", fmt.Errorf("cannot determine the local time zone; set TZ or pass a zone name such as Europe/Berlin")
+}
+
+// LocalLocale 返回本机的语言环境：$LC_ALL，其次为$LANG
+func LocalLocale() (string, error) {
+	for _, name := range []string{"LC_ALL", "LANG"} {
+		if locale := os.Getenv(name); locale != "" && locale != "C" && locale != "POSIX" {
+			return locale, nil
+		}
+	}
+	return "", fmt.Errorf("cannot determine the local locale; set LANG or pass a locale such as en_US.UTF-8")
+}
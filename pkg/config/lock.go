@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	lockFileName   = "config.lock"
+	lockTimeout    = 10 * time.Second
+	lockRetryDelay = 50 * time.Millisecond
+)
+
+// lockConfig 获取配置目录的排他锁，其他devssh进程持有锁时重试直到超时
+func lockConfig() (*flock.Flock, error) {
+	// 锁文件与配置文件放在同一目录，DEVSSH_CONFIG 指向其他位置时也能互斥
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	configDir := filepath.Dir(configPath)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	lock := flock.New(filepath.Join(configDir, lockFileName))
+
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+
+	locked, err := lock.TryLockContext(ctx, lockRetryDelay)
+	if err != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("failed to lock config: %w", err)
+	}
+	if !locked {
+		return nil, fmt.Errorf("timed out waiting for config lock %s (is another devssh process stuck?)", lock.Path())
+	}
+
+	return lock, nil
+}
+
+// WithLock 在配置锁内执行fn，用于直接替换配置文件等操作
+func WithLock(fn func() error) error {
+	lock, err := lockConfig()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return fn()
+}
+
+// Update 在配置锁内重新加载配置、执行修改并保存，避免并发进程互相覆盖。
+// fn返回错误时不保存；成功后c更新为最新的配置。
+func (c *Config) Update(fn func(*Config) error) error {
+	lock, err := lockConfig()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	latest := NewConfig()
+	if err := latest.Load(); err != nil {
+		return err
+	}
+
+	if err := fn(latest); err != nil {
+		return err
+	}
+
+	if err := latest.write(); err != nil {
+		return err
+	}
+
+	*c = *latest
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package config
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// processExists 通过发送0信号检查进程是否存在
+func processExists(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// terminateProcess 发送SIGTERM，让进程有序退出
+func terminateProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(syscall.SIGTERM)
+}
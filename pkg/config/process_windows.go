@@ -0,0 +1,24 @@
+//go:build windows
+
+package config
+
+import "os"
+
+// processExists Windows上FindProcess会打开进程句柄，失败即表示进程不存在
+func processExists(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
+
+// terminateProcess Windows不支持SIGTERM，直接结束进程
+func terminateProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConnectionProfile 按链路质量调整的一组连接、传输和IDE参数，用 --profile 一次切换
+type ConnectionProfile struct {
+	Name string
+	// Timeout SSH连接超时
+	Timeout time.Duration
+	// KeepAlive 发送keepalive的间隔，无响应时断开连接
+	KeepAlive time.Duration
+	// Compress 压缩上传
+	Compress bool
+	// Parallel 大文件上传使用的SSH通道数
+	Parallel int
+	// IDESettings 合并到IDE设置中的项，用户已设置的项不覆盖
+	IDESettings map[string]interface{}
+}
+
+// profiles 内置的连接配置档
+var profiles = map[string]ConnectionProfile{
+	// slow LTE、卫星或跨洲VPN：容忍长延迟，减少后台流量
+	"slow": {
+		Name:      "slow",
+		Timeout:   90 * time.Second,
+		KeepAlive: 15 * time.Second,
+		Compress:  true,
+		Parallel:  4,
+		IDESettings: map[string]interface{}{
+			"extensions.autoUpdate":                          false,
+			"extensions.autoCheckUpdates":                    false,
+			"update.mode":                                    "none",
+			"telemetry.telemetryLevel":                       "off",
+			"workbench.enableExperiments":                    false,
+			"workbench.settings.enableNaturalLanguageSearch": false,
+		},
+	},
+	"normal": {
+		Name:      "normal",
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Parallel:  1,
+	},
+	// fast 局域网或同一数据中心：尽快发现失败，不压缩以节省CPU
+	"fast": {
+		Name:      "fast",
+		Timeout:   10 * time.Second,
+		KeepAlive: 60 * time.Second,
+		Parallel:  1,
+	},
+}
+
+// ProfileNames 返回内置连接配置档的名称
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupProfile 按名称查找连接配置档
+func LookupProfile(name string) (ConnectionProfile, error) {
+	profile, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		return ConnectionProfile{}, fmt.Errorf("unknown connection profile %q (use %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// MergeSettings 把配置档的IDE设置合并到settings.json内容中，已有的项保持不变
+func (p ConnectionProfile) MergeSettings(settings string) (string, error) {
+	if len(p.IDESettings) == 0 {
+		return settings, nil
+	}
+
+	merged := make(map[string]interface{})
+	if strings.TrimSpace(settings) != "" {
+		if err := json.Unmarshal([]byte(settings), &merged); err != nil {
+			return "", fmt.Errorf("invalid IDE settings: %w", err)
+		}
+	}
+	for key, value := range p.IDESettings {
+		if _, exists := merged[key]; !exists {
+			merged[key] = value
+		}
+	}
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
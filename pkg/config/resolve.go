@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"devssh/pkg/ssh"
+)
+
+// HostSource 主机连接设置的来源
+type HostSource string
+
+const (
+	// HostSourceSSHConfig ~/.ssh/config 中的Host
+	HostSourceSSHConfig HostSource = "ssh_config"
+	// HostSourceDevSSH devssh配置中的主机（import导入或云主机）
+	HostSourceDevSSH HostSource = "devssh"
+	// HostSourceAddress 直接给出的地址
+	HostSourceAddress HostSource = "address"
+)
+
+// ResolvedHost 命令行主机参数的解析结果
+type ResolvedHost struct {
+	// Name 去掉用户名后的主机名，用于查找主机默认值和保存的转发
+	Name   string
+	Source HostSource
+	// SSH 合并命令行参数后的连接设置
+	SSH ssh.Config
+	// Host Source为devssh时的主机配置，云主机需要在连接前刷新地址，见 UpdateHostConfig
+	Host *HostConfig
+
+	override ssh.Config
+}
+
+// ParseHostArg 拆分 [user@]host[:port]，IPv6地址带端口时写在方括号中（user@[2001:db8::1]:2222），
+// 不带端口时也可以不加方括号；没有的部分为空
+func ParseHostArg(arg string) (user, host, port string, err error) {
+	address := arg
+	if i := strings.LastIndex(arg, "@"); i != -1 {
+		user, address = arg[:i], arg[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(address, "["):
+		end := strings.Index(address, "]")
+		if end == -1 {
+			return "", "", "", fmt.Errorf("invalid host %q: missing ]", arg)
+		}
+		host = address[1:end]
+		if rest := address[end+1:]; rest != "" {
+			var ok bool
+			if port, ok = strings.CutPrefix(rest, ":"); !ok {
+				return "", "", "", fmt.Errorf("invalid host %q: unexpected %q after ]", arg, rest)
+			}
+		}
+	case strings.Count(address, ":") == 1:
+		host, port, _ = strings.Cut(address, ":")
+	default:
+		// 没有端口，或者是不带方括号的IPv6地址
+		host = address
+	}
+
+	if host == "" {
+		return "", "", "", fmt.Errorf("invalid host %q: empty host name", arg)
+	}
+	if port != "" {
+		if n, convErr := strconv.Atoi(port); convErr != nil || n < 1 || n > 65535 {
+			return "", "", "", fmt.Errorf("invalid host %q: port must be between 1 and 65535", arg)
+		}
+	}
+	return user, host, port, nil
+}
+
+// ResolveHost 解析命令行中的 [user@]host[:port]：依次查找SSH配置文件、devssh配置中的主机，否则视为地址。
+// override中的非空字段覆盖查到的设置，Port为空表示未指定，此时使用参数中的端口；
+// cfg为nil时不查找devssh配置
+func ResolveHost(cfg *Config, arg string, override ssh.Config) (*ResolvedHost, error) {
+	user, name, port, err := ParseHostArg(arg)
+	if err != nil {
+		return nil, err
+	}
+	if override.Username == "" {
+		override.Username = user
+	}
+	if override.Port == "" {
+		override.Port = port
+	}
+
+	resolved := &ResolvedHost{Name: name, override: override}
+
+	_, sshErr := ssh.NewSSHConfigParser().GetHost(name)
+	if sshErr == nil {
+		sshConfig, err := ssh.ConfigFromSSHConfig(name, &override)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Source = HostSourceSSHConfig
+		resolved.SSH = *sshConfig
+		return resolved, nil
+	}
+	if strings.Contains(sshErr.Error(), "is a special pattern") {
+		return nil, fmt.Errorf("cannot connect to %s: %v", name, sshErr)
+	}
+
+	if cfg != nil {
+		if host, exists := cfg.GetHost(name); exists {
+			resolved.Source = HostSourceDevSSH
+			if err := resolved.UpdateHostConfig(host); err != nil {
+				return nil, err
+			}
+			return resolved, nil
+		}
+	}
+
+	resolved.Source = HostSourceAddress
+	resolved.SSH = override
+	resolved.SSH.Host = name
+	applySSHGlobalOptions(&resolved.SSH)
+	if resolved.SSH.Port == "" {
+		resolved.SSH.Port = "22"
+	}
+	if resolved.SSH.Username == "" {
+		return nil, fmt.Errorf("username is required when host is not in SSH config file. Use -u flag or user@host format")
+	}
+	return resolved, nil
+}
+
+// applySSHGlobalOptions 不在SSH配置文件中的主机使用配置文件中的全局主机密钥和环境变量设置，
+// 已指定的known_hosts文件和策略不变
+func applySSHGlobalOptions(c *ssh.Config) {
+	global, err := ssh.NewSSHConfigParser().GlobalOptions()
+	if err != nil {
+		return
+	}
+	c.HashKnownHosts = global.HashKnownHosts == "yes"
+	if c.KnownHostsFile == "" {
+		c.KnownHostsFile = global.UserKnownHostsFile
+	}
+	if c.StrictHostKeyChecking == "" {
+		c.StrictHostKeyChecking = global.StrictHostKeyChecking
+	}
+	c.SendEnv = append(append([]string{}, global.SendEnv...), c.SendEnv...)
+	c.SetEnv = ssh.MergeEnv(global.SetEnv, c.SetEnv)
+}
+
+// PersistSSHConfigCache 把SSH配置文件（含Include）的解析结果保存在缓存目录中，
+// 文件都未修改时之后的进程不再解析。进程内的缓存始终开启
+func PersistSSHConfigCache() error {
+	dir, err := GetCacheDir()
+	if err != nil {
+		return err
+	}
+	ssh.SetConfigCacheFile(filepath.Join(dir, "ssh_config_cache.json"))
+	return nil
+}
+
+// UpdateHostConfig 使用（刷新后的）devssh主机配置重新计算连接设置
+func (r *ResolvedHost) UpdateHostConfig(host HostConfig) error {
+	r.Host = &host
+	r.SSH = ssh.Config{
+		Host:                  host.Host,
+		Port:                  host.Port,
+		Username:              host.Username,
+		KeyPath:               host.KeyPath,
+		Password:              r.override.Password,
+		Passphrase:            r.override.Passphrase,
+		Timeout:               r.override.Timeout,
+		KeepAlive:             r.override.KeepAlive,
+		KnownHostsFile:        r.override.KnownHostsFile,
+		StrictHostKeyChecking: r.override.StrictHostKeyChecking,
+		SendEnv:               r.override.SendEnv,
+		SetEnv:                r.override.SetEnv,
+	}
+	applySSHGlobalOptions(&r.SSH)
+	if r.override.Username != "" {
+		r.SSH.Username = r.override.Username
+	}
+	if r.override.Port != "" {
+		r.SSH.Port = r.override.Port
+	}
+	if r.SSH.Port == "" {
+		r.SSH.Port = "22"
+	}
+	if r.override.KeyPath != "" {
+		r.SSH.KeyPath = r.override.KeyPath
+	}
+	if r.SSH.Username == "" {
+		return fmt.Errorf("username is required for host %s. Use -u flag or set hosts.%s.username", r.Name, r.Name)
+	}
+	return nil
+}
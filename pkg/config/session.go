@@ -0,0 +1,92 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ForwardState 会话中的一条端口转发
+type ForwardState struct {
+	Name       string `json:"name"`
+	LocalHost  string `json:"local_host,omitempty"`
+	LocalPort  int    `json:"local_port"`
+	RemoteHost string `json:"remote_host,omitempty"`
+	RemotePort int    `json:"remote_port"`
+}
+
+// NewSessionID 生成新的会话ID
+func NewSessionID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// SessionSocketPath 返回会话控制socket的路径（状态目录下的 sessions/<id>.sock），并确保目录存在
+func SessionSocketPath(id string) (string, error) {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(stateDir, "sessions")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	return filepath.Join(dir, id+".sock"), nil
+}
+
+// IsAlive 检查会话对应的本地devssh进程是否仍在运行。
+// 只检查本地PID，不连接远程主机：本地进程在运行时远程IDE可能已经退出，
+// 需要确认远程端口时由调用方通过SSH检查
+func (c ConnectionConfig) IsAlive() bool {
+	return c.PID > 0 && processExists(c.PID)
+}
+
+// Terminate 结束会话对应的本地devssh进程
+func (c ConnectionConfig) Terminate() error {
+	if c.PID <= 0 {
+		return fmt.Errorf("session %s has no local process", c.ID)
+	}
+	if c.PID == os.Getpid() {
+		return fmt.Errorf("refusing to terminate the current process")
+	}
+	return terminateProcess(c.PID)
+}
+
+// PruneStaleConnections 删除本地进程已退出的会话记录，返回被删除的会话
+func (c *Config) PruneStaleConnections() ([]ConnectionConfig, error) {
+	hasStale := false
+	for _, conn := range c.Connections {
+		if !conn.IsAlive() {
+			hasStale = true
+			break
+		}
+	}
+	if !hasStale {
+		return nil, nil
+	}
+
+	var stale []ConnectionConfig
+	err := c.Update(func(latest *Config) error {
+		stale = nil
+		for id, conn := range latest.Connections {
+			if conn.IsAlive() {
+				continue
+			}
+			stale = append(stale, conn)
+			delete(latest.Connections, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return stale, fmt.Errorf("failed to save config after pruning stale sessions: %w", err)
+	}
+
+	return stale, nil
+}
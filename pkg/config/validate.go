@@ -0,0 +1,261 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// ValidationError 配置文件中的一个问题，Path为出错字段的路径（如 hosts.gpu01.port）
+type ValidationError struct {
+	Path       string
+	Message    string
+	UnknownKey bool
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidateFile 按配置结构校验配置文件，返回发现的全部问题；文件无法读取或语法错误时返回error
+func ValidateFile(path string) ([]ValidationError, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	jsonData, err := toJSON(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return Validate(jsonData)
+}
+
+// Validate 校验JSON格式的配置内容：未知字段、类型错误
+func Validate(data []byte) ([]ValidationError, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	var issues []ValidationError
+	validateValue("", raw, reflect.TypeOf(Config{}), &issues)
+	return issues, nil
+}
+
+// isYAMLPath 根据扩展名判断是否为YAML配置文件
+func isYAMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// toJSON 将YAML配置转换为JSON，JSON配置原样返回
+func toJSON(path string, data []byte) ([]byte, error) {
+	if !isYAMLPath(path) {
+		return data, nil
+	}
+
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+	}
+	return jsonData, nil
+}
+
+// validateValue 递归比较解析出的值与结构体定义
+func validateValue(path string, value interface{}, t reflect.Type, issues *[]ValidationError) {
+	if value == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	addIssue := func(format string, args ...interface{}) {
+		*issues = append(*issues, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	mismatch := func(expected string) {
+		msg := fmt.Sprintf("expected %s, got %s", expected, describeValue(value))
+		// YAML中未加引号的数字会被解析为数字
+		if expected == "string" {
+			if _, ok := value.(float64); ok {
+				msg += " (quote the value)"
+			}
+		}
+		addIssue("%s", msg)
+	}
+
+	switch t {
+	case rawMessageType:
+		return
+	case timeType:
+		s, ok := value.(string)
+		if !ok {
+			mismatch("RFC 3339 timestamp")
+			return
+		}
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			addIssue("invalid timestamp %q (expected RFC 3339)", s)
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch("object")
+			return
+		}
+
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fieldPath := joinPath(path, key)
+			field, exists := fields[key]
+			if !exists {
+				msg := "unknown key"
+				if suggestion := suggestKey(key, fields); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				*issues = append(*issues, ValidationError{Path: fieldPath, Message: msg, UnknownKey: true})
+				continue
+			}
+			validateValue(fieldPath, obj[key], field, issues)
+		}
+
+	case reflect.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch("object")
+			return
+		}
+		for key, item := range obj {
+			validateValue(joinPath(path, key), item, t.Elem(), issues)
+		}
+
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			mismatch("list")
+			return
+		}
+		for i, item := range list {
+			validateValue(fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), issues)
+		}
+
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			mismatch("string")
+		}
+
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			mismatch("boolean")
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(float64)
+		if !ok {
+			mismatch("integer")
+			return
+		}
+		if n != math.Trunc(n) {
+			addIssue("expected integer, got %v", n)
+		}
+	}
+}
+
+// jsonFields 返回结构体的JSON字段名到类型的映射，展开嵌入的结构体
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embeddedName, embeddedType := range jsonFields(field.Type) {
+				fields[embeddedName] = embeddedType
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// suggestKey 为拼写不同的字段名（大小写、下划线、连字符）给出建议
+func suggestKey(key string, fields map[string]reflect.Type) string {
+	normalize := func(s string) string {
+		s = strings.ToLower(s)
+		s = strings.ReplaceAll(s, "_", "")
+		return strings.ReplaceAll(s, "-", "")
+	}
+
+	target := normalize(key)
+	for name := range fields {
+		if normalize(name) == target {
+			return name
+		}
+	}
+	return ""
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// describeValue 描述JSON值的类型，用于错误信息
+func describeValue(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// GetValue 按点分路径读取配置项（如 defaults.ide、hosts.gpu01.port），未设置时返回nil
+func (c *Config) GetValue(path string) (interface{}, error) {
+	tree, err := c.toTree()
+	if err != nil {
+		return nil, err
+	}
+
+	parent, key, _, err := lookupPath(tree, path, false)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, nil
+	}
+	return parent[key], nil
+}
+
+// SetValue 按点分路径设置配置项，值按字段类型解析，校验通过后保存
+func (c *Config) SetValue(path, value string) error {
+	return c.Update(func(latest *Config) error {
+		return latest.setValue(path, value)
+	})
+}
+
+// setValue 修改内存中的配置项，不保存
+func (c *Config) setValue(path, value string) error {
+	tree, err := c.toTree()
+	if err != nil {
+		return err
+	}
+
+	parent, key, fieldType, err := lookupPath(tree, path, true)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseValue(value, fieldType)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", path, err)
+	}
+	parent[key] = parsed
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	issues, err := Validate(data)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		if !issue.UnknownKey {
+			return issue
+		}
+	}
+
+	updated := NewConfig()
+	if err := json.Unmarshal(data, updated); err != nil {
+		return fmt.Errorf("failed to apply %s: %w", path, err)
+	}
+
+	*c = *updated
+	return nil
+}
+
+// toTree 将配置转换为通用的map结构
+func (c *Config) toTree() (map[string]interface{}, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return tree, nil
+}
+
+// splitPath 拆分点分路径，方括号内为完整的键名（如 hosts[gpu01.example.com].port）
+func splitPath(path string) ([]string, error) {
+	var (
+		segments []string
+		current  strings.Builder
+		quoted   bool
+	)
+
+	flush := func() error {
+		if current.Len() == 0 {
+			return fmt.Errorf("invalid config path %q", path)
+		}
+		segments = append(segments, current.String())
+		current.Reset()
+		return nil
+	}
+
+	for i := 0; i < len(path); i++ {
+		ch := path[i]
+		switch {
+		case quoted && ch == ']':
+			quoted = false
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			// ]后只能是.或路径结尾
+			if i+1 < len(path) {
+				if path[i+1] != '.' {
+					return nil, fmt.Errorf("invalid config path %q", path)
+				}
+				i++
+			}
+		case quoted:
+			current.WriteByte(ch)
+		case ch == '[':
+			if current.Len() > 0 {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			quoted = true
+		case ch == '.':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			current.WriteByte(ch)
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("invalid config path %q: missing ]", path)
+	}
+	if current.Len() > 0 || len(segments) == 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+	return segments, nil
+}
+
+// lookupPath 沿路径查找配置项，返回所在的对象、键名和字段类型。
+// map中的键可以包含点（如主机名 gpu01.example.com），按最长匹配处理。
+// create为true时创建缺失的中间对象；为false时路径合法但未设置则返回nil对象。
+func lookupPath(tree map[string]interface{}, path string, create bool) (map[string]interface{}, string, reflect.Type, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	node := tree
+	t := reflect.TypeOf(Config{})
+	walked := ""
+
+	for i := 0; i < len(segments); {
+		var (
+			key       string
+			fieldType reflect.Type
+		)
+
+		switch {
+		case t.Kind() == reflect.Struct:
+			key = segments[i]
+			i++
+			fields := jsonFields(t)
+			ft, exists := fields[key]
+			if !exists {
+				msg := fmt.Sprintf("unknown config key %q", joinPath(walked, key))
+				if suggestion := suggestKey(key, fields); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				return nil, "", nil, fmt.Errorf("%s", msg)
+			}
+			fieldType = ft
+
+		case t.Kind() == reflect.Map && node != nil:
+			// 优先匹配已存在的最长键
+			j := len(segments)
+			for ; j > i+1; j-- {
+				if _, exists := node[strings.Join(segments[i:j], ".")]; exists {
+					break
+				}
+			}
+			key = strings.Join(segments[i:j], ".")
+			i = j
+			fieldType = t.Elem()
+
+		case t.Kind() == reflect.Map:
+			key = segments[i]
+			i++
+			fieldType = t.Elem()
+
+		default:
+			return nil, "", nil, fmt.Errorf("%s is not an object", walked)
+		}
+
+		walked = joinPath(walked, key)
+
+		if i == len(segments) {
+			return node, key, fieldType, nil
+		}
+
+		if node != nil {
+			child, ok := node[key].(map[string]interface{})
+			if !ok && node[key] != nil {
+				return nil, "", nil, fmt.Errorf("%s is not an object", walked)
+			}
+			if !ok && create {
+				child = make(map[string]interface{})
+				node[key] = child
+			}
+			node = child
+		}
+		t = fieldType
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+
+	return nil, "", nil, fmt.Errorf("invalid config path %q", path)
+}
+
+// parseValue 按字段类型解析命令行上的值：字符串原样保存，列表可用逗号分隔，其余按YAML解析
+func parseValue(value string, t reflect.Type) (interface{}, error) {
+	if t != rawMessageType {
+		switch t.Kind() {
+		case reflect.String:
+			return value, nil
+		case reflect.Slice:
+			if t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+				items := []interface{}{}
+				for _, item := range strings.Split(value, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						items = append(items, item)
+					}
+				}
+				return items, nil
+			}
+		}
+	}
+
+	data, err := yaml.YAMLToJSON([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
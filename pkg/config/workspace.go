@@ -0,0 +1,85 @@
+package config
+
+import "fmt"
+
+// WorkspaceConfig 命名的工作区：主机、IDE、目录、端口转发和扩展的组合
+type WorkspaceConfig struct {
+	Name       string   `json:"name"`
+	Host       string   `json:"host"`
+	IDE        string   `json:"ide,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Folder     string   `json:"folder,omitempty"`
+	Forwards   []string `json:"forwards,omitempty"`
+	Extensions []string `json:"extensions,omitempty"`
+	// Setup 连接后在远程执行的准备任务，已完成的任务不重复执行
+	Setup []SetupTask `json:"setup,omitempty"`
+	// Environment IDE进程的环境，叠加在主机默认值之上
+	Environment *IDEEnvironment `json:"environment,omitempty"`
+}
+
+// SetupTask 一个远程准备任务，Run和Script二选一
+type SetupTask struct {
+	Name string `json:"name,omitempty"`
+	// Run 在远程shell中执行的命令
+	Run string `json:"run,omitempty"`
+	// Script 本地脚本文件，上传到远程后执行
+	Script string `json:"script,omitempty"`
+	// Check 该命令在远程成功时认为任务已完成
+	Check string `json:"check,omitempty"`
+	// Always 每次连接都执行，不记录完成状态
+	Always bool `json:"always,omitempty"`
+}
+
+// Label 任务的显示名称，未命名时使用命令或脚本路径
+func (t SetupTask) Label() string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case t.Script != "":
+		return t.Script
+	default:
+		return t.Run
+	}
+}
+
+func (c *Config) AddWorkspace(workspace WorkspaceConfig) error {
+	if workspace.Name == "" {
+		return fmt.Errorf("workspace name is required")
+	}
+	if workspace.Host == "" {
+		return fmt.Errorf("workspace host is required")
+	}
+
+	return c.Update(func(latest *Config) error {
+		// setup只能在配置文件中编辑，替换工作区时保留
+		if existing, exists := latest.Workspaces[workspace.Name]; exists && workspace.Setup == nil {
+			workspace.Setup = existing.Setup
+		}
+		latest.Workspaces[workspace.Name] = workspace
+		return nil
+	})
+}
+
+func (c *Config) RemoveWorkspace(name string) error {
+	return c.Update(func(latest *Config) error {
+		if _, exists := latest.Workspaces[name]; !exists {
+			return fmt.Errorf("workspace %s not found", name)
+		}
+
+		delete(latest.Workspaces, name)
+		return nil
+	})
+}
+
+func (c *Config) GetWorkspace(name string) (WorkspaceConfig, bool) {
+	workspace, exists := c.Workspaces[name]
+	return workspace, exists
+}
+
+func (c *Config) ListWorkspaces() []WorkspaceConfig {
+	workspaces := make([]WorkspaceConfig, 0, len(c.Workspaces))
+	for _, workspace := range c.Workspaces {
+		workspaces = append(workspaces, workspace)
+	}
+	return workspaces
+}
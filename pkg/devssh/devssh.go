@@ -0,0 +1,307 @@
+// Package devssh 供其他Go程序嵌入的devssh工作流：连接主机、确保IDE运行、转发端口、查询状态和关闭环境。
+// 所有输出都经过Options中的logger和进度reporter，不直接写stdout。
+package devssh
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"devssh/pkg/config"
+	"devssh/pkg/ide"
+	"devssh/pkg/preflight"
+	"devssh/pkg/progress"
+	"devssh/pkg/ssh"
+	"devssh/pkg/tunnel"
+
+	"github.com/loft-sh/log"
+)
+
+// Options 会话的可选参数
+type Options struct {
+	// Logger 为nil时丢弃所有日志
+	Logger log.Logger
+	// Progress 为nil时不报告进度
+	Progress progress.Reporter
+}
+
+// Session 一台远程主机上的连接，方法可以并发调用
+type Session struct {
+	client   *ssh.Client
+	logger   log.Logger
+	progress progress.Reporter
+
+	mu      sync.Mutex
+	tunnels *tunnel.TunnelManager
+}
+
+// Connect 连接远程主机；cfg.Host可以是 [user@]host，在~/.ssh/config中时以其中的设置为基础，
+// cfg中的其他非空字段覆盖。devssh配置中的主机不会被查找
+func Connect(ctx context.Context, cfg ssh.Config, opts Options) (*Session, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.Discard
+	}
+
+	host := cfg.Host
+	cfg.Host = ""
+	resolved, err := config.ResolveHost(nil, host, cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := ssh.NewClientWithLogger(&resolved.SSH, logger)
+
+	step := newTracker(opts.Progress, resolved.Name, progress.PhaseConnect).Start(progress.PhaseConnect)
+	err = client.ConnectContext(ctx)
+	step.End(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	return NewSession(client, opts), nil
+}
+
+// NewSession 使用已建立的SSH连接创建会话，Close时关闭该连接
+func NewSession(client *ssh.Client, opts Options) *Session {
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.Discard
+	}
+	return &Session{client: client, logger: logger, progress: opts.Progress}
+}
+
+// Client 返回底层SSH连接
+func (s *Session) Client() *ssh.Client {
+	return s.client
+}
+
+// Close 停止全部端口转发并断开连接
+func (s *Session) Close() error {
+	s.mu.Lock()
+	if s.tunnels != nil {
+		_ = s.tunnels.StopAllTunnels()
+	}
+	s.mu.Unlock()
+	return s.client.Close()
+}
+
+// IDEOptions EnsureIDE的参数，零值表示默认的openvscode-server
+type IDEOptions struct {
+	// Type IDE类型，为空时为vscode
+	Type ide.IDE
+	// Version 为空时使用内置版本
+	Version    string
+	Extensions []string
+	// Settings settings.json的内容
+	Settings string
+	// Port 远程端口，为0时使用IDE的默认端口
+	Port   int
+	Limits config.ResourceLimits
+	// Environment IDE进程的登录shell、环境变量和env文件
+	Environment config.IDEEnvironment
+	// SkipPreflight 远程主机不满足安装要求时仍然安装
+	SkipPreflight bool
+	// LocalArchive 预先下载的安装包，设置时不联网下载
+	LocalArchive string
+}
+
+// IDE 已运行的远程IDE
+type IDE struct {
+	Type ide.IDE `json:"type"`
+	Port int     `json:"port"`
+	// Installed 本次调用是否新安装了IDE
+	Installed bool `json:"installed"`
+	PID       int  `json:"pid,omitempty"`
+}
+
+// EnsureIDE 未安装时安装IDE，然后确保它在指定端口上运行
+func (s *Session) EnsureIDE(ctx context.Context, opts IDEOptions) (*IDE, error) {
+	installer, err := s.installer(ctx, opts.Type)
+	if err != nil {
+		return nil, err
+	}
+	installer.SetVersion(opts.Version)
+	installer.SetOpenVSCodeExtensions(opts.Extensions)
+	installer.SetOpenVSCodeSettings(opts.Settings)
+	if err := installer.SetResourceLimits(opts.Limits); err != nil {
+		return nil, err
+	}
+	if err := installer.SetEnvironment(opts.Environment); err != nil {
+		return nil, err
+	}
+	if err := installer.SetLocalArchive(opts.LocalArchive); err != nil {
+		return nil, err
+	}
+
+	tracker := newTracker(s.progress, s.client.GetConfig().Host, progress.PhaseInstall, progress.PhaseStart)
+	result := &IDE{Type: s.ideType(opts.Type), Port: opts.Port}
+	if result.Port == 0 {
+		result.Port = installer.GetDefaultPort()
+	}
+
+	step := tracker.Start(progress.PhaseInstall)
+	result.Installed, err = s.install(installer, opts.SkipPreflight)
+	step.End(err)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	step = tracker.Start(progress.PhaseStart)
+	err = installer.Start(result.Port)
+	step.End(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start IDE: %w", err)
+	}
+	result.PID, _ = installer.GetPID(result.Port)
+
+	return result, nil
+}
+
+// install 未安装时检查远程主机并安装，返回是否新安装
+func (s *Session) install(installer *ide.Installer, skipPreflight bool) (bool, error) {
+	installed, err := installer.IsInstalled()
+	if err != nil {
+		return false, fmt.Errorf("failed to check IDE installation: %w", err)
+	}
+	if installed {
+		return false, nil
+	}
+
+	if !skipPreflight {
+		info, err := preflight.Collect(s.client)
+		if err != nil {
+			return false, err
+		}
+		for _, issue := range preflight.Check(info, preflight.DefaultRequirements()) {
+			if issue.Fatal {
+				return false, fmt.Errorf("%w: %w", preflight.ErrRequirementsNotMet, issue)
+			}
+			s.logger.Warnf("%s", issue.Message)
+		}
+	}
+
+	if err := installer.Install(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Forward 建立端口转发，本地端口被占用时顺延；转发在Close时停止
+func (s *Session) Forward(ctx context.Context, forwards ...tunnel.ForwardConfig) ([]tunnel.PortForwardResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.tunnels == nil {
+		s.tunnels = tunnel.NewTunnelManagerWithLogger(s.logger)
+		s.tunnels.Follow(s.client)
+	}
+	manager := s.tunnels
+	s.mu.Unlock()
+
+	step := newTracker(s.progress, s.client.GetConfig().Host, progress.PhaseForward).Start(progress.PhaseForward)
+	results, err := tunnel.CreatePortForwards(s.client, forwards, manager)
+	step.End(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create port forwards: %w", err)
+	}
+	return results, nil
+}
+
+// Status 远程主机和IDE的状态
+type Status struct {
+	System       *preflight.HostInfo `json:"system"`
+	IDE          string              `json:"ide"`
+	IDEInstalled bool                `json:"ide_installed"`
+	IDERunning   bool                `json:"ide_running"`
+	IDEPort      int                 `json:"ide_port"`
+	Issues       []preflight.Issue   `json:"issues,omitempty"`
+}
+
+// Status 收集远程主机的资源信息以及IDE是否安装和运行，port为0时检查默认端口
+func (s *Session) Status(ctx context.Context, ideType ide.IDE, port int) (*Status, error) {
+	installer, err := s.installer(ctx, ideType)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := preflight.Collect(s.client)
+	if err != nil {
+		return nil, err
+	}
+	if port == 0 {
+		port = installer.GetDefaultPort()
+	}
+
+	status := &Status{
+		System:  info,
+		IDE:     string(s.ideType(ideType)),
+		IDEPort: port,
+		Issues:  preflight.Check(info, preflight.DefaultRequirements()),
+	}
+	if status.IDEInstalled, err = installer.IsInstalled(); err != nil {
+		return nil, fmt.Errorf("failed to check IDE installation: %w", err)
+	}
+	if status.IDEInstalled {
+		if status.IDERunning, err = installer.IsRunning(port); err != nil {
+			s.logger.Warnf("Failed to check whether the IDE is running: %v", err)
+		}
+	}
+	return status, nil
+}
+
+// Down 停止本会话的端口转发，以及指定端口上的IDE和它的常驻服务，port为0时为默认端口
+func (s *Session) Down(ctx context.Context, ideType ide.IDE, port int) error {
+	s.mu.Lock()
+	if s.tunnels != nil {
+		_ = s.tunnels.StopAllTunnels()
+	}
+	s.mu.Unlock()
+
+	installer, err := s.installer(ctx, ideType)
+	if err != nil {
+		return err
+	}
+	if port == 0 {
+		port = installer.GetDefaultPort()
+	}
+	if err := installer.RemoveService(port); err != nil {
+		s.logger.Warnf("Failed to remove IDE service: %v", err)
+	}
+	if err := installer.Stop(port); err != nil {
+		return fmt.Errorf("failed to stop IDE: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) installer(ctx context.Context, ideType ide.IDE) (*ide.Installer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	installer := ide.NewInstallerWithOptions(s.client, s.ideType(ideType), nil, s.logger)
+	installer.SetContext(ctx)
+	return installer, nil
+}
+
+func (s *Session) ideType(ideType ide.IDE) ide.IDE {
+	if ideType == "" {
+		return ide.VSCode
+	}
+	return ideType
+}
+
+// newTracker reporter为nil时进度事件被丢弃
+func newTracker(reporter progress.Reporter, host string, phases ...string) *progress.Tracker {
+	if reporter == nil {
+		reporter = discardReporter{}
+	}
+	return progress.NewTracker(reporter, host, phases...)
+}
+
+type discardReporter struct{}
+
+func (discardReporter) Report(progress.Event) {}
@@ -0,0 +1,115 @@
+package filesync
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// patch 按块比较本地文件与远程旧版本，只发送md5不同的块，最后截断到新大小并恢复修改时间和权限
+// 远程直接在原文件上写入，中途失败时由调用方整体上传覆盖
+func (s *Syncer) patch(file FileInfo) (int64, error) {
+	remoteSums, err := s.remoteBlockSums(file.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open(filepath.Join(s.localDir, filepath.FromSlash(file.Path)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", file.Path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", file.Path, err)
+	}
+
+	session, err := s.client.NewSession()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	// 每个块以 "偏移 长度" 一行开头，后跟块数据；read逐字节读取管道，不会读入块数据
+	cmd := fmt.Sprintf(`cd %s && f=%s && while read off len; do dd of="$f" bs="$len" count=1 iflag=fullblock oflag=seek_bytes seek="$off" conv=notrunc status=none || exit 1; done && truncate -s %d "$f" && chmod %o "$f" && touch -d @%d "$f"`,
+		remotePath(s.remoteDir), shellQuote(file.Path), info.Size(), info.Mode().Perm(), info.ModTime().Unix())
+	if err := session.Start(cmd); err != nil {
+		return 0, fmt.Errorf("failed to start remote patch: %w", err)
+	}
+
+	sent, writeErr := writeChangedBlocks(stdin, io.LimitReader(f, info.Size()), remoteSums, s.opts.BlockSize)
+	stdin.Close()
+	if err := session.Wait(); err != nil {
+		return 0, fmt.Errorf("remote patch of %s failed: %w: %s", file.Path, err, strings.TrimSpace(stderr.String()))
+	}
+	if writeErr != nil {
+		return 0, fmt.Errorf("failed to send %s: %w", file.Path, writeErr)
+	}
+	return sent, nil
+}
+
+// remoteBlockSums 按块计算远程文件的md5，依赖GNU split的--filter
+func (s *Syncer) remoteBlockSums(rel string) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := fmt.Sprintf("cd %s && split -b %d --filter=md5sum -- %s",
+		remotePath(s.remoteDir), s.opts.BlockSize, shellQuote(rel))
+	if err := s.client.RunCommandWithOutput(cmd, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("failed to compute remote block checksums: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var sums []string
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		sum, _, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || len(sum) != md5.Size*2 {
+			return nil, fmt.Errorf("unexpected block checksum line %q", scanner.Text())
+		}
+		sums = append(sums, sum)
+	}
+	return sums, scanner.Err()
+}
+
+// writeChangedBlocks 逐块读取本地内容，写出与远程md5不同或超出远程长度的块，返回发送的数据量
+func writeChangedBlocks(w io.Writer, r io.Reader, remoteSums []string, blockSize int) (int64, error) {
+	buf := make([]byte, blockSize)
+	var sent int64
+	for index := 0; ; index++ {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			return sent, nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return sent, err
+		}
+
+		block := buf[:n]
+		sum := md5.Sum(block)
+		if index >= len(remoteSums) || hex.EncodeToString(sum[:]) != remoteSums[index] {
+			if _, err := fmt.Fprintf(w, "%d %d\n", int64(index)*int64(blockSize), n); err != nil {
+				return sent, err
+			}
+			if _, err := w.Write(block); err != nil {
+				return sent, err
+			}
+			sent += int64(n)
+		}
+
+		if n < blockSize {
+			return sent, nil
+		}
+	}
+}
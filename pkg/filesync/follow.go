@@ -0,0 +1,157 @@
+package filesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// followDebounce 最后一次文件事件之后等待这么久再上传，合并编辑器保存时的多次写入
+const followDebounce = 100 * time.Millisecond
+
+// Follow 监听本地目录的文件系统事件，把变化的文件立即推送到远程，直到ctx被取消
+// 先同步一次整个目录；之后本地删除的文件同样从远程删除。每次推送的结果或错误交给onSync
+func (s *Syncer) Follow(ctx context.Context, onSync func(*Result, error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// 先开始监听再做首次同步，避免遗漏同步期间的修改
+	if err := s.watchTree(watcher, "."); err != nil {
+		return err
+	}
+	onSync(s.Sync())
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(followDebounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			s.logger.Warnf("File watcher error: %v", err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			rel, ok := s.relPath(event.Name)
+			if !ok || Excluded(rel, s.opts.Excludes) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				// 新目录需要加入监听，其中已有的内容由推送时的扫描带上
+				if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+					if err := s.watchTree(watcher, rel); err != nil {
+						s.logger.Warnf("Failed to watch %s: %v", rel, err)
+					}
+				}
+			}
+			pending[rel] = true
+			timer.Reset(followDebounce)
+		case <-timer.C:
+			paths := make([]string, 0, len(pending))
+			for rel := range pending {
+				paths = append(paths, rel)
+			}
+			pending = make(map[string]bool)
+			onSync(s.Push(paths))
+		}
+	}
+}
+
+// watchTree 监听目录及其下未被排除的全部子目录
+func (s *Syncer) watchTree(watcher *fsnotify.Watcher, rel string) error {
+	root := filepath.Join(s.localDir, filepath.FromSlash(rel))
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 目录在遍历时被删除
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if r, ok := s.relPath(p); ok && r != "." && Excluded(r, s.opts.Excludes) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(p); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", p, err)
+		}
+		return nil
+	})
+}
+
+// relPath 把本地路径转换为相对于同步目录的路径，使用/分隔
+func (s *Syncer) relPath(p string) (string, bool) {
+	rel, err := filepath.Rel(s.localDir, p)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return rel, true
+}
+
+// Push 只同步给定的相对路径：本地存在的文件整体上传，目录连同其内容上传，本地已不存在的从远程删除
+func (s *Syncer) Push(paths []string) (*Result, error) {
+	start := time.Now()
+
+	files := make(Manifest)
+	var p plan
+	for _, rel := range paths {
+		local := filepath.Join(s.localDir, filepath.FromSlash(rel))
+		info, err := os.Lstat(local)
+		if errors.Is(err, fs.ErrNotExist) {
+			p.deletes = append(p.deletes, rel)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", rel, err)
+		}
+
+		switch {
+		case info.IsDir():
+			files[rel] = FileInfo{Path: rel, Dir: true, Mtime: info.ModTime().Unix(), Mode: info.Mode().Perm()}
+			sub, err := ScanLocal(local, s.opts.Excludes, false)
+			if err != nil {
+				return nil, err
+			}
+			for subRel, file := range sub {
+				file.Path = path.Join(rel, subRel)
+				files[file.Path] = file
+			}
+		case info.Mode().IsRegular():
+			files[rel] = FileInfo{Path: rel, Size: info.Size(), Mtime: info.ModTime().Unix(), Mode: info.Mode().Perm()}
+		}
+	}
+	for _, rel := range sortedPaths(files) {
+		p.uploads = append(p.uploads, files[rel])
+	}
+
+	result, err := s.apply(p)
+	if err != nil {
+		return nil, err
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
@@ -0,0 +1,198 @@
+package filesync
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileInfo 同步比较用的文件信息
+type FileInfo struct {
+	Path  string // 相对路径，使用/分隔
+	Dir   bool
+	Size  int64
+	Mtime int64 // 修改时间，秒
+	Mode  fs.FileMode
+	Sum   string // 内容的md5，只在校验和模式下填充
+}
+
+// Manifest 相对路径到文件信息的映射
+type Manifest map[string]FileInfo
+
+// ScanLocal 扫描本地目录，跳过被排除的路径、符号链接和特殊文件
+func ScanLocal(root string, excludes []string, checksum bool) (Manifest, error) {
+	manifest := make(Manifest)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if Excluded(rel, excludes) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		file := FileInfo{
+			Path:  rel,
+			Dir:   d.IsDir(),
+			Size:  info.Size(),
+			Mtime: info.ModTime().Unix(),
+			Mode:  info.Mode().Perm(),
+		}
+		if file.Dir {
+			file.Size = 0
+		} else if checksum {
+			if file.Sum, err = fileSum(p); err != nil {
+				return err
+			}
+		}
+		manifest[rel] = file
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return manifest, nil
+}
+
+// Excluded 判断相对路径是否被排除：不含/的模式匹配任意一级名称，含/的模式匹配从根开始的路径
+func Excluded(rel string, excludes []string) bool {
+	for _, pattern := range excludes {
+		pattern = strings.TrimSuffix(pattern, "/")
+		if pattern == "" {
+			continue
+		}
+		if strings.Contains(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+			// 排除目录时其下的全部路径同样排除
+			for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+				if ok, _ := path.Match(pattern, p); ok {
+					return true
+				}
+			}
+			continue
+		}
+		for _, name := range strings.Split(rel, "/") {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fileSum 计算文件内容的md5
+func fileSum(p string) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// remoteManifestCommand 列出远程目录内容的命令，每行为 类型\t大小\t修改时间\t权限\t相对路径；目录不存在时输出为空
+// 不含/的排除模式直接在find中剪枝，避免遍历 node_modules 之类的大目录
+func remoteManifestCommand(dir string, excludes []string) string {
+	var prune []string
+	for _, pattern := range excludes {
+		pattern = strings.TrimSuffix(pattern, "/")
+		if pattern != "" && !strings.Contains(pattern, "/") {
+			prune = append(prune, "-name "+shellQuote(pattern))
+		}
+	}
+
+	find := "find . -mindepth 1"
+	if len(prune) > 0 {
+		find += ` \( ` + strings.Join(prune, " -o ") + ` \) -prune -o`
+	}
+	find += ` \( -type f -o -type d \) -printf '%y\t%s\t%T@\t%m\t%P\n'`
+
+	return fmt.Sprintf(`d=%s; if [ -d "$d" ]; then cd "$d" && %s; fi`, remotePath(dir), find)
+}
+
+// parseRemoteManifest 解析remoteManifestCommand的输出
+func parseRemoteManifest(r io.Reader, excludes []string) (Manifest, error) {
+	manifest := make(Manifest)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 5)
+		if len(fields) != 5 || fields[4] == "" {
+			return nil, fmt.Errorf("unexpected remote listing line %q (remote find must support -printf)", scanner.Text())
+		}
+		rel := fields[4]
+		if Excluded(rel, excludes) {
+			continue
+		}
+
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size in remote listing line %q", scanner.Text())
+		}
+		seconds, _, _ := strings.Cut(fields[2], ".")
+		mtime, err := strconv.ParseInt(seconds, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modification time in remote listing line %q", scanner.Text())
+		}
+		mode, err := strconv.ParseUint(fields[3], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode in remote listing line %q", scanner.Text())
+		}
+
+		manifest[rel] = FileInfo{
+			Path:  rel,
+			Dir:   fields[0] == "d",
+			Size:  size,
+			Mtime: mtime,
+			Mode:  fs.FileMode(mode),
+		}
+	}
+	return manifest, scanner.Err()
+}
+
+// remotePath 引用远程路径，保留开头的 ~/ 以便由远程shell展开
+func remotePath(p string) string {
+	if p == "~" {
+		return `"$HOME"`
+	}
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		return `"$HOME"/` + shellQuote(rest)
+	}
+	return shellQuote(p)
+}
+
+// shellQuote 用单引号引用字符串
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,389 @@
+package filesync
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+)
+
+const (
+	// DefaultBlockSize 块级差异传输的默认块大小
+	DefaultBlockSize = 64 * 1024
+	// DefaultDeltaThreshold 远程已有旧版本且不小于该大小的文件只传输变化的块
+	DefaultDeltaThreshold = 1024 * 1024
+)
+
+// Options 同步选项
+type Options struct {
+	Checksum bool     // 按内容校验和而不是大小和修改时间判断文件是否变化
+	Delete   bool     // 删除远程目录中本地不存在的文件
+	Excludes []string // 排除的路径模式，如 .git、node_modules、build/*.o
+	DryRun   bool     // 只计算需要执行的操作，不修改远程
+	// BlockSize 块级差异传输的块大小，0使用DefaultBlockSize
+	BlockSize int
+	// DeltaThreshold 启用块级差异传输的最小文件大小，0使用DefaultDeltaThreshold，负数禁用
+	DeltaThreshold int64
+}
+
+// Result 一次同步的结果
+type Result struct {
+	Uploaded []string // 整体上传的文件和目录
+	Patched  []string // 按块传输差异的文件
+	Deleted  []string // 从远程删除的路径
+	Bytes    int64    // 发送的文件数据量（压缩前）
+	Duration time.Duration
+}
+
+// Changed 返回是否有任何变化
+func (r *Result) Changed() bool {
+	return len(r.Uploaded)+len(r.Patched)+len(r.Deleted) > 0
+}
+
+// plan 比较本地和远程清单后需要执行的操作
+type plan struct {
+	uploads []FileInfo
+	patches []FileInfo
+	deletes []string
+}
+
+// Syncer 通过SSH把本地目录增量同步到远程目录
+// 文件按大小和修改时间（或校验和）比较，变化的文件打包为一个tar流上传；
+// 远程已有旧版本的大文件按固定大小的块比较md5，只发送变化的块（不处理插入造成的偏移）
+type Syncer struct {
+	client    *ssh.Client
+	localDir  string
+	remoteDir string
+	opts      Options
+	logger    log.Logger
+}
+
+// NewSyncer 创建同步器
+func NewSyncer(client *ssh.Client, localDir, remoteDir string, opts Options, logger log.Logger) *Syncer {
+	if opts.BlockSize <= 0 {
+		opts.BlockSize = DefaultBlockSize
+	}
+	if opts.DeltaThreshold == 0 {
+		opts.DeltaThreshold = DefaultDeltaThreshold
+	}
+	return &Syncer{
+		client:    client,
+		localDir:  localDir,
+		remoteDir: strings.TrimSuffix(remoteDir, "/"),
+		opts:      opts,
+		logger:    logger,
+	}
+}
+
+// Sync 比较本地和远程目录并上传差异
+func (s *Syncer) Sync() (*Result, error) {
+	start := time.Now()
+
+	local, err := ScanLocal(s.localDir, s.opts.Excludes, s.opts.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := s.remoteManifest(local)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.apply(s.plan(local, remote, s.opts.Checksum))
+	if err != nil {
+		return nil, err
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// remoteManifest 获取远程目录清单，校验和模式下只为大小与本地相同的文件计算md5
+func (s *Syncer) remoteManifest(local Manifest) (Manifest, error) {
+	var stdout, stderr bytes.Buffer
+	if err := s.client.RunCommandWithOutput(remoteManifestCommand(s.remoteDir, s.opts.Excludes), &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("failed to list remote directory %s: %w: %s", s.remoteDir, err, strings.TrimSpace(stderr.String()))
+	}
+	remote, err := parseRemoteManifest(&stdout, s.opts.Excludes)
+	if err != nil {
+		return nil, err
+	}
+	if !s.opts.Checksum {
+		return remote, nil
+	}
+
+	var candidates []string
+	for rel, file := range remote {
+		if l, ok := local[rel]; ok && !file.Dir && !l.Dir && l.Size == file.Size {
+			candidates = append(candidates, rel)
+		}
+	}
+	if len(candidates) == 0 {
+		return remote, nil
+	}
+
+	sums, err := s.remoteSums(candidates)
+	if err != nil {
+		return nil, err
+	}
+	for rel, sum := range sums {
+		file := remote[rel]
+		file.Sum = sum
+		remote[rel] = file
+	}
+	return remote, nil
+}
+
+// remoteSums 计算远程文件的md5，路径通过标准输入传递
+func (s *Syncer) remoteSums(paths []string) (map[string]string, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = strings.NewReader(strings.Join(paths, "\n") + "\n")
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	cmd := fmt.Sprintf(`cd %s && tr '\n' '\0' | xargs -0 md5sum --`, remotePath(s.remoteDir))
+	if err := session.Run(cmd); err != nil {
+		return nil, fmt.Errorf("failed to compute remote checksums: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	sums := make(map[string]string, len(paths))
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		// md5sum对含特殊字符的文件名以\开头转义，这些文件按内容不同处理
+		sum, rel, ok := strings.Cut(line, "  ")
+		if ok && !strings.HasPrefix(sum, `\`) {
+			sums[rel] = sum
+		}
+	}
+	return sums, nil
+}
+
+// plan 计算需要上传、按块更新和删除的路径
+func (s *Syncer) plan(local, remote Manifest, checksum bool) plan {
+	var p plan
+	replaced := make(map[string]bool)
+
+	for _, rel := range sortedPaths(local) {
+		file := local[rel]
+		existing, ok := remote[rel]
+		switch {
+		case !ok:
+			p.uploads = append(p.uploads, file)
+		case file.Dir != existing.Dir:
+			// 文件和目录互相替换时先删除远程路径
+			p.deletes = append(p.deletes, rel)
+			replaced[rel] = true
+			p.uploads = append(p.uploads, file)
+		case file.Dir:
+		case unchanged(file, existing, checksum):
+		case s.opts.DeltaThreshold > 0 && existing.Size > 0 && file.Size >= s.opts.DeltaThreshold:
+			p.patches = append(p.patches, file)
+		default:
+			p.uploads = append(p.uploads, file)
+		}
+	}
+
+	if s.opts.Delete {
+		var removed []string
+		for _, rel := range sortedPaths(remote) {
+			if _, ok := local[rel]; ok || replaced[rel] || under(rel, removed) {
+				continue
+			}
+			removed = append(removed, rel)
+		}
+		p.deletes = append(p.deletes, removed...)
+	}
+	return p
+}
+
+// unchanged 判断本地文件与远程文件是否相同
+func unchanged(local, remote FileInfo, checksum bool) bool {
+	if local.Size != remote.Size {
+		return false
+	}
+	if checksum {
+		return local.Sum != "" && local.Sum == remote.Sum
+	}
+	return local.Mtime == remote.Mtime
+}
+
+// under 判断路径是否位于已删除的目录中
+func under(rel string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedPaths 按路径排序，目录排在其内容之前
+func sortedPaths(m Manifest) []string {
+	paths := make([]string, 0, len(m))
+	for rel := range m {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// apply 依次执行删除、整体上传和按块更新
+func (s *Syncer) apply(p plan) (*Result, error) {
+	result := &Result{Deleted: p.deletes}
+	for _, file := range p.uploads {
+		result.Uploaded = append(result.Uploaded, file.Path)
+	}
+	for _, file := range p.patches {
+		result.Patched = append(result.Patched, file.Path)
+	}
+	if s.opts.DryRun {
+		return result, nil
+	}
+
+	if len(p.deletes) > 0 {
+		if err := s.remove(p.deletes); err != nil {
+			return nil, err
+		}
+	}
+
+	uploads := p.uploads
+	result.Patched = nil
+	for _, file := range p.patches {
+		sent, err := s.patch(file)
+		if err != nil {
+			// 远程缺少GNU split/dd等工具时改为整体上传
+			s.logger.Debugf("Block transfer of %s failed, uploading the whole file: %v", file.Path, err)
+			uploads = append(uploads, file)
+			result.Uploaded = append(result.Uploaded, file.Path)
+			continue
+		}
+		result.Patched = append(result.Patched, file.Path)
+		result.Bytes += sent
+	}
+
+	if len(uploads) > 0 {
+		sent, err := s.upload(uploads)
+		if err != nil {
+			return nil, err
+		}
+		result.Bytes += sent
+	}
+	return result, nil
+}
+
+// remove 删除远程路径
+func (s *Syncer) remove(paths []string) error {
+	quoted := make([]string, len(paths))
+	for i, rel := range paths {
+		quoted[i] = shellQuote(rel)
+	}
+	cmd := fmt.Sprintf("cd %s && rm -rf -- %s", remotePath(s.remoteDir), strings.Join(quoted, " "))
+	if output, err := s.client.RunCommand(cmd); err != nil {
+		return fmt.Errorf("failed to delete remote files: %w: %s", err, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// upload 把文件打包为tar.gz流，在远程解压到目标目录，保留修改时间和权限
+func (s *Syncer) upload(files []FileInfo) (int64, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	dir := remotePath(s.remoteDir)
+	if err := session.Start(fmt.Sprintf("mkdir -p %s && tar -xzf - -C %s", dir, dir)); err != nil {
+		return 0, fmt.Errorf("failed to start remote tar: %w", err)
+	}
+
+	sent, writeErr := s.writeArchive(stdin, files)
+	stdin.Close()
+	if err := session.Wait(); err != nil {
+		return 0, fmt.Errorf("remote tar failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if writeErr != nil {
+		return 0, writeErr
+	}
+	return sent, nil
+}
+
+// writeArchive 写入tar.gz，返回文件数据量
+func (s *Syncer) writeArchive(w io.Writer, files []FileInfo) (int64, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	var sent int64
+	for _, file := range files {
+		n, err := s.writeEntry(tw, file)
+		if err != nil {
+			return sent, err
+		}
+		sent += n
+	}
+
+	if err := tw.Close(); err != nil {
+		return sent, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return sent, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return sent, nil
+}
+
+// writeEntry 写入一个文件或目录，文件按打开时的实际内容写入
+func (s *Syncer) writeEntry(tw *tar.Writer, file FileInfo) (int64, error) {
+	header := &tar.Header{
+		Name:    file.Path,
+		Mode:    int64(file.Mode),
+		ModTime: time.Unix(file.Mtime, 0),
+	}
+	if file.Dir {
+		header.Typeflag = tar.TypeDir
+		header.Name += "/"
+		return 0, tw.WriteHeader(header)
+	}
+
+	f, err := os.Open(filepath.Join(s.localDir, filepath.FromSlash(file.Path)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", file.Path, err)
+	}
+	defer f.Close()
+
+	// 扫描之后文件可能又被修改，以打开时的大小和时间为准
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", file.Path, err)
+	}
+	header.Typeflag = tar.TypeReg
+	header.Size = info.Size()
+	header.ModTime = info.ModTime()
+
+	if err := tw.WriteHeader(header); err != nil {
+		return 0, fmt.Errorf("failed to write archive header for %s: %w", file.Path, err)
+	}
+	n, err := io.CopyN(tw, f, header.Size)
+	if err != nil {
+		return n, fmt.Errorf("failed to send %s: %w", file.Path, err)
+	}
+	return n, nil
+}
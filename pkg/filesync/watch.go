@@ -0,0 +1,58 @@
+package filesync
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWatchInterval 持续同步时扫描本地目录的默认间隔
+const DefaultWatchInterval = 2 * time.Second
+
+// Watch 先同步一次，之后定期扫描本地目录，发现变化时再次同步，直到ctx被取消
+// 每次同步的结果或错误交给onSync，同步失败不会结束监视
+func (s *Syncer) Watch(ctx context.Context, interval time.Duration, onSync func(*Result, error)) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	previous, err := ScanLocal(s.localDir, s.opts.Excludes, false)
+	if err != nil {
+		return err
+	}
+	onSync(s.Sync())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := ScanLocal(s.localDir, s.opts.Excludes, false)
+		if err != nil {
+			s.logger.Warnf("Failed to scan %s: %v", s.localDir, err)
+			continue
+		}
+		if current.Equal(previous) {
+			continue
+		}
+		previous = current
+		onSync(s.Sync())
+	}
+}
+
+// Equal 按类型、大小、修改时间和权限比较两个清单，不比较校验和
+func (m Manifest) Equal(other Manifest) bool {
+	if len(m) != len(other) {
+		return false
+	}
+	for rel, file := range m {
+		o, ok := other[rel]
+		if !ok || file.Dir != o.Dir || file.Size != o.Size || file.Mtime != o.Mtime || file.Mode != o.Mode {
+			return false
+		}
+	}
+	return true
+}
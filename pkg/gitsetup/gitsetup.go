@@ -0,0 +1,180 @@
+package gitsetup
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+)
+
+const (
+	// DefaultTokenHost 未指定时令牌对应的git服务器
+	DefaultTokenHost = "github.com"
+	// DefaultTokenUser 未指定时写入凭据的用户名，GitHub和GitLab的令牌不校验用户名
+	DefaultTokenUser = "git"
+	// credentialsFile 远程保存令牌的凭据文件，与用户自己的 ~/.git-credentials 分开
+	credentialsFile = "~/.config/devssh/git-credentials"
+)
+
+// Options 写入远程的git配置
+type Options struct {
+	// Identity 复制本地全局配置中的 user.name 和 user.email
+	Identity bool
+	// Token 访问令牌，只用于TokenHost上的HTTPS仓库
+	Token     string
+	TokenHost string
+	TokenUser string
+}
+
+// Identity git提交者信息
+type Identity struct {
+	Name  string
+	Email string
+}
+
+// LocalIdentity 读取本地全局git配置中的用户名和邮箱，未设置的项为空
+func LocalIdentity() (Identity, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return Identity{}, fmt.Errorf("git is not installed locally")
+	}
+	return Identity{
+		Name:  localConfig("user.name"),
+		Email: localConfig("user.email"),
+	}, nil
+}
+
+// localConfig 读取本地全局git配置项，未设置时返回空
+func localConfig(key string) string {
+	output, err := exec.Command("git", "config", "--global", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// Bootstrapper 在远程主机上准备git，使首次提交和推送无需手动配置
+type Bootstrapper struct {
+	client *ssh.Client
+	logger log.Logger
+}
+
+// NewBootstrapper 创建git配置引导器
+func NewBootstrapper(client *ssh.Client, logger log.Logger) *Bootstrapper {
+	return &Bootstrapper{client: client, logger: logger}
+}
+
+// Run 写入提交者信息和令牌凭据，远程没有git时跳过
+func (b *Bootstrapper) Run(opts Options) error {
+	if _, err := b.client.RunCommand("command -v git"); err != nil {
+		b.logger.Warnf("git is not installed on the remote host, skipping git setup")
+		return nil
+	}
+
+	if opts.Identity {
+		identity, err := LocalIdentity()
+		if err != nil {
+			return err
+		}
+		if err := b.setIdentity(identity); err != nil {
+			return err
+		}
+	}
+
+	if opts.Token != "" {
+		if err := b.setToken(opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setIdentity 设置远程的 user.name 和 user.email，远程已有不同的值时保留并提示
+func (b *Bootstrapper) setIdentity(identity Identity) error {
+	values := []struct{ key, value string }{
+		{"user.name", identity.Name},
+		{"user.email", identity.Email},
+	}
+	for _, item := range values {
+		if item.value == "" {
+			b.logger.Warnf("Local git %s is not set, not copying it", item.key)
+			continue
+		}
+
+		current, _ := b.client.RunCommand("git config --global --get " + item.key)
+		current = strings.TrimSpace(current)
+		switch current {
+		case item.value:
+			continue
+		case "":
+		default:
+			b.logger.Warnf("Remote git %s is already %q, keeping it", item.key, current)
+			continue
+		}
+
+		if output, err := b.client.RunCommand(fmt.Sprintf("git config --global %s %s", item.key, shellQuote(item.value))); err != nil {
+			return fmt.Errorf("failed to set remote git %s: %w: %s", item.key, err, strings.TrimSpace(output))
+		}
+		b.logger.Infof("Set remote git %s to %s", item.key, item.value)
+	}
+	return nil
+}
+
+// setToken 把令牌写入远程的独立凭据文件，并只为该服务器的HTTPS地址启用这个凭据文件
+// 令牌通过标准输入传递，不出现在远程命令行中
+func (b *Bootstrapper) setToken(opts Options) error {
+	host := opts.TokenHost
+	if host == "" {
+		host = DefaultTokenHost
+	}
+	user := opts.TokenUser
+	if user == "" {
+		user = DefaultTokenUser
+	}
+	credential := (&url.URL{Scheme: "https", User: url.UserPassword(user, opts.Token), Host: host}).String()
+
+	session, err := b.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+
+	var output bytes.Buffer
+	session.Stdin = strings.NewReader(credential + "\n")
+	session.Stdout = &output
+	session.Stderr = &output
+
+	file := `"$HOME"/` + strings.TrimPrefix(credentialsFile, "~/")
+	// 替换该服务器已有的凭据，保留其他服务器的
+	cmd := fmt.Sprintf(`umask 077 && f=%s && mkdir -p "$(dirname "$f")" && touch "$f" && { grep -v %s "$f"; cat; } > "$f.tmp" && mv "$f.tmp" "$f" && git config --global %s %s`,
+		file, shellQuote("@"+host+"$"), shellQuote("credential.https://"+host+".helper"), shellQuote("store --file "+credentialsFile))
+	if err := session.Run(cmd); err != nil {
+		return fmt.Errorf("failed to store git credentials on the remote host: %w: %s", err, strings.TrimSpace(output.String()))
+	}
+	b.logger.Infof("Stored git token for %s on the remote host", host)
+	return nil
+}
+
+// RemoveToken 删除远程的凭据文件，并取消 setToken 为各服务器设置的 credential helper，
+// 用户自己配置的helper不受影响
+func (b *Bootstrapper) RemoveToken() error {
+	file := `"$HOME"/` + strings.TrimPrefix(credentialsFile, "~/")
+	cmd := fmt.Sprintf(`rm -f %s; command -v git >/dev/null 2>&1 || exit 0
+git config --global --get-regexp '^credential\.https://.*\.helper$' | while read -r key value; do
+	if [ "$value" = %s ]; then git config --global --unset-all "$key" || exit 1; fi
+done`, file, shellQuote("store --file "+credentialsFile))
+	if output, err := b.client.RunCommand(cmd); err != nil {
+		return fmt.Errorf("failed to remove git credentials from the remote host: %w: %s", err, strings.TrimSpace(output))
+	}
+	b.logger.Debugf("Removed devssh git credentials from the remote host")
+	return nil
+}
+
+// shellQuote 用单引号引用字符串
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
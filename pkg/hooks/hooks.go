@@ -0,0 +1,182 @@
+package hooks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"devssh/pkg/config"
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+)
+
+// Stage 会话生命周期阶段
+type Stage string
+
+const (
+	PreConnect  Stage = "pre_connect"
+	PostConnect Stage = "post_connect"
+	PostInstall Stage = "post_install"
+	PostStart   Stage = "post_start"
+	PostStop    Stage = "post_stop"
+)
+
+// remote 判断阶段的钩子是否在远程执行
+func (s Stage) remote() bool {
+	return s == PostConnect || s == PostInstall
+}
+
+// Env 传给钩子的会话信息，以 DEVSSH_* 环境变量提供，尚未确定的值不设置
+type Env struct {
+	Host       string
+	IDE        string
+	SessionID  string
+	LocalPort  int
+	RemotePort int
+	URL        string
+}
+
+// vars 返回钩子的环境变量
+func (e Env) vars(stage Stage) map[string]string {
+	vars := map[string]string{
+		"DEVSSH_HOOK": string(stage),
+		"DEVSSH_HOST": e.Host,
+	}
+	if e.IDE != "" {
+		vars["DEVSSH_IDE"] = e.IDE
+	}
+	if e.SessionID != "" {
+		vars["DEVSSH_SESSION_ID"] = e.SessionID
+	}
+	if e.LocalPort != 0 {
+		vars["DEVSSH_IDE_PORT"] = strconv.Itoa(e.LocalPort)
+	}
+	if e.RemotePort != 0 {
+		vars["DEVSSH_REMOTE_PORT"] = strconv.Itoa(e.RemotePort)
+	}
+	if e.URL != "" {
+		vars["DEVSSH_IDE_URL"] = e.URL
+	}
+	return vars
+}
+
+// Runner 执行配置的钩子，本地钩子通过sh（Windows上为cmd）执行，远程钩子通过SSH执行
+// 钩子的输出直接写到devssh的标准输出和标准错误
+type Runner struct {
+	hooks  config.Hooks
+	client *ssh.Client
+	logger log.Logger
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// NewRunner 创建钩子执行器，hooks为nil时不执行任何钩子
+func NewRunner(hooks *config.Hooks, logger log.Logger) *Runner {
+	r := &Runner{
+		logger: logger,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+	if hooks != nil {
+		r.hooks = *hooks
+	}
+	return r
+}
+
+// SetClient 设置执行远程钩子的SSH连接
+func (r *Runner) SetClient(client *ssh.Client) {
+	r.client = client
+}
+
+// commands 返回阶段配置的命令
+func (r *Runner) commands(stage Stage) []string {
+	switch stage {
+	case PreConnect:
+		return r.hooks.PreConnect
+	case PostConnect:
+		return r.hooks.PostConnect
+	case PostInstall:
+		return r.hooks.PostInstall
+	case PostStart:
+		return r.hooks.PostStart
+	case PostStop:
+		return r.hooks.PostStop
+	}
+	return nil
+}
+
+// Run 按顺序执行阶段的全部钩子，任一钩子失败时停止并返回错误
+func (r *Runner) Run(stage Stage, env Env) error {
+	commands := r.commands(stage)
+	if len(commands) == 0 {
+		return nil
+	}
+	if stage.remote() && r.client == nil {
+		return fmt.Errorf("%s hooks need an SSH connection", stage)
+	}
+
+	vars := env.vars(stage)
+	for i, command := range commands {
+		r.logger.Infof("Running %s hook %d/%d: %s", stage, i+1, len(commands), command)
+
+		var err error
+		if stage.remote() {
+			err = r.runRemote(command, vars)
+		} else {
+			err = r.runLocal(command, vars)
+		}
+		if err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", stage, command, err)
+		}
+	}
+	return nil
+}
+
+// runLocal 在本地shell中执行命令
+func (r *Runner) runLocal(command string, vars map[string]string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	cmd.Env = os.Environ()
+	for _, name := range sortedNames(vars) {
+		cmd.Env = append(cmd.Env, name+"="+vars[name])
+	}
+	cmd.Stdout = r.stdout
+	cmd.Stderr = r.stderr
+	return cmd.Run()
+}
+
+// runRemote 在远程shell中执行命令，环境变量通过export传入
+func (r *Runner) runRemote(command string, vars map[string]string) error {
+	var script strings.Builder
+	for _, name := range sortedNames(vars) {
+		fmt.Fprintf(&script, "export %s=%s; ", name, shellQuote(vars[name]))
+	}
+	script.WriteString(command)
+
+	return r.client.RunCommandWithOutput("sh -c "+shellQuote(script.String()), r.stdout, r.stderr)
+}
+
+func sortedNames(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shellQuote 用单引号引用字符串
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,112 @@
+package ide
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	devsshconfig "devssh/pkg/config"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// posixShells 可以作为登录shell执行启动脚本的shell，其他shell（如fish）改用bash
+var posixShells = []string{"bash", "zsh", "ksh", "sh", "dash"}
+
+// ValidateEnvironment 检查IDE环境的取值，它们会写入远程shell脚本
+func ValidateEnvironment(env devsshconfig.IDEEnvironment) error {
+	for name := range env.Env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+	}
+	for _, file := range env.EnvFiles {
+		if strings.TrimSpace(file) == "" {
+			return fmt.Errorf("env file path must not be empty")
+		}
+	}
+	_, err := env.ResolveLocal()
+	return err
+}
+
+// environmentScript 生成定义 launch_ide 函数的shell片段，launch_ide 通过exec启动IDE，PID不变。
+// 设置了登录shell时由它加载profile，之后依次设置时区和语言环境、加载env文件和变量，后设置的优先
+func environmentScript(env devsshconfig.IDEEnvironment) string {
+	if env.IsZero() {
+		return "launch_ide() {\n    exec \"$@\"\n}\n"
+	}
+
+	var script strings.Builder
+	for _, file := range env.EnvFiles {
+		fmt.Fprintf(&script, "[ -f %s ] || printf 'warning: env file %%s not found on the remote host\\n' %s\n",
+			remotePath(file), shellQuote(file))
+	}
+
+	if env.Locale != "" {
+		// locale -a 列出的名称不区分大小写，UTF-8写作utf8
+		fmt.Fprintf(&script, `if command -v locale >/dev/null 2>&1 && ! locale -a 2>/dev/null | tr 'A-Z' 'a-z' | sed 's/utf-8/utf8/' | grep -qxF %s; then
+    printf 'warning: locale %%s is not installed on the remote host\n' %s
+fi
+`, shellQuote(normalizeLocale(env.Locale)), shellQuote(env.Locale))
+	}
+
+	var prelude strings.Builder
+	if env.TZ != "" {
+		fmt.Fprintf(&prelude, "export TZ=%s; ", shellQuote(env.TZ))
+	}
+	if env.Locale != "" {
+		fmt.Fprintf(&prelude, "export LANG=%s; unset LC_ALL; ", shellQuote(env.Locale))
+	}
+	for _, file := range env.EnvFiles {
+		fmt.Fprintf(&prelude, "if [ -f %[1]s ]; then set -a; . %[1]s; set +a; fi; ", remotePath(file))
+	}
+	names := make([]string, 0, len(env.Env))
+	for name := range env.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&prelude, "export %s=%s; ", name, shellQuote(env.Env[name]))
+	}
+	prelude.WriteString(`exec "$@"`)
+	fmt.Fprintf(&script, "IDE_ENV=%s\n", shellQuote(prelude.String()))
+
+	if !env.LoginShell {
+		script.WriteString("launch_ide() {\n    exec sh -c \"${IDE_ENV}\" devssh-ide \"$@\"\n}\n")
+		return script.String()
+	}
+
+	// 与VS Code解析shell环境的方式一致，使用交互式登录shell，~/.bashrc中的conda、nvm初始化也会生效
+	fmt.Fprintf(&script, `IDE_SHELL="${SHELL:-/bin/bash}"
+case "${IDE_SHELL##*/}" in
+    %s) ;;
+    *)
+        echo "warning: login shell ${IDE_SHELL} cannot run the IDE launcher, using bash"
+        IDE_SHELL=/bin/bash
+        ;;
+esac
+launch_ide() {
+    exec "${IDE_SHELL}" -i -l -c "${IDE_ENV}" devssh-ide "$@"
+}
+`, strings.Join(posixShells, "|"))
+	return script.String()
+}
+
+// normalizeLocale 转换为 locale -a 的写法，如 de_DE.UTF-8 为 de_DE.utf8
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(locale), "utf-8", "utf8")
+}
+
+// remotePath 引用远程路径，~/ 开头时相对于远程用户的主目录
+func remotePath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return `"$HOME"/` + shellQuote(rest)
+	}
+	return shellQuote(path)
+}
+
+// shellQuote 用单引号引用字符串
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
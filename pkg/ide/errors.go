@@ -0,0 +1,13 @@
+package ide
+
+import "errors"
+
+// IDE操作失败的分类，调用方用errors.Is区分
+var (
+	// ErrIDENotInstalled IDE尚未安装到远程主机
+	ErrIDENotInstalled = errors.New("IDE is not installed")
+	// ErrInstallFailed 下载、上传或解压IDE失败
+	ErrInstallFailed = errors.New("failed to install IDE")
+	// ErrUnsupportedIDE 没有内置支持也没有对应插件的IDE
+	ErrUnsupportedIDE = errors.New("unsupported IDE")
+)
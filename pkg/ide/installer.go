@@ -1,14 +1,18 @@
 package ide
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 
+	devsshconfig "devssh/pkg/config"
+	"devssh/pkg/logging"
 	"devssh/pkg/ssh"
 
 	"github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/devpod/pkg/ide/openvscode"
 	"github.com/loft-sh/log"
-	"github.com/sirupsen/logrus"
 )
 
 type IDE string
@@ -19,10 +23,20 @@ const (
 )
 
 type Installer struct {
-	sshClient *ssh.Client
-	ideType   IDE
-	values    map[string]config.OptionValue
-	logger    log.Logger
+	sshClient  *ssh.Client
+	ideType    IDE
+	values     map[string]config.OptionValue
+	logger     log.Logger
+	extensions []string
+	settings   string
+	// ctx 追踪安装过程的父span，取消时中止远程命令；未设置时为Background
+	ctx context.Context
+	// limits 启动IDE时应用的资源限制
+	limits devsshconfig.ResourceLimits
+	// environment 启动IDE时的环境
+	environment devsshconfig.IDEEnvironment
+	// localArchive 预先下载的安装包，为空时联网下载
+	localArchive string
 }
 
 func NewInstaller(sshClient *ssh.Client, ideType IDE) *Installer {
@@ -33,14 +47,11 @@ func NewInstaller(sshClient *ssh.Client, ideType IDE) *Installer {
 		"VERSION":       {Value: "v1.105.1"},
 	}
 
-	// 创建一个简单的logger
-	logger := log.NewStreamLogger(os.Stdout, os.Stderr, logrus.InfoLevel)
-
 	return &Installer{
 		sshClient: sshClient,
 		ideType:   ideType,
 		values:    values,
-		logger:    logger,
+		logger:    logging.GetGlobalLogger(),
 	}
 }
 
@@ -50,7 +61,7 @@ func NewInstallerWithOptions(sshClient *ssh.Client, ideType IDE, values map[stri
 		values = make(map[string]config.OptionValue)
 	}
 	if logger == nil {
-		logger = log.NewStreamLogger(os.Stdout, os.Stderr, logrus.InfoLevel)
+		logger = logging.GetGlobalLogger()
 	}
 
 	return &Installer{
@@ -63,20 +74,29 @@ func NewInstallerWithOptions(sshClient *ssh.Client, ideType IDE, values map[stri
 
 func (i *Installer) Install() error {
 	if !i.sshClient.IsConnected() {
-		return fmt.Errorf("SSH client not connected")
+		return ssh.ErrNotConnected
 	}
 
+	var err error
 	switch i.ideType {
 	case VSCode, CodeServer:
-		return i.installOpenVSCode()
+		err = i.installOpenVSCode()
 	default:
-		return fmt.Errorf("unsupported IDE: %s", i.ideType)
+		var plugin *pluginIDE
+		plugin, err = i.newPluginIDE()
+		if err == nil {
+			err = plugin.Install()
+		}
 	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInstallFailed, err)
+	}
+	return nil
 }
 
 func (i *Installer) installOpenVSCode() error {
 	// 使用新的SSHOpenVSCodeServer适配器
-	server := NewSSHOpenVSCodeServer(i.sshClient, i.values, i.logger)
+	server := i.newOpenVSCodeServer()
 	return server.Install()
 }
 
@@ -85,24 +105,92 @@ func (i *Installer) Start(port int) error {
 	case VSCode, CodeServer:
 		return i.startOpenVSCode(port)
 	default:
-		return fmt.Errorf("unsupported IDE: %s", i.ideType)
+		plugin, err := i.newPluginIDE()
+		if err != nil {
+			return err
+		}
+		return plugin.Start(port)
 	}
 }
 
 func (i *Installer) startOpenVSCode(port int) error {
 	// 使用新的SSHOpenVSCodeServer适配器
-	server := NewSSHOpenVSCodeServer(i.sshClient, i.values, i.logger)
+	server := i.newOpenVSCodeServer()
 	return server.Start(port)
 }
 
+// Stop 停止IDE
+func (i *Installer) Stop(port int) error {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		server := i.newOpenVSCodeServer()
+		return server.Stop(port)
+	default:
+		plugin, err := i.newPluginIDE()
+		if err != nil {
+			return err
+		}
+		return plugin.Stop(port)
+	}
+}
+
+// Uninstall 卸载IDE及其服务和日志，purgeData为true时同时删除扩展和设置
+func (i *Installer) Uninstall(purgeData bool) error {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		server := i.newOpenVSCodeServer()
+		return server.Uninstall(purgeData)
+	default:
+		plugin, err := i.newPluginIDE()
+		if err != nil {
+			return err
+		}
+		return plugin.Uninstall(purgeData)
+	}
+}
+
+// GetPID 获取远程IDE进程PID，未知时返回0
+func (i *Installer) GetPID(port int) (int, error) {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		server := i.newOpenVSCodeServer()
+		return server.GetPID(port)
+	default:
+		plugin, err := i.newPluginIDE()
+		if err != nil {
+			return 0, err
+		}
+		return plugin.GetPID(port)
+	}
+}
+
 func (i *Installer) IsInstalled() (bool, error) {
 	switch i.ideType {
 	case VSCode, CodeServer:
 		// 使用新的SSHOpenVSCodeServer适配器检查
-		server := NewSSHOpenVSCodeServer(i.sshClient, i.values, i.logger)
+		server := i.newOpenVSCodeServer()
 		return server.IsInstalled()
 	default:
-		return false, fmt.Errorf("unsupported IDE: %s", i.ideType)
+		plugin, err := i.newPluginIDE()
+		if err != nil {
+			return false, err
+		}
+		return plugin.IsInstalled()
+	}
+}
+
+// IsRunning 检查IDE进程是否在指定端口运行
+func (i *Installer) IsRunning(port int) (bool, error) {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		server := i.newOpenVSCodeServer()
+		return server.IsProcessRunning(port)
+	default:
+		plugin, err := i.newPluginIDE()
+		if err != nil {
+			return false, err
+		}
+		return plugin.IsRunning(port)
 	}
 }
 
@@ -110,10 +198,14 @@ func (i *Installer) GetDefaultPort() int {
 	switch i.ideType {
 	case VSCode, CodeServer:
 		// 使用新的SSHOpenVSCodeServer适配器获取默认端口
-		server := NewSSHOpenVSCodeServer(i.sshClient, i.values, i.logger)
+		server := i.newOpenVSCodeServer()
 		return server.GetDefaultPort()
 	default:
-		return 8080
+		plugin, err := i.newPluginIDE()
+		if err != nil {
+			return 8080
+		}
+		return plugin.GetDefaultPort()
 	}
 }
 
@@ -125,18 +217,204 @@ func (i *Installer) SetLogger(logger log.Logger) {
 	i.logger = logger
 }
 
+// SetVersion 设置要安装的IDE版本，空字符串表示使用默认版本
+func (i *Installer) SetVersion(version string) {
+	if version != "" {
+		i.values[openvscode.VersionOption] = config.OptionValue{Value: version}
+	}
+}
+
+// WithContext 返回使用ctx的副本，原安装器不受影响，可与使用原安装器的goroutine并发
+func (i *Installer) WithContext(ctx context.Context) *Installer {
+	clone := *i
+	clone.ctx = ctx
+	return &clone
+}
+
+// SetContext 设置安装和启动过程的上下文，用于追踪，取消时中止正在执行的远程命令
+func (i *Installer) SetContext(ctx context.Context) {
+	i.ctx = ctx
+}
+
+// SetEnvironment 设置启动IDE时的登录shell、时区、语言环境、环境变量和env文件，
+// 取值为local的时区和语言环境替换为本机的设置；取值无效时返回错误
+func (i *Installer) SetEnvironment(env devsshconfig.IDEEnvironment) error {
+	if err := ValidateEnvironment(env); err != nil {
+		return err
+	}
+	resolved, err := env.ResolveLocal()
+	if err != nil {
+		return err
+	}
+	i.environment = resolved
+	return nil
+}
+
+// SetResourceLimits 设置启动IDE时的优先级和资源上限，取值无效时返回错误
+func (i *Installer) SetResourceLimits(limits devsshconfig.ResourceLimits) error {
+	if err := ValidateResourceLimits(limits); err != nil {
+		return err
+	}
+	i.limits = limits
+	return nil
+}
+
+// ValidateLocalArchive 检查--local-tar指定的安装包是否存在，为空时不检查
+func ValidateLocalArchive(path string) error {
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("invalid local archive: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("invalid local archive: %s is not a file", path)
+	}
+	return nil
+}
+
+// SetLocalArchive 安装和升级时使用本地已有的安装包，不联网下载，为空时恢复下载；文件不存在时返回错误
+func (i *Installer) SetLocalArchive(path string) error {
+	if err := ValidateLocalArchive(path); err != nil {
+		return err
+	}
+	i.localArchive = path
+	return nil
+}
+
 // SetOpenVSCodeExtensions 设置openvscode扩展
 func (i *Installer) SetOpenVSCodeExtensions(extensions []string) {
-	if i.ideType == VSCode || i.ideType == CodeServer {
-		server := NewSSHOpenVSCodeServer(i.sshClient, i.values, i.logger)
-		server.SetExtensions(extensions)
-	}
+	i.extensions = extensions
 }
 
 // SetOpenVSCodeSettings 设置openvscode配置
 func (i *Installer) SetOpenVSCodeSettings(settings string) {
-	if i.ideType == VSCode || i.ideType == CodeServer {
-		server := NewSSHOpenVSCodeServer(i.sshClient, i.values, i.logger)
-		server.SetSettings(settings)
+	i.settings = settings
+}
+
+// InstallExtensions 在已安装的IDE上安装配置的扩展
+func (i *Installer) InstallExtensions() error {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		return i.newOpenVSCodeServer().InstallExtensions()
+	default:
+		plugin, err := i.newPluginIDE()
+		if err != nil {
+			return err
+		}
+		return plugin.InstallExtensions()
+	}
+}
+
+// InstallSettings 在已安装的IDE上写入配置
+func (i *Installer) InstallSettings() error {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		return i.newOpenVSCodeServer().InstallSettings()
+	default:
+		plugin, err := i.newPluginIDE()
+		if err != nil {
+			return err
+		}
+		return plugin.InstallSettings()
+	}
+}
+
+// newOpenVSCodeServer 创建带有当前扩展和配置的openvscode适配器
+func (i *Installer) newOpenVSCodeServer() *SSHOpenVSCodeServer {
+	server := NewSSHOpenVSCodeServer(i.client(), i.values, i.logger)
+	server.SetExtensions(i.extensions)
+	server.SetSettings(i.settings)
+	server.SetContext(i.ctx)
+	server.SetResourceLimits(i.limits)
+	server.SetEnvironment(i.environment)
+	server.SetLocalArchive(i.localArchive)
+	return server
+}
+
+// client 返回绑定了SetContext上下文的SSH客户端，上下文取消时正在执行的远程命令被中止
+func (i *Installer) client() *ssh.Client {
+	if i.ctx == nil {
+		return i.sshClient
+	}
+	return i.sshClient.WithContext(i.ctx)
+}
+
+// newPluginIDE 为内置类型以外的IDE查找插件目录中的同名插件
+func (i *Installer) newPluginIDE() (*pluginIDE, error) {
+	plugin, err := FindPlugin(string(i.ideType))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := i.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &pluginIDE{
+		plugin:       plugin,
+		sshClient:    i.client(),
+		logger:       i.logger,
+		ctx:          ctx,
+		version:      i.values[openvscode.VersionOption].Value,
+		extensions:   i.extensions,
+		settings:     i.settings,
+		localArchive: i.localArchive,
+		environment:  i.environment,
+	}, nil
+}
+
+// Upgrade 把已安装的IDE升级到当前设置的版本，保留扩展和设置，失败时回滚；插件IDE不支持
+func (i *Installer) Upgrade(port int, force bool) (*UpgradeResult, error) {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		return i.newOpenVSCodeServer().Upgrade(port, force)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedIDE, i.ideType)
+	}
+}
+
+// InstallService 将IDE安装为远程常驻服务，插件IDE不支持
+func (i *Installer) InstallService(port int) (ServiceManager, error) {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		server := i.newOpenVSCodeServer()
+		return server.InstallService(port)
+	default:
+		return ServiceNone, fmt.Errorf("%w: %s", ErrUnsupportedIDE, i.ideType)
+	}
+}
+
+// GetServiceStatus 获取远程IDE服务状态，插件IDE不支持
+func (i *Installer) GetServiceStatus(port int) (*ServiceStatus, error) {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		server := i.newOpenVSCodeServer()
+		return server.GetServiceStatus(port)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedIDE, i.ideType)
+	}
+}
+
+// RemoveService 删除远程IDE服务，插件IDE不支持
+func (i *Installer) RemoveService(port int) error {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		server := i.newOpenVSCodeServer()
+		return server.RemoveService(port)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedIDE, i.ideType)
+	}
+}
+
+// StreamLogs 输出远程IDE日志，插件IDE不支持
+func (i *Installer) StreamLogs(port, lines int, follow bool, stdout, stderr io.Writer) error {
+	switch i.ideType {
+	case VSCode, CodeServer:
+		server := i.newOpenVSCodeServer()
+		return server.StreamLogs(port, lines, follow, stdout, stderr)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedIDE, i.ideType)
 	}
 }
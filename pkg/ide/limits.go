@@ -0,0 +1,119 @@
+package ide
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	devsshconfig "devssh/pkg/config"
+)
+
+var (
+	memoryMaxPattern = regexp.MustCompile(`^([0-9]+[KMGT]?|[0-9]+%)$`)
+	cpuQuotaPattern  = regexp.MustCompile(`^[0-9]+%$`)
+)
+
+// ioClasses ionice调度类对应的参数
+var ioClasses = map[string]string{
+	"idle":        "-c 3",
+	"best-effort": "-c 2 -n 7",
+}
+
+// ValidateResourceLimits 检查资源限制的取值，它们会直接写入远程shell命令和systemd单元
+func ValidateResourceLimits(limits devsshconfig.ResourceLimits) error {
+	if limits.Nice < -20 || limits.Nice > 19 {
+		return fmt.Errorf("invalid nice value %d: must be between -20 and 19", limits.Nice)
+	}
+	if limits.IOClass != "" {
+		if _, ok := ioClasses[limits.IOClass]; !ok {
+			return fmt.Errorf("invalid I/O class %q: must be idle or best-effort", limits.IOClass)
+		}
+	}
+	if limits.MemoryMax != "" && !memoryMaxPattern.MatchString(limits.MemoryMax) {
+		return fmt.Errorf("invalid memory limit %q: use a size such as 4G or a percentage such as 50%%", limits.MemoryMax)
+	}
+	if limits.CPUQuota != "" && !cpuQuotaPattern.MatchString(limits.CPUQuota) {
+		return fmt.Errorf("invalid CPU quota %q: use a percentage such as 200%%", limits.CPUQuota)
+	}
+	return nil
+}
+
+// launchPrefixScript 生成设置 LAUNCH 变量的shell片段，LAUNCH 为启动IDE时的命令前缀
+// 内存和CPU上限需要systemd用户实例，不可用时输出warning并跳过；nice和ionice通过exec保留进程PID
+func launchPrefixScript(limits devsshconfig.ResourceLimits) string {
+	var script strings.Builder
+	script.WriteString("LAUNCH=\"\"\n")
+
+	var properties []string
+	if limits.MemoryMax != "" {
+		properties = append(properties, "-p MemoryMax="+limits.MemoryMax)
+	}
+	if limits.CPUQuota != "" {
+		properties = append(properties, "-p CPUQuota="+limits.CPUQuota)
+	}
+	if len(properties) > 0 {
+		fmt.Fprintf(&script, `if systemd-run --user --scope --quiet true >/dev/null 2>&1; then
+    LAUNCH="systemd-run --user --scope --quiet %s"
+else
+    echo "warning: systemd-run --user is not available, memory and CPU limits are not applied"
+fi
+`, strings.Join(properties, " "))
+	}
+
+	if limits.IOClass != "" {
+		fmt.Fprintf(&script, `if command -v ionice >/dev/null 2>&1; then
+    LAUNCH="${LAUNCH} ionice %s"
+else
+    echo "warning: ionice is not installed, I/O priority is not changed"
+fi
+`, ioClasses[limits.IOClass])
+	}
+
+	if limits.Nice != 0 {
+		fmt.Fprintf(&script, "LAUNCH=\"${LAUNCH} nice -n %d\"\n", limits.Nice)
+	}
+	return script.String()
+}
+
+// systemdLimitDirectives 返回systemd单元[Service]段中的资源限制配置
+func systemdLimitDirectives(limits devsshconfig.ResourceLimits) string {
+	var lines []string
+	if limits.Nice != 0 {
+		lines = append(lines, fmt.Sprintf("Nice=%d", limits.Nice))
+	}
+	if limits.IOClass != "" {
+		lines = append(lines, "IOSchedulingClass="+limits.IOClass)
+	}
+	if limits.MemoryMax != "" {
+		// 单元文件中 % 是说明符前缀，需要写成 %%
+		lines = append(lines, "MemoryMax="+strings.ReplaceAll(limits.MemoryMax, "%", "%%"))
+	}
+	if limits.CPUQuota != "" {
+		lines = append(lines, "CPUQuota="+strings.ReplaceAll(limits.CPUQuota, "%", "%%"))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// cronLaunchPrefix 返回crontab条目的命令前缀，crontab中无法使用systemd scope，只应用nice和ionice
+func cronLaunchPrefix(limits devsshconfig.ResourceLimits) string {
+	var prefix string
+	if limits.IOClass != "" {
+		prefix += "ionice " + ioClasses[limits.IOClass] + " "
+	}
+	if limits.Nice != 0 {
+		prefix += fmt.Sprintf("nice -n %d ", limits.Nice)
+	}
+	return prefix
+}
+
+// logStartWarnings 输出启动脚本中资源限制和IDE环境相关的warning行
+func (s *SSHOpenVSCodeServer) logStartWarnings(output string) {
+	for _, line := range strings.Split(output, "\n") {
+		if warning, ok := strings.CutPrefix(strings.TrimSpace(line), "warning: "); ok {
+			s.logger.Warnf("%s", warning)
+		}
+	}
+}
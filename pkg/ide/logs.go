@@ -0,0 +1,61 @@
+package ide
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"devssh/pkg/ssh"
+)
+
+const (
+	MaxLogSize    = 10 * 1024 * 1024 // 单个日志文件的最大字节数
+	MaxLogBackups = 3                // 保留的历史日志数量
+)
+
+// LogPath 返回指定端口的openvscode-server日志路径
+func LogPath(port int) string {
+	return fmt.Sprintf("/tmp/openvscode-%d.log", port)
+}
+
+// rotateLogScript 生成按大小轮转日志的shell片段
+// 超过maxSize时依次重命名为 .1 ... .keep，最旧的被删除
+func rotateLogScript(path string, maxSize int64, keep int) string {
+	return fmt.Sprintf(`
+if [ -f "%[1]s" ] && [ "$(stat -c %%s "%[1]s" 2>/dev/null || wc -c < "%[1]s")" -gt %[2]d ]; then
+    rm -f "%[1]s.%[3]d"
+    for i in $(seq %[3]d -1 2); do
+        [ -f "%[1]s.$((i-1))" ] && mv -f "%[1]s.$((i-1))" "%[1]s.${i}"
+    done
+    mv -f "%[1]s" "%[1]s.1"
+fi
+`, path, maxSize, keep)
+}
+
+// StreamLogs 输出远程IDE日志的最后lines行，lines小于0时从头输出；follow为true时持续跟踪直到连接关闭
+func (s *SSHOpenVSCodeServer) StreamLogs(port, lines int, follow bool, stdout, stderr io.Writer) error {
+	if !s.sshClient.IsConnected() {
+		return ssh.ErrNotConnected
+	}
+
+	logPath := LogPath(port)
+	exists, err := s.sshClient.NewSCPClient().CheckRemoteFileExists(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to check log file: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("log file %s not found on remote host", logPath)
+	}
+
+	start := strconv.Itoa(lines)
+	if lines < 0 {
+		start = "+1"
+	}
+	tailCmd := fmt.Sprintf("tail -n %s %s", start, logPath)
+	if follow {
+		// -F 在日志轮转后继续跟踪新文件
+		tailCmd = fmt.Sprintf("tail -n %s -F %s", start, logPath)
+	}
+
+	return s.sshClient.RunCommandWithOutput(tailCmd, stdout, stderr)
+}
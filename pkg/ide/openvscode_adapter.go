@@ -1,18 +1,23 @@
 package ide
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	devsshconfig "devssh/pkg/config"
 	"devssh/pkg/download"
 	"devssh/pkg/ssh"
+	"devssh/pkg/tracing"
 
 	"github.com/loft-sh/devpod/pkg/config"
 	"github.com/loft-sh/devpod/pkg/ide/openvscode"
 	"github.com/loft-sh/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // SSHOpenVSCodeServer SSH适配器，复用DevPod核心逻辑
@@ -22,6 +27,13 @@ type SSHOpenVSCodeServer struct {
 	values     map[string]config.OptionValue
 	extensions []string
 	settings   string
+	ctx        context.Context
+	// limits 启动IDE时应用的资源限制
+	limits devsshconfig.ResourceLimits
+	// localArchive 预先下载的安装包，设置时不再联网下载
+	localArchive string
+	// environment 启动IDE进程时的环境
+	environment devsshconfig.IDEEnvironment
 }
 
 // OpenVSCodeOptions 复用DevPod的选项定义
@@ -65,10 +77,30 @@ func (s *SSHOpenVSCodeServer) SetSettings(settings string) {
 	s.settings = settings
 }
 
+// SetResourceLimits 设置启动IDE时的优先级和资源上限
+func (s *SSHOpenVSCodeServer) SetResourceLimits(limits devsshconfig.ResourceLimits) {
+	s.limits = limits
+}
+
+// SetEnvironment 设置启动IDE时的登录shell、环境变量和env文件
+func (s *SSHOpenVSCodeServer) SetEnvironment(env devsshconfig.IDEEnvironment) {
+	s.environment = env
+}
+
+// SetContext 设置追踪上下文，下载、上传和解压记录为其子span
+func (s *SSHOpenVSCodeServer) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// SetLocalArchive 使用本地已有的安装包代替下载，用于两端都不能访问外网的环境
+func (s *SSHOpenVSCodeServer) SetLocalArchive(path string) {
+	s.localArchive = path
+}
+
 // Install 安装openvscode-server
 func (s *SSHOpenVSCodeServer) Install() error {
 	if !s.sshClient.IsConnected() {
-		return fmt.Errorf("SSH client not connected")
+		return ssh.ErrNotConnected
 	}
 
 	// 检查是否已经安装
@@ -84,28 +116,16 @@ func (s *SSHOpenVSCodeServer) Install() error {
 
 	s.logger.Infof("Installing openvscode-server...")
 
-	// 获取下载URL
-	url, err := s.getReleaseUrl()
-	if err != nil {
-		return fmt.Errorf("failed to get release URL: %w", err)
-	}
-
 	// 本地下载文件
-	localPath, err := s.downloadLocally(url)
+	localPath, cleanup, err := s.fetchArchive()
 	if err != nil {
-		return fmt.Errorf("failed to download locally: %w", err)
-	}
-	defer os.Remove(localPath)
-
-	// 上传到远程服务器
-	remotePath := "~/openvscode-server.tar.gz"
-	if err := s.uploadToRemote(localPath, remotePath); err != nil {
-		return fmt.Errorf("failed to upload to remote: %w", err)
+		return err
 	}
+	defer cleanup()
 
-	// 在远程服务器解压安装
-	if err := s.extractOnRemote(remotePath); err != nil {
-		return fmt.Errorf("failed to extract on remote: %w", err)
+	// 上传的同时在远程解压，压缩包不落地
+	if err := s.streamExtract(localPath, fmt.Sprintf("mkdir -p %[1]s && tar -xzf - -C %[1]s --strip-components=1", installDir)); err != nil {
+		return fmt.Errorf("failed to install on remote: %w", err)
 	}
 
 	s.logger.Infof("openvscode-server installed successfully")
@@ -140,48 +160,83 @@ func (s *SSHOpenVSCodeServer) downloadLocally(url string) (string, error) {
 	return downloader.Download(url)
 }
 
-// uploadToRemote 上传文件到远程服务器
-func (s *SSHOpenVSCodeServer) uploadToRemote(localPath, remotePath string) error {
-	scpClient := ssh.NewSCPClient(s.sshClient)
-	return scpClient.Upload(localPath, remotePath)
-}
-
-// extractOnRemote 在远程服务器解压文件
-func (s *SSHOpenVSCodeServer) extractOnRemote(remotePath string) error {
-	extractScript := `
-#!/bin/bash
-set -e
-
-# Create Path
-mkdir -p ~/.openvscode-server
+// fetchArchive 返回要上传的安装包路径：设置了本地安装包时直接使用，否则下载到本地；
+// cleanup删除下载的文件，不会删除用户提供的安装包
+func (s *SSHOpenVSCodeServer) fetchArchive() (string, func(), error) {
+	if s.localArchive != "" {
+		s.logger.Infof("Using local archive %s", s.localArchive)
+		return s.localArchive, func() {}, nil
+	}
 
-# Extract File
-echo "Extracting openvscode-server..."
-tar -xzf ~/openvscode-server.tar.gz -C ~/.openvscode-server --strip-components=1
+	url, err := s.getReleaseUrl()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get release URL: %w", err)
+	}
 
-# Clean temp file
-rm -f ~/openvscode-server.tar.gz
+	_, span := tracing.Start(s.ctx, "ide.download", attribute.String("ide.url", url))
+	localPath, err := s.downloadLocally(url)
+	tracing.End(span, err)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to download locally: %w", err)
+	}
+	return localPath, func() { os.Remove(localPath) }, nil
+}
 
-if [ $? -eq 0 ]; then
-	echo "openvscode-server extracted successfully"
-else
-	echo "Failed to extract openvscode-server"
-	exit 1
-fi
-`
+// streamExtract 把本地安装包经SSH传给远程从标准输入读取压缩包的解压命令，
+// 网络传输和远程解压同时进行
+func (s *SSHOpenVSCodeServer) streamExtract(localPath, extractCmd string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	var stats ssh.TransferStats
+	_, span := tracing.Start(s.ctx, "ide.upload_extract")
+	if info, statErr := file.Stat(); statErr == nil {
+		stats.Bytes, stats.WireBytes = info.Size(), info.Size()
+		span.SetAttributes(attribute.Int64("ide.upload_bytes", info.Size()))
+	}
+	if s.sshClient.TransferOptions().Parallel > 1 && stats.Bytes >= ssh.ChunkedThreshold {
+		// 多通道上传需要先在远程拼接出完整的压缩包
+		err = s.uploadThenExtract(localPath, extractCmd)
+		tracing.End(span, err)
+		return err
+	}
+	s.logger.Infof("Uploading and extracting openvscode-server...")
+	start := time.Now()
+	output, err := s.sshClient.RunCommandWithInput("set -e; "+extractCmd, file)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("%w, output: %s", err, strings.TrimSpace(output))
+	}
+	stats.Duration = time.Since(start)
+	s.logger.Infof("Uploaded and extracted %s", stats)
+	return nil
+}
 
-	_, err := s.sshClient.RunCommand(extractScript)
-	return err
+// uploadThenExtract 先上传压缩包再解压，解压命令从标准输入读取压缩包
+func (s *SSHOpenVSCodeServer) uploadThenExtract(localPath, extractCmd string) error {
+	remotePath := "~/openvscode-server.tar.gz"
+	s.logger.Infof("Uploading openvscode-server...")
+	if err := s.sshClient.NewSCPClient().Upload(localPath, remotePath); err != nil {
+		return fmt.Errorf("failed to upload to remote: %w", err)
+	}
+	output, err := s.sshClient.RunCommand(fmt.Sprintf("set -e; trap 'rm -f %[1]s' EXIT; { %[2]s; } < %[1]s", remotePath, extractCmd))
+	if err != nil {
+		return fmt.Errorf("%w, output: %s", err, strings.TrimSpace(output))
+	}
+	return nil
 }
 
 // getCacheDir 获取缓存目录
 func (s *SSHOpenVSCodeServer) getCacheDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	baseDir, err := devsshconfig.GetCacheDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
 	}
 
-	cacheDir := filepath.Join(homeDir, ".cache", "devssh", "openvscode")
+	cacheDir := filepath.Join(baseDir, "openvscode")
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create cache directory: %w", err)
 	}
@@ -192,7 +247,7 @@ func (s *SSHOpenVSCodeServer) getCacheDir() (string, error) {
 // IsProcessRunning 检查openvscode进程是否在运行
 func (s *SSHOpenVSCodeServer) IsProcessRunning(port int) (bool, error) {
 	if !s.sshClient.IsConnected() {
-		return false, fmt.Errorf("SSH client not connected")
+		return false, ssh.ErrNotConnected
 	}
 
 	// 方法1：检查进程命令行（最精确）- 改进版本
@@ -291,7 +346,7 @@ func (s *SSHOpenVSCodeServer) InstallSettings() error {
 // Start 启动openvscode-server
 func (s *SSHOpenVSCodeServer) Start(port int) error {
 	if !s.sshClient.IsConnected() {
-		return fmt.Errorf("SSH client not connected")
+		return ssh.ErrNotConnected
 	}
 
 	// 检查是否已安装
@@ -300,7 +355,7 @@ func (s *SSHOpenVSCodeServer) Start(port int) error {
 		return fmt.Errorf("failed to check installation: %w", err)
 	}
 	if !installed {
-		return fmt.Errorf("openvscode-server is not installed")
+		return fmt.Errorf("%w: openvscode-server", ErrIDENotInstalled)
 	}
 
 	// 严格检查进程是否已在运行
@@ -325,7 +380,7 @@ set -e
 
 PORT=%d
 PID_FILE="/tmp/openvscode-server-${PORT}.pid"
-LOG_FILE="/tmp/openvscode-${PORT}.log"
+LOG_FILE="%s"
 
 # 再次检查端口是否被占用
 if lsof -i :${PORT} >/dev/null 2>&1; then
@@ -333,12 +388,19 @@ if lsof -i :${PORT} >/dev/null 2>&1; then
     exit 1
 fi
 
+# 日志超过大小限制时轮转
+%s
+
+# 资源限制的命令前缀
+%s
+# IDE进程的环境
+%s
 # 启动openvscode-server
-~/.openvscode-server/bin/openvscode-server \
+launch_ide ${LAUNCH} ~/.openvscode-server/bin/openvscode-server \
     --host 0.0.0.0 \
     --port ${PORT} \
     --without-connection-token \
-    > "${LOG_FILE}" 2>&1 &
+    >> "${LOG_FILE}" 2>&1 &
 
 SERVER_PID=$!
 
@@ -365,12 +427,13 @@ echo "Timeout waiting for openvscode-server to start"
 kill ${SERVER_PID} 2>/dev/null || true
 rm -f "${PID_FILE}"
 exit 1
-`, port)
+`, port, LogPath(port), rotateLogScript(LogPath(port), MaxLogSize, MaxLogBackups), launchPrefixScript(s.limits), environmentScript(s.environment))
 
 	output, err := s.sshClient.RunCommand(startScript)
 	if err != nil {
 		return fmt.Errorf("failed to start openvscode-server: %w, output: %s", err, output)
 	}
+	s.logStartWarnings(output)
 
 	// 验证进程确实在运行
 	time.Sleep(2 * time.Second)
@@ -385,10 +448,125 @@ exit 1
 	return nil
 }
 
+// Stop 停止由devssh启动的openvscode-server进程
+// 只处理PID文件记录的进程，不影响systemd等服务管理的实例
+func (s *SSHOpenVSCodeServer) Stop(port int) error {
+	if !s.sshClient.IsConnected() {
+		return ssh.ErrNotConnected
+	}
+
+	stopScript := fmt.Sprintf(`
+PID_FILE="/tmp/openvscode-server-%d.pid"
+if [ ! -f "${PID_FILE}" ]; then
+    echo "not_running"
+    exit 0
+fi
+
+SERVER_PID=$(cat "${PID_FILE}")
+if ps -p ${SERVER_PID} >/dev/null 2>&1; then
+    kill ${SERVER_PID} 2>/dev/null || true
+    for i in {1..10}; do
+        ps -p ${SERVER_PID} >/dev/null 2>&1 || break
+        sleep 0.5
+    done
+    ps -p ${SERVER_PID} >/dev/null 2>&1 && kill -9 ${SERVER_PID} 2>/dev/null
+fi
+
+rm -f "${PID_FILE}"
+echo "stopped"
+`, port)
+
+	output, err := s.sshClient.RunCommand(stopScript)
+	if err != nil {
+		return fmt.Errorf("failed to stop openvscode-server: %w, output: %s", err, output)
+	}
+
+	if strings.Contains(output, "not_running") {
+		s.logger.Debugf("No openvscode-server started by devssh on port %d", port)
+		return nil
+	}
+
+	s.logger.Infof("openvscode-server on port %d stopped", port)
+	return nil
+}
+
+// Uninstall 停止devssh启动的全部openvscode-server实例，删除其常驻服务、日志、PID文件和安装目录
+// purgeData为false时保留安装目录中的扩展和用户数据，之后重新安装可以继续使用
+func (s *SSHOpenVSCodeServer) Uninstall(purgeData bool) error {
+	if !s.sshClient.IsConnected() {
+		return ssh.ErrNotConnected
+	}
+
+	removeInstall := "rm -rf " + installDir
+	if !purgeData {
+		var keep []string
+		for _, dir := range userDataDirs {
+			keep = append(keep, "! -name "+dir)
+		}
+		removeInstall = fmt.Sprintf("[ -d %[1]s ] && find %[1]s -mindepth 1 -maxdepth 1 %[2]s -exec rm -rf {} +; rmdir %[1]s 2>/dev/null; true", installDir, strings.Join(keep, " "))
+	}
+
+	uninstallScript := fmt.Sprintf(`
+# 停止devssh启动的实例
+for f in /tmp/openvscode-server-*.pid; do
+    [ -f "$f" ] || continue
+    kill $(cat "$f") 2>/dev/null || true
+    rm -f "$f"
+done
+
+# 删除全部端口的常驻服务
+UNIT_DIR="${XDG_CONFIG_HOME:-$HOME/.config}/systemd/user"
+RELOAD=""
+for u in "${UNIT_DIR}"/devssh-openvscode-*.service; do
+    [ -f "$u" ] || continue
+    systemctl --user disable --now "$(basename "$u")" >/dev/null 2>&1 || true
+    rm -f "$u"
+    RELOAD=1
+done
+[ -n "${RELOAD}" ] && { systemctl --user daemon-reload >/dev/null 2>&1 || true; }
+if command -v crontab >/dev/null 2>&1 && crontab -l 2>/dev/null | grep -q 'devssh-openvscode-[0-9]*$'; then
+    crontab -l 2>/dev/null | grep -v 'devssh-openvscode-[0-9]*$' | crontab - || true
+fi
+
+rm -rf %s %s ~/openvscode-server.tar.gz %s /tmp/openvscode-*.log* "$HOME"/.devssh-openvscode-*.sh
+%s
+`, upgradeDir, backupDir, upgradeArchive, removeInstall)
+
+	if output, err := s.sshClient.RunCommand(uninstallScript); err != nil {
+		return fmt.Errorf("failed to uninstall openvscode-server: %w, output: %s", err, output)
+	}
+
+	if purgeData {
+		s.logger.Infof("openvscode-server and its data uninstalled")
+	} else {
+		s.logger.Infof("openvscode-server uninstalled, extensions and settings kept in %s", installDir)
+	}
+	return nil
+}
+
+// GetPID 读取由devssh启动的openvscode-server进程PID，未运行时返回0
+func (s *SSHOpenVSCodeServer) GetPID(port int) (int, error) {
+	if !s.sshClient.IsConnected() {
+		return 0, ssh.ErrNotConnected
+	}
+
+	cmd := fmt.Sprintf("PID=$(cat /tmp/openvscode-server-%d.pid 2>/dev/null) && ps -p $PID >/dev/null 2>&1 && echo $PID", port)
+	output, err := s.sshClient.RunCommand(cmd)
+	if err != nil {
+		return 0, nil
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return 0, nil
+	}
+	return pid, nil
+}
+
 // IsInstalled 检查是否已安装
 func (s *SSHOpenVSCodeServer) IsInstalled() (bool, error) {
 	if !s.sshClient.IsConnected() {
-		return false, fmt.Errorf("SSH client not connected")
+		return false, ssh.ErrNotConnected
 	}
 
 	checkCmd := "test -f ~/.openvscode-server/bin/openvscode-server && echo installed"
@@ -0,0 +1,264 @@
+package ide
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	devsshconfig "devssh/pkg/config"
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+)
+
+// PluginPrefix 插件可执行文件的名称前缀，--ide NAME 对应插件目录中的 devssh-ide-NAME
+const PluginPrefix = "devssh-ide-"
+
+// pluginTimeout 插件生成脚本的最长时间，远程脚本的执行时间不计入
+const pluginTimeout = 30 * time.Second
+
+// 插件动作，插件不支持的动作返回空脚本
+const (
+	PluginDescribe   = "describe"
+	PluginInstall    = "install"
+	PluginStart      = "start"
+	PluginStop       = "stop"
+	PluginUninstall  = "uninstall"
+	PluginInstalled  = "installed"
+	PluginRunning    = "running"
+	PluginPID        = "pid"
+	PluginExtensions = "extensions"
+	PluginSettings   = "settings"
+)
+
+// PluginRequest devssh通过stdin发送给插件的JSON请求
+type PluginRequest struct {
+	Action     string   `json:"action"`
+	Port       int      `json:"port,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Extensions []string `json:"extensions,omitempty"`
+	Settings   string   `json:"settings,omitempty"`
+	// PurgeData uninstall时是否同时删除用户数据
+	PurgeData bool `json:"purge_data,omitempty"`
+	// Archive install时devssh已上传到远程的本地安装包（如wheel或npm包），
+	// 插件应从该文件安装而不联网下载；脚本执行后文件被删除
+	Archive string `json:"archive,omitempty"`
+	// Environment start时IDE进程应使用的登录shell、环境变量和env文件
+	Environment *devsshconfig.IDEEnvironment `json:"environment,omitempty"`
+}
+
+// PluginResponse 插件写到stdout的JSON响应。插件只生成脚本，由devssh通过SSH在远程执行：
+// installed和running的脚本输出true或false，pid的脚本输出进程号
+type PluginResponse struct {
+	Script string `json:"script,omitempty"`
+	// DefaultPort describe的结果
+	DefaultPort int    `json:"default_port,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Plugin 插件目录中的一个IDE提供者
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// PluginDir 返回插件目录：配置目录下的plugins
+func PluginDir() (string, error) {
+	configDir, err := devsshconfig.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "plugins"), nil
+}
+
+// ListPlugins 列出插件目录中可执行的IDE插件，目录不存在时返回空
+func ListPlugins() ([]Plugin, error) {
+	dir, err := PluginDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	var plugins []Plugin
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+		if !ok || name == "" || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Mode()&0111 == 0 {
+			continue
+		}
+		plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, entry.Name())})
+	}
+	return plugins, nil
+}
+
+// FindPlugin 查找名为name的IDE插件
+func FindPlugin(name string) (*Plugin, error) {
+	plugins, err := ListPlugins()
+	if err != nil {
+		return nil, err
+	}
+	for _, plugin := range plugins {
+		if plugin.Name == name {
+			return &plugin, nil
+		}
+	}
+
+	dir, _ := PluginDir()
+	return nil, fmt.Errorf("%w: %s (no %s%s plugin in %s)", ErrUnsupportedIDE, name, PluginPrefix, name, dir)
+}
+
+// Call 执行插件并解析响应
+func (p *Plugin) Call(ctx context.Context, req PluginRequest) (*PluginResponse, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path, req.Action)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("IDE plugin %s failed on %s: %w, stderr: %s", p.Name, req.Action, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("IDE plugin %s returned invalid JSON for %s: %w", p.Name, req.Action, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("IDE plugin %s: %s", p.Name, resp.Error)
+	}
+	return &resp, nil
+}
+
+// pluginIDE 通过插件生成的脚本在远程管理IDE
+type pluginIDE struct {
+	plugin     *Plugin
+	sshClient  *ssh.Client
+	logger     log.Logger
+	ctx        context.Context
+	version    string
+	extensions []string
+	settings   string
+	// localArchive 本地安装包，install前上传到远程
+	localArchive string
+	environment  devsshconfig.IDEEnvironment
+}
+
+// run 向插件请求脚本并在远程执行，插件返回空脚本时不执行，返回输出
+func (p *pluginIDE) run(req PluginRequest) (string, error) {
+	resp, err := p.plugin.Call(p.ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if resp.Script == "" {
+		return "", nil
+	}
+
+	p.logger.Debugf("Running %s %s script on remote host", p.plugin.Name, req.Action)
+	output, err := p.sshClient.RunCommand(resp.Script)
+	if err != nil {
+		return output, fmt.Errorf("%s %s failed: %w, output: %s", p.plugin.Name, req.Action, err, strings.TrimSpace(output))
+	}
+	return output, nil
+}
+
+// check 执行输出true/false的检查脚本
+func (p *pluginIDE) check(req PluginRequest) (bool, error) {
+	output, err := p.run(req)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(output) == "true", nil
+}
+
+func (p *pluginIDE) Install() error {
+	req := PluginRequest{Action: PluginInstall, Version: p.version, Extensions: p.extensions, Settings: p.settings}
+	if p.localArchive != "" {
+		// 保留文件名，pip等工具依赖扩展名识别安装包类型
+		req.Archive = "~/.devssh-" + filepath.Base(p.localArchive)
+		p.logger.Infof("Uploading %s...", p.localArchive)
+		if err := ssh.NewSCPClient(p.sshClient).Upload(p.localArchive, req.Archive); err != nil {
+			return fmt.Errorf("failed to upload to remote: %w", err)
+		}
+		defer p.sshClient.RunCommand("rm -f " + req.Archive)
+	}
+	_, err := p.run(req)
+	return err
+}
+
+func (p *pluginIDE) Start(port int) error {
+	req := PluginRequest{Action: PluginStart, Port: port, Version: p.version}
+	if !p.environment.IsZero() {
+		req.Environment = &p.environment
+	}
+	_, err := p.run(req)
+	return err
+}
+
+func (p *pluginIDE) Stop(port int) error {
+	_, err := p.run(PluginRequest{Action: PluginStop, Port: port})
+	return err
+}
+
+func (p *pluginIDE) Uninstall(purgeData bool) error {
+	_, err := p.run(PluginRequest{Action: PluginUninstall, PurgeData: purgeData})
+	return err
+}
+
+func (p *pluginIDE) IsInstalled() (bool, error) {
+	return p.check(PluginRequest{Action: PluginInstalled, Version: p.version})
+}
+
+func (p *pluginIDE) IsRunning(port int) (bool, error) {
+	return p.check(PluginRequest{Action: PluginRunning, Port: port})
+}
+
+func (p *pluginIDE) GetPID(port int) (int, error) {
+	output, err := p.run(PluginRequest{Action: PluginPID, Port: port})
+	if err != nil {
+		return 0, err
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(output))
+	return pid, nil
+}
+
+// GetDefaultPort 插件未声明默认端口时使用8080
+func (p *pluginIDE) GetDefaultPort() int {
+	resp, err := p.plugin.Call(p.ctx, PluginRequest{Action: PluginDescribe})
+	if err != nil || resp.DefaultPort == 0 {
+		return 8080
+	}
+	return resp.DefaultPort
+}
+
+func (p *pluginIDE) InstallExtensions() error {
+	_, err := p.run(PluginRequest{Action: PluginExtensions, Extensions: p.extensions})
+	return err
+}
+
+func (p *pluginIDE) InstallSettings() error {
+	_, err := p.run(PluginRequest{Action: PluginSettings, Settings: p.settings})
+	return err
+}
@@ -0,0 +1,248 @@
+package ide
+
+import (
+	"fmt"
+	"strings"
+
+	devsshconfig "devssh/pkg/config"
+	"devssh/pkg/ssh"
+)
+
+// ServiceManager 远程IDE常驻服务的管理方式
+type ServiceManager string
+
+const (
+	ServiceSystemd ServiceManager = "systemd" // systemd用户服务
+	ServiceCron    ServiceManager = "cron"    // 无systemd时使用crontab @reboot兜底
+	ServiceNone    ServiceManager = "none"
+)
+
+// ServiceStatus 远程IDE服务状态
+type ServiceStatus struct {
+	Manager ServiceManager `json:"manager"`
+	Unit    string         `json:"unit,omitempty"`
+	Enabled bool           `json:"enabled"`
+	Active  bool           `json:"active"`
+	Detail  string         `json:"detail,omitempty"`
+}
+
+// serviceUnitName 返回指定端口的systemd单元名
+func serviceUnitName(port int) string {
+	return fmt.Sprintf("devssh-openvscode-%d.service", port)
+}
+
+// serviceLauncherPath 返回常驻服务的启动脚本在远程的路径
+func serviceLauncherPath(port int) string {
+	return fmt.Sprintf("$HOME/.devssh-openvscode-%d.sh", port)
+}
+
+// serviceLauncherScript 生成常驻服务的启动脚本，设置IDE环境后exec参数中的命令。
+// systemd和cron不经过SSH会话，登录shell、时区、语言环境、环境变量和env文件都由它设置
+func serviceLauncherScript(env devsshconfig.IDEEnvironment) string {
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n# generated by devssh service install\n")
+	if env.LoginShell {
+		// systemd和cron不一定设置SHELL，cron设置为/bin/sh，从passwd读取用户的登录shell
+		script.WriteString("SHELL=$(getent passwd \"$(id -un)\" 2>/dev/null | cut -d: -f7)\n")
+	}
+	script.WriteString(environmentScript(env))
+	script.WriteString("launch_ide \"$@\"\n")
+	return script.String()
+}
+
+// writeServiceLauncher 在远程写入port的启动脚本
+func (s *SSHOpenVSCodeServer) writeServiceLauncher(port int) error {
+	path := serviceLauncherPath(port)
+	cmd := fmt.Sprintf("printf '%%s' %s > \"%s\" && chmod 0755 \"%s\"", shellQuote(serviceLauncherScript(s.environment)), path, path)
+	if output, err := s.sshClient.RunCommand(cmd); err != nil {
+		return fmt.Errorf("failed to write service launcher: %w, output: %s", err, output)
+	}
+
+	if !s.environment.IsZero() {
+		// 服务的输出写入日志文件，先用true试运行一次，和up一样提示语言环境未安装、env文件不存在等问题
+		output, _ := s.sshClient.RunCommand(fmt.Sprintf("/bin/sh \"%s\" true", path))
+		s.logStartWarnings(output)
+	}
+	return nil
+}
+
+// detectServiceManager 检测远程主机是否可用systemd用户服务
+func (s *SSHOpenVSCodeServer) detectServiceManager() ServiceManager {
+	output, err := s.sshClient.RunCommand("systemctl --user show-environment >/dev/null 2>&1 && echo systemd")
+	if err == nil && strings.Contains(output, "systemd") {
+		return ServiceSystemd
+	}
+
+	output, err = s.sshClient.RunCommand("command -v crontab >/dev/null 2>&1 && echo cron")
+	if err == nil && strings.Contains(output, "cron") {
+		return ServiceCron
+	}
+
+	return ServiceNone
+}
+
+// InstallService 将openvscode-server安装为常驻服务，使其不随SSH会话退出
+func (s *SSHOpenVSCodeServer) InstallService(port int) (ServiceManager, error) {
+	if !s.sshClient.IsConnected() {
+		return ServiceNone, ssh.ErrNotConnected
+	}
+
+	installed, err := s.IsInstalled()
+	if err != nil {
+		return ServiceNone, fmt.Errorf("failed to check installation: %w", err)
+	}
+	if !installed {
+		return ServiceNone, fmt.Errorf("%w: openvscode-server", ErrIDENotInstalled)
+	}
+
+	// 先确定服务管理方式并写好启动脚本，无法安装服务时不影响正在运行的IDE
+	manager := s.detectServiceManager()
+	if manager == ServiceNone {
+		return manager, fmt.Errorf("neither systemd user services nor crontab are available on the remote host")
+	}
+	if err := s.writeServiceLauncher(port); err != nil {
+		return ServiceNone, err
+	}
+
+	// 停止通过nohup启动的实例，避免端口冲突
+	if err := s.Stop(port); err != nil {
+		return ServiceNone, err
+	}
+
+	if manager == ServiceSystemd {
+		return manager, s.installSystemdService(port)
+	}
+	return manager, s.installCronService(port)
+}
+
+func (s *SSHOpenVSCodeServer) installSystemdService(port int) error {
+	unit := serviceUnitName(port)
+	s.logger.Infof("Installing systemd user unit %s...", unit)
+
+	installScript := fmt.Sprintf(`
+#!/bin/bash
+set -e
+
+UNIT_DIR="${XDG_CONFIG_HOME:-$HOME/.config}/systemd/user"
+mkdir -p "${UNIT_DIR}"
+
+cat > "${UNIT_DIR}/%s" << EOF
+[Unit]
+Description=openvscode-server on port %d (managed by devssh)
+After=network.target
+
+[Service]
+Type=simple
+ExecStart=/bin/sh %s ${HOME}/.openvscode-server/bin/openvscode-server --host 0.0.0.0 --port %d --without-connection-token
+Restart=on-failure
+RestartSec=5
+StandardOutput=append:%s
+StandardError=append:%s
+%s
+[Install]
+WantedBy=default.target
+EOF
+
+# 开启linger，使服务在用户退出登录后继续运行
+loginctl enable-linger "$(id -un)" >/dev/null 2>&1 || echo "warning: failed to enable linger"
+
+systemctl --user daemon-reload
+systemctl --user enable --now %s
+`, unit, port, serviceLauncherPath(port), port, LogPath(port), LogPath(port), systemdLimitDirectives(s.limits), unit)
+
+	output, err := s.sshClient.RunCommand(installScript)
+	if err != nil {
+		return fmt.Errorf("failed to install systemd unit: %w, output: %s", err, output)
+	}
+	if strings.Contains(output, "failed to enable linger") {
+		s.logger.Warnf("Could not enable linger; the service may stop when you log out")
+	}
+
+	s.logger.Infof("openvscode-server is now managed by systemd (%s)", unit)
+	return nil
+}
+
+func (s *SSHOpenVSCodeServer) installCronService(port int) error {
+	s.logger.Infof("systemd user services unavailable, falling back to crontab @reboot entry")
+
+	if s.limits.MemoryMax != "" || s.limits.CPUQuota != "" {
+		s.logger.Warnf("Memory and CPU limits need systemd and are not applied when the IDE is started by cron after a reboot")
+	}
+	// 与Start一样记录PID文件，重启后由cron拉起的实例也能被Stop和RemoveService停止
+	entry := fmt.Sprintf("@reboot %s/bin/sh %s $HOME/.openvscode-server/bin/openvscode-server --host 0.0.0.0 --port %d --without-connection-token >> %s 2>&1 & echo $! > /tmp/openvscode-server-%d.pid # devssh-openvscode-%d",
+		cronLaunchPrefix(s.limits), serviceLauncherPath(port), port, LogPath(port), port, port)
+	installCmd := fmt.Sprintf("(crontab -l 2>/dev/null | grep -v 'devssh-openvscode-%d$'; echo '%s') | crontab -", port, entry)
+	if output, err := s.sshClient.RunCommand(installCmd); err != nil {
+		return fmt.Errorf("failed to install crontab entry: %w, output: %s", err, output)
+	}
+
+	// cron只负责重启后拉起，当前立即启动一次
+	return s.Start(port)
+}
+
+// GetServiceStatus 获取远程IDE服务状态
+func (s *SSHOpenVSCodeServer) GetServiceStatus(port int) (*ServiceStatus, error) {
+	if !s.sshClient.IsConnected() {
+		return nil, ssh.ErrNotConnected
+	}
+
+	unit := serviceUnitName(port)
+	status := &ServiceStatus{Manager: s.detectServiceManager(), Unit: unit}
+
+	switch status.Manager {
+	case ServiceSystemd:
+		enabled, _ := s.sshClient.RunCommand(fmt.Sprintf("systemctl --user is-enabled %s 2>/dev/null", unit))
+		active, _ := s.sshClient.RunCommand(fmt.Sprintf("systemctl --user is-active %s 2>/dev/null", unit))
+		status.Enabled = strings.TrimSpace(enabled) == "enabled"
+		status.Active = strings.TrimSpace(active) == "active"
+		status.Detail = strings.TrimSpace(active)
+	case ServiceCron:
+		output, _ := s.sshClient.RunCommand(fmt.Sprintf("crontab -l 2>/dev/null | grep 'devssh-openvscode-%d$'", port))
+		status.Enabled = strings.TrimSpace(output) != ""
+		running, err := s.IsProcessRunning(port)
+		if err != nil {
+			return nil, err
+		}
+		status.Active = running
+	}
+
+	return status, nil
+}
+
+// RemoveService 删除远程IDE服务
+func (s *SSHOpenVSCodeServer) RemoveService(port int) error {
+	if !s.sshClient.IsConnected() {
+		return ssh.ErrNotConnected
+	}
+
+	unit := serviceUnitName(port)
+	removeScript := fmt.Sprintf(`
+UNIT_DIR="${XDG_CONFIG_HOME:-$HOME/.config}/systemd/user"
+if [ -f "${UNIT_DIR}/%s" ]; then
+	systemctl --user disable --now %s >/dev/null 2>&1 || true
+	rm -f "${UNIT_DIR}/%s"
+	systemctl --user daemon-reload >/dev/null 2>&1 || true
+fi
+if command -v crontab >/dev/null 2>&1; then
+	if crontab -l 2>/dev/null | grep -q 'devssh-openvscode-%[4]d$'; then
+		echo "cron_removed"
+	fi
+	crontab -l 2>/dev/null | grep -v 'devssh-openvscode-%[4]d$' | crontab - || true
+fi
+rm -f "%[5]s"
+`, unit, unit, unit, port, serviceLauncherPath(port))
+
+	output, err := s.sshClient.RunCommand(removeScript)
+	if err != nil {
+		return fmt.Errorf("failed to remove service: %w, output: %s", err, output)
+	}
+	// systemctl disable --now 已停止systemd管理的实例，cron拉起的实例按PID文件停止
+	if strings.Contains(output, "cron_removed") {
+		if err := s.Stop(port); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Infof("Removed openvscode-server service for port %d", port)
+	return nil
+}
@@ -0,0 +1,231 @@
+package ide
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/devpod/pkg/ide/openvscode"
+)
+
+const (
+	installDir = "~/.openvscode-server"
+	// upgradeDir 新版本解压的临时目录，切换时改名为installDir
+	upgradeDir = "~/.openvscode-server.new"
+	// backupDir 切换后保留的旧版本，健康检查通过后删除
+	backupDir = "~/.openvscode-server.old"
+	// upgradeArchive 旧版本devssh上传升级包的位置，卸载时清理
+	upgradeArchive = "~/openvscode-server-upgrade.tar.gz"
+	// healthTimeout 升级后等待IDE端口可访问的时间
+	healthTimeout = 30 * time.Second
+)
+
+// userDataDirs 安装目录中属于用户的数据：扩展和Machine设置等，升级时移到新版本中
+var userDataDirs = []string{"data", "extensions"}
+
+// UpgradeResult 升级结果
+type UpgradeResult struct {
+	PreviousVersion string `json:"previous_version"`
+	Version         string `json:"version"`
+	// Restarted 升级前IDE在运行，升级后已重新启动
+	Restarted bool `json:"restarted"`
+}
+
+// InstalledVersion 返回远程已安装的openvscode-server版本，如 1.105.1
+func (s *SSHOpenVSCodeServer) InstalledVersion() (string, error) {
+	output, err := s.sshClient.RunCommand(installDir + "/bin/openvscode-server --version 2>/dev/null | head -n 1")
+	if err != nil {
+		return "", fmt.Errorf("failed to read installed version: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// Upgrade 把已安装的openvscode-server换成当前设置的版本，保留扩展和设置
+// 新版本先解压到临时目录，停止IDE后通过改名切换；原来在运行的IDE会重新启动，
+// 启动或健康检查失败时恢复旧版本。force为false且版本相同时不做任何事
+func (s *SSHOpenVSCodeServer) Upgrade(port int, force bool) (*UpgradeResult, error) {
+	if !s.sshClient.IsConnected() {
+		return nil, ssh.ErrNotConnected
+	}
+
+	installed, err := s.IsInstalled()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check installation: %w", err)
+	}
+	if !installed {
+		return nil, fmt.Errorf("%w: openvscode-server, use devssh install instead", ErrIDENotInstalled)
+	}
+
+	result := &UpgradeResult{Version: strings.TrimPrefix(OpenVSCodeOptions.GetValue(s.values, openvscode.VersionOption), "v")}
+	if result.PreviousVersion, err = s.InstalledVersion(); err != nil {
+		return nil, err
+	}
+	if result.PreviousVersion == result.Version && !force {
+		s.logger.Infof("openvscode-server %s is already installed", result.Version)
+		return result, nil
+	}
+
+	s.logger.Infof("Upgrading openvscode-server from %s to %s...", result.PreviousVersion, result.Version)
+	if err := s.stageUpgrade(); err != nil {
+		s.sshClient.RunCommand("rm -rf " + upgradeDir)
+		return nil, err
+	}
+
+	service, err := s.GetServiceStatus(port)
+	if err != nil {
+		return nil, err
+	}
+	systemd := service.Manager == ServiceSystemd && service.Active
+	running, err := s.IsProcessRunning(port)
+	if err != nil {
+		return nil, err
+	}
+	result.Restarted = running
+
+	if running {
+		s.logger.Infof("Stopping openvscode-server on port %d...", port)
+		if err := s.stopForUpgrade(port, systemd); err != nil {
+			return nil, err
+		}
+	}
+
+	if output, err := s.sshClient.RunCommand(swapScript()); err != nil {
+		// 切换失败时脚本已经尽量恢复，重新启动原来的IDE
+		if running {
+			s.startAfterUpgrade(port, systemd)
+		}
+		return nil, fmt.Errorf("failed to switch installations: %w, output: %s", err, output)
+	}
+
+	if err := s.checkUpgrade(port, running, systemd); err != nil {
+		s.logger.Warnf("New version failed its health check: %v", err)
+		s.logger.Infof("Rolling back to openvscode-server %s...", result.PreviousVersion)
+		if running {
+			s.stopForUpgrade(port, systemd)
+		}
+		if output, rollbackErr := s.sshClient.RunCommand(rollbackScript()); rollbackErr != nil {
+			return nil, fmt.Errorf("upgrade failed (%v) and rollback failed: %w, output: %s", err, rollbackErr, output)
+		}
+		if running {
+			if restartErr := s.startAfterUpgrade(port, systemd); restartErr != nil {
+				s.logger.Warnf("Failed to restart the previous version: %v", restartErr)
+			}
+		}
+		return nil, fmt.Errorf("upgrade to %s failed and was rolled back: %w", result.Version, err)
+	}
+
+	if output, err := s.sshClient.RunCommand("rm -rf " + backupDir); err != nil {
+		s.logger.Warnf("Failed to remove the previous installation: %v: %s", err, strings.TrimSpace(output))
+	}
+	s.logger.Infof("openvscode-server upgraded to %s", result.Version)
+	return result, nil
+}
+
+// stageUpgrade 下载新版本并解压到临时目录，不影响正在运行的IDE
+func (s *SSHOpenVSCodeServer) stageUpgrade() error {
+	localPath, cleanup, err := s.fetchArchive()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	extractCmd := fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s && tar -xzf - -C %[1]s --strip-components=1", upgradeDir)
+	if err := s.streamExtract(localPath, extractCmd); err != nil {
+		return fmt.Errorf("failed to extract the new version: %w", err)
+	}
+	if output, err := s.sshClient.RunCommand(upgradeDir + "/bin/openvscode-server --version >/dev/null"); err != nil {
+		return fmt.Errorf("the new version does not run: %w, output: %s", err, output)
+	}
+	return nil
+}
+
+// swapScript 把用户数据移到新版本中，然后用两次改名切换安装目录，中途失败时恢复原状
+func swapScript() string {
+	return fmt.Sprintf(`
+set -e
+rm -rf %[3]s
+for d in %[4]s; do
+    if [ -e %[1]s/$d ]; then rm -rf %[2]s/$d; mv %[1]s/$d %[2]s/$d; fi
+done
+if ! mv %[1]s %[3]s; then
+    for d in %[4]s; do
+        if [ -e %[2]s/$d ]; then mv %[2]s/$d %[1]s/$d; fi
+    done
+    exit 1
+fi
+if ! mv %[2]s %[1]s; then
+    mv %[3]s %[1]s
+    exit 1
+fi
+`, installDir, upgradeDir, backupDir, strings.Join(userDataDirs, " "))
+}
+
+// rollbackScript 恢复旧版本，并把用户数据移回去
+func rollbackScript() string {
+	return fmt.Sprintf(`
+set -e
+test -d %[3]s
+rm -rf %[2]s
+mv %[1]s %[2]s
+mv %[3]s %[1]s
+for d in %[4]s; do
+    if [ -e %[2]s/$d ]; then rm -rf %[1]s/$d; mv %[2]s/$d %[1]s/$d; fi
+done
+rm -rf %[2]s
+`, installDir, upgradeDir, backupDir, strings.Join(userDataDirs, " "))
+}
+
+// stopForUpgrade 停止IDE，systemd管理的实例通过systemctl停止
+func (s *SSHOpenVSCodeServer) stopForUpgrade(port int, systemd bool) error {
+	if !systemd {
+		return s.Stop(port)
+	}
+	if output, err := s.sshClient.RunCommand("systemctl --user stop " + serviceUnitName(port)); err != nil {
+		return fmt.Errorf("failed to stop service: %w, output: %s", err, output)
+	}
+	return nil
+}
+
+// startAfterUpgrade 重新启动IDE
+func (s *SSHOpenVSCodeServer) startAfterUpgrade(port int, systemd bool) error {
+	if !systemd {
+		return s.Start(port)
+	}
+	if output, err := s.sshClient.RunCommand("systemctl --user start " + serviceUnitName(port)); err != nil {
+		return fmt.Errorf("failed to start service: %w, output: %s", err, output)
+	}
+	return nil
+}
+
+// checkUpgrade 验证新版本：原来在运行时重新启动并等待端口可访问，否则只检查能否执行
+func (s *SSHOpenVSCodeServer) checkUpgrade(port int, restart, systemd bool) error {
+	if !restart {
+		if output, err := s.sshClient.RunCommand(installDir + "/bin/openvscode-server --version"); err != nil {
+			return fmt.Errorf("new version does not run: %w, output: %s", err, output)
+		}
+		return nil
+	}
+
+	s.logger.Infof("Restarting openvscode-server on port %d...", port)
+	if err := s.startAfterUpgrade(port, systemd); err != nil {
+		return err
+	}
+
+	checkScript := fmt.Sprintf(`
+for i in $(seq 1 %d); do
+    if command -v curl >/dev/null 2>&1; then
+        curl -s -o /dev/null http://localhost:%[2]d/ && exit 0
+    elif timeout 1 bash -c "echo > /dev/tcp/localhost/%[2]d" 2>/dev/null; then
+        exit 0
+    fi
+    sleep 1
+done
+exit 1
+`, int(healthTimeout/time.Second), port)
+	if _, err := s.sshClient.RunCommand(checkScript); err != nil {
+		return fmt.Errorf("IDE did not respond on port %d within %v", port, healthTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package ide
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loft-sh/log"
+)
+
+const (
+	DefaultWatchdogInterval   = 15 * time.Second // 默认健康检查间隔
+	DefaultWatchdogMaxBackoff = 5 * time.Minute  // 重启退避的最大间隔
+)
+
+// WatchdogEventType 看门狗事件类型
+type WatchdogEventType string
+
+const (
+	WatchdogIDEDown       WatchdogEventType = "ide_down"
+	WatchdogIDERestarting WatchdogEventType = "ide_restarting"
+	WatchdogIDERestarted  WatchdogEventType = "ide_restarted"
+	WatchdogRestartFailed WatchdogEventType = "ide_restart_failed"
+)
+
+// WatchdogEvent 看门狗事件
+type WatchdogEvent struct {
+	Type    WatchdogEventType
+	IDE     IDE
+	Port    int
+	Attempt int
+	Err     error
+	Time    time.Time
+}
+
+// Watchdog 监控远程IDE进程，崩溃后按退避策略自动重启
+type Watchdog struct {
+	installer  *Installer
+	port       int
+	interval   time.Duration
+	maxBackoff time.Duration
+	logger     log.Logger
+	onEvent    func(WatchdogEvent)
+}
+
+// NewWatchdog 创建IDE看门狗
+func NewWatchdog(installer *Installer, port int, logger log.Logger) *Watchdog {
+	if logger == nil {
+		logger = installer.logger
+	}
+
+	return &Watchdog{
+		installer:  installer,
+		port:       port,
+		interval:   DefaultWatchdogInterval,
+		maxBackoff: DefaultWatchdogMaxBackoff,
+		logger:     logger,
+	}
+}
+
+// SetInterval 设置健康检查间隔
+func (w *Watchdog) SetInterval(interval time.Duration) {
+	if interval > 0 {
+		w.interval = interval
+	}
+}
+
+// OnEvent 设置事件回调，用于把重启事件通知给CLI
+func (w *Watchdog) OnEvent(handler func(WatchdogEvent)) {
+	w.onEvent = handler
+}
+
+// Run 运行监控循环，直到ctx被取消
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		running, err := w.installer.IsRunning(w.port)
+		if err != nil {
+			// 连接断开后无法检查也无法重启，停止监控
+			if !w.installer.sshClient.IsConnected() {
+				w.logger.Warnf("SSH connection lost, no longer watching %s on port %d", w.installer.GetName(), w.port)
+				return
+			}
+			w.logger.Debugf("IDE health check failed: %v", err)
+			continue
+		}
+		if running {
+			continue
+		}
+
+		w.emit(WatchdogEvent{Type: WatchdogIDEDown})
+		w.logger.Warnf("%s on port %d is not responding, restarting...", w.installer.GetName(), w.port)
+
+		if !w.restart(ctx) {
+			return
+		}
+	}
+}
+
+// restart 按指数退避重启IDE，ctx取消或连接断开时返回false
+func (w *Watchdog) restart(ctx context.Context) bool {
+	backoff := time.Second
+
+	for attempt := 1; ; attempt++ {
+		w.emit(WatchdogEvent{Type: WatchdogIDERestarting, Attempt: attempt})
+
+		err := w.installer.Start(w.port)
+		if err == nil {
+			w.emit(WatchdogEvent{Type: WatchdogIDERestarted, Attempt: attempt})
+			w.logger.Infof("%s restarted on port %d", w.installer.GetName(), w.port)
+			return true
+		}
+
+		w.emit(WatchdogEvent{Type: WatchdogRestartFailed, Attempt: attempt, Err: err})
+		if !w.installer.sshClient.IsConnected() {
+			w.logger.Warnf("SSH connection lost, giving up restarting %s on port %d", w.installer.GetName(), w.port)
+			return false
+		}
+		w.logger.Warnf("Failed to restart %s (attempt %d), retrying in %v: %v", w.installer.GetName(), attempt, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
+
+func (w *Watchdog) emit(event WatchdogEvent) {
+	if w.onEvent == nil {
+		return
+	}
+
+	event.IDE = w.installer.ideType
+	event.Port = w.port
+	event.Time = time.Now()
+	w.onEvent(event)
+}
+
+// String 返回事件的可读描述
+func (e WatchdogEvent) String() string {
+	switch e.Type {
+	case WatchdogIDEDown:
+		return fmt.Sprintf("%s on port %d went down", e.IDE, e.Port)
+	case WatchdogIDERestarting:
+		return fmt.Sprintf("restarting %s on port %d (attempt %d)", e.IDE, e.Port, e.Attempt)
+	case WatchdogIDERestarted:
+		return fmt.Sprintf("%s restarted on port %d", e.IDE, e.Port)
+	case WatchdogRestartFailed:
+		return fmt.Sprintf("failed to restart %s on port %d: %v", e.IDE, e.Port, e.Err)
+	default:
+		return string(e.Type)
+	}
+}
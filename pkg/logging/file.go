@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/acarl005/stripansi"
+	"github.com/loft-sh/log"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 日志文件轮转参数
+const (
+	MaxLogFileSizeMB  = 10
+	MaxLogFileBackups = 5
+)
+
+// newFileSink 创建写入日志文件的logger，去除颜色并按大小轮转
+func newFileSink(path string) (log.Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	writer := stripANSIWriter{
+		out: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    MaxLogFileSizeMB,
+			MaxBackups: MaxLogFileBackups,
+		},
+	}
+
+	redacted := NewRedactWriter(writer)
+	return fileSink{log.NewStreamLoggerWithFormat(redacted, redacted, logrus.DebugLevel, log.TextFormat)}, nil
+}
+
+// fileSink 作为sink接收的消息已带换行，去掉以免重复空行
+type fileSink struct {
+	log.Logger
+}
+
+func (s fileSink) Print(level logrus.Level, args ...interface{}) {
+	s.Logger.Print(level, strings.TrimSuffix(fmt.Sprint(args...), "\n"))
+}
+
+// stripANSIWriter 写入前去除ANSI颜色控制符
+type stripANSIWriter struct {
+	out io.Writer
+}
+
+func (w stripANSIWriter) Write(p []byte) (int, error) {
+	if _, err := w.out.Write([]byte(stripansi.Strip(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
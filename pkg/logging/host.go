@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"fmt"
+
+	"github.com/loft-sh/log"
+	"github.com/sirupsen/logrus"
+)
+
+// WithHost 返回标识主机的logger，供多主机并发操作区分输出：
+// JSON格式写入host字段，其他格式在每条消息前加 [host] 前缀
+func WithHost(logger log.Logger, host string) log.Logger {
+	if j, ok := logger.(*jsonLogger); ok {
+		clone := *j
+		clone.host = host
+		return &clone
+	}
+	return &hostLogger{
+		Logger: logger,
+		prefix: fmt.Sprintf("[%s] ", host),
+	}
+}
+
+// hostLogger 在消息前添加主机前缀
+type hostLogger struct {
+	log.Logger
+	prefix string
+}
+
+func (h *hostLogger) Debug(args ...interface{}) {
+	h.Logger.Debug(h.prefix + fmt.Sprint(args...))
+}
+
+func (h *hostLogger) Debugf(format string, args ...interface{}) {
+	h.Logger.Debugf(h.prefix+format, args...)
+}
+
+func (h *hostLogger) Info(args ...interface{}) {
+	h.Logger.Info(h.prefix + fmt.Sprint(args...))
+}
+
+func (h *hostLogger) Infof(format string, args ...interface{}) {
+	h.Logger.Infof(h.prefix+format, args...)
+}
+
+func (h *hostLogger) Warn(args ...interface{}) {
+	h.Logger.Warn(h.prefix + fmt.Sprint(args...))
+}
+
+func (h *hostLogger) Warnf(format string, args ...interface{}) {
+	h.Logger.Warnf(h.prefix+format, args...)
+}
+
+func (h *hostLogger) Error(args ...interface{}) {
+	h.Logger.Error(h.prefix + fmt.Sprint(args...))
+}
+
+func (h *hostLogger) Errorf(format string, args ...interface{}) {
+	h.Logger.Errorf(h.prefix+format, args...)
+}
+
+func (h *hostLogger) Fatal(args ...interface{}) {
+	h.Logger.Fatal(h.prefix + fmt.Sprint(args...))
+}
+
+func (h *hostLogger) Fatalf(format string, args ...interface{}) {
+	h.Logger.Fatalf(h.prefix+format, args...)
+}
+
+func (h *hostLogger) Done(args ...interface{}) {
+	h.Logger.Done(h.prefix + fmt.Sprint(args...))
+}
+
+func (h *hostLogger) Donef(format string, args ...interface{}) {
+	h.Logger.Donef(h.prefix+format, args...)
+}
+
+func (h *hostLogger) Print(level logrus.Level, args ...interface{}) {
+	h.Logger.Print(level, h.prefix+fmt.Sprint(args...))
+}
+
+func (h *hostLogger) Printf(level logrus.Level, format string, args ...interface{}) {
+	h.Logger.Printf(level, h.prefix+format, args...)
+}
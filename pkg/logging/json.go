@@ -0,0 +1,192 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loft-sh/log"
+	"github.com/sirupsen/logrus"
+)
+
+// 日志格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var contextFields sync.Map
+
+// SetContextField 设置附加到每条JSON日志的字段（如 host、session）
+func SetContextField(key, value string) {
+	if value == "" {
+		contextFields.Delete(key)
+		return
+	}
+	contextFields.Store(key, value)
+}
+
+// jsonEntry 一条结构化日志
+type jsonEntry struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Component string    `json:"component,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Session   string    `json:"session,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// jsonLogger 每条日志输出一行JSON，未覆盖的接口方法委托给基础logger
+type jsonLogger struct {
+	log.Logger
+	out    io.Writer
+	errOut io.Writer
+	level  logrus.Level
+	sink   log.Logger
+	// host 非空时覆盖全局的host字段，见WithHost
+	host string
+	// mu 由WithHost派生的logger共享，保证整行写入
+	mu *sync.Mutex
+}
+
+func newJSONLogger(out, errOut io.Writer, level logrus.Level, sink log.Logger) *jsonLogger {
+	return &jsonLogger{
+		Logger: log.NewStreamLoggerWithFormat(out, errOut, level, log.JSONFormat),
+		out:    out,
+		errOut: errOut,
+		level:  level,
+		sink:   sink,
+		mu:     &sync.Mutex{},
+	}
+}
+
+func (j *jsonLogger) write(level logrus.Level, message string) {
+	// JSON转义后可能无法匹配，先隐藏密钥
+	message = Redact(strings.TrimSuffix(message, "\n"))
+	if j.sink != nil {
+		j.sink.Print(level, message)
+	}
+	if j.level < level {
+		return
+	}
+
+	host := j.host
+	if host == "" {
+		host = contextField("host")
+	}
+
+	entry := jsonEntry{
+		Time:      time.Now(),
+		Level:     level.String(),
+		Component: callerComponent(),
+		Host:      host,
+		Session:   contextField("session"),
+		Message:   message,
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	out := j.out
+	if level <= logrus.WarnLevel {
+		out = j.errOut
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, _ = out.Write(append(line, '\n'))
+}
+
+func contextField(key string) string {
+	if value, ok := contextFields.Load(key); ok {
+		return value.(string)
+	}
+	return ""
+}
+
+// callerComponent 根据调用者所在的包确定组件名，如 ssh、tunnel、ide，命令行为cli
+func callerComponent() string {
+	pc := make([]uintptr, 10)
+	// 跳过 runtime.Callers、callerComponent、write 以及jsonLogger的日志方法
+	n := runtime.Callers(4, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		// 跳过logging包内部的调用（如便捷函数）
+		if !strings.Contains(frame.Function, "devssh/pkg/logging.") {
+			return componentName(frame.Function)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func componentName(function string) string {
+	if strings.HasPrefix(function, "main.") {
+		return "cli"
+	}
+
+	pkgPath := function
+	if lastSlash := strings.LastIndex(pkgPath, "/"); lastSlash != -1 {
+		pkgPath = pkgPath[lastSlash+1:]
+	}
+	if dot := strings.Index(pkgPath, "."); dot != -1 {
+		pkgPath = pkgPath[:dot]
+	}
+	return pkgPath
+}
+
+func (j *jsonLogger) Debug(args ...interface{}) { j.write(logrus.DebugLevel, fmt.Sprint(args...)) }
+func (j *jsonLogger) Debugf(format string, args ...interface{}) {
+	j.write(logrus.DebugLevel, fmt.Sprintf(format, args...))
+}
+func (j *jsonLogger) Info(args ...interface{}) { j.write(logrus.InfoLevel, fmt.Sprint(args...)) }
+func (j *jsonLogger) Infof(format string, args ...interface{}) {
+	j.write(logrus.InfoLevel, fmt.Sprintf(format, args...))
+}
+func (j *jsonLogger) Done(args ...interface{}) { j.write(logrus.InfoLevel, fmt.Sprint(args...)) }
+func (j *jsonLogger) Donef(format string, args ...interface{}) {
+	j.write(logrus.InfoLevel, fmt.Sprintf(format, args...))
+}
+func (j *jsonLogger) Warn(args ...interface{}) { j.write(logrus.WarnLevel, fmt.Sprint(args...)) }
+func (j *jsonLogger) Warnf(format string, args ...interface{}) {
+	j.write(logrus.WarnLevel, fmt.Sprintf(format, args...))
+}
+func (j *jsonLogger) Error(args ...interface{}) { j.write(logrus.ErrorLevel, fmt.Sprint(args...)) }
+func (j *jsonLogger) Errorf(format string, args ...interface{}) {
+	j.write(logrus.ErrorLevel, fmt.Sprintf(format, args...))
+}
+
+func (j *jsonLogger) Fatal(args ...interface{}) {
+	j.write(logrus.FatalLevel, fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+func (j *jsonLogger) Fatalf(format string, args ...interface{}) {
+	j.write(logrus.FatalLevel, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+func (j *jsonLogger) Print(level logrus.Level, args ...interface{}) {
+	j.write(level, fmt.Sprint(args...))
+}
+
+func (j *jsonLogger) Printf(level logrus.Level, format string, args ...interface{}) {
+	j.write(level, fmt.Sprintf(format, args...))
+}
+
+func (j *jsonLogger) SetLevel(level logrus.Level) {
+	j.level = level
+	j.Logger.SetLevel(level)
+}
+
+func (j *jsonLogger) GetLevel() logrus.Level {
+	return j.level
+}
@@ -2,6 +2,7 @@ package logging
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"runtime"
@@ -19,18 +20,73 @@ var (
 
 // Init 初始化日志系统
 func Init(level logrus.Level, enableCaller bool) log.Logger {
+	return InitWithOutput(os.Stdout, level, enableCaller)
+}
+
+// Options 日志系统的配置
+type Options struct {
+	// Out 普通日志的输出，默认为stdout；错误日志始终写入stderr
+	Out          io.Writer
+	Level        logrus.Level
+	EnableCaller bool
+	// LogFile 非空时将全部日志（含debug级别）同时写入该文件，按大小轮转
+	LogFile string
+	// Format 输出格式，text（默认）或json
+	Format string
+}
+
+// InitWithOutput 初始化日志系统，普通日志写入out，错误日志写入stderr
+func InitWithOutput(out io.Writer, level logrus.Level, enableCaller bool) log.Logger {
+	logger, _ := InitWithOptions(Options{Out: out, Level: level, EnableCaller: enableCaller})
+	return logger
+}
+
+// InitWithOptions 按配置初始化日志系统
+func InitWithOptions(opts Options) (log.Logger, error) {
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	// 所有输出都经过密钥隐藏
+	out = NewRedactWriter(out)
+	errOut := NewRedactWriter(os.Stderr)
+
+	var sink log.Logger
+	if opts.LogFile != "" {
+		var err error
+		if sink, err = newFileSink(opts.LogFile); err != nil {
+			return nil, err
+		}
+	}
+
+	switch opts.Format {
+	case FormatText, "":
+	case FormatJSON:
+		// JSON日志自带组件信息，不需要源代码位置包装器
+		return newJSONLogger(out, errOut, opts.Level, sink), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format %q (use text or json)", opts.Format)
+	}
+
 	// 创建基础的stream logger
-	logger := log.NewStreamLogger(os.Stdout, os.Stderr, level)
+	logger := log.NewStreamLogger(out, errOut, opts.Level)
+
+	// 文件接收全部级别的日志，包装器需要放行到debug级别
+	callerLevel := opts.Level
+	if sink != nil {
+		logger.AddSink(sink)
+		callerLevel = logrus.DebugLevel
+	}
 
 	// 如果需要源代码位置，创建包装器
-	if enableCaller {
+	if opts.EnableCaller {
 		return &callerLogger{
 			Logger: logger,
-			level:  level,
-		}
+			level:  callerLevel,
+		}, nil
 	}
 
-	return logger
+	return logger, nil
 }
 
 // InitDefault 使用默认配置初始化日志系统
@@ -67,8 +123,12 @@ func (c *callerLogger) getCaller() string {
 		return ""
 	}
 
+	// 跳过logging包内的包装器（如WithHost）
 	frames := runtime.CallersFrames(pc[:n])
-	frame, _ := frames.Next()
+	frame, more := frames.Next()
+	for more && strings.HasPrefix(frame.Function, "devssh/pkg/logging.") {
+		frame, more = frames.Next()
+	}
 
 	// 简化文件路径，只显示文件名
 	filename := path.Base(frame.File)
@@ -178,6 +238,8 @@ func GetGlobalLogger() log.Logger {
 
 // SetGlobalLogger 设置全局logger实例
 func SetGlobalLogger(logger log.Logger) {
+	// 标记已初始化，避免之后的GetGlobalLogger用默认logger覆盖
+	initOnce.Do(func() {})
 	globalLogger = logger
 }
 
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	redactedText = "******"
+	// minSecretLength 过短的值（如单个字符）替换后会破坏正常输出，不做处理
+	minSecretLength = 3
+)
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// RegisterSecret 登记需要在日志和错误输出中隐藏的值（密码、令牌、口令）
+func RegisterSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	for _, existing := range secrets {
+		if existing == value {
+			return
+		}
+	}
+	secrets = append(secrets, value)
+
+	// 先替换较长的值，避免一个密钥包含另一个时只被部分隐藏
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+}
+
+// Redact 将已登记的密钥替换为 ******
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+
+	for _, secret := range secrets {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, redactedText)
+		}
+	}
+	return s
+}
+
+// NewRedactWriter 返回写入前隐藏已登记密钥的writer
+func NewRedactWriter(out io.Writer) io.Writer {
+	return redactWriter{out: out}
+}
+
+type redactWriter struct {
+	out io.Writer
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	if _, err := w.out.Write([]byte(Redact(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
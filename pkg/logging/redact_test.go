@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+)
+
+// resetSecrets 清空已登记的密钥，测试结束时恢复
+func resetSecrets(t *testing.T) {
+	t.Helper()
+	secretsMu.Lock()
+	saved := secrets
+	secrets = nil
+	secretsMu.Unlock()
+	t.Cleanup(func() {
+		secretsMu.Lock()
+		secrets = saved
+		secretsMu.Unlock()
+	})
+}
+
+func TestRedact(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		secrets []string
+		input   string
+		want    string
+	}{
+		{"no secrets", nil, "password s3cret", "password s3cret"},
+		{"single", []string{"s3cret"}, "password s3cret", "password ******"},
+		{"every occurrence", []string{"s3cret"}, "s3cret and s3cret", "****** and ******"},
+		{"several", []string{"alpha", "bravo"}, "alpha bravo", "****** ******"},
+		// 较短的值先登记，仍然先替换较长的值
+		{"contained secret", []string{"token", "token-suffix"}, "auth token-suffix", "auth ******"},
+		{"contained secret registered later", []string{"token-suffix", "token"}, "token-suffix token", "****** ******"},
+		{"shorter than minSecretLength", []string{"ab"}, "ab cab", "ab cab"},
+		{"minSecretLength", []string{"abc"}, "abc", "******"},
+		{"registered twice", []string{"s3cret", "s3cret"}, "s3cret", "******"},
+		{"empty", []string{""}, "output", "output"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resetSecrets(t)
+			for _, secret := range tc.secrets {
+				RegisterSecret(secret)
+			}
+			if got := Redact(tc.input); got != tc.want {
+				t.Errorf("Redact(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewRedactWriter(t *testing.T) {
+	resetSecrets(t)
+	RegisterSecret("s3cret")
+	RegisterSecret("s3cret-token")
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "connected\n", "connected\n"},
+		{"secret", "password=s3cret\n", "password=******\n"},
+		{"longer secret first", "token=s3cret-token\n", "token=******\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			n, err := NewRedactWriter(&out).Write([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			// 返回写入前的长度，调用方不会因替换后长度变化而认为写入不完整
+			if n != len(tc.input) {
+				t.Errorf("Write returned %d, want %d", n, len(tc.input))
+			}
+			if out.String() != tc.want {
+				t.Errorf("wrote %q, want %q", out.String(), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"github.com/loft-sh/log"
+)
+
+// Event 通知事件类型
+type Event string
+
+const (
+	EventReady        Event = "ready"
+	EventReconnecting Event = "reconnecting"
+	EventFailure      Event = "failure"
+	EventPorts        Event = "ports"
+)
+
+// Notifier 发送桌面通知，未启用时不做任何事
+type Notifier struct {
+	enabled bool
+	logger  log.Logger
+}
+
+// NewNotifier 创建通知器
+func NewNotifier(enabled bool, logger log.Logger) *Notifier {
+	return &Notifier{
+		enabled: enabled,
+		logger:  logger,
+	}
+}
+
+// Notify 发送一条桌面通知，失败时只记录调试日志
+func (n *Notifier) Notify(event Event, message string) {
+	if n == nil || !n.enabled {
+		return
+	}
+
+	title := "DevSSH"
+	switch event {
+	case EventReady:
+		title = "DevSSH: environment ready"
+	case EventReconnecting:
+		title = "DevSSH: reconnecting"
+	case EventFailure:
+		title = "DevSSH: failure"
+	case EventPorts:
+		title = "DevSSH: port forwarding"
+	}
+
+	if err := send(title, message); err != nil {
+		n.logger.Debugf("Failed to send desktop notification: %v", err)
+	}
+}
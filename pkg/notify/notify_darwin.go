@@ -0,0 +1,12 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+func send(title, message string) error {
+	script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(message), strconv.Quote(title))
+	return exec.Command("osascript", "-e", script).Run()
+}
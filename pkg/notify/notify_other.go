@@ -0,0 +1,16 @@
+//go:build !darwin && !windows
+
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+func send(title, message string) error {
+	path, err := exec.LookPath("notify-send")
+	if err != nil {
+		return fmt.Errorf("notify-send not found: %w", err)
+	}
+	return exec.Command(path, "--app-name=devssh", title, message).Run()
+}
@@ -0,0 +1,25 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func send(title, message string) error {
+	quote := func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+
+	script := fmt.Sprintf(`
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode(%s)) | Out-Null
+$text.Item(1).AppendChild($template.CreateTextNode(%s)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('DevSSH').Show($toast)
+`, quote(title), quote(message))
+
+	return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).Run()
+}
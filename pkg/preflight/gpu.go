@@ -0,0 +1,114 @@
+package preflight
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"devssh/pkg/ssh"
+)
+
+// GPUInfo 远程NVIDIA GPU环境
+type GPUInfo struct {
+	DriverVersion string `json:"driver_version,omitempty"`
+	// CUDAVersion 驱动支持的最高CUDA版本（nvidia-smi显示的版本）
+	CUDAVersion string `json:"cuda_version,omitempty"`
+	// ToolkitVersion 已安装的CUDA工具包版本（nvcc），未安装时为空
+	ToolkitVersion string `json:"toolkit_version,omitempty"`
+	GPUs           []GPU  `json:"gpus,omitempty"`
+}
+
+// GPU 单块GPU
+type GPU struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	Memory int64  `json:"memory,omitempty"`
+}
+
+// gpuScript 查询nvidia-smi和nvcc，没有安装时不输出
+const gpuScript = `if command -v nvidia-smi >/dev/null 2>&1; then
+nvidia-smi --query-gpu=index,name,memory.total,driver_version --format=csv,noheader,nounits 2>/dev/null | sed 's/^/gpu=/'
+nvidia-smi 2>/dev/null | sed -n 's/.*CUDA Version: *\([0-9.]*\).*/cuda=\1/p'
+fi
+nvcc --version 2>/dev/null | sed -n 's/.*release \([0-9.]*\),.*/nvcc=\1/p'
+true`
+
+// CollectGPU 只收集远程GPU信息，没有NVIDIA GPU时返回nil
+func CollectGPU(client *ssh.Client) (*GPUInfo, error) {
+	output, err := client.RunCommand(gpuScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect remote GPUs: %w", err)
+	}
+	return parseHostInfo(output).GPU, nil
+}
+
+// parseGPULine 解析gpuScript输出的一行，首次遇到GPU信息时创建GPUInfo
+func parseGPULine(gpu **GPUInfo, key, value string) {
+	info := *gpu
+	if info == nil {
+		info = &GPUInfo{}
+	}
+
+	switch key {
+	case "gpu":
+		// 格式为 index, name, memory.total(MiB), driver_version
+		fields := strings.Split(value, ",")
+		if len(fields) < 4 {
+			return
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		index, _ := strconv.Atoi(fields[0])
+		memory, _ := strconv.ParseInt(fields[2], 10, 64)
+		info.GPUs = append(info.GPUs, GPU{Index: index, Name: fields[1], Memory: memory * 1024 * 1024})
+		info.DriverVersion = fields[3]
+	case "cuda":
+		info.CUDAVersion = value
+	case "nvcc":
+		info.ToolkitVersion = value
+	default:
+		return
+	}
+	*gpu = info
+}
+
+// Summary 返回一行GPU概况，如 2x NVIDIA A100 (80.0 GB), driver 535.104, CUDA 12.2
+func (g *GPUInfo) Summary() string {
+	if g == nil || len(g.GPUs) == 0 {
+		if g != nil && g.ToolkitVersion != "" {
+			return fmt.Sprintf("no NVIDIA GPU, CUDA toolkit %s", g.ToolkitVersion)
+		}
+		return "none"
+	}
+
+	// 按型号合并，多数机器上所有GPU相同
+	var names []string
+	counts := make(map[string]int)
+	for _, gpu := range g.GPUs {
+		name := gpu.Name
+		if gpu.Memory > 0 {
+			name += " (" + FormatBytes(gpu.Memory) + ")"
+		}
+		if counts[name] == 0 {
+			names = append(names, name)
+		}
+		counts[name]++
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%dx %s", counts[name], name)
+	}
+
+	summary := strings.Join(parts, ", ")
+	if g.DriverVersion != "" {
+		summary += ", driver " + g.DriverVersion
+	}
+	if g.CUDAVersion != "" {
+		summary += ", CUDA " + g.CUDAVersion
+	}
+	if g.ToolkitVersion != "" && g.ToolkitVersion != g.CUDAVersion {
+		summary += " (toolkit " + g.ToolkitVersion + ")"
+	}
+	return summary
+}
@@ -0,0 +1,246 @@
+package preflight
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"devssh/pkg/ssh"
+)
+
+// 安装openvscode-server的默认要求：下载包约100MB，解压后约400MB
+const (
+	DefaultMinDiskBytes = 600 * 1024 * 1024
+	DefaultMinMemBytes  = 1024 * 1024 * 1024
+	// DefaultMinGlibc 当前VS Code服务端要求的最低glibc版本
+	DefaultMinGlibc = "2.28"
+)
+
+// installTools 安装IDE时远程需要的命令：安装包经SSH通道直接传给tar解压，不需要scp
+var installTools = []string{"tar", "gzip"}
+
+// HostInfo 远程主机的系统和资源信息，无法获取的项为零值
+type HostInfo struct {
+	OS     string `json:"os"`
+	Arch   string `json:"arch"`
+	Kernel string `json:"kernel,omitempty"`
+	CPUs   int    `json:"cpus,omitempty"`
+	// Load 1、5、15分钟平均负载
+	Load []float64 `json:"load,omitempty"`
+	// Libc C库实现，glibc或musl
+	Libc         string `json:"libc,omitempty"`
+	GlibcVersion string `json:"glibc_version,omitempty"`
+	MemTotal     int64  `json:"mem_total,omitempty"`
+	MemAvailable int64  `json:"mem_available,omitempty"`
+	// DiskFree 远程主目录所在文件系统的可用空间
+	DiskFree  int64 `json:"disk_free,omitempty"`
+	DiskTotal int64 `json:"disk_total,omitempty"`
+	// ContainerRuntimes 远程可用的容器运行时，如docker、podman
+	ContainerRuntimes []string `json:"container_runtimes,omitempty"`
+	// MissingTools 安装IDE需要但远程没有的命令
+	MissingTools []string `json:"missing_tools,omitempty"`
+	// GPU 远程没有NVIDIA驱动时为nil
+	GPU *GPUInfo `json:"gpu,omitempty"`
+}
+
+// collectScript 一次往返收集主机信息，每行一个 key=value，内存和磁盘以KB为单位
+const collectScript = `echo "os=$(uname -s)"
+echo "arch=$(uname -m)"
+echo "kernel=$(uname -r)"
+echo "cpus=$(getconf _NPROCESSORS_ONLN 2>/dev/null || nproc 2>/dev/null)"
+[ -r /proc/loadavg ] && awk '{print "load=" $1 " " $2 " " $3}' /proc/loadavg
+v=$(getconf GNU_LIBC_VERSION 2>/dev/null) && echo "glibc=${v#glibc }"
+ldd --version 2>&1 | grep -qi musl && echo "libc=musl"
+[ -r /proc/meminfo ] && awk '/^MemTotal:/{print "mem_total_kb=" $2} /^MemAvailable:/{print "mem_available_kb=" $2}' /proc/meminfo
+df -Pk "$HOME" 2>/dev/null | awk 'NR==2{print "disk_total_kb=" $2; print "disk_free_kb=" $4}'
+for r in docker podman; do command -v "$r" >/dev/null 2>&1 && echo "container_runtime=$r"; done
+true`
+
+// Collect 收集远程主机信息，包括GPU
+func Collect(client *ssh.Client) (*HostInfo, error) {
+	toolScript := fmt.Sprintf(`for t in %s; do command -v "$t" >/dev/null 2>&1 || echo "missing_tool=$t"; done`, strings.Join(installTools, " "))
+	output, err := client.RunCommand(collectScript + "\n" + toolScript + "\n" + gpuScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect remote host information: %w", err)
+	}
+	return parseHostInfo(output), nil
+}
+
+// parseHostInfo 解析collectScript的输出，忽略无法识别的行
+func parseHostInfo(output string) *HostInfo {
+	info := &HostInfo{}
+	kb := func(value string) int64 {
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n * 1024
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "os":
+			info.OS = value
+		case "arch":
+			info.Arch = value
+		case "kernel":
+			info.Kernel = value
+		case "cpus":
+			info.CPUs, _ = strconv.Atoi(value)
+		case "load":
+			info.Load = nil
+			for _, field := range strings.Fields(value) {
+				if load, err := strconv.ParseFloat(field, 64); err == nil {
+					info.Load = append(info.Load, load)
+				}
+			}
+		case "container_runtime":
+			info.ContainerRuntimes = append(info.ContainerRuntimes, value)
+		case "missing_tool":
+			info.MissingTools = append(info.MissingTools, value)
+		case "glibc":
+			info.Libc = "glibc"
+			info.GlibcVersion = value
+		case "libc":
+			info.Libc = value
+		case "mem_total_kb":
+			info.MemTotal = kb(value)
+		case "mem_available_kb":
+			info.MemAvailable = kb(value)
+		case "disk_total_kb":
+			info.DiskTotal = kb(value)
+		case "disk_free_kb":
+			info.DiskFree = kb(value)
+		default:
+			parseGPULine(&info.GPU, key, value)
+		}
+	}
+	return info
+}
+
+// Requirements 安装IDE的最低要求，0或空表示不检查
+type Requirements struct {
+	MinDiskBytes int64
+	MinMemBytes  int64
+	MinGlibc     string
+}
+
+// DefaultRequirements 返回openvscode-server的默认要求
+func DefaultRequirements() Requirements {
+	return Requirements{
+		MinDiskBytes: DefaultMinDiskBytes,
+		MinMemBytes:  DefaultMinMemBytes,
+		MinGlibc:     DefaultMinGlibc,
+	}
+}
+
+// ErrRequirementsNotMet 远程主机不满足必要条件，安装不会成功
+var ErrRequirementsNotMet = errors.New("remote host does not meet the IDE requirements")
+
+// Issue 一项未满足的要求，Fatal为true时安装无法成功
+type Issue struct {
+	Check   string `json:"check"`
+	Message string `json:"message"`
+	Fatal   bool   `json:"fatal"`
+}
+
+func (i Issue) Error() string {
+	return i.Message
+}
+
+// Check 比较主机信息与要求，无法获取的信息不视为问题
+func Check(info *HostInfo, req Requirements) []Issue {
+	var issues []Issue
+
+	if info.OS != "" && info.OS != "Linux" {
+		issues = append(issues, Issue{
+			Check:   "os",
+			Message: fmt.Sprintf("remote OS is %s, the web IDE only runs on Linux", info.OS),
+			Fatal:   true,
+		})
+	}
+
+	if info.Libc == "musl" {
+		issues = append(issues, Issue{
+			Check:   "libc",
+			Message: "remote host uses musl libc (e.g. Alpine); the web IDE needs glibc",
+			Fatal:   true,
+		})
+	} else if req.MinGlibc != "" && info.GlibcVersion != "" && compareVersions(info.GlibcVersion, req.MinGlibc) < 0 {
+		issues = append(issues, Issue{
+			Check:   "glibc",
+			Message: fmt.Sprintf("remote glibc %s is older than the required %s", info.GlibcVersion, req.MinGlibc),
+			Fatal:   true,
+		})
+	}
+
+	// 缺少的命令需要管理员安装，IDE本身安装在用户目录，不需要root
+	if len(info.MissingTools) > 0 {
+		issues = append(issues, Issue{
+			Check: "tools",
+			Message: fmt.Sprintf("remote host is missing %s; ask an administrator to install them (e.g. apt-get install %s), the IDE itself installs without root",
+				strings.Join(info.MissingTools, ", "), strings.Join(info.MissingTools, " ")),
+			Fatal: true,
+		})
+	}
+
+	if req.MinDiskBytes > 0 && info.DiskTotal > 0 && info.DiskFree < req.MinDiskBytes {
+		issues = append(issues, Issue{
+			Check: "disk",
+			Message: fmt.Sprintf("only %s free in the remote home directory, installing needs about %s",
+				FormatBytes(info.DiskFree), FormatBytes(req.MinDiskBytes)),
+			Fatal: true,
+		})
+	}
+
+	// 内存不足时IDE仍可运行，只是可能很慢
+	if req.MinMemBytes > 0 && info.MemTotal > 0 && info.MemTotal < req.MinMemBytes {
+		issues = append(issues, Issue{
+			Check: "memory",
+			Message: fmt.Sprintf("remote host has %s of memory, at least %s is recommended",
+				FormatBytes(info.MemTotal), FormatBytes(req.MinMemBytes)),
+		})
+	}
+
+	return issues
+}
+
+// compareVersions 按数字逐段比较点分版本号
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// FormatBytes 以1024为进制显示字节数，如 1.5 GB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
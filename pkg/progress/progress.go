@@ -0,0 +1,176 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"devssh/pkg/logging"
+
+	"github.com/loft-sh/log"
+)
+
+// 长流程的阶段
+const (
+	PhaseConnect = "connect"
+	PhasePrepare = "prepare"
+	PhaseInstall = "install"
+	PhaseStart   = "start"
+	PhaseForward = "forward"
+	PhaseReady   = "ready"
+)
+
+// phaseTitles 文本输出中各阶段的说明
+var phaseTitles = map[string]string{
+	PhaseConnect: "Connecting",
+	PhasePrepare: "Preparing the remote host",
+	PhaseInstall: "Installing the IDE",
+	PhaseStart:   "Starting the IDE",
+	PhaseForward: "Forwarding ports",
+	PhaseReady:   "Ready",
+}
+
+// Status 阶段状态
+type Status string
+
+const (
+	StatusStarted Status = "started"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Event 一个阶段开始或结束时的进度事件
+type Event struct {
+	Time  time.Time `json:"time"`
+	Host  string    `json:"host,omitempty"`
+	Phase string    `json:"phase"`
+	// Step 从1开始的阶段序号，Steps为阶段总数
+	Step   int    `json:"step"`
+	Steps  int    `json:"steps"`
+	Status Status `json:"status"`
+	// Percent 已完成阶段占全部阶段的百分比
+	Percent int    `json:"percent"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Reporter 接收进度事件，需要支持并发调用
+type Reporter interface {
+	Report(event Event)
+}
+
+// Tracker 按固定的阶段列表跟踪一个主机上的流程
+type Tracker struct {
+	reporter Reporter
+	host     string
+	phases   []string
+	mu       sync.Mutex
+	done     int
+}
+
+// NewTracker 创建跟踪器，phases为流程依次经过的阶段
+func NewTracker(reporter Reporter, host string, phases ...string) *Tracker {
+	return &Tracker{reporter: reporter, host: host, phases: phases}
+}
+
+// Step 进行中的阶段，由Tracker.Start返回
+type Step struct {
+	tracker *Tracker
+	phase   string
+	step    int
+}
+
+// Start 报告阶段开始，阶段结束时调用返回值的End
+func (t *Tracker) Start(phase string) *Step {
+	step := &Step{tracker: t, phase: phase, step: t.index(phase)}
+	t.report(step, StatusStarted, nil)
+	return step
+}
+
+// End 报告阶段结束，err非空时标记为失败
+func (s *Step) End(err error) {
+	status := StatusDone
+	if err != nil {
+		status = StatusFailed
+	} else {
+		s.tracker.mu.Lock()
+		s.tracker.done++
+		s.tracker.mu.Unlock()
+	}
+	s.tracker.report(s, status, err)
+}
+
+func (t *Tracker) index(phase string) int {
+	for i, p := range t.phases {
+		if p == phase {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (t *Tracker) report(step *Step, status Status, err error) {
+	t.mu.Lock()
+	percent := 100
+	if len(t.phases) > 0 {
+		percent = min(t.done*100/len(t.phases), 100)
+	}
+	t.mu.Unlock()
+
+	event := Event{
+		Time:    time.Now(),
+		Host:    t.host,
+		Phase:   step.phase,
+		Step:    step.step,
+		Steps:   len(t.phases),
+		Status:  status,
+		Percent: percent,
+		Message: phaseTitles[step.phase],
+	}
+	if err != nil {
+		event.Error = logging.Redact(err.Error())
+	}
+	t.reporter.Report(event)
+}
+
+// logReporter 以 [n/m] 步骤行输出到日志，失败由调用方记录错误
+type logReporter struct {
+	logger log.Logger
+}
+
+// NewLogReporter 创建输出到终端日志的reporter
+func NewLogReporter(logger log.Logger) Reporter {
+	return logReporter{logger: logger}
+}
+
+func (r logReporter) Report(event Event) {
+	switch event.Status {
+	case StatusStarted:
+		r.logger.Infof("[%d/%d] %s...", event.Step, event.Steps, event.Message)
+	case StatusDone:
+		r.logger.Debugf("[%d/%d] %s done", event.Step, event.Steps, event.Message)
+	}
+}
+
+// jsonReporter 每个事件输出一行JSON，供编辑器插件等程序读取
+type jsonReporter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewJSONReporter 创建向out逐行写入JSON事件的reporter
+func NewJSONReporter(out io.Writer) Reporter {
+	return &jsonReporter{out: out}
+}
+
+func (r *jsonReporter) Report(event Event) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = r.out.Write(append(line, '\n'))
+}
@@ -0,0 +1,156 @@
+package provision
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"devssh/pkg/config"
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+)
+
+// markerDir 远程记录已完成任务的目录，每个任务一个以内容哈希命名的空文件
+const markerDir = "~/.devssh/setup"
+
+// Status 任务执行结果
+type Status string
+
+const (
+	StatusDone    Status = "done"    // 本次执行成功
+	StatusSkipped Status = "skipped" // 之前已完成或检查命令成功
+	StatusFailed  Status = "failed"
+	StatusPending Status = "pending" // 前面的任务失败，未执行
+)
+
+// TaskResult 一个任务的执行结果
+type TaskResult struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Provisioner 按顺序在远程执行准备任务
+// 任务成功后在远程记录其内容哈希，命令或脚本内容不变时之后的连接跳过该任务
+type Provisioner struct {
+	client *ssh.Client
+	logger log.Logger
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// NewProvisioner 创建任务执行器，任务输出写到标准输出和标准错误
+func NewProvisioner(client *ssh.Client, logger log.Logger) *Provisioner {
+	return &Provisioner{
+		client: client,
+		logger: logger,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+}
+
+// Run 依次执行任务，遇到失败的任务时停止，其后的任务标记为pending
+func (p *Provisioner) Run(tasks []config.SetupTask) ([]TaskResult, error) {
+	results := make([]TaskResult, len(tasks))
+	for i, task := range tasks {
+		results[i] = TaskResult{Name: task.Label(), Status: StatusPending}
+	}
+
+	for i, task := range tasks {
+		start := time.Now()
+		status, err := p.runTask(i, len(tasks), task)
+		results[i].Status = status
+		results[i].Duration = time.Since(start).Truncate(time.Millisecond)
+		if err != nil {
+			results[i].Error = err.Error()
+			return results, fmt.Errorf("setup task %q failed: %w", task.Label(), err)
+		}
+	}
+	return results, nil
+}
+
+// runTask 执行单个任务，已完成时跳过
+func (p *Provisioner) runTask(index, total int, task config.SetupTask) (Status, error) {
+	label := task.Label()
+	if (task.Run == "") == (task.Script == "") {
+		return StatusFailed, fmt.Errorf("needs exactly one of run or script")
+	}
+
+	script, err := taskScript(task)
+	if err != nil {
+		return StatusFailed, err
+	}
+	marker := markerDir + "/" + taskKey(task, script)
+
+	if task.Check != "" {
+		if _, err := p.client.RunCommand(task.Check); err == nil {
+			p.logger.Infof("[%d/%d] %s: already satisfied", index+1, total, label)
+			return StatusSkipped, nil
+		}
+	} else if !task.Always {
+		if _, err := p.client.RunCommand("test -f " + marker); err == nil {
+			p.logger.Infof("[%d/%d] %s: already done", index+1, total, label)
+			return StatusSkipped, nil
+		}
+	}
+
+	p.logger.Infof("[%d/%d] %s...", index+1, total, label)
+	if task.Script != "" {
+		err = p.runScript(script)
+	} else {
+		err = p.client.RunCommandWithOutput(task.Run, p.stdout, p.stderr)
+	}
+	if err != nil {
+		return StatusFailed, err
+	}
+
+	if !task.Always {
+		if output, err := p.client.RunCommand(fmt.Sprintf("mkdir -p %s && touch %s", markerDir, marker)); err != nil {
+			p.logger.Warnf("Failed to record setup task %s as done: %v: %s", label, err, strings.TrimSpace(output))
+		}
+	}
+	p.logger.Donef("[%d/%d] %s", index+1, total, label)
+	return StatusDone, nil
+}
+
+// runScript 通过标准输入上传脚本到远程临时文件并执行，脚本可以用 #! 指定解释器
+func (p *Provisioner) runScript(script []byte) error {
+	session, err := p.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdin = bytes.NewReader(script)
+	session.Stdout = p.stdout
+	session.Stderr = p.stderr
+	return session.Run(`f=$(mktemp) && cat > "$f" && chmod +x "$f" && { "$f" </dev/null; rc=$?; rm -f "$f"; exit $rc; }`)
+}
+
+// taskScript 读取任务的本地脚本，run任务返回nil
+func taskScript(task config.SetupTask) ([]byte, error) {
+	if task.Script == "" {
+		return nil, nil
+	}
+	script, err := os.ReadFile(task.Script)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read setup script: %w", err)
+	}
+	return script, nil
+}
+
+// taskKey 由任务命令或脚本内容计算的标识，内容变化后任务会重新执行
+func taskKey(task config.SetupTask, script []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(task.Run))
+	hash.Write([]byte{0})
+	hash.Write(script)
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
@@ -0,0 +1,441 @@
+//go:build linux || darwin
+
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/loft-sh/log"
+	"github.com/pkg/sftp"
+)
+
+// Mount 通过FUSE挂载的远程目录
+type Mount struct {
+	server   *fuse.Server
+	localDir string
+	logger   log.Logger
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewMount 把远程目录挂载到本地空目录，文件操作通过SFTP转发到远程
+func NewMount(client *sftp.Client, remoteDir, localDir string, opts Options, logger log.Logger) (*Mount, error) {
+	root, err := resolveRoot(client, remoteDir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.CacheTimeout <= 0 {
+		opts.CacheTimeout = DefaultCacheTimeout
+	}
+
+	fsys := &remoteFS{
+		client:   client,
+		root:     root,
+		readOnly: opts.ReadOnly,
+		uid:      uint32(os.Getuid()),
+		gid:      uint32(os.Getgid()),
+	}
+	mountOpts := &fs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName: "devssh:" + root,
+			Name:   "devssh",
+		},
+		EntryTimeout: &opts.CacheTimeout,
+		AttrTimeout:  &opts.CacheTimeout,
+	}
+	if opts.ReadOnly {
+		mountOpts.MountOptions.Options = append(mountOpts.MountOptions.Options, "ro")
+	}
+
+	server, err := fs.Mount(localDir, &node{fsys: fsys}, mountOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mount %s on %s (is FUSE installed?): %w", root, localDir, err)
+	}
+	logger.Debugf("Mounted remote %s on %s", root, localDir)
+
+	m := &Mount{
+		server:   server,
+		localDir: localDir,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+	go func() {
+		server.Wait()
+		close(m.done)
+	}()
+	return m, nil
+}
+
+// Unmount 卸载文件系统，挂载点仍被占用时返回错误
+func (m *Mount) Unmount() error {
+	var err error
+	m.once.Do(func() {
+		if err = m.server.Unmount(); err != nil {
+			err = fmt.Errorf("failed to unmount %s: %w", m.localDir, err)
+			return
+		}
+		m.logger.Debugf("Unmounted %s", m.localDir)
+	})
+	return err
+}
+
+// Done 返回文件系统被卸载时关闭的channel（包括在外部用umount卸载）
+func (m *Mount) Done() <-chan struct{} {
+	return m.done
+}
+
+// remoteFS 所有节点共享的SFTP连接和挂载参数
+type remoteFS struct {
+	client   *sftp.Client
+	root     string
+	readOnly bool
+	uid      uint32
+	gid      uint32
+}
+
+// node 远程文件或目录，路径由节点在树中的位置得出，因此重命名后无需更新
+type node struct {
+	fs.Inode
+	fsys *remoteFS
+}
+
+var (
+	_ fs.NodeGetattrer  = (*node)(nil)
+	_ fs.NodeSetattrer  = (*node)(nil)
+	_ fs.NodeLookuper   = (*node)(nil)
+	_ fs.NodeReaddirer  = (*node)(nil)
+	_ fs.NodeOpener     = (*node)(nil)
+	_ fs.NodeCreater    = (*node)(nil)
+	_ fs.NodeMkdirer    = (*node)(nil)
+	_ fs.NodeUnlinker   = (*node)(nil)
+	_ fs.NodeRmdirer    = (*node)(nil)
+	_ fs.NodeRenamer    = (*node)(nil)
+	_ fs.NodeReadlinker = (*node)(nil)
+	_ fs.NodeSymlinker  = (*node)(nil)
+	_ fs.NodeStatfser   = (*node)(nil)
+)
+
+// remotePath 节点（或其子项name）对应的远程绝对路径
+func (n *node) remotePath(name ...string) string {
+	return path.Join(append([]string{n.fsys.root, n.Path(nil)}, name...)...)
+}
+
+func (n *node) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	info, err := n.fsys.client.Lstat(n.remotePath())
+	if err != nil {
+		return toErrno(err)
+	}
+	n.fsys.fillAttr(info, &out.Attr)
+	return fs.OK
+}
+
+func (n *node) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if n.fsys.readOnly {
+		return syscall.EROFS
+	}
+	p := n.remotePath()
+	if size, ok := in.GetSize(); ok {
+		if err := n.fsys.client.Truncate(p, int64(size)); err != nil {
+			return toErrno(err)
+		}
+	}
+	if mode, ok := in.GetMode(); ok {
+		if err := n.fsys.client.Chmod(p, os.FileMode(mode&0o7777)); err != nil {
+			return toErrno(err)
+		}
+	}
+	mtime, hasMtime := in.GetMTime()
+	atime, hasAtime := in.GetATime()
+	if hasMtime || hasAtime {
+		// SFTP只能同时设置两个时间，缺少的一个保持原值
+		if !hasMtime || !hasAtime {
+			info, err := n.fsys.client.Lstat(p)
+			if err != nil {
+				return toErrno(err)
+			}
+			if !hasMtime {
+				mtime = info.ModTime()
+			}
+			if !hasAtime {
+				atime = accessTime(info)
+			}
+		}
+		if err := n.fsys.client.Chtimes(p, atime, mtime); err != nil {
+			return toErrno(err)
+		}
+	}
+	return n.Getattr(ctx, f, out)
+}
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	info, err := n.fsys.client.Lstat(n.remotePath(name))
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	return n.newChild(ctx, info, out), fs.OK
+}
+
+func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	infos, err := n.fsys.client.ReadDir(n.remotePath())
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	entries := make([]fuse.DirEntry, 0, len(infos))
+	for _, info := range infos {
+		entries = append(entries, fuse.DirEntry{Name: info.Name(), Mode: posixMode(info)})
+	}
+	return fs.NewListDirStream(entries), fs.OK
+}
+
+func (n *node) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if n.fsys.readOnly && flags&syscall.O_ACCMODE != syscall.O_RDONLY {
+		return nil, 0, syscall.EROFS
+	}
+	file, err := n.fsys.client.OpenFile(n.remotePath(), openFlags(flags))
+	if err != nil {
+		return nil, 0, toErrno(err)
+	}
+	return &handle{file: file}, 0, fs.OK
+}
+
+func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	if n.fsys.readOnly {
+		return nil, nil, 0, syscall.EROFS
+	}
+	p := n.remotePath(name)
+	file, err := n.fsys.client.OpenFile(p, openFlags(flags)|os.O_CREATE)
+	if err != nil {
+		return nil, nil, 0, toErrno(err)
+	}
+	if err := n.fsys.client.Chmod(p, os.FileMode(mode&0o777)); err != nil {
+		file.Close()
+		return nil, nil, 0, toErrno(err)
+	}
+	info, err := n.fsys.client.Lstat(p)
+	if err != nil {
+		file.Close()
+		return nil, nil, 0, toErrno(err)
+	}
+	return n.newChild(ctx, info, out), &handle{file: file}, 0, fs.OK
+}
+
+func (n *node) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if n.fsys.readOnly {
+		return nil, syscall.EROFS
+	}
+	p := n.remotePath(name)
+	if err := n.fsys.client.Mkdir(p); err != nil {
+		return nil, toErrno(err)
+	}
+	if err := n.fsys.client.Chmod(p, os.FileMode(mode&0o7777)); err != nil {
+		return nil, toErrno(err)
+	}
+	info, err := n.fsys.client.Lstat(p)
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	return n.newChild(ctx, info, out), fs.OK
+}
+
+func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
+	if n.fsys.readOnly {
+		return syscall.EROFS
+	}
+	return toErrno(n.fsys.client.Remove(n.remotePath(name)))
+}
+
+func (n *node) Rmdir(ctx context.Context, name string) syscall.Errno {
+	if n.fsys.readOnly {
+		return syscall.EROFS
+	}
+	return toErrno(n.fsys.client.RemoveDirectory(n.remotePath(name)))
+}
+
+func (n *node) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if n.fsys.readOnly {
+		return syscall.EROFS
+	}
+	if flags != 0 {
+		// RENAME_EXCHANGE/RENAME_NOREPLACE 无法通过SFTP实现
+		return syscall.ENOTSUP
+	}
+	parent, ok := newParent.(*node)
+	if !ok {
+		return syscall.EXDEV
+	}
+	from, to := n.remotePath(name), parent.remotePath(newName)
+	// 优先使用可覆盖目标的posix-rename扩展，服务器不支持时退回普通重命名
+	err := n.fsys.client.PosixRename(from, to)
+	var status *sftp.StatusError
+	if errors.As(err, &status) && status.FxCode() == sftp.ErrSSHFxOpUnsupported {
+		err = n.fsys.client.Rename(from, to)
+	}
+	return toErrno(err)
+}
+
+func (n *node) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	target, err := n.fsys.client.ReadLink(n.remotePath())
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	return []byte(target), fs.OK
+}
+
+func (n *node) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if n.fsys.readOnly {
+		return nil, syscall.EROFS
+	}
+	p := n.remotePath(name)
+	if err := n.fsys.client.Symlink(target, p); err != nil {
+		return nil, toErrno(err)
+	}
+	info, err := n.fsys.client.Lstat(p)
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	return n.newChild(ctx, info, out), fs.OK
+}
+
+func (n *node) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	stat, err := n.fsys.client.StatVFS(n.remotePath())
+	if err != nil {
+		// 服务器不支持statvfs扩展时报告空的统计信息
+		return fs.OK
+	}
+	out.Blocks = stat.Blocks
+	out.Bfree = stat.Bfree
+	out.Bavail = stat.Bavail
+	out.Files = stat.Files
+	out.Ffree = stat.Ffree
+	out.Bsize = uint32(stat.Bsize)
+	out.Frsize = uint32(stat.Frsize)
+	out.NameLen = uint32(stat.Namemax)
+	return fs.OK
+}
+
+// newChild 为远程路径创建子节点并填充属性
+func (n *node) newChild(ctx context.Context, info os.FileInfo, out *fuse.EntryOut) *fs.Inode {
+	n.fsys.fillAttr(info, &out.Attr)
+	child := &node{fsys: n.fsys}
+	return n.NewInode(ctx, child, fs.StableAttr{Mode: posixMode(info) & syscall.S_IFMT})
+}
+
+// fillAttr 把SFTP返回的属性转换为FUSE属性，文件属主显示为本地用户
+func (f *remoteFS) fillAttr(info os.FileInfo, out *fuse.Attr) {
+	out.Mode = posixMode(info)
+	out.Size = uint64(info.Size())
+	out.Blocks = (out.Size + 511) / 512
+	out.Nlink = 1
+	out.Owner = fuse.Owner{Uid: f.uid, Gid: f.gid}
+	mtime, atime := info.ModTime(), accessTime(info)
+	out.SetTimes(&atime, &mtime, &mtime)
+}
+
+// handle 打开的远程文件
+type handle struct {
+	file *sftp.File
+}
+
+var (
+	_ fs.FileReader   = (*handle)(nil)
+	_ fs.FileWriter   = (*handle)(nil)
+	_ fs.FileFsyncer  = (*handle)(nil)
+	_ fs.FileReleaser = (*handle)(nil)
+)
+
+func (h *handle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	n, err := h.file.ReadAt(dest, off)
+	// 读到文件末尾时返回已读取的部分
+	if err != nil && err != io.EOF {
+		return nil, toErrno(err)
+	}
+	return fuse.ReadResultData(dest[:n]), fs.OK
+}
+
+func (h *handle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	n, err := h.file.WriteAt(data, off)
+	if err != nil {
+		return uint32(n), toErrno(err)
+	}
+	return uint32(n), fs.OK
+}
+
+func (h *handle) Fsync(ctx context.Context, flags uint32) syscall.Errno {
+	err := h.file.Sync()
+	var status *sftp.StatusError
+	if errors.As(err, &status) && status.FxCode() == sftp.ErrSSHFxOpUnsupported {
+		// 服务器不支持fsync扩展时数据已随写请求提交
+		return fs.OK
+	}
+	return toErrno(err)
+}
+
+func (h *handle) Release(ctx context.Context) syscall.Errno {
+	return toErrno(h.file.Close())
+}
+
+// openFlags 把open(2)标志转换为SFTP打开标志
+func openFlags(flags uint32) int {
+	return int(flags) & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR | os.O_APPEND | os.O_CREATE | os.O_EXCL | os.O_TRUNC)
+}
+
+// posixMode 取得包含文件类型位的POSIX权限
+func posixMode(info os.FileInfo) uint32 {
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		return stat.Mode
+	}
+	mode := uint32(info.Mode().Perm())
+	switch {
+	case info.IsDir():
+		mode |= syscall.S_IFDIR
+	case info.Mode()&os.ModeSymlink != 0:
+		mode |= syscall.S_IFLNK
+	default:
+		mode |= syscall.S_IFREG
+	}
+	return mode
+}
+
+// accessTime 取得访问时间，属性中没有时使用修改时间
+func accessTime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		return time.Unix(int64(stat.Atime), 0)
+	}
+	return info.ModTime()
+}
+
+// toErrno 把SFTP错误转换为errno
+func toErrno(err error) syscall.Errno {
+	var status *sftp.StatusError
+	switch {
+	case err == nil:
+		return fs.OK
+	case errors.Is(err, iofs.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, iofs.ErrPermission):
+		return syscall.EACCES
+	case errors.Is(err, iofs.ErrExist):
+		return syscall.EEXIST
+	case errors.As(err, &status):
+		switch status.FxCode() {
+		case sftp.ErrSSHFxOpUnsupported:
+			return syscall.ENOTSUP
+		case sftp.ErrSSHFxNoSuchFile:
+			return syscall.ENOENT
+		case sftp.ErrSSHFxPermissionDenied:
+			return syscall.EACCES
+		}
+	}
+	return syscall.EIO
+}
@@ -0,0 +1,31 @@
+//go:build !linux && !darwin
+
+package remotefs
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/loft-sh/log"
+	"github.com/pkg/sftp"
+)
+
+// Mount 当前平台不支持挂载
+type Mount struct{}
+
+// NewMount 当前平台没有可用的FUSE实现，直接返回错误
+func NewMount(client *sftp.Client, remoteDir, localDir string, opts Options, logger log.Logger) (*Mount, error) {
+	return nil, fmt.Errorf("mounting remote directories is not supported on %s; use \"devssh sync\" to copy files instead", runtime.GOOS)
+}
+
+// Unmount 卸载文件系统
+func (m *Mount) Unmount() error {
+	return nil
+}
+
+// Done 返回文件系统被卸载时关闭的channel
+func (m *Mount) Done() <-chan struct{} {
+	done := make(chan struct{})
+	close(done)
+	return done
+}
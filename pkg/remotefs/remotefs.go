@@ -0,0 +1,43 @@
+package remotefs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// DefaultCacheTimeout 内核缓存文件属性和目录项的默认时间
+const DefaultCacheTimeout = time.Second
+
+// Options 挂载选项
+type Options struct {
+	ReadOnly bool
+	// CacheTimeout 内核缓存文件属性和目录项的时间，0使用DefaultCacheTimeout
+	CacheTimeout time.Duration
+}
+
+// resolveRoot 把远程目录解析为绝对路径并确认是目录，~ 和 ~/ 开头的路径相对于远程用户主目录
+func resolveRoot(client *sftp.Client, dir string) (string, error) {
+	// SFTP会话的当前目录即为主目录
+	switch {
+	case dir == "" || dir == "~":
+		dir = "."
+	case strings.HasPrefix(dir, "~/"):
+		dir = strings.TrimPrefix(dir, "~/")
+	}
+
+	root, err := client.RealPath(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve remote path %s: %w", dir, err)
+	}
+	info, err := client.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to access remote path %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("remote path %s is not a directory", root)
+	}
+	return root, nil
+}
@@ -0,0 +1,257 @@
+package secrets
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"devssh/pkg/config"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const secretsFileName = "secrets.enc"
+
+// scrypt参数
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 16
+)
+
+// encryptedFile 加密文件格式，Data为加密后的JSON对象（ID到值）
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// fileStore 使用主口令加密的本地文件保存密钥
+type fileStore struct {
+	path       string
+	passphrase PassphraseFunc
+	cached     string
+}
+
+func newFileStore(passphrase PassphraseFunc) (*fileStore, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == nil {
+		return nil, fmt.Errorf("a master passphrase is required for the encrypted secrets file")
+	}
+
+	return &fileStore{
+		path:       filepath.Join(configDir, secretsFileName),
+		passphrase: passphrase,
+	}, nil
+}
+
+func (s *fileStore) Backend() Backend {
+	return BackendFile
+}
+
+func (s *fileStore) Get(id string) (string, error) {
+	if err := ValidateID(id); err != nil {
+		return "", err
+	}
+
+	values, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	value, exists := values[id]
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return value, nil
+}
+
+func (s *fileStore) Set(id, value string) error {
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+
+	return s.update(func(values map[string]string) error {
+		values[id] = value
+		return nil
+	})
+}
+
+func (s *fileStore) Delete(id string) error {
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+
+	return s.update(func(values map[string]string) error {
+		if _, exists := values[id]; !exists {
+			return fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		delete(values, id)
+		return nil
+	})
+}
+
+// update 在配置锁内读取、修改并保存加密文件，避免并发的devssh进程互相覆盖；
+// 主口令在加锁前获取，等待输入时不阻塞其他进程
+func (s *fileStore) update(fn func(values map[string]string) error) error {
+	if _, err := s.getPassphrase(!s.exists()); err != nil {
+		return err
+	}
+
+	return config.WithLock(func() error {
+		values, err := s.load()
+		if err != nil {
+			return err
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+		return s.save(values)
+	})
+}
+
+// exists 判断加密文件是否已经存在
+func (s *fileStore) exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// List 返回加密文件中的全部密钥ID
+func (s *fileStore) List() ([]string, error) {
+	values, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// getPassphrase 获取主口令，同一进程内只询问一次；create为true时正在创建加密文件
+func (s *fileStore) getPassphrase(create bool) (string, error) {
+	if s.cached != "" {
+		return s.cached, nil
+	}
+
+	passphrase, err := s.passphrase(create)
+	if err != nil {
+		return "", fmt.Errorf("failed to get master passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("master passphrase must not be empty")
+	}
+
+	s.cached = passphrase
+	return passphrase, nil
+}
+
+func (s *fileStore) load() (map[string]string, error) {
+	values := make(map[string]string)
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("unsupported secrets file version %d", file.Version)
+	}
+
+	passphrase, err := s.getPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		// 口令错误时清除缓存，便于重试
+		s.cached = ""
+		return nil, fmt.Errorf("failed to decrypt secrets file (wrong master passphrase?)")
+	}
+
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted secrets: %w", err)
+	}
+	return values, nil
+}
+
+func (s *fileStore) save(values map[string]string) error {
+	passphrase, err := s.getPassphrase(!s.exists())
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+
+	// 每次保存都使用新的salt和nonce
+	file := encryptedFile{Version: 1, Salt: make([]byte, saltLen)}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := newAEAD(passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Data = aead.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	return config.WriteFileAtomic(s.path, data, 0600)
+}
+
+// newAEAD 由主口令派生密钥并创建XChaCha20-Poly1305加密器
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
@@ -0,0 +1,153 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"devssh/pkg/config"
+)
+
+// newTestFileStore 在临时目录中创建加密文件存储，口令固定为passphrase，
+// 配置锁也放在该目录中
+func newTestFileStore(t *testing.T, passphrase string) *fileStore {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(config.EnvConfig, filepath.Join(dir, "config.yaml"))
+	return &fileStore{
+		path:       filepath.Join(dir, secretsFileName),
+		passphrase: func(bool) (string, error) { return passphrase, nil },
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	store := newTestFileStore(t, "correct horse")
+	if err := store.Set("prod-password", "s3cret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Set("deploy-key", "other"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Error("secrets file contains the plaintext value")
+	}
+
+	// 新的存储实例重新解密文件
+	reopened := &fileStore{path: store.path, passphrase: store.passphrase}
+	value, err := reopened.Get("prod-password")
+	if err != nil || value != "s3cret" {
+		t.Errorf("Get = %q, %v, want %q", value, err, "s3cret")
+	}
+	ids, err := reopened.List()
+	if err != nil || strings.Join(ids, ",") != "deploy-key,prod-password" {
+		t.Errorf("List = %q, %v", ids, err)
+	}
+
+	if err := reopened.Delete("prod-password"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := reopened.Get("prod-password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := reopened.Delete("prod-password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete of a missing secret: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	store := newTestFileStore(t, "correct horse")
+	if err := store.Set("prod-password", "s3cret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	wrong := &fileStore{path: store.path, passphrase: func(bool) (string, error) { return "battery staple", nil }}
+	if _, err := wrong.Get("prod-password"); err == nil || !strings.Contains(err.Error(), "wrong master passphrase") {
+		t.Errorf("Get with a wrong passphrase: err = %v", err)
+	}
+	if wrong.cached != "" {
+		t.Error("wrong passphrase is still cached")
+	}
+	// 口令错误时不能覆盖已有文件
+	if err := wrong.Set("other", "value"); err == nil {
+		t.Error("Set with a wrong passphrase succeeded")
+	}
+	if value, err := store.Get("prod-password"); err != nil || value != "s3cret" {
+		t.Errorf("Get after a failed Set = %q, %v", value, err)
+	}
+}
+
+func TestFileStoreCorruptedFile(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		corrupt func(data []byte) []byte
+		wantErr string
+	}{
+		{"garbage", func([]byte) []byte { return []byte("not json") }, "failed to parse secrets file"},
+		{"tampered data", func(data []byte) []byte {
+			var file encryptedFile
+			json.Unmarshal(data, &file)
+			file.Data[0] ^= 0xff
+			data, _ = json.Marshal(file)
+			return data
+		}, "failed to decrypt secrets file"},
+		{"unknown version", func(data []byte) []byte {
+			var file encryptedFile
+			json.Unmarshal(data, &file)
+			file.Version = 2
+			data, _ = json.Marshal(file)
+			return data
+		}, "unsupported secrets file version"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newTestFileStore(t, "correct horse")
+			if err := store.Set("prod-password", "s3cret"); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			data, err := os.ReadFile(store.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(store.path, tc.corrupt(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			reopened := &fileStore{path: store.path, passphrase: store.passphrase}
+			if _, err := reopened.Get("prod-password"); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Get: err = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileStoreAsksToCreate(t *testing.T) {
+	store := newTestFileStore(t, "")
+	var calls []bool
+	store.passphrase = func(create bool) (string, error) {
+		calls = append(calls, create)
+		return "correct horse", nil
+	}
+
+	if err := store.Set("prod-password", "s3cret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if len(calls) != 1 || !calls[0] {
+		t.Errorf("passphrase calls = %v, want one with create", calls)
+	}
+
+	reopened := &fileStore{path: store.path, passphrase: store.passphrase}
+	calls = nil
+	if _, err := reopened.Get("prod-password"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(calls) != 1 || calls[0] {
+		t.Errorf("passphrase calls = %v, want one without create", calls)
+	}
+}
@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainAvailable macOS 通过 security 命令访问钥匙串
+func keychainAvailable() bool {
+	_, err := exec.LookPath("security")
+	return err == nil
+}
+
+func keychainGet(id string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "find-generic-password", "-s", serviceName, "-a", id, "-w")
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if strings.Contains(stderr.String(), "could not be found") {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return "", fmt.Errorf("failed to read keychain item %s: %v: %s", id, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+// securityMaxLine security -i 每行命令的最大长度
+const securityMaxLine = 4096
+
+func keychainSet(id, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("failed to write keychain item %s: values with line breaks are not supported", id)
+	}
+
+	// 命令行参数中的值可被其他用户通过ps看到，改为在 security -i 的交互模式下从stdin传入命令；
+	// -q 不输出提示符，-U 已存在时更新
+	line := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		securityQuote(serviceName), securityQuote(id), securityQuote(value))
+	if len(line) >= securityMaxLine {
+		return fmt.Errorf("failed to write keychain item %s: value is too long for the keychain", id)
+	}
+	cmd := exec.Command("security", "-q", "-i")
+	cmd.Stdin = strings.NewReader(line)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to write keychain item %s: %v: %s", id, err, strings.TrimSpace(string(output)))
+	}
+	// 成功时没有输出，有输出说明命令失败
+	if msg := strings.TrimSpace(string(output)); msg != "" {
+		return fmt.Errorf("failed to write keychain item %s: %s", id, msg)
+	}
+	return nil
+}
+
+// securityQuote 按 security -i 的命令行解析规则用双引号引用参数
+func securityQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func keychainDelete(id string) error {
+	output, err := exec.Command("security", "delete-generic-password", "-s", serviceName, "-a", id).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "could not be found") {
+			return fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return fmt.Errorf("failed to delete keychain item %s: %v: %s", id, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+//go:build !darwin && !windows
+
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// keychainAvailable Linux 通过 secret-tool（libsecret）访问 Secret Service，需要会话总线
+func keychainAvailable() bool {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return false
+	}
+	return os.Getenv("DBUS_SESSION_BUS_ADDRESS") != ""
+}
+
+func keychainGet(id string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", serviceName, "account", id)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		// 找不到时 secret-tool 以1退出且没有输出
+		if stderr.Len() == 0 {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return "", fmt.Errorf("failed to read secret %s: %v: %s", id, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+func keychainSet(id, value string) error {
+	// 通过stdin传入，避免值出现在进程列表中
+	cmd := exec.Command("secret-tool", "store", "--label", "devssh: "+id, "service", serviceName, "account", id)
+	cmd.Stdin = strings.NewReader(value)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to store secret %s: %v: %s", id, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func keychainDelete(id string) error {
+	if _, err := keychainGet(id); err != nil {
+		return err
+	}
+
+	output, err := exec.Command("secret-tool", "clear", "service", serviceName, "account", id).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to delete secret %s: %v: %s", id, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
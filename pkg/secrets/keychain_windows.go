@@ -0,0 +1,20 @@
+package secrets
+
+import "fmt"
+
+// keychainAvailable Windows 凭据管理器无法通过命令行读取密码，使用加密文件
+func keychainAvailable() bool {
+	return false
+}
+
+func keychainGet(id string) (string, error) {
+	return "", fmt.Errorf("OS keychain is not supported on Windows")
+}
+
+func keychainSet(id, value string) error {
+	return fmt.Errorf("OS keychain is not supported on Windows")
+}
+
+func keychainDelete(id string) error {
+	return fmt.Errorf("OS keychain is not supported on Windows")
+}
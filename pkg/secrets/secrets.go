@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Backend 密钥存储后端
+type Backend string
+
+const (
+	BackendAuto     Backend = "auto"
+	BackendKeychain Backend = "keychain"
+	BackendFile     Backend = "file"
+)
+
+// serviceName 在系统钥匙串中使用的服务名
+const serviceName = "devssh"
+
+// ErrNotFound 指定ID的密钥不存在
+var ErrNotFound = errors.New("secret not found")
+
+// Store 按ID保存密码、令牌和私钥口令
+type Store interface {
+	Get(id string) (string, error)
+	Set(id, value string) error
+	Delete(id string) error
+	Backend() Backend
+}
+
+// PassphraseFunc 获取加密文件的主口令；create为true时加密文件尚不存在，
+// 输错的口令会使密钥无法解密，应要求用户输入两次确认
+type PassphraseFunc func(create bool) (string, error)
+
+// NewStore 创建密钥存储。auto优先使用系统钥匙串，不可用时使用加密文件
+func NewStore(backend Backend, passphrase PassphraseFunc) (Store, error) {
+	switch backend {
+	case BackendAuto, "":
+		if keychainAvailable() {
+			return &keychainStore{}, nil
+		}
+		return newFileStore(passphrase)
+	case BackendKeychain:
+		if !keychainAvailable() {
+			return nil, fmt.Errorf("no OS keychain available on this system")
+		}
+		return &keychainStore{}, nil
+	case BackendFile:
+		return newFileStore(passphrase)
+	default:
+		return nil, fmt.Errorf("unknown secrets backend %q (use auto, keychain or file)", backend)
+	}
+}
+
+// ValidateID 检查密钥ID是否合法
+func ValidateID(id string) error {
+	if id == "" {
+		return fmt.Errorf("secret id is required")
+	}
+	if strings.ContainsAny(id, " \t\n/\\") {
+		return fmt.Errorf("invalid secret id %q: whitespace and slashes are not allowed", id)
+	}
+	return nil
+}
+
+// keychainStore 使用系统钥匙串保存密钥
+type keychainStore struct{}
+
+func (s *keychainStore) Get(id string) (string, error) {
+	if err := ValidateID(id); err != nil {
+		return "", err
+	}
+	return keychainGet(id)
+}
+
+func (s *keychainStore) Set(id, value string) error {
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	return keychainSet(id, value)
+}
+
+func (s *keychainStore) Delete(id string) error {
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	return keychainDelete(id)
+}
+
+func (s *keychainStore) Backend() Backend {
+	return BackendKeychain
+}
@@ -0,0 +1,100 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ChunkedThreshold 小于该大小的文件不分块，多通道的额外开销大于收益
+const ChunkedThreshold = 64 * 1024 * 1024
+
+// uploadChunked 把文件分为parallel块，每块通过单独的SSH通道写入远程临时目录，
+// 拼接后用sha256校验；任一步失败时删除临时文件并返回错误
+func (s *SCPClient) uploadChunked(file *os.File, remotePath string, size int64, mode os.FileMode, parallel int) error {
+	checksum, err := fileSHA256(file)
+	if err != nil {
+		return err
+	}
+
+	partsDir := remotePath + ".devssh-parts"
+	quotedDir := quoteRemotePath(partsDir)
+	if output, err := s.client.RunCommand(fmt.Sprintf("command -v sha256sum >/dev/null && rm -rf -- %[1]s && mkdir -p -- %[1]s", quotedDir)); err != nil {
+		return fmt.Errorf("failed to prepare chunked upload: %w, output: %s", err, strings.TrimSpace(output))
+	}
+	defer func() {
+		if output, err := s.client.RunCommand("rm -rf -- " + quotedDir); err != nil {
+			s.client.logger.Warnf("Failed to remove chunk directory %s: %v, output: %s", partsDir, err, strings.TrimSpace(output))
+		}
+	}()
+
+	chunkSize := (size + int64(parallel) - 1) / int64(parallel)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		parts    []string
+	)
+	for i := 0; int64(i)*chunkSize < size; i++ {
+		offset := int64(i) * chunkSize
+		part := fmt.Sprintf("%s/%04d", partsDir, i)
+		parts = append(parts, quoteRemotePath(part))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chunk := io.NewSectionReader(file, offset, min(chunkSize, size-offset))
+			// 每次执行命令都会打开新的SSH通道
+			if output, err := s.client.RunCommandWithInput("cat > "+quoteRemotePath(part), chunk); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to upload chunk %s: %w, output: %s", part, err, strings.TrimSpace(output))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+
+	quotedPath := quoteRemotePath(remotePath)
+	assemble := fmt.Sprintf("cat -- %[2]s > %[1]s && chmod %04[3]o -- %[1]s && sha256sum -- %[1]s", quotedPath, strings.Join(parts, " "), mode&0777)
+	output, err := s.client.RunCommand(assemble)
+	if err != nil {
+		return fmt.Errorf("failed to assemble chunks: %w, output: %s", err, strings.TrimSpace(output))
+	}
+	if fields := strings.Fields(output); len(fields) == 0 || fields[0] != checksum {
+		if output, err := s.client.RunCommand("rm -f -- " + quotedPath); err != nil {
+			s.client.logger.Warnf("Failed to remove %s after a checksum mismatch: %v, output: %s", remotePath, err, strings.TrimSpace(output))
+		}
+		return fmt.Errorf("checksum mismatch after chunked upload of %s", remotePath)
+	}
+	return nil
+}
+
+// quoteRemotePath 引用远程路径，~/ 开头时相对于远程用户的主目录
+func quoteRemotePath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return `"$HOME"/` + shellQuote(rest)
+	}
+	return shellQuote(path)
+}
+
+// fileSHA256 计算文件内容的sha256，不改变文件的读取位置
+func fileSHA256(file *os.File) (string, error) {
+	hash := sha256.New()
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat local file: %w", err)
+	}
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, info.Size())); err != nil {
+		return "", fmt.Errorf("failed to checksum local file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
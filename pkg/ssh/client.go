@@ -1,6 +1,8 @@
 package ssh
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -8,8 +10,12 @@ import (
 	"path/filepath"
 	"time"
 
+	"devssh/pkg/logging"
+	"devssh/pkg/tracing"
+
 	"github.com/loft-sh/log"
-	"github.com/sirupsen/logrus"
+	"github.com/pkg/sftp"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 )
@@ -20,40 +26,73 @@ type Config struct {
 	Username string
 	KeyPath  string
 	Password string
-	Timeout  time.Duration
+	// Passphrase 私钥口令，为空时使用Password
+	Passphrase string
+	Timeout    time.Duration
+	// KeepAlive 大于0时按该间隔发送keepalive，一个间隔内无响应则断开连接
+	KeepAlive time.Duration
+	// KnownHostsFile 校验主机密钥的文件，空格分隔多个时新主机记录到第一个；
+	// 为空时为~/.ssh/known_hosts，none表示不读写known_hosts
+	KnownHostsFile string
+	// HashKnownHosts 新记录的主机名经过哈希，对应SSH配置的HashKnownHosts
+	HashKnownHosts bool
+	// StrictHostKeyChecking 主机密钥策略（accept-new、yes、no），为空时为accept-new
+	StrictHostKeyChecking string
+	// SendEnv 在DefaultSendEnv之外发送到远程会话的本地环境变量模式，见 sendEnvMatch
+	SendEnv []string
+	// SetEnv 为远程会话设置的环境变量，优先于SendEnv
+	SetEnv map[string]string
 }
 
 type Client struct {
 	config *Config
 	client *ssh.Client
 	logger log.Logger
+	// runAs 不为nil时命令通过sudo以其他用户执行，见 AsUser
+	runAs *RunAs
+	// transfer SCP和SFTP上传的调优参数
+	transfer TransferOptions
+	// passwordPrompt 没有密码时在其他认证方式失败后询问密码，为nil时不询问
+	passwordPrompt func() (string, error)
+	// health 连接状态，见 State
+	health *connectionHealth
+	// ctx 不为nil时结束后中止正在执行的命令，见 WithContext
+	ctx context.Context
 }
 
+// NewClient 创建SSH客户端，使用全局logger
 func NewClient(config *Config) *Client {
-	// 创建一个不输出任何内容的logger
-	logger := log.NewStreamLogger(io.Discard, io.Discard, logrus.InfoLevel)
-	return &Client{
-		config: config,
-		logger: logger,
-	}
+	return NewClientWithLogger(config, logging.GetGlobalLogger())
 }
 
 func NewClientWithLogger(config *Config, logger log.Logger) *Client {
+	// 密码和口令不应出现在日志中
+	logging.RegisterSecret(config.Password)
+	logging.RegisterSecret(config.Passphrase)
+
 	return &Client{
 		config: config,
 		logger: logger,
+		health: newConnectionHealth(),
 	}
 }
 
 // NewClientFromSSHConfig 从SSH配置文件创建客户端
 func NewClientFromSSHConfig(hostName string, overrideConfig *Config) (*Client, error) {
-	// 创建一个不输出任何内容的logger
-	logger := log.NewStreamLogger(io.Discard, io.Discard, logrus.InfoLevel)
-	return NewClientFromSSHConfigWithLogger(hostName, overrideConfig, logger)
+	return NewClientFromSSHConfigWithLogger(hostName, overrideConfig, logging.GetGlobalLogger())
 }
 
 // NewClientFromSSHConfigWithLogger 从SSH配置文件创建客户端（带logger）
 func NewClientFromSSHConfigWithLogger(hostName string, overrideConfig *Config, logger log.Logger) (*Client, error) {
+	config, err := ConfigFromSSHConfig(hostName, overrideConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithLogger(config, logger), nil
+}
+
+// ConfigFromSSHConfig 读取SSH配置文件中主机的连接设置，overrideConfig中的非空字段覆盖配置文件
+func ConfigFromSSHConfig(hostName string, overrideConfig *Config) (*Config, error) {
 	parser := NewSSHConfigParser()
 	sshHostConfig, err := parser.GetHost(hostName)
 	if err != nil {
@@ -76,25 +115,51 @@ func NewClientFromSSHConfigWithLogger(hostName string, overrideConfig *Config, l
 		if overrideConfig.Password != "" {
 			config.Password = overrideConfig.Password
 		}
+		if overrideConfig.Passphrase != "" {
+			config.Passphrase = overrideConfig.Passphrase
+		}
 		if overrideConfig.Timeout > 0 {
 			config.Timeout = overrideConfig.Timeout
 		}
+		if overrideConfig.KeepAlive > 0 {
+			config.KeepAlive = overrideConfig.KeepAlive
+		}
+		if overrideConfig.KnownHostsFile != "" {
+			config.KnownHostsFile = overrideConfig.KnownHostsFile
+		}
+		if overrideConfig.StrictHostKeyChecking != "" {
+			config.StrictHostKeyChecking = overrideConfig.StrictHostKeyChecking
+		}
+		config.SendEnv = append(config.SendEnv, overrideConfig.SendEnv...)
+		config.SetEnv = MergeEnv(config.SetEnv, overrideConfig.SetEnv)
 	}
 
-	return NewClientWithLogger(config, logger), nil
+	return config, nil
 }
 
 func (c *Client) Connect() error {
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext 建立SSH连接，TCP拨号和认证握手分别记录为ctx下的子span
+func (c *Client) ConnectContext(ctx context.Context) error {
 	authMethods, err := c.getAuthMethods()
 	if err != nil {
 		return fmt.Errorf("failed to get auth methods: %w", err)
 	}
 
+	address := net.JoinHostPort(c.config.Host, c.config.Port)
+	verifier, err := c.hostKeyVerifier()
+	if err != nil {
+		return err
+	}
+
 	sshConfig := &ssh.ClientConfig{
-		User:            c.config.Username,
-		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         c.config.Timeout,
+		User:              c.config.Username,
+		Auth:              authMethods,
+		HostKeyCallback:   verifier.check,
+		HostKeyAlgorithms: verifier.algorithms(address),
+		Timeout:           c.config.Timeout,
 		Config: ssh.Config{
 			Ciphers: []string{
 				"aes128-ctr", "aes192-ctr", "aes256-ctr",
@@ -105,7 +170,6 @@ func (c *Client) Connect() error {
 		ClientVersion: "SSH-2.0-OpenSSH_9.2",
 	}
 
-	address := net.JoinHostPort(c.config.Host, c.config.Port)
 	c.logger.Infof("Attempting to connect to %s as user '%s' with timeout %v", address, c.config.Username, c.config.Timeout)
 
 	// 显示使用的认证方法
@@ -116,24 +180,59 @@ func (c *Client) Connect() error {
 		c.logger.Infof("Using private key: %s", c.config.KeyPath)
 	}
 
+	attrs := []attribute.KeyValue{
+		attribute.String("net.peer.name", c.config.Host),
+		attribute.String("net.peer.port", c.config.Port),
+	}
+
 	// 先测试TCP连接
+	_, dialSpan := tracing.Start(ctx, "ssh.dial", attrs...)
 	tcpConn, tcpErr := net.DialTimeout("tcp", address, c.config.Timeout)
 	if tcpErr != nil {
-		return fmt.Errorf("TCP connection failed: %w", tcpErr)
+		err := fmt.Errorf("%w: TCP connection failed: %w", ErrHostUnreachable, tcpErr)
+		tracing.End(dialSpan, err)
+		return err
 	}
 	tcpConn.Close()
+	tracing.End(dialSpan, nil)
 	c.logger.Infof("TCP connection successful, attempting SSH handshake...")
 
+	_, authSpan := tracing.Start(ctx, "ssh.auth", append(attrs,
+		attribute.String("ssh.user", c.config.Username),
+		attribute.Int("ssh.auth_methods", len(authMethods)))...)
 	client, err := ssh.Dial("tcp", address, sshConfig)
 	if err != nil {
-		return fmt.Errorf("failed to dial SSH: %w", err)
+		err = classifyDialError(err)
+		tracing.End(authSpan, err)
+		return err
 	}
+	tracing.End(authSpan, nil)
 
 	c.client = client
+	c.health.track(client)
 	c.logger.Infof("SSH connection established successfully")
+	if c.config.KeepAlive > 0 {
+		go c.keepAlive(client, c.config.KeepAlive)
+	}
 	return nil
 }
 
+// keepAlive 定期发送keepalive请求，防止空闲连接被中间设备断开；
+// 请求超时时关闭连接，使等待中的操作尽快失败。连接关闭后退出
+func (c *Client) keepAlive(client *ssh.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := probe(client, interval); err != nil {
+			if errors.Is(err, errNoResponse) {
+				c.logger.Warnf("SSH keepalive got no response within %s, closing the connection", interval)
+			}
+			return
+		}
+	}
+}
+
 func (c *Client) Close() error {
 	if c.client != nil {
 		return c.client.Close()
@@ -142,30 +241,62 @@ func (c *Client) Close() error {
 }
 
 func (c *Client) RunCommand(cmd string) (string, error) {
-	if c.client == nil {
-		return "", fmt.Errorf("not connected")
+	if !c.IsConnected() {
+		return "", ErrNotConnected
 	}
 
-	session, err := c.client.NewSession()
+	session, err := c.newSession()
 	if err != nil {
 		return "", fmt.Errorf("failed to create session: %w", err)
 	}
 	defer session.Close()
 
+	cmd = c.wrapCommand(cmd)
+	session.Stdin = c.sudoStdin(nil)
+	c.logger.Debugf("Running remote command: %s", cmd)
+	stop := c.closeOnDone(session)
 	output, err := session.CombinedOutput(cmd)
-	if err != nil {
+	stop()
+	if err = c.interrupted(err); err != nil {
+		c.logger.Debugf("Remote command failed: %v\n%s", err, output)
 		return string(output), fmt.Errorf("command failed: %w", err)
 	}
+	c.logger.Debugf("Remote command output:\n%s", output)
+
+	return string(output), nil
+}
+
+// RunCommandWithInput 执行命令并把stdin作为它的标准输入，返回合并的输出
+func (c *Client) RunCommandWithInput(cmd string, stdin io.Reader) (string, error) {
+	if !c.IsConnected() {
+		return "", ErrNotConnected
+	}
+
+	session, err := c.newSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
 
+	cmd = c.wrapCommand(cmd)
+	session.Stdin = c.sudoStdin(stdin)
+	c.logger.Debugf("Running remote command (with input): %s", cmd)
+	stop := c.closeOnDone(session)
+	output, err := session.CombinedOutput(cmd)
+	stop()
+	if err = c.interrupted(err); err != nil {
+		c.logger.Debugf("Remote command failed: %v\n%s", err, output)
+		return string(output), fmt.Errorf("command failed: %w", err)
+	}
 	return string(output), nil
 }
 
 func (c *Client) RunCommandWithOutput(cmd string, stdout, stderr io.Writer) error {
-	if c.client == nil {
-		return fmt.Errorf("not connected")
+	if !c.IsConnected() {
+		return ErrNotConnected
 	}
 
-	session, err := c.client.NewSession()
+	session, err := c.newSession()
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
@@ -174,14 +305,18 @@ func (c *Client) RunCommandWithOutput(cmd string, stdout, stderr io.Writer) erro
 	session.Stdout = stdout
 	session.Stderr = stderr
 
-	return session.Run(cmd)
+	cmd = c.wrapCommand(cmd)
+	session.Stdin = c.sudoStdin(nil)
+	c.logger.Debugf("Running remote command (streaming): %s", cmd)
+	defer c.closeOnDone(session)()
+	return c.interrupted(session.Run(cmd))
 }
 
 func (c *Client) NewSession() (*ssh.Session, error) {
-	if c.client == nil {
-		return nil, fmt.Errorf("not connected")
+	if !c.IsConnected() {
+		return nil, ErrNotConnected
 	}
-	return c.client.NewSession()
+	return c.newSession()
 }
 
 func (c *Client) getAuthMethods() ([]ssh.AuthMethod, error) {
@@ -209,8 +344,12 @@ func (c *Client) getAuthMethods() ([]ssh.AuthMethod, error) {
 				signer, err := ssh.ParsePrivateKey(key)
 				if err != nil {
 					// 私钥可能有密码保护，尝试使用密码解析
-					if c.config.Password != "" {
-						signer, err := ssh.ParsePrivateKeyWithPassphrase(key, []byte(c.config.Password))
+					passphrase := c.config.Passphrase
+					if passphrase == "" {
+						passphrase = c.config.Password
+					}
+					if passphrase != "" {
+						signer, err := ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
 						if err == nil {
 							authMethods = append(authMethods, ssh.PublicKeys(signer))
 							c.logger.Infof("Added private key authentication (with passphrase) from config: %s", c.config.KeyPath)
@@ -258,16 +397,34 @@ func (c *Client) getAuthMethods() ([]ssh.AuthMethod, error) {
 		}
 	}
 
+	// 密钥都未通过时询问密码，和OpenSSH一样最多尝试3次
+	if c.config.Password == "" && c.passwordPrompt != nil {
+		authMethods = append(authMethods, ssh.RetryableAuthMethod(ssh.PasswordCallback(c.promptPassword), 3))
+		c.logger.Debugf("Added interactive password authentication method")
+	}
+
 	if len(authMethods) == 0 {
-		return nil, fmt.Errorf("no authentication methods available")
+		return nil, fmt.Errorf("%w: no authentication methods available", ErrAuthFailed)
 	}
 
 	c.logger.Infof("Total authentication methods: %d", len(authMethods))
 	return authMethods, nil
 }
 
-func (c *Client) IsConnected() bool {
-	return c.client != nil
+// SetPasswordPrompt 设置询问密码的函数，配置中没有密码且服务端要求密码认证时调用
+func (c *Client) SetPasswordPrompt(prompt func() (string, error)) {
+	c.passwordPrompt = prompt
+}
+
+// promptPassword 询问密码，输入的密码用于之后的重新连接
+func (c *Client) promptPassword() (string, error) {
+	password, err := c.passwordPrompt()
+	if err != nil {
+		return "", err
+	}
+	logging.RegisterSecret(password)
+	c.config.Password = password
+	return password, nil
 }
 
 func (c *Client) GetClient() *ssh.Client {
@@ -283,7 +440,29 @@ func (c *Client) GetConfig() *Config {
 	return c.config
 }
 
+// SetTransferOptions 设置之后创建的SCP和SFTP客户端的上传参数
+func (c *Client) SetTransferOptions(options TransferOptions) {
+	c.transfer = options
+}
+
+// TransferOptions 返回上传参数
+func (c *Client) TransferOptions() TransferOptions {
+	return c.transfer
+}
+
 // NewSCPClient 创建SCP客户端
 func (c *Client) NewSCPClient() *SCPClient {
 	return NewSCPClient(c)
 }
+
+// NewSFTPClient 在当前连接上打开SFTP子系统
+func (c *Client) NewSFTPClient() (*sftp.Client, error) {
+	if !c.IsConnected() {
+		return nil, ErrNotConnected
+	}
+	client, err := sftp.NewClient(c.client, c.transfer.sftpOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start SFTP: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,327 @@
+package ssh_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	devssh "devssh/pkg/ssh"
+	"devssh/pkg/ssh/sshtest"
+
+	"github.com/loft-sh/log"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// newServer 启动测试服务端，测试结束时关闭
+func newServer(t testing.TB) *sshtest.Server {
+	t.Helper()
+	srv, err := sshtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start sshtest server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// connect 用config连接，测试结束时关闭；不使用本机的ssh-agent和~/.ssh中的密钥
+func connect(t testing.TB, config *devssh.Config) *devssh.Client {
+	t.Helper()
+	isolateAuth(t)
+	client := devssh.NewClientWithLogger(config, log.Discard)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func isolateAuth(t testing.TB) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SSH_AUTH_SOCK", "")
+}
+
+func TestRunCommand(t *testing.T) {
+	srv := newServer(t)
+	srv.Handle("echo hello", sshtest.Response{Stdout: "hello\n"})
+	srv.Handle("false", sshtest.Response{Stderr: "failed\n", ExitStatus: 1})
+	client := connect(t, srv.Config())
+
+	output, err := client.RunCommand("echo hello")
+	if err != nil {
+		t.Fatalf("RunCommand: %v", err)
+	}
+	if output != "hello\n" {
+		t.Errorf("output = %q, want %q", output, "hello\n")
+	}
+
+	output, err = client.RunCommand("false")
+	if err == nil {
+		t.Fatal("RunCommand succeeded for a command exiting with 1")
+	}
+	if output != "failed\n" {
+		t.Errorf("output = %q, want stderr of the failed command", output)
+	}
+
+	if got := srv.Commands(); len(got) != 2 || got[0] != "echo hello" || got[1] != "false" {
+		t.Errorf("commands = %q", got)
+	}
+}
+
+func TestRunCommandWithInput(t *testing.T) {
+	srv := newServer(t)
+	srv.HandleFunc(func(command string, stdin io.Reader, stdout, stderr io.Writer) int {
+		io.Copy(stdout, stdin)
+		return 0
+	})
+	client := connect(t, srv.Config())
+
+	output, err := client.RunCommandWithInput("cat", strings.NewReader("from stdin"))
+	if err != nil {
+		t.Fatalf("RunCommandWithInput: %v", err)
+	}
+	if output != "from stdin" {
+		t.Errorf("output = %q, want %q", output, "from stdin")
+	}
+}
+
+func TestRunCommandWithOutput(t *testing.T) {
+	srv := newServer(t)
+	srv.Handle("both", sshtest.Response{Stdout: "out", Stderr: "err"})
+	client := connect(t, srv.Config())
+
+	var stdout, stderr strings.Builder
+	if err := client.RunCommandWithOutput("both", &stdout, &stderr); err != nil {
+		t.Fatalf("RunCommandWithOutput: %v", err)
+	}
+	if stdout.String() != "out" || stderr.String() != "err" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestSetEnv(t *testing.T) {
+	srv := newServer(t)
+	srv.Handle("env", sshtest.Response{})
+	config := srv.Config()
+	config.SetEnv = map[string]string{"DEVSSH_TEST": "value"}
+	client := connect(t, config)
+
+	if _, err := client.RunCommand("env"); err != nil {
+		t.Fatalf("RunCommand: %v", err)
+	}
+	if got := srv.LastEnv()["DEVSSH_TEST"]; got != "value" {
+		t.Errorf("DEVSSH_TEST = %q, want %q", got, "value")
+	}
+}
+
+func TestWithContextInterruptsCommand(t *testing.T) {
+	srv := newServer(t)
+	release := make(chan struct{})
+	defer close(release)
+	srv.HandleFunc(func(command string, stdin io.Reader, stdout, stderr io.Writer) int {
+		<-release
+		return 0
+	})
+	client := connect(t, srv.Config())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.WithContext(ctx).RunCommand("sleep 60")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RunCommand returned after %s", elapsed)
+	}
+
+	// 原客户端不受影响
+	srv.Handle("true", sshtest.Response{})
+	if _, err := client.RunCommand("true"); err != nil {
+		t.Errorf("RunCommand on the original client: %v", err)
+	}
+}
+
+func TestConnectionState(t *testing.T) {
+	srv := newServer(t)
+	client := devssh.NewClientWithLogger(srv.Config(), log.Discard)
+	if client.IsConnected() {
+		t.Fatal("client is connected before Connect")
+	}
+	if _, err := client.RunCommand("true"); !errors.Is(err, devssh.ErrNotConnected) {
+		t.Errorf("RunCommand before Connect: err = %v, want ErrNotConnected", err)
+	}
+
+	isolateAuth(t)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	changed := make(chan devssh.ConnectionState, 1)
+	client.OnStateChange(func(state devssh.ConnectionState) { changed <- state })
+	if err := client.Probe(time.Second); err != nil {
+		t.Errorf("Probe: %v", err)
+	}
+
+	client.Close()
+	select {
+	case state := <-changed:
+		if state != devssh.StateDisconnected {
+			t.Errorf("state = %s, want disconnected", state)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no state change after Close")
+	}
+	if client.IsConnected() {
+		t.Error("client is connected after Close")
+	}
+}
+
+func TestAuthFailed(t *testing.T) {
+	srv := newServer(t)
+	isolateAuth(t)
+	config := srv.Config()
+	config.Password = "wrong"
+
+	err := devssh.NewClientWithLogger(config, log.Discard).Connect()
+	if !errors.Is(err, devssh.ErrAuthFailed) {
+		t.Errorf("err = %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestHostUnreachable(t *testing.T) {
+	srv := newServer(t)
+	isolateAuth(t)
+	config := srv.Config()
+	srv.Close()
+
+	err := devssh.NewClientWithLogger(config, log.Discard).Connect()
+	if !errors.Is(err, devssh.ErrHostUnreachable) {
+		t.Errorf("err = %v, want ErrHostUnreachable", err)
+	}
+}
+
+// knownHostsEntry 返回srv在known_hosts中的主机名和密钥部分
+func knownHostsEntry(t *testing.T, srv *sshtest.Server) (host, key string) {
+	t.Helper()
+	line, err := os.ReadFile(srv.KnownHostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, key, _ = strings.Cut(string(line), " ")
+	return host, key
+}
+
+// writeKnownHosts 把lines写入临时的known_hosts文件，返回路径
+func writeKnownHosts(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHostKeyRejected(t *testing.T) {
+	srv := newServer(t)
+	other := newServer(t)
+	isolateAuth(t)
+
+	// 把另一个服务端的主机密钥记录为srv的密钥
+	host, _ := knownHostsEntry(t, srv)
+	_, otherKey := knownHostsEntry(t, other)
+	for _, tc := range []struct {
+		name string
+		host string
+	}{
+		{"plain", host},
+		{"hashed", knownhosts.HashHostname(host)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := srv.Config()
+			config.KnownHostsFile = writeKnownHosts(t, tc.host+" "+otherKey)
+			err := devssh.NewClientWithLogger(config, log.Discard).Connect()
+			if !errors.Is(err, devssh.ErrHostKeyRejected) {
+				t.Errorf("err = %v, want ErrHostKeyRejected", err)
+			}
+		})
+	}
+}
+
+func TestHashedKnownHost(t *testing.T) {
+	srv := newServer(t)
+	host, key := knownHostsEntry(t, srv)
+	config := srv.Config()
+	config.KnownHostsFile = writeKnownHosts(t, knownhosts.HashHostname(host)+" "+key)
+	connect(t, config)
+
+	// 已知的主机不再追加记录
+	recorded, err := os.ReadFile(config.KnownHostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(recorded), "\n"); lines != 1 {
+		t.Errorf("known_hosts has %d lines, want the hashed entry only", lines)
+	}
+}
+
+func TestAcceptNewHostKey(t *testing.T) {
+	srv := newServer(t)
+	config := srv.Config()
+	config.KnownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	connect(t, config)
+
+	recorded, err := os.ReadFile(config.KnownHostsFile)
+	if err != nil {
+		t.Fatalf("known_hosts was not written: %v", err)
+	}
+	_, port, _ := strings.Cut(srv.Addr, ":")
+	if !strings.Contains(string(recorded), "[127.0.0.1]:"+port) {
+		t.Errorf("known_hosts = %q, want an entry for %s", recorded, srv.Addr)
+	}
+}
+
+func TestAcceptNewHostKeyHashed(t *testing.T) {
+	srv := newServer(t)
+	config := srv.Config()
+	config.KnownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	config.HashKnownHosts = true
+	connect(t, config)
+
+	recorded, err := os.ReadFile(config.KnownHostsFile)
+	if err != nil {
+		t.Fatalf("known_hosts was not written: %v", err)
+	}
+	if !strings.HasPrefix(string(recorded), "|1|") || strings.Contains(string(recorded), "127.0.0.1") {
+		t.Errorf("known_hosts = %q, want a hashed entry", recorded)
+	}
+
+	// 哈希后的记录能用于下次连接
+	connect(t, config)
+}
+
+func TestSFTP(t *testing.T) {
+	srv := newServer(t)
+	client := connect(t, srv.Config())
+
+	sftpClient, err := client.NewSFTPClient()
+	if err != nil {
+		t.Fatalf("NewSFTPClient: %v", err)
+	}
+	defer sftpClient.Close()
+
+	file, err := sftpClient.Create("sftp.txt")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	io.WriteString(file, "via sftp")
+	file.Close()
+
+	data, err := os.ReadFile(filepath.Join(srv.Root, "sftp.txt"))
+	if err != nil || string(data) != "via sftp" {
+		t.Errorf("remote file = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,161 @@
+package ssh
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// configStamp 解析时读过的一个文件或Include模式的状态，任一项变化时缓存失效
+type configStamp struct {
+	// Path 文件路径，或 "glob:" 加Include模式
+	Path string `json:"path"`
+	// State 文件为 修改时间/大小，不存在时为空；模式为匹配到的文件列表
+	State string `json:"state"`
+}
+
+// cachedConfig 一个配置文件的解析结果
+type cachedConfig struct {
+	Stamps []configStamp             `json:"stamps"`
+	Hosts  map[string]*SSHHostConfig `json:"hosts"`
+}
+
+// configCacheVersion SSHHostConfig增加字段时递增，旧版本写入的持久化缓存不再使用
+const configCacheVersion = 3
+
+// persistedCache 持久化文件的内容
+type persistedCache struct {
+	Version int                      `json:"version"`
+	Entries map[string]*cachedConfig `json:"entries"`
+}
+
+// configCache 进程内共享的解析缓存，按主配置文件路径区分；设置了持久化文件时跨进程复用
+var configCache = struct {
+	sync.Mutex
+	entries map[string]*cachedConfig
+	// persistPath 持久化文件路径，为空时只在进程内缓存
+	persistPath string
+	loaded      bool
+}{entries: make(map[string]*cachedConfig)}
+
+// SetConfigCacheFile 把SSH配置的解析结果持久化到path，后续进程在文件未变化时不再解析
+func SetConfigCacheFile(path string) {
+	configCache.Lock()
+	defer configCache.Unlock()
+	configCache.persistPath = path
+	configCache.loaded = false
+}
+
+// ResetConfigCache 清空进程内的解析缓存
+func ResetConfigCache() {
+	configCache.Lock()
+	defer configCache.Unlock()
+	configCache.entries = make(map[string]*cachedConfig)
+}
+
+// cachedHosts 返回仍然有效的缓存结果的副本，没有时返回nil
+func cachedHosts(configPath string) map[string]*SSHHostConfig {
+	configCache.Lock()
+	defer configCache.Unlock()
+
+	loadPersistedCache()
+	entry, ok := configCache.entries[configPath]
+	if !ok {
+		return nil
+	}
+	for _, stamp := range entry.Stamps {
+		if currentStamp(stamp.Path).State != stamp.State {
+			delete(configCache.entries, configPath)
+			return nil
+		}
+	}
+	return copyHosts(entry.Hosts)
+}
+
+// storeHosts 保存解析结果，持久化失败时只保留在进程内
+func storeHosts(configPath string, stamps []configStamp, hosts map[string]*SSHHostConfig) {
+	configCache.Lock()
+	defer configCache.Unlock()
+
+	configCache.entries[configPath] = &cachedConfig{Stamps: stamps, Hosts: copyHosts(hosts)}
+	if configCache.persistPath == "" {
+		return
+	}
+	data, err := json.Marshal(persistedCache{Version: configCacheVersion, Entries: configCache.entries})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(configCache.persistPath), 0700); err != nil {
+		return
+	}
+	writeFileAtomic(configCache.persistPath, data, 0600)
+}
+
+// loadPersistedCache 首次使用时读取持久化文件，调用方持有锁
+func loadPersistedCache() {
+	if configCache.loaded || configCache.persistPath == "" {
+		return
+	}
+	configCache.loaded = true
+
+	data, err := os.ReadFile(configCache.persistPath)
+	if err != nil {
+		return
+	}
+	var persisted persistedCache
+	if json.Unmarshal(data, &persisted) != nil || persisted.Version != configCacheVersion {
+		return
+	}
+	for path, entry := range persisted.Entries {
+		if entry == nil {
+			continue
+		}
+		if _, exists := configCache.entries[path]; !exists {
+			configCache.entries[path] = entry
+		}
+	}
+}
+
+// fileStamp 返回文件当前的状态
+func fileStamp(path string) configStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return configStamp{Path: path}
+	}
+	return configStamp{Path: path, State: fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())}
+}
+
+// globStamp 返回Include模式当前匹配到的文件
+func globStamp(pattern string) configStamp {
+	matches, _ := filepath.Glob(pattern)
+	return configStamp{Path: "glob:" + pattern, State: strings.Join(matches, "\n")}
+}
+
+func currentStamp(path string) configStamp {
+	if pattern, ok := strings.CutPrefix(path, "glob:"); ok {
+		return globStamp(pattern)
+	}
+	return fileStamp(path)
+}
+
+// copyHosts 复制主机配置，调用方修改结果不影响缓存；同一Host行的多个别名仍共享一个副本
+func copyHosts(hosts map[string]*SSHHostConfig) map[string]*SSHHostConfig {
+	copies := make(map[*SSHHostConfig]*SSHHostConfig)
+	result := make(map[string]*SSHHostConfig, len(hosts))
+	for name, host := range hosts {
+		if host == nil {
+			continue
+		}
+		c, ok := copies[host]
+		if !ok {
+			clone := *host
+			c = &clone
+			copies[host] = c
+		}
+		result[name] = c
+	}
+	return result
+}
@@ -19,8 +19,21 @@ type SSHHostConfig struct {
 	IdentityFile string
 	ProxyJump    string
 	ForwardAgent string
+	// HashKnownHosts 为yes时新写入known_hosts的主机名经过哈希
+	HashKnownHosts string
+	// StrictHostKeyChecking 未知主机的处理方式，见 ParseHostKeyPolicy
+	StrictHostKeyChecking string
+	// UserKnownHostsFile 空格分隔的known_hosts文件，新主机记录到第一个
+	UserKnownHostsFile string
+	// SendEnv 发送到远程会话的本地环境变量模式，多行累加
+	SendEnv []string
+	// SetEnv 为远程会话设置的环境变量
+	SetEnv map[string]string
 }
 
+// globalHost 保存不属于具体主机的选项（第一个Host之前的行和Host *块）的键
+const globalHost = "*"
+
 // SSHConfigParser 用于解析SSH配置文件
 type SSHConfigParser struct {
 	configPath string
@@ -44,28 +57,111 @@ func (p *SSHConfigParser) WithConfigPath(path string) *SSHConfigParser {
 	return p
 }
 
-// Parse 解析SSH配置文件
+// ConfigPath 返回SSH配置文件路径
+func (p *SSHConfigParser) ConfigPath() string {
+	return p.configPath
+}
+
+// maxIncludeDepth Include嵌套的最大层数，防止循环包含
+const maxIncludeDepth = 16
+
+// Parse 解析SSH配置文件及其Include的文件。结果在进程内缓存，
+// 配置文件、被包含的文件和Include模式的匹配结果都未变化时直接返回缓存
+// 全局选项（第一个Host之前和Host *中的设置）补充到没有设置该项的主机中
 func (p *SSHConfigParser) Parse() (map[string]*SSHHostConfig, error) {
-	file, err := os.Open(p.configPath)
+	hosts, err := p.parseAll()
+	if err != nil {
+		return nil, err
+	}
+	global := hosts[globalHost]
+	delete(hosts, globalHost)
+	if global != nil {
+		for _, host := range hosts {
+			host.applyGlobal(global)
+		}
+	}
+	return hosts, nil
+}
+
+// GlobalOptions 返回不属于具体主机的选项，用于不在配置文件中的主机
+func (p *SSHConfigParser) GlobalOptions() (*SSHHostConfig, error) {
+	hosts, err := p.parseAll()
+	if err != nil {
+		return nil, err
+	}
+	if global := hosts[globalHost]; global != nil {
+		return global, nil
+	}
+	return &SSHHostConfig{}, nil
+}
+
+// parseAll 返回全部主机和键为globalHost的全局选项，优先使用缓存
+func (p *SSHConfigParser) parseAll() (map[string]*SSHHostConfig, error) {
+	if hosts := cachedHosts(p.configPath); hosts != nil {
+		return hosts, nil
+	}
+
+	hosts := map[string]*SSHHostConfig{globalHost: {}}
+	var stamps []configStamp
+	if err := p.parseFile(p.configPath, hosts, &stamps, 0); err != nil {
+		return nil, err
+	}
+	storeHosts(p.configPath, stamps, hosts)
+	return hosts, nil
+}
+
+// applyGlobal 主机没有设置的选项使用全局值
+func (h *SSHHostConfig) applyGlobal(global *SSHHostConfig) {
+	if h.HashKnownHosts == "" {
+		h.HashKnownHosts = global.HashKnownHosts
+	}
+	if h.StrictHostKeyChecking == "" {
+		h.StrictHostKeyChecking = global.StrictHostKeyChecking
+	}
+	if h.UserKnownHostsFile == "" {
+		h.UserKnownHostsFile = global.UserKnownHostsFile
+	}
+	// SendEnv累加，SetEnv中主机的设置优先
+	h.SendEnv = append(append([]string{}, global.SendEnv...), h.SendEnv...)
+	h.SetEnv = MergeEnv(global.SetEnv, h.SetEnv)
+}
+
+// parseFile 解析一个配置文件，主机写入hosts，读过的文件和Include模式记录到stamps
+func (p *SSHConfigParser) parseFile(path string, hosts map[string]*SSHHostConfig, stamps *[]configStamp, depth int) error {
+	*stamps = append(*stamps, fileStamp(path))
+	file, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return make(map[string]*SSHHostConfig), nil
+			return nil
 		}
-		return nil, fmt.Errorf("failed to open SSH config file: %w", err)
+		return fmt.Errorf("failed to open SSH config file: %w", err)
 	}
 	defer file.Close()
 
-	hosts := make(map[string]*SSHHostConfig)
-	var currentHost *SSHHostConfig
+	// 第一个Host之前的选项属于全局
+	currentHost := hosts[globalHost]
 	var currentHostNames []string
 
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
+	inManagedBlock := false
 
 	for scanner.Scan() {
 		lineNum++
 		line := strings.TrimSpace(scanner.Text())
 
+		// devssh写入的主机以devssh配置为准，解析时跳过
+		switch {
+		case line == ManagedBlockBegin:
+			inManagedBlock = true
+			continue
+		case line == ManagedBlockEnd:
+			inManagedBlock = false
+			continue
+		case inManagedBlock:
+			continue
+		}
+
 		// 跳过空行和注释
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
@@ -103,6 +199,13 @@ func (p *SSHConfigParser) Parse() (map[string]*SSHHostConfig, error) {
 				}
 			}
 
+			// Host * 的选项适用于所有主机
+			if value == globalHost {
+				currentHost = hosts[globalHost]
+				currentHostNames = nil
+				continue
+			}
+
 			// 创建新的主机配置
 			currentHost = &SSHHostConfig{
 				Port: "22", // 默认端口
@@ -153,6 +256,46 @@ func (p *SSHConfigParser) Parse() (map[string]*SSHHostConfig, error) {
 			if currentHost != nil {
 				currentHost.ForwardAgent = value
 			}
+
+		case "hashknownhosts":
+			if currentHost != nil {
+				currentHost.HashKnownHosts = strings.ToLower(value)
+			}
+
+		case "stricthostkeychecking":
+			if currentHost != nil {
+				currentHost.StrictHostKeyChecking = strings.ToLower(value)
+			}
+
+		case "userknownhostsfile":
+			if currentHost != nil {
+				currentHost.UserKnownHostsFile = value
+			}
+
+		case "sendenv":
+			if currentHost != nil {
+				currentHost.SendEnv = append(currentHost.SendEnv, strings.Fields(value)...)
+			}
+
+		case "setenv":
+			if currentHost != nil {
+				currentHost.SetEnv = MergeEnv(currentHost.SetEnv, ParseSetEnv(value))
+			}
+
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("SSH config Include nested too deeply in %s", path)
+			}
+			for _, pattern := range strings.Fields(value) {
+				pattern = p.includePath(pattern)
+				*stamps = append(*stamps, globStamp(pattern))
+				matches, _ := filepath.Glob(pattern)
+				for _, match := range matches {
+					if err := p.parseFile(match, hosts, stamps, depth+1); err != nil {
+						return err
+					}
+				}
+			}
 		}
 	}
 
@@ -167,10 +310,23 @@ func (p *SSHConfigParser) Parse() (map[string]*SSHHostConfig, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading SSH config file: %w", err)
+		return fmt.Errorf("error reading SSH config file: %w", err)
 	}
 
-	return hosts, nil
+	return nil
+}
+
+// includePath 展开Include中的波浪号，相对路径基于主配置文件所在目录（~/.ssh）
+func (p *SSHConfigParser) includePath(pattern string) string {
+	if strings.HasPrefix(pattern, "~") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, pattern[1:])
+		}
+	}
+	if !filepath.IsAbs(pattern) {
+		return filepath.Join(filepath.Dir(p.configPath), pattern)
+	}
+	return pattern
 }
 
 // GetHost 获取指定主机的配置
@@ -239,6 +395,12 @@ func (h *SSHHostConfig) GetHostConfigForSSH() *Config {
 		Timeout:  30 * time.Second,
 	}
 
+	config.HashKnownHosts = h.HashKnownHosts == "yes"
+	config.StrictHostKeyChecking = h.StrictHostKeyChecking
+	config.KnownHostsFile = h.UserKnownHostsFile
+	config.SendEnv = h.SendEnv
+	config.SetEnv = h.SetEnv
+
 	// 如果没有指定主机名，使用主机别名
 	if config.Host == "" {
 		config.Host = h.Host
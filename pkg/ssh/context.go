@@ -0,0 +1,53 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// WithContext 返回在同一连接上执行命令的副本，ctx取消时正在执行的远程命令被结束，
+// 之后的命令直接返回ctx的错误。原客户端不受影响，可用于退出时的清理
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// contextErr 返回绑定的上下文已经结束的原因，没有绑定或未结束时为nil
+func (c *Client) contextErr() error {
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Err()
+}
+
+// closeOnDone 绑定的上下文结束时向远程命令发送SIGTERM并关闭session，使阻塞的Run、Wait返回；
+// 命令结束后调用返回的函数停止监视
+func (c *Client) closeOnDone(session *ssh.Session) func() {
+	if c.ctx == nil || c.ctx.Done() == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			session.Signal(ssh.SIGTERM)
+			session.Close()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// interrupted 命令因上下文结束而失败时改为返回上下文的错误，调用方可用errors.Is识别
+func (c *Client) interrupted(err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := c.contextErr(); ctxErr != nil {
+		return fmt.Errorf("command interrupted: %w", ctxErr)
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultSendEnv 默认发送到远程会话的本地环境变量，和多数发行版的ssh_config一致；
+// 在SendEnv中写 -LANG 等可以取消
+var DefaultSendEnv = []string{"TERM", "LANG", "LC_*"}
+
+// newSession 创建会话并按SendEnv和SetEnv设置环境变量；绑定的上下文已结束时不再创建
+func (c *Client) newSession() (*ssh.Session, error) {
+	if err := c.contextErr(); err != nil {
+		return nil, fmt.Errorf("command interrupted: %w", err)
+	}
+	session, err := c.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	env := c.sessionEnv()
+	for _, name := range sortedKeys(env) {
+		c.setenv(session, name, env[name])
+	}
+	return session, nil
+}
+
+// setenv 发送env请求。和OpenSSH一样不等待回复，服务端AcceptEnv不允许的变量被静默忽略，
+// 每个会话不增加往返
+func (c *Client) setenv(session *ssh.Session, name, value string) {
+	payload := ssh.Marshal(struct{ Name, Value string }{name, value})
+	if _, err := session.SendRequest("env", false, payload); err != nil {
+		c.logger.Debugf("Failed to send environment variable %s: %v", name, err)
+	}
+}
+
+// sessionEnv 返回会话的环境变量：匹配SendEnv的本地变量，再叠加SetEnv
+func (c *Client) sessionEnv() map[string]string {
+	env := make(map[string]string)
+	patterns := append(append([]string{}, DefaultSendEnv...), c.config.SendEnv...)
+	for _, entry := range os.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if ok && sendEnvMatch(patterns, name) {
+			env[name] = value
+		}
+	}
+	for name, value := range c.config.SetEnv {
+		env[name] = value
+	}
+	return env
+}
+
+// sendEnvMatch 按顺序匹配SendEnv模式，最后一个匹配的模式决定是否发送，- 开头的模式表示不发送
+func sendEnvMatch(patterns []string, name string) bool {
+	send := false
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "-")
+		if matched, _ := path.Match(strings.TrimPrefix(pattern, "-"), name); matched {
+			send = !negate
+		}
+	}
+	return send
+}
+
+// ParseSetEnv 解析SetEnv的值：空格分隔的 NAME=VALUE，值可以用双引号包含空格
+func ParseSetEnv(value string) map[string]string {
+	env := make(map[string]string)
+	for _, item := range splitQuoted(value) {
+		if name, val, ok := strings.Cut(item, "="); ok && name != "" {
+			env[name] = val
+		}
+	}
+	return env
+}
+
+// splitQuoted 按空白分割，双引号内的空白不分割，引号本身去掉
+func splitQuoted(value string) []string {
+	var (
+		items   []string
+		current strings.Builder
+		quoted  bool
+		started bool
+	)
+	for _, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if started {
+				items = append(items, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		items = append(items, current.String())
+	}
+	return items
+}
+
+// MergeEnv 合并环境变量，override中的同名变量优先
+func MergeEnv(base, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range override {
+		merged[name] = value
+	}
+	return merged
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
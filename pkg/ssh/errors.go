@@ -0,0 +1,38 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// 连接失败的分类，调用方用errors.Is区分
+var (
+	// ErrAuthFailed 服务端没有接受任何认证方式（密钥、密码）
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrHostUnreachable 无法建立TCP连接，或握手时连接超时、被断开
+	ErrHostUnreachable = errors.New("host is unreachable")
+	// ErrHostKeyRejected 主机密钥与known_hosts中的不一致，或严格模式下主机未知
+	ErrHostKeyRejected = errors.New("host key verification failed")
+	// ErrNotConnected 连接尚未建立或已经关闭
+	ErrNotConnected = errors.New("SSH client not connected")
+	// ErrConnectionLost 会话进行中连接断开
+	ErrConnectionLost = errors.New("SSH connection lost")
+)
+
+// classifyDialError 按失败原因给SSH握手的错误加上分类
+func classifyDialError(err error) error {
+	if errors.Is(err, ErrHostKeyRejected) {
+		return err
+	}
+	if strings.Contains(err.Error(), "unable to authenticate") {
+		return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: SSH handshake failed: %w", ErrHostUnreachable, err)
+	}
+	return fmt.Errorf("failed to dial SSH: %w", err)
+}
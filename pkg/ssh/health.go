@@ -0,0 +1,145 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultProbeTimeout Probe等待keepalive响应的默认时间
+const DefaultProbeTimeout = 5 * time.Second
+
+// errNoResponse keepalive请求超时
+var errNoResponse = errors.New("SSH connection did not respond")
+
+// ConnectionState SSH连接的状态
+type ConnectionState int
+
+const (
+	// StateDisconnected 未连接，或连接已关闭、断开
+	StateDisconnected ConnectionState = iota
+	// StateConnected 连接已建立且未关闭
+	StateConnected
+)
+
+func (s ConnectionState) String() string {
+	if s == StateConnected {
+		return "connected"
+	}
+	return "disconnected"
+}
+
+// connectionHealth 连接状态和状态变化的回调，AsUser得到的副本共享同一个
+type connectionHealth struct {
+	mu    sync.Mutex
+	state ConnectionState
+	// conn 当前的连接，重新连接后旧连接的关闭不再改变状态
+	conn     *ssh.Client
+	handlers map[int]func(ConnectionState)
+	nextID   int
+}
+
+func newConnectionHealth() *connectionHealth {
+	return &connectionHealth{handlers: make(map[int]func(ConnectionState))}
+}
+
+// track 记录新建立的连接，连接关闭时切换为未连接
+func (h *connectionHealth) track(conn *ssh.Client) {
+	h.mu.Lock()
+	h.conn = conn
+	h.mu.Unlock()
+	h.set(conn, StateConnected)
+
+	go func() {
+		conn.Wait()
+		h.set(conn, StateDisconnected)
+	}()
+}
+
+// set 更新conn的状态，状态变化时在不持有锁时调用回调
+func (h *connectionHealth) set(conn *ssh.Client, state ConnectionState) {
+	h.mu.Lock()
+	if h.conn != conn || h.state == state {
+		h.mu.Unlock()
+		return
+	}
+	h.state = state
+	handlers := make([]func(ConnectionState), 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler)
+	}
+	h.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(state)
+	}
+}
+
+func (h *connectionHealth) current() ConnectionState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.state
+}
+
+// State 返回连接状态，不访问网络
+func (c *Client) State() ConnectionState {
+	if c.client == nil {
+		return StateDisconnected
+	}
+	return c.health.current()
+}
+
+// IsConnected 判断连接已建立且未关闭；连接断开但尚未被发现时仍返回true，需要确认时使用 Probe
+func (c *Client) IsConnected() bool {
+	return c.State() == StateConnected
+}
+
+// OnStateChange 注册连接状态变化的回调，可以注册多个，返回取消注册的函数。
+// 回调在检测到变化的goroutine中调用，不应阻塞
+func (c *Client) OnStateChange(handler func(ConnectionState)) func() {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	id := c.health.nextID
+	c.health.nextID++
+	c.health.handlers[id] = handler
+	return func() {
+		c.health.mu.Lock()
+		defer c.health.mu.Unlock()
+		delete(c.health.handlers, id)
+	}
+}
+
+// Probe 发送一次keepalive请求确认连接可用；timeout内没有响应时关闭连接，
+// 状态变为未连接，等待中的操作随之失败。timeout不大于0时使用DefaultProbeTimeout
+func (c *Client) Probe(timeout time.Duration) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	return probe(c.client, timeout)
+}
+
+// probe 在conn上发送keepalive请求，服务端拒绝该请求也说明连接可用
+func probe(conn *ssh.Client, timeout time.Duration) error {
+	reply := make(chan error, 1)
+	go func() {
+		_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+		reply <- err
+	}()
+
+	select {
+	case err := <-reply:
+		if err != nil {
+			return fmt.Errorf("SSH connection is closed: %w", err)
+		}
+		return nil
+	case <-time.After(timeout):
+		conn.Close()
+		return fmt.Errorf("%w within %s", errNoResponse, timeout)
+	}
+}
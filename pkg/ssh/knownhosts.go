@@ -0,0 +1,231 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/loft-sh/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// knownHostsMu 串行化同一进程内对known_hosts的追加，并发连接多台主机时行不会交错
+var knownHostsMu sync.Mutex
+
+// DefaultKnownHostsFile 返回~/.ssh/known_hosts
+func DefaultKnownHostsFile() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return filepath.Join(homeDir, ".ssh", "known_hosts")
+}
+
+// 主机密钥策略，对应SSH配置的StrictHostKeyChecking
+const (
+	// HostKeyAcceptNew 记录未知主机的密钥，拒绝已变化的密钥
+	HostKeyAcceptNew = "accept-new"
+	// HostKeyStrict 只接受known_hosts中已有的密钥
+	HostKeyStrict = "yes"
+	// HostKeyIgnore 接受未知主机，密钥变化时只警告
+	HostKeyIgnore = "no"
+)
+
+// ParseHostKeyPolicy 规范化StrictHostKeyChecking的取值：off同no；
+// devssh不交互询问，ask和未设置同accept-new
+func ParseHostKeyPolicy(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "ask", HostKeyAcceptNew:
+		return HostKeyAcceptNew, nil
+	case HostKeyStrict:
+		return HostKeyStrict, nil
+	case HostKeyIgnore, "off":
+		return HostKeyIgnore, nil
+	}
+	return "", fmt.Errorf("invalid StrictHostKeyChecking %q: must be accept-new, yes or no", value)
+}
+
+// knownHostsFiles 返回连接使用的known_hosts文件，第一个用于记录新主机；none时为空
+func (c *Client) knownHostsFiles() []string {
+	if c.config.KnownHostsFile == "" {
+		return []string{DefaultKnownHostsFile()}
+	}
+	var files []string
+	for _, file := range strings.Fields(c.config.KnownHostsFile) {
+		if file != "none" {
+			files = append(files, expandHome(file))
+		}
+	}
+	return files
+}
+
+// hostKeyVerifier 创建本次连接的主机密钥校验
+func (c *Client) hostKeyVerifier() (*hostKeyVerifier, error) {
+	policy, err := ParseHostKeyPolicy(c.config.StrictHostKeyChecking)
+	if err != nil {
+		return nil, err
+	}
+	return newHostKeyVerifier(c.knownHostsFiles(), policy, c.config.HashKnownHosts, c.logger)
+}
+
+// hostKeyVerifier 按策略校验主机密钥：已记录的主机（含哈希过的 |1| 条目）密钥必须一致，
+// 未记录的主机追加到第一个known_hosts文件，HashKnownHosts时写入哈希后的主机名
+type hostKeyVerifier struct {
+	files  []string
+	policy string
+	hash   bool
+	logger log.Logger
+	// callback 没有可读的known_hosts文件时为nil，所有主机都是未知的
+	callback ssh.HostKeyCallback
+}
+
+func newHostKeyVerifier(files []string, policy string, hash bool, logger log.Logger) (*hostKeyVerifier, error) {
+	v := &hostKeyVerifier{files: files, policy: policy, hash: hash, logger: logger}
+
+	// 会记录新主机时预先创建文件，严格模式下不修改known_hosts
+	if len(files) > 0 && policy != HostKeyStrict {
+		if err := os.MkdirAll(filepath.Dir(files[0]), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create known_hosts directory: %w", err)
+		}
+		file, err := os.OpenFile(files[0], os.O_CREATE|os.O_RDONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open known_hosts: %w", err)
+		}
+		file.Close()
+	}
+
+	var existing []string
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			existing = append(existing, file)
+		}
+	}
+	if len(existing) > 0 {
+		callback, err := knownhosts.New(existing...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read known_hosts: %w", err)
+		}
+		v.callback = callback
+	}
+	return v, nil
+}
+
+// check 作为ssh.HostKeyCallback使用
+func (v *hostKeyVerifier) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	var keyErr *knownhosts.KeyError
+	if v.callback != nil {
+		err := v.callback(hostname, remote, key)
+		if err == nil || !errors.As(err, &keyErr) {
+			return err
+		}
+	}
+
+	if keyErr != nil && len(keyErr.Want) > 0 {
+		if v.policy == HostKeyIgnore {
+			v.logger.Warnf("Host key for %s has changed (%s %s), continuing because StrictHostKeyChecking is no",
+				hostname, key.Type(), ssh.FingerprintSHA256(key))
+			return nil
+		}
+		return fmt.Errorf("%w: host key for %s has changed (%s %s, expected the key at %s:%d); "+
+			"if the host was reinstalled, remove the old key with: ssh-keygen -R %s -f %s",
+			ErrHostKeyRejected, hostname, key.Type(), ssh.FingerprintSHA256(key), keyErr.Want[0].Filename, keyErr.Want[0].Line,
+			knownhosts.Normalize(hostname), keyErr.Want[0].Filename)
+	}
+
+	if v.policy == HostKeyStrict {
+		return fmt.Errorf("%w: no host key is known for %s (%s %s) and StrictHostKeyChecking is yes; "+
+			"verify the fingerprint and add the key to %s", ErrHostKeyRejected, hostname, key.Type(), ssh.FingerprintSHA256(key), v.recordFile())
+	}
+	if len(v.files) == 0 {
+		return nil
+	}
+	if err := v.add(hostname, key); err != nil {
+		return err
+	}
+	v.logger.Infof("Added %s key %s for %s to %s", key.Type(), ssh.FingerprintSHA256(key), hostname, v.files[0])
+	return nil
+}
+
+// recordFile 返回记录新主机的文件，用于提示
+func (v *hostKeyVerifier) recordFile() string {
+	if len(v.files) == 0 {
+		return DefaultKnownHostsFile()
+	}
+	return v.files[0]
+}
+
+// add 把新主机的密钥追加到第一个known_hosts文件
+func (v *hostKeyVerifier) add(hostname string, key ssh.PublicKey) error {
+	host := knownhosts.Normalize(hostname)
+	if v.hash {
+		host = knownhosts.HashHostname(host)
+	}
+
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	file, err := os.OpenFile(v.files[0], os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, knownhosts.Line([]string{host}, key)); err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+	return nil
+}
+
+// algorithms 返回known_hosts中已记录的该主机密钥对应的算法，让服务端优先出示这些密钥；
+// 没有记录时返回nil，使用默认顺序
+func (v *hostKeyVerifier) algorithms(address string) []string {
+	if v.callback == nil {
+		return nil
+	}
+	// 用不会匹配的密钥查询，KeyError.Want中是已记录的全部密钥
+	err := v.callback(address, &net.TCPAddr{}, probeKey{})
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	seen := make(map[string]bool)
+	for _, known := range keyErr.Want {
+		for _, algorithm := range keyAlgorithms(known.Key.Type()) {
+			if !seen[algorithm] {
+				seen[algorithm] = true
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+	}
+	return algorithms
+}
+
+// keyAlgorithms RSA密钥可以用多种签名算法出示
+func keyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// expandHome 展开路径开头的波浪号
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, path[1:])
+		}
+	}
+	return path
+}
+
+// probeKey 查询已记录密钥时使用的占位密钥
+type probeKey struct{}
+
+func (probeKey) Type() string                                 { return "devssh-probe" }
+func (probeKey) Marshal() []byte                              { return []byte("devssh-probe") }
+func (probeKey) Verify(data []byte, sig *ssh.Signature) error { return errors.New("probe key") }
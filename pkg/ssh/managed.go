@@ -0,0 +1,227 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// devssh写入SSH配置文件的主机块使用的标记
+const (
+	ManagedBlockBegin = "# BEGIN devssh managed hosts"
+	ManagedBlockEnd   = "# END devssh managed hosts"
+)
+
+// ManagedHost 写入SSH配置文件的主机
+type ManagedHost struct {
+	Alias        string
+	HostName     string
+	User         string
+	Port         string
+	IdentityFile string
+}
+
+// splitManagedBlock 拆分出devssh管理的块，返回块前后的内容和块内的行（不含标记）
+func splitManagedBlock(content string) (before, block, after []string, found bool) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	state := 0
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case state == 0 && trimmed == ManagedBlockBegin:
+			state = 1
+			found = true
+		case state == 1 && trimmed == ManagedBlockEnd:
+			state = 2
+		case state == 0:
+			before = append(before, line)
+		case state == 1:
+			block = append(block, line)
+		default:
+			after = append(after, line)
+		}
+	}
+	return before, block, after, found
+}
+
+// readConfig 读取SSH配置文件，不存在时返回空内容
+func (p *SSHConfigParser) readConfig() (string, error) {
+	data, err := os.ReadFile(p.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read SSH config file: %w", err)
+	}
+	return string(data), nil
+}
+
+// UserHosts 返回用户自己定义（devssh管理块之外）的主机别名
+func (p *SSHConfigParser) UserHosts() (map[string]bool, error) {
+	content, err := p.readConfig()
+	if err != nil {
+		return nil, err
+	}
+	before, _, after, _ := splitManagedBlock(content)
+
+	hosts := make(map[string]bool)
+	for _, line := range append(before, after...) {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && strings.EqualFold(fields[0], "host") {
+			for _, alias := range fields[1:] {
+				hosts[alias] = true
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// ManagedHostNames 返回devssh管理块中的主机别名
+func (p *SSHConfigParser) ManagedHostNames() ([]string, error) {
+	content, err := p.readConfig()
+	if err != nil {
+		return nil, err
+	}
+	_, block, _, _ := splitManagedBlock(content)
+
+	var names []string
+	for _, line := range block {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && strings.EqualFold(fields[0], "host") {
+			names = append(names, fields[1:]...)
+		}
+	}
+	return names, nil
+}
+
+// WriteManagedHosts 用给定主机替换devssh管理块；hosts为空时移除该块。
+// 新建的块放在文件开头，因为SSH对每个选项采用第一次出现的值，放在 Host * 之后会被覆盖
+func (p *SSHConfigParser) WriteManagedHosts(hosts []ManagedHost) error {
+	content, err := p.readConfig()
+	if err != nil {
+		return err
+	}
+
+	updated := RenderManagedConfig(content, hosts)
+	if updated == content {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.configPath), 0700); err != nil {
+		return fmt.Errorf("failed to create SSH config directory: %w", err)
+	}
+
+	// 修改前保留一份备份
+	if content != "" {
+		if err := os.WriteFile(p.configPath+".devssh.bak", []byte(content), 0600); err != nil {
+			return fmt.Errorf("failed to back up SSH config file: %w", err)
+		}
+	}
+
+	// 配置文件可能是指向dotfiles仓库的符号链接，写入链接目标
+	target := p.configPath
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+
+	perm := os.FileMode(0600)
+	if info, err := os.Stat(target); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return writeFileAtomic(target, []byte(updated), perm)
+}
+
+// RenderManagedConfig 返回替换devssh管理块后的SSH配置内容
+func RenderManagedConfig(content string, hosts []ManagedHost) string {
+	before, _, after, found := splitManagedBlock(content)
+
+	var block []string
+	if len(hosts) > 0 {
+		block = append(block, ManagedBlockBegin)
+		block = append(block, "# Generated by devssh ssh-config export; edits inside this block are overwritten")
+		for _, host := range hosts {
+			block = append(block, renderManagedHost(host)...)
+		}
+		block = append(block, ManagedBlockEnd)
+	}
+
+	var lines []string
+	switch {
+	case found:
+		// 移除块时去掉插入时添加的空行
+		if len(block) == 0 && len(after) > 0 && strings.TrimSpace(after[0]) == "" &&
+			(len(before) == 0 || strings.TrimSpace(before[len(before)-1]) == "") {
+			after = after[1:]
+		}
+		// 保持原有位置
+		lines = append(append(append(lines, before...), block...), after...)
+	case len(block) > 0:
+		lines = append(lines, block...)
+		if len(before) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, before...)
+	default:
+		lines = before
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// renderManagedHost 生成单个Host块
+func renderManagedHost(host ManagedHost) []string {
+	lines := []string{"Host " + host.Alias}
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		// 含空格的值需要加引号
+		if strings.ContainsAny(value, " \t") {
+			value = `"` + value + `"`
+		}
+		lines = append(lines, fmt.Sprintf("    %s %s", key, value))
+	}
+
+	add("HostName", host.HostName)
+	add("User", host.User)
+	if host.Port != "22" {
+		add("Port", host.Port)
+	}
+	add("IdentityFile", host.IdentityFile)
+	return lines
+}
+
+// writeFileAtomic 写入临时文件后重命名
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
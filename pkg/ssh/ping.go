@@ -0,0 +1,104 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// PingResult 已建立连接上的延迟和吞吐量
+type PingResult struct {
+	// RTT 协议层往返时间（keepalive请求），不含远程进程启动
+	RTTMin time.Duration `json:"rtt_min"`
+	RTTAvg time.Duration `json:"rtt_avg"`
+	RTTMax time.Duration `json:"rtt_max"`
+	// Exec 执行一条空命令的时间，即每条远程命令的固定开销
+	Exec time.Duration `json:"exec"`
+	// Upload和Download 每秒字节数，payload为0时不测量
+	Upload   float64 `json:"upload_bytes_per_second,omitempty"`
+	Download float64 `json:"download_bytes_per_second,omitempty"`
+}
+
+// Ping 发送count次keepalive请求测量往返时间，然后各上传和下载payload字节测量吞吐量
+func (c *Client) Ping(count int, payload int64) (*PingResult, error) {
+	if !c.IsConnected() {
+		return nil, ErrNotConnected
+	}
+	if count < 1 {
+		count = 1
+	}
+
+	result := &PingResult{}
+	var total time.Duration
+	for i := 0; i < count; i++ {
+		start := time.Now()
+		if _, _, err := c.client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+			return nil, fmt.Errorf("keepalive request failed: %w", err)
+		}
+		rtt := time.Since(start)
+		total += rtt
+		if result.RTTMin == 0 || rtt < result.RTTMin {
+			result.RTTMin = rtt
+		}
+		result.RTTMax = max(result.RTTMax, rtt)
+	}
+	result.RTTAvg = total / time.Duration(count)
+
+	start := time.Now()
+	if _, err := c.RunCommand("true"); err != nil {
+		return nil, err
+	}
+	result.Exec = time.Since(start)
+
+	if payload <= 0 {
+		return result, nil
+	}
+
+	// 随机数据不会被链路上的压缩缩小
+	start = time.Now()
+	if output, err := c.RunCommandWithInput("cat > /dev/null", io.LimitReader(randomReader{}, payload)); err != nil {
+		return nil, fmt.Errorf("upload test failed: %w, output: %s", err, output)
+	}
+	result.Upload = float64(payload) / time.Since(start).Seconds()
+
+	received := &byteCounter{}
+	start = time.Now()
+	if err := c.RunCommandWithOutput(fmt.Sprintf("head -c %d /dev/urandom", payload), received, io.Discard); err != nil {
+		return nil, fmt.Errorf("download test failed: %w", err)
+	}
+	if received.n != payload {
+		return nil, fmt.Errorf("download test received %d of %d bytes", received.n, payload)
+	}
+	result.Download = float64(payload) / time.Since(start).Seconds()
+
+	return result, nil
+}
+
+// FormatThroughput 显示每秒字节数，如 12.5 MB/s
+func FormatThroughput(bytesPerSecond float64) string {
+	return formatBytes(int64(bytesPerSecond)) + "/s"
+}
+
+// byteCounter 丢弃写入的数据，只统计字节数
+type byteCounter struct {
+	n int64
+}
+
+func (b *byteCounter) Write(p []byte) (int, error) {
+	b.n += int64(len(p))
+	return len(p), nil
+}
+
+// randomReader 产生伪随机字节，足够快且不可压缩
+type randomReader struct{}
+
+func (randomReader) Read(p []byte) (int, error) {
+	var x uint64 = uint64(time.Now().UnixNano()) | 1
+	for i := range p {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+		p[i] = byte(x)
+	}
+	return len(p), nil
+}
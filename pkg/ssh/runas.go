@@ -0,0 +1,102 @@
+package ssh
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"devssh/pkg/logging"
+)
+
+// ErrSudoPassword sudo需要密码而没有提供
+var ErrSudoPassword = errors.New("sudo requires a password")
+
+// RunAs 通过sudo以其他用户身份执行远程命令
+type RunAs struct {
+	// User 目标用户，为空时为root
+	User string
+	// Password sudo密码，通过askpass提供给sudo，见 askpassScript；为空时要求免密sudo（sudo -n）
+	Password string
+}
+
+// AsUser 返回共享同一连接的客户端，其RunCommand、RunCommandWithOutput和SCP上传通过sudo以目标用户执行，
+// HOME为目标用户的主目录；NewSession返回的会话不受影响。关闭任一客户端都会关闭连接
+func (c *Client) AsUser(runAs RunAs) *Client {
+	logging.RegisterSecret(runAs.Password)
+	clone := *c
+	clone.runAs = &runAs
+	return &clone
+}
+
+// RunAsUser 返回执行命令的远程用户描述，未切换用户时为空
+func (c *Client) RunAsUser() string {
+	if c.runAs == nil {
+		return ""
+	}
+	if c.runAs.User == "" {
+		return "root"
+	}
+	return c.runAs.User
+}
+
+// CheckSudo 检查能否切换到目标用户，需要密码而未提供时返回ErrSudoPassword
+func (c *Client) CheckSudo() error {
+	if c.runAs == nil {
+		return nil
+	}
+	output, err := c.RunCommand("true")
+	if err == nil {
+		return nil
+	}
+	if strings.Contains(output, "password is required") || strings.Contains(output, "incorrect password") ||
+		strings.Contains(output, "Sorry, try again") {
+		return ErrSudoPassword
+	}
+	return errors.New(strings.TrimSpace(output))
+}
+
+// sudoPasswordEnv 远程shell保存sudo密码的环境变量，askpass程序从中读取
+const sudoPasswordEnv = "DEVSSH_SUDO_PASSWORD"
+
+// wrapCommand 把命令包装为sudo调用，命令由目标用户的bash执行
+func (c *Client) wrapCommand(cmd string) string {
+	if c.runAs == nil {
+		return cmd
+	}
+
+	target := " -H"
+	if c.runAs.User != "" {
+		target += " -u " + shellQuote(c.runAs.User)
+	}
+	if c.runAs.Password == "" {
+		return "sudo -n" + target + " -- bash -c " + shellQuote(cmd)
+	}
+	return "sh -c " + shellQuote(askpassScript(target, cmd))
+}
+
+// askpassScript 需要密码时的远程脚本：shell先读取标准输入的第一行作为密码，
+// sudo通过只输出该环境变量的askpass程序获取。密码不出现在命令行和磁盘上，
+// sudo有缓存的凭据不需要密码时也不会把密码留给命令的标准输入
+func askpassScript(target, cmd string) string {
+	return "IFS= read -r " + sudoPasswordEnv + " || exit 1; export " + sudoPasswordEnv + "; " +
+		`askpass=$(mktemp "$HOME/.devssh-askpass.XXXXXX") || exit 1; trap 'rm -f "$askpass"' EXIT; ` +
+		`printf '%s\n' '#!/bin/sh' 'printenv ` + sudoPasswordEnv + `' > "$askpass" && chmod 700 "$askpass" || exit 1; ` +
+		`SUDO_ASKPASS="$askpass" sudo -A` + target + " -- bash -c " + shellQuote("unset "+sudoPasswordEnv+"; "+cmd)
+}
+
+// sudoStdin 返回sudo读取密码的输入，后接命令自己的输入；不需要密码时原样返回
+func (c *Client) sudoStdin(stdin io.Reader) io.Reader {
+	if c.runAs == nil || c.runAs.Password == "" {
+		return stdin
+	}
+	password := strings.NewReader(c.runAs.Password + "\n")
+	if stdin == nil {
+		return password
+	}
+	return io.MultiReader(password, stdin)
+}
+
+// shellQuote 用单引号引用字符串
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
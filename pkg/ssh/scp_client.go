@@ -6,10 +6,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type SCPClient struct {
 	client *Client
+	// stats 最近一次上传的统计
+	stats TransferStats
 }
 
 func NewSCPClient(client *Client) *SCPClient {
@@ -18,9 +21,21 @@ func NewSCPClient(client *Client) *SCPClient {
 	}
 }
 
+// writeSudoPassword 以其他用户执行时，先把sudo密码写入标准输入
+func (s *SCPClient) writeSudoPassword(stdin io.Writer) error {
+	password := s.client.sudoStdin(nil)
+	if password == nil {
+		return nil
+	}
+	if _, err := io.Copy(stdin, password); err != nil {
+		return fmt.Errorf("failed to send sudo password: %w", err)
+	}
+	return nil
+}
+
 func (s *SCPClient) Upload(localPath, remotePath string) error {
 	if !s.client.IsConnected() {
-		return fmt.Errorf("SSH client not connected")
+		return ErrNotConnected
 	}
 
 	fileInfo, err := os.Stat(localPath)
@@ -46,15 +61,71 @@ func (s *SCPClient) Upload(localPath, remotePath string) error {
 		}
 	}
 
-	return s.uploadViaSSH(file, remotePath, fileInfo.Size(), fileInfo.Mode())
+	return s.upload(file, remotePath, fileInfo.Size(), fileInfo.Mode())
+}
+
+// Stats 返回最近一次成功上传的统计
+func (s *SCPClient) Stats() TransferStats {
+	return s.stats
 }
 
-func (s *SCPClient) uploadViaSSH(file *os.File, remotePath string, size int64, mode os.FileMode) error {
-	session, err := s.client.client.NewSession()
+// upload 按客户端的传输参数上传：大文件在开启多通道时分块并行上传，失败时退回单通道；
+// 开启压缩且目标不是压缩文件时压缩传输，否则使用scp协议
+func (s *SCPClient) upload(file *os.File, remotePath string, size int64, mode os.FileMode) error {
+	options := s.client.transfer
+	stats := TransferStats{Bytes: size, WireBytes: size}
+	start := time.Now()
+
+	if options.Parallel > 1 && size >= ChunkedThreshold {
+		err := s.uploadChunked(file, remotePath, size, mode, options.Parallel)
+		if err == nil {
+			stats.Channels = options.Parallel
+			stats.Duration = time.Since(start)
+			s.stats = stats
+			s.client.logger.Infof("Uploaded %s: %s", filepath.Base(remotePath), stats)
+			return nil
+		}
+		s.client.logger.Warnf("Chunked upload failed, falling back to a single stream: %v", err)
+		start = time.Now()
+	}
+
+	var err error
+	if options.Compress && !IsCompressed(remotePath) {
+		stats.Compressed = true
+		stats.WireBytes, err = s.uploadCompressed(file, remotePath, mode, options.bufferSize())
+	} else {
+		err = s.uploadViaSSH(file, remotePath, size, mode, options.bufferSize())
+	}
+	if err != nil {
+		return err
+	}
+
+	stats.Duration = time.Since(start)
+	s.stats = stats
+	s.client.logger.Infof("Uploaded %s: %s", filepath.Base(remotePath), stats)
+	return nil
+}
+
+// uploadCompressed 本地gzip压缩后经标准输入传给远程的gzip解压写入，返回传输的字节数
+func (s *SCPClient) uploadCompressed(file *os.File, remotePath string, mode os.FileMode, bufferSize int) (int64, error) {
+	src := gzipReader(file, bufferSize)
+	defer src.Close()
+	wire := &countingReader{reader: src}
+
+	cmd := fmt.Sprintf("gzip -dc > %[1]s && chmod %04[2]o %[1]s", remotePath, mode&0777)
+	if output, err := s.client.RunCommandWithInput(cmd, wire); err != nil {
+		return 0, fmt.Errorf("compressed upload failed: %w, output: %s", err, strings.TrimSpace(output))
+	}
+	return wire.n.Load(), nil
+}
+
+func (s *SCPClient) uploadViaSSH(file *os.File, remotePath string, size int64, mode os.FileMode, bufferSize int) error {
+	session, err := s.client.newSession()
 	if err != nil {
 		return fmt.Errorf("failed to create SSH session: %w", err)
 	}
 	defer session.Close()
+	defer s.client.closeOnDone(session)()
 
 	stdin, err := session.StdinPipe()
 	if err != nil {
@@ -66,9 +137,12 @@ func (s *SCPClient) uploadViaSSH(file *os.File, remotePath string, size int64, m
 		return fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
 
-	if err := session.Start(fmt.Sprintf("scp -t %s", remotePath)); err != nil {
+	if err := session.Start(s.client.wrapCommand(fmt.Sprintf("scp -t %s", remotePath))); err != nil {
 		return fmt.Errorf("failed to start SCP command: %w", err)
 	}
+	if err := s.writeSudoPassword(stdin); err != nil {
+		return err
+	}
 
 	errors := make(chan error, 2)
 	go func() {
@@ -81,7 +155,7 @@ func (s *SCPClient) uploadViaSSH(file *os.File, remotePath string, size int64, m
 
 		fmt.Fprintf(stdin, "C%04o %d %s\n", mode&0777, size, filepath.Base(remotePath))
 
-		buf := make([]byte, 32*1024)
+		buf := make([]byte, bufferSize)
 		_, err := io.CopyBuffer(stdin, file, buf)
 		if err != nil {
 			errors <- err
@@ -95,7 +169,7 @@ func (s *SCPClient) uploadViaSSH(file *os.File, remotePath string, size int64, m
 	err1 := <-errors
 	err2 := <-errors
 
-	if err := session.Wait(); err != nil {
+	if err := s.client.interrupted(session.Wait()); err != nil {
 		return fmt.Errorf("SCP command failed: %w", err)
 	}
 
@@ -111,7 +185,7 @@ func (s *SCPClient) uploadViaSSH(file *os.File, remotePath string, size int64, m
 
 func (s *SCPClient) UploadWithReader(reader io.Reader, remotePath string, size int64) error {
 	if !s.client.IsConnected() {
-		return fmt.Errorf("SSH client not connected")
+		return ErrNotConnected
 	}
 
 	tempFile, err := os.CreateTemp("", "devssh-scp-upload-*")
@@ -140,12 +214,12 @@ func (s *SCPClient) UploadWithReader(reader io.Reader, remotePath string, size i
 	}
 	defer file.Close()
 
-	return s.uploadViaSSH(file, remotePath, fileInfo.Size(), fileInfo.Mode())
+	return s.upload(file, remotePath, fileInfo.Size(), fileInfo.Mode())
 }
 
 func (s *SCPClient) Download(remotePath, localPath string) error {
 	if !s.client.IsConnected() {
-		return fmt.Errorf("SSH client not connected")
+		return ErrNotConnected
 	}
 
 	localDir := filepath.Dir(localPath)
@@ -153,11 +227,12 @@ func (s *SCPClient) Download(remotePath, localPath string) error {
 		return fmt.Errorf("failed to create local directory: %w", err)
 	}
 
-	session, err := s.client.client.NewSession()
+	session, err := s.client.newSession()
 	if err != nil {
 		return fmt.Errorf("failed to create SSH session: %w", err)
 	}
 	defer session.Close()
+	defer s.client.closeOnDone(session)()
 
 	stdin, err := session.StdinPipe()
 	if err != nil {
@@ -169,9 +244,12 @@ func (s *SCPClient) Download(remotePath, localPath string) error {
 		return fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
 
-	if err := session.Start(fmt.Sprintf("scp -f %s", remotePath)); err != nil {
+	if err := session.Start(s.client.wrapCommand(fmt.Sprintf("scp -f %s", remotePath))); err != nil {
 		return fmt.Errorf("failed to start SCP command: %w", err)
 	}
+	if err := s.writeSudoPassword(stdin); err != nil {
+		return err
+	}
 
 	fmt.Fprint(stdin, "\x00")
 
@@ -200,7 +278,7 @@ func (s *SCPClient) Download(remotePath, localPath string) error {
 
 	fmt.Fprint(stdin, "\x00")
 
-	if err := session.Wait(); err != nil {
+	if err := s.client.interrupted(session.Wait()); err != nil {
 		return fmt.Errorf("SCP command failed: %w", err)
 	}
 
@@ -209,7 +287,7 @@ func (s *SCPClient) Download(remotePath, localPath string) error {
 
 func (s *SCPClient) CheckRemoteFileExists(remotePath string) (bool, error) {
 	if !s.client.IsConnected() {
-		return false, fmt.Errorf("SSH client not connected")
+		return false, ErrNotConnected
 	}
 
 	checkCmd := fmt.Sprintf("test -f %s && echo exists", remotePath)
@@ -223,7 +301,7 @@ func (s *SCPClient) CheckRemoteFileExists(remotePath string) (bool, error) {
 
 func (s *SCPClient) GetRemoteFileSize(remotePath string) (int64, error) {
 	if !s.client.IsConnected() {
-		return 0, fmt.Errorf("SSH client not connected")
+		return 0, ErrNotConnected
 	}
 
 	sizeCmd := fmt.Sprintf("stat -c %%s %s 2>/dev/null || wc -c < %s 2>/dev/null", remotePath, remotePath)
@@ -0,0 +1,269 @@
+package ssh_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	devssh "devssh/pkg/ssh"
+)
+
+// fileHandler 在root下执行上传用到的远程命令：scp -t、gzip -dc，以及分块上传的cat、sha256sum和rm
+func fileHandler(root string) func(string, io.Reader, io.Writer, io.Writer) int {
+	return func(command string, stdin io.Reader, stdout, stderr io.Writer) int {
+		var err error
+		switch {
+		case strings.HasPrefix(command, "scp -t "):
+			err = scpSink(filepath.Join(root, strings.TrimPrefix(command, "scp -t ")), stdin)
+		case strings.HasPrefix(command, "gzip -dc > "):
+			path, _, _ := strings.Cut(strings.TrimPrefix(command, "gzip -dc > "), " ")
+			var reader *gzip.Reader
+			if reader, err = gzip.NewReader(stdin); err == nil {
+				err = writeFile(filepath.Join(root, path), reader)
+			}
+		case strings.HasPrefix(command, "cat > "):
+			err = writeFile(filepath.Join(root, unquotePath(strings.TrimPrefix(command, "cat > "))), stdin)
+		case strings.HasPrefix(command, "command -v sha256sum"):
+			_, dir, _ := strings.Cut(command, "mkdir -p -- ")
+			err = os.MkdirAll(filepath.Join(root, unquotePath(dir)), 0755)
+		case strings.HasPrefix(command, "cat -- "):
+			err = assembleChunks(root, strings.TrimPrefix(command, "cat -- "), stdout)
+		case strings.HasPrefix(command, "rm -"):
+			_, path, _ := strings.Cut(command, " -- ")
+			err = os.RemoveAll(filepath.Join(root, unquotePath(path)))
+		default:
+			fmt.Fprintf(stderr, "unexpected command: %s\n", command)
+			return 127
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+}
+
+// scpSink 按scp协议读取一个文件：C<mode> <size> <name>\n，内容，\x00
+func scpSink(path string, stdin io.Reader) error {
+	reader := bufio.NewReader(stdin)
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	var (
+		mode uint32
+		size int64
+		name string
+	)
+	if _, err := fmt.Sscanf(header, "C%o %d %s", &mode, &size, &name); err != nil {
+		return fmt.Errorf("invalid scp header %q: %w", header, err)
+	}
+	if err := writeFile(path, io.LimitReader(reader, size)); err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, reader)
+	return err
+}
+
+// shellWords 按shell规则拆分单引号引用的参数，"$HOME" 展开为空，使 ~/ 开头的路径相对于root
+func shellWords(s string) []string {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		inQuote bool
+	)
+	s = strings.ReplaceAll(s, `"$HOME"`, "")
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case inQuote && c == '\'':
+			inQuote = false
+		case inQuote:
+			word.WriteByte(c)
+		case c == '\'':
+			inQuote, inWord = true, true
+		case c == '\\' && i+1 < len(s):
+			i++
+			word.WriteByte(s[i])
+			inWord = true
+		case c == ' ':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// unquotePath 还原引用后的单个远程路径
+func unquotePath(quoted string) string {
+	return strings.Join(shellWords(quoted), " ")
+}
+
+// assembleChunks 执行分块上传的拼接命令：cat -- PARTS > PATH && chmod MODE -- PATH && sha256sum -- PATH
+func assembleChunks(root, args string, stdout io.Writer) error {
+	parts, rest, _ := strings.Cut(args, " > ")
+	quotedPath, _, _ := strings.Cut(rest, " && ")
+	path := unquotePath(quotedPath)
+
+	hash := sha256.New()
+	var readers []io.Reader
+	for _, part := range shellWords(parts) {
+		file, err := os.Open(filepath.Join(root, part))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+	if err := writeFile(filepath.Join(root, path), io.TeeReader(io.MultiReader(readers...), hash)); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%x  %s\n", hash.Sum(nil), path)
+	return nil
+}
+
+func writeFile(path string, content io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// localFile 在临时目录中创建内容为content的文件
+func localFile(t testing.TB, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUpload(t *testing.T) {
+	content := bytes.Repeat([]byte("devssh upload\n"), 10000)
+
+	for _, tc := range []struct {
+		name    string
+		options devssh.TransferOptions
+	}{
+		{"plain", devssh.TransferOptions{}},
+		{"small buffer", devssh.TransferOptions{BufferSize: 1024}},
+		{"compressed", devssh.TransferOptions{Compress: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newServer(t)
+			srv.HandleFunc(fileHandler(srv.Root))
+			client := connect(t, srv.Config())
+			client.SetTransferOptions(tc.options)
+
+			scp := client.NewSCPClient()
+			if err := scp.Upload(localFile(t, "upload.txt", content), "upload.txt"); err != nil {
+				t.Fatalf("Upload: %v", err)
+			}
+
+			uploaded, err := os.ReadFile(filepath.Join(srv.Root, "upload.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(uploaded, content) {
+				t.Errorf("uploaded %d bytes, want %d", len(uploaded), len(content))
+			}
+
+			stats := scp.Stats()
+			if stats.Bytes != int64(len(content)) || stats.Compressed != tc.options.Compress {
+				t.Errorf("stats = %+v", stats)
+			}
+			if tc.options.Compress && stats.WireBytes >= stats.Bytes {
+				t.Errorf("compressed upload sent %d bytes for a %d byte file", stats.WireBytes, stats.Bytes)
+			}
+		})
+	}
+}
+
+func TestUploadSkipsCompressionForCompressedFiles(t *testing.T) {
+	srv := newServer(t)
+	srv.HandleFunc(fileHandler(srv.Root))
+	client := connect(t, srv.Config())
+	client.SetTransferOptions(devssh.TransferOptions{Compress: true})
+
+	scp := client.NewSCPClient()
+	if err := scp.Upload(localFile(t, "archive.tar.gz", []byte("already compressed")), "archive.tar.gz"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if scp.Stats().Compressed {
+		t.Error("a .gz file was compressed again")
+	}
+	if commands := srv.Commands(); len(commands) != 1 || !strings.HasPrefix(commands[0], "scp -t ") {
+		t.Errorf("commands = %q, want a single scp", commands)
+	}
+}
+
+func TestUploadWithReader(t *testing.T) {
+	srv := newServer(t)
+	srv.HandleFunc(fileHandler(srv.Root))
+	client := connect(t, srv.Config())
+
+	if err := client.NewSCPClient().UploadWithReader(strings.NewReader("from reader"), "reader.txt", 11); err != nil {
+		t.Fatalf("UploadWithReader: %v", err)
+	}
+	uploaded, err := os.ReadFile(filepath.Join(srv.Root, "reader.txt"))
+	if err != nil || string(uploaded) != "from reader" {
+		t.Errorf("uploaded = %q, %v", uploaded, err)
+	}
+}
+
+func TestUploadChunked(t *testing.T) {
+	if testing.Short() {
+		t.Skip("uploads a file of ChunkedThreshold bytes")
+	}
+	content := make([]byte, devssh.ChunkedThreshold+1)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	srv := newServer(t)
+	srv.HandleFunc(fileHandler(srv.Root))
+	client := connect(t, srv.Config())
+	client.SetTransferOptions(devssh.TransferOptions{Parallel: 4})
+
+	scp := client.NewSCPClient()
+	// 带空格和单引号的路径需要在远程命令中正确引用
+	if err := scp.Upload(localFile(t, "chunked.bin", content), "it's chunked.bin"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if channels := scp.Stats().Channels; channels != 4 {
+		t.Errorf("uploaded over %d channels, want 4", channels)
+	}
+
+	uploaded, err := os.ReadFile(filepath.Join(srv.Root, "it's chunked.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(uploaded, content) {
+		t.Errorf("uploaded %d bytes differ from the local file", len(uploaded))
+	}
+	if _, err := os.Stat(filepath.Join(srv.Root, "it's chunked.bin.devssh-parts")); !os.IsNotExist(err) {
+		t.Error("chunk directory was not removed")
+	}
+}
@@ -0,0 +1,317 @@
+// Package sshtest 提供内存中的SSH服务端，用于在没有真实主机的情况下测试ssh、scp、sftp和端口转发。
+// 命令按脚本返回预设的输出，sftp子系统和direct-tcpip转发使用本机的文件系统和网络。
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	devssh "devssh/pkg/ssh"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// 测试服务端接受的登录凭据，公钥登录接受任意密钥
+const (
+	User     = "devssh"
+	Password = "sshtest-password"
+)
+
+// Response 脚本化命令的结果
+type Response struct {
+	Stdout     string
+	Stderr     string
+	ExitStatus int
+}
+
+// HandlerFunc 处理未预设结果的命令，返回退出码
+type HandlerFunc func(command string, stdin io.Reader, stdout, stderr io.Writer) int
+
+// Server 监听127.0.0.1随机端口的SSH服务端
+type Server struct {
+	// Addr 监听地址，host:port
+	Addr string
+	// Root sftp的工作目录，相对路径基于该目录
+	Root string
+	// KnownHostsFile 记录了本服务端主机密钥的known_hosts，Config使用它校验服务端
+	KnownHostsFile string
+
+	listener net.Listener
+	config   *ssh.ServerConfig
+	wg       sync.WaitGroup
+
+	mu        sync.Mutex
+	responses map[string]Response
+	handler   HandlerFunc
+	commands  []string
+	// lastEnv 最近一条命令所在会话收到的env请求
+	lastEnv map[string]string
+}
+
+// NewServer 启动服务端，sftp的工作目录为新建的临时目录，Close时删除
+func NewServer() (*Server, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate host key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create host key signer: %w", err)
+	}
+
+	root, err := os.MkdirTemp("", "sshtest-")
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == User && string(password) == Password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("invalid credentials for %s", conn.User())
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+
+	knownHosts, err := os.CreateTemp("", "sshtest-known_hosts-")
+	if err != nil {
+		listener.Close()
+		os.RemoveAll(root)
+		return nil, err
+	}
+	fmt.Fprintln(knownHosts, knownhosts.Line([]string{knownhosts.Normalize(listener.Addr().String())}, signer.PublicKey()))
+	knownHosts.Close()
+
+	s := &Server{
+		Addr:           listener.Addr().String(),
+		Root:           root,
+		KnownHostsFile: knownHosts.Name(),
+		listener:       listener,
+		config:         config,
+		responses:      make(map[string]Response),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Config 返回连接该服务端的devssh客户端配置
+func (s *Server) Config() *devssh.Config {
+	host, port, _ := net.SplitHostPort(s.Addr)
+	return &devssh.Config{
+		Host:     host,
+		Port:     port,
+		Username: User,
+		Password: Password,
+
+		KnownHostsFile: s.KnownHostsFile,
+	}
+}
+
+// Handle 为完全相同的命令预设结果
+func (s *Server) Handle(command string, response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[command] = response
+}
+
+// HandleFunc 设置未预设结果的命令的处理函数，未设置时这些命令返回127
+func (s *Server) HandleFunc(handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
+// Commands 返回按顺序执行过的命令
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// LastEnv 返回最近一条命令所在会话通过env请求设置的环境变量
+func (s *Server) LastEnv() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	env := make(map[string]string, len(s.lastEnv))
+	for name, value := range s.lastEnv {
+		env[name] = value
+	}
+	return env
+}
+
+// Close 停止监听并删除sftp工作目录和known_hosts，已建立的连接随客户端关闭
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	os.RemoveAll(s.Root)
+	os.Remove(s.KnownHostsFile)
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer serverConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		switch newChannel.ChannelType() {
+		case "session":
+			go s.handleSession(newChannel)
+		case "direct-tcpip":
+			go handleDirectTCPIP(newChannel)
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+func (s *Server) handleSession(newChannel ssh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	env := make(map[string]string)
+	for req := range requests {
+		switch req.Type {
+		case "exec":
+			command, ok := parseString(req.Payload)
+			req.Reply(ok, nil)
+			if !ok {
+				return
+			}
+			s.mu.Lock()
+			s.lastEnv = env
+			s.mu.Unlock()
+			status := s.exec(command, channel, channel, channel.Stderr())
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+			return
+		case "subsystem":
+			name, ok := parseString(req.Payload)
+			if !ok || name != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			server, err := sftp.NewServer(channel, sftp.WithServerWorkingDirectory(s.Root))
+			if err != nil {
+				return
+			}
+			server.Serve()
+			server.Close()
+			return
+		case "env":
+			var variable struct{ Name, Value string }
+			if ssh.Unmarshal(req.Payload, &variable) == nil {
+				env[variable.Name] = variable.Value
+			}
+			req.Reply(true, nil)
+		case "pty-req":
+			req.Reply(true, nil)
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// exec 返回预设结果或交给处理函数
+func (s *Server) exec(command string, stdin io.Reader, stdout, stderr io.Writer) int {
+	s.mu.Lock()
+	s.commands = append(s.commands, command)
+	response, scripted := s.responses[command]
+	handler := s.handler
+	s.mu.Unlock()
+
+	if scripted {
+		io.WriteString(stdout, response.Stdout)
+		io.WriteString(stderr, response.Stderr)
+		return response.ExitStatus
+	}
+	if handler != nil {
+		return handler(command, stdin, stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "sshtest: unexpected command: %s\n", command)
+	return 127
+}
+
+// handleDirectTCPIP 把客户端的本地转发连接到本机的目标地址
+func handleDirectTCPIP(newChannel ssh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, "invalid direct-tcpip request")
+		return
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	go func() {
+		io.Copy(channel, conn)
+		channel.CloseWrite()
+	}()
+	io.Copy(conn, channel)
+	conn.Close()
+	channel.Close()
+}
+
+// parseString 解析SSH请求中以长度开头的字符串
+func parseString(payload []byte) (string, bool) {
+	if len(payload) < 4 {
+		return "", false
+	}
+	length := binary.BigEndian.Uint32(payload)
+	if uint32(len(payload)-4) < length {
+		return "", false
+	}
+	return string(payload[4 : 4+length]), true
+}
@@ -0,0 +1,134 @@
+package ssh
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// DefaultBufferSize 上传时每次写入SSH通道的字节数
+const DefaultBufferSize = 32 * 1024
+
+// compressedExts 已经压缩的文件类型，再压缩只会浪费CPU
+var compressedExts = []string{
+	".gz", ".tgz", ".xz", ".txz", ".bz2", ".tbz2", ".zst", ".zip", ".whl", ".jar", ".7z",
+	".png", ".jpg", ".jpeg", ".gif", ".webp", ".mp4", ".mkv", ".mp3",
+}
+
+// TransferOptions 文件上传的调优参数，零值为不压缩、默认缓冲区
+type TransferOptions struct {
+	// Compress 上传未压缩的文件时在本地用gzip压缩，远程解压后写入，适合高延迟或低带宽的链路
+	Compress bool
+	// BufferSize 每次写入的字节数，0为DefaultBufferSize
+	BufferSize int
+	// MaxRequests SFTP每个文件同时在途的请求数，相当于传输窗口，0为sftp库的默认值
+	MaxRequests int
+	// Parallel 大于1时，不小于ChunkedThreshold的文件分块后通过多个SSH通道同时上传
+	Parallel int
+}
+
+func (o TransferOptions) bufferSize() int {
+	if o.BufferSize > 0 {
+		return o.BufferSize
+	}
+	return DefaultBufferSize
+}
+
+// sftpOptions 把调优参数转换为sftp客户端选项
+func (o TransferOptions) sftpOptions() []sftp.ClientOption {
+	var opts []sftp.ClientOption
+	if o.MaxRequests > 0 {
+		opts = append(opts, sftp.MaxConcurrentRequestsPerFile(o.MaxRequests), sftp.UseConcurrentWrites(true))
+	}
+	return opts
+}
+
+// IsCompressed 根据扩展名判断文件是否已经压缩
+func IsCompressed(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, compressed := range compressedExts {
+		if ext == compressed {
+			return true
+		}
+	}
+	return false
+}
+
+// TransferStats 一次上传的统计
+type TransferStats struct {
+	// Bytes 文件的字节数
+	Bytes int64 `json:"bytes"`
+	// WireBytes 实际写入SSH通道的字节数，压缩时小于Bytes
+	WireBytes  int64         `json:"wire_bytes"`
+	Duration   time.Duration `json:"duration"`
+	Compressed bool          `json:"compressed"`
+	// Channels 分块上传时使用的SSH通道数
+	Channels int `json:"channels,omitempty"`
+}
+
+// Throughput 返回按文件字节数计算的每秒字节数
+func (s TransferStats) Throughput() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Duration.Seconds()
+}
+
+// String 以简短形式显示，如 "120.5 MB in 3.204s (37.6 MB/s)"
+func (s TransferStats) String() string {
+	summary := fmt.Sprintf("%s in %s (%s/s)", formatBytes(s.Bytes), s.Duration.Round(time.Millisecond), formatBytes(int64(s.Throughput())))
+	if s.Compressed && s.Bytes > 0 {
+		summary += fmt.Sprintf(", compressed to %.1f%%", float64(s.WireBytes)*100/float64(s.Bytes))
+	}
+	if s.Channels > 1 {
+		summary += fmt.Sprintf(", %d channels", s.Channels)
+	}
+	return summary
+}
+
+// formatBytes 以1024为进制显示字节数，如 1.5 MB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// countingReader 统计读出的字节数
+type countingReader struct {
+	reader io.Reader
+	n      atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// gzipReader 返回读出src的gzip压缩结果的reader，压缩在后台进行
+func gzipReader(src io.Reader, bufferSize int) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		gz, _ := gzip.NewWriterLevel(writer, gzip.BestSpeed)
+		_, err := io.CopyBuffer(gz, src, make([]byte, bufferSize))
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+		writer.CloseWithError(err)
+	}()
+	return reader
+}
@@ -0,0 +1,67 @@
+package ssh_test
+
+import (
+	"bytes"
+	"testing"
+
+	devssh "devssh/pkg/ssh"
+)
+
+// 基准测试连接本机的sshtest服务端，没有网络延迟和带宽限制，
+// 比较的是各上传方式在本机的CPU开销和吞吐，链路越慢压缩和分块的收益越大
+
+// benchmarkUpload 用options反复上传内容为content的文件
+func benchmarkUpload(b *testing.B, options devssh.TransferOptions, content []byte) {
+	srv := newServer(b)
+	srv.HandleFunc(fileHandler(srv.Root))
+	client := connect(b, srv.Config())
+	client.SetTransferOptions(options)
+	local := localFile(b, "bench.bin", content)
+	scp := client.NewSCPClient()
+
+	b.SetBytes(int64(len(content)))
+	for b.Loop() {
+		if err := scp.Upload(local, "bench.bin"); err != nil {
+			b.Fatalf("Upload: %v", err)
+		}
+	}
+}
+
+// textContent 返回压缩率与源代码相近的内容
+func textContent(size int) []byte {
+	line := []byte("func (c *Client) RunCommand(cmd string) (string, error) { return c.run(cmd) }\n")
+	return bytes.Repeat(line, size/len(line)+1)[:size]
+}
+
+// binaryContent 返回几乎无法压缩的内容
+func binaryContent(size int) []byte {
+	content := make([]byte, size)
+	state := uint32(1)
+	for i := range content {
+		state ^= state << 13
+		state ^= state >> 17
+		state ^= state << 5
+		content[i] = byte(state)
+	}
+	return content
+}
+
+func BenchmarkUploadPlain(b *testing.B) {
+	benchmarkUpload(b, devssh.TransferOptions{}, textContent(8<<20))
+}
+
+func BenchmarkUploadCompressed(b *testing.B) {
+	benchmarkUpload(b, devssh.TransferOptions{Compress: true}, textContent(8<<20))
+}
+
+func BenchmarkUploadCompressedBinary(b *testing.B) {
+	benchmarkUpload(b, devssh.TransferOptions{Compress: true}, binaryContent(8<<20))
+}
+
+func BenchmarkUploadSingleStream(b *testing.B) {
+	benchmarkUpload(b, devssh.TransferOptions{}, binaryContent(devssh.ChunkedThreshold))
+}
+
+func BenchmarkUploadChunked(b *testing.B) {
+	benchmarkUpload(b, devssh.TransferOptions{Parallel: 4}, binaryContent(devssh.ChunkedThreshold))
+}
@@ -7,7 +7,10 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/loft-sh/log"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -24,6 +27,61 @@ type Tunnel struct {
 	listener net.Listener
 	closed   bool
 	mu       sync.Mutex
+	logger   log.Logger
+
+	// 流量统计，原子更新
+	bytesIn      atomic.Int64 // 远程 -> 本地
+	bytesOut     atomic.Int64 // 本地 -> 远程
+	active       atomic.Int64
+	total        atomic.Int64
+	lastActivity atomic.Int64 // UnixNano
+}
+
+// TunnelStats 隧道的流量统计
+type TunnelStats struct {
+	BytesIn           int64     `json:"bytes_in"`
+	BytesOut          int64     `json:"bytes_out"`
+	ActiveConnections int64     `json:"active_connections"`
+	TotalConnections  int64     `json:"total_connections"`
+	LastActivity      time.Time `json:"last_activity"`
+}
+
+// Stats 返回隧道当前的流量统计
+func (t *Tunnel) Stats() TunnelStats {
+	return TunnelStats{
+		BytesIn:           t.bytesIn.Load(),
+		BytesOut:          t.bytesOut.Load(),
+		ActiveConnections: t.active.Load(),
+		TotalConnections:  t.total.Load(),
+		LastActivity:      time.Unix(0, t.lastActivity.Load()),
+	}
+}
+
+// IdleFor 返回隧道没有活动连接且没有数据传输的时长，有活动连接时返回0
+func (t *Tunnel) IdleFor() time.Duration {
+	if t.active.Load() > 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, t.lastActivity.Load()))
+}
+
+// touch 记录最近一次活动时间
+func (t *Tunnel) touch() {
+	t.lastActivity.Store(time.Now().UnixNano())
+}
+
+// countingWriter 统计写入字节数并刷新活动时间
+type countingWriter struct {
+	w       io.Writer
+	counter *atomic.Int64
+	tunnel  *Tunnel
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.counter.Add(int64(n))
+	c.tunnel.touch()
+	return n, err
 }
 
 func (t *Tunnel) GetConfig() *TunnelConfig {
@@ -31,9 +89,15 @@ func (t *Tunnel) GetConfig() *TunnelConfig {
 }
 
 func NewTunnel(client *ssh.Client, config *TunnelConfig) *Tunnel {
+	return NewTunnelWithLogger(client, config, log.Discard)
+}
+
+// NewTunnelWithLogger 创建端口转发隧道，连接失败记录到logger
+func NewTunnelWithLogger(client *ssh.Client, config *TunnelConfig, logger log.Logger) *Tunnel {
 	return &Tunnel{
 		config: config,
 		client: client,
+		logger: logger,
 	}
 }
 
@@ -52,6 +116,7 @@ func (t *Tunnel) Start() error {
 	}
 
 	t.listener = listener
+	t.touch()
 
 	go t.acceptConnections()
 
@@ -88,9 +153,18 @@ func (t *Tunnel) acceptConnections() {
 func (t *Tunnel) handleConnection(localConn net.Conn) {
 	defer localConn.Close()
 
+	t.total.Add(1)
+	t.active.Add(1)
+	t.touch()
+	defer func() {
+		t.active.Add(-1)
+		t.touch()
+	}()
+
 	remoteAddr := net.JoinHostPort(t.config.RemoteHost, strconv.Itoa(t.config.RemotePort))
 	remoteConn, err := t.client.Dial("tcp", remoteAddr)
 	if err != nil {
+		t.logger.Debugf("Failed to dial %s through tunnel on local port %d: %v", remoteAddr, t.config.LocalPort, err)
 		return
 	}
 	defer remoteConn.Close()
@@ -99,12 +173,14 @@ func (t *Tunnel) handleConnection(localConn net.Conn) {
 	done := make(chan struct{}, 2)
 
 	go func() {
-		_, _ = io.Copy(remoteConn, localConn)
+		_, _ = io.Copy(&countingWriter{w: remoteConn, counter: &t.bytesOut, tunnel: t}, localConn)
+		closeWrite(remoteConn)
 		done <- struct{}{}
 	}()
 
 	go func() {
-		_, _ = io.Copy(localConn, remoteConn)
+		_, _ = io.Copy(&countingWriter{w: localConn, counter: &t.bytesIn, tunnel: t}, remoteConn)
+		closeWrite(localConn)
 		done <- struct{}{}
 	}()
 
@@ -112,33 +188,120 @@ func (t *Tunnel) handleConnection(localConn net.Conn) {
 	<-done
 }
 
-func ParsePortForward(forward string) (localPort, remotePort int, err error) {
-	parts := strings.Split(forward, ":")
+// closeWrite 一个方向的数据结束后通知对端，否则等待对端先关闭的服务会使连接一直不释放
+func closeWrite(conn net.Conn) {
+	if c, ok := conn.(interface{ CloseWrite() error }); ok {
+		c.CloseWrite()
+	}
+}
+
+// DefaultTunnelHost 未指定绑定地址或远程主机时使用的地址
+const DefaultTunnelHost = "127.0.0.1"
+
+// ParseTunnelSpec 解析与 ssh -L 相同的转发格式：
+//
+//	PORT                          本地和远程端口相同
+//	LOCAL:REMOTE                  本地端口:远程端口
+//	LOCAL:HOST:REMOTE             经开发机访问HOST上的端口
+//	BIND:LOCAL:REMOTE             在BIND地址上监听
+//	BIND:LOCAL:HOST:REMOTE
+//
+// IPv6地址需放在方括号中，如 [::]:8080:[fd00::1]:5432
+func ParseTunnelSpec(spec string) (*TunnelConfig, error) {
+	parts, err := splitTunnelSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &TunnelConfig{LocalHost: DefaultTunnelHost, RemoteHost: DefaultTunnelHost}
+	var localPort, remotePort string
 
 	switch len(parts) {
 	case 1:
-		// 格式: 8080 (本地和远程端口相同)
-		port, err := strconv.Atoi(parts[0])
-		if err != nil {
-			return 0, 0, fmt.Errorf("invalid port: %w", err)
+		localPort, remotePort = parts[0], parts[0]
+	case 2:
+		localPort, remotePort = parts[0], parts[1]
+	case 3:
+		// 第一段是端口时为 LOCAL:HOST:REMOTE，否则为 BIND:LOCAL:REMOTE
+		if _, err := strconv.Atoi(parts[0]); err == nil {
+			localPort, config.RemoteHost, remotePort = parts[0], parts[1], parts[2]
+		} else {
+			config.LocalHost, localPort, remotePort = parts[0], parts[1], parts[2]
 		}
-		return port, port, nil
+	case 4:
+		config.LocalHost, localPort, config.RemoteHost, remotePort = parts[0], parts[1], parts[2], parts[3]
+	default:
+		return nil, fmt.Errorf("invalid port forward format: %s", spec)
+	}
 
-	case 2:
-		// 格式: 8080:80 (本地端口:远程端口)
-		localPort, err := strconv.Atoi(parts[0])
-		if err != nil {
-			return 0, 0, fmt.Errorf("invalid local port: %w", err)
+	if config.LocalHost == "" || config.LocalHost == "*" {
+		config.LocalHost = "0.0.0.0"
+	}
+	if config.RemoteHost == "" {
+		return nil, fmt.Errorf("invalid port forward format: %s", spec)
+	}
+
+	if config.LocalPort, err = parseTunnelPort(localPort); err != nil {
+		return nil, fmt.Errorf("invalid local port in %s: %w", spec, err)
+	}
+	if config.RemotePort, err = parseTunnelPort(remotePort); err != nil {
+		return nil, fmt.Errorf("invalid remote port in %s: %w", spec, err)
+	}
+
+	return config, nil
+}
+
+// splitTunnelSpec 按冒号拆分转发格式，方括号内的IPv6地址不拆分
+func splitTunnelSpec(spec string) ([]string, error) {
+	var parts []string
+	for spec != "" {
+		var part string
+		if strings.HasPrefix(spec, "[") {
+			end := strings.Index(spec, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("missing ']' in port forward: %s", spec)
+			}
+			part, spec = spec[1:end], spec[end+1:]
+			if spec != "" && !strings.HasPrefix(spec, ":") {
+				return nil, fmt.Errorf("invalid port forward format: %s", spec)
+			}
+		} else if idx := strings.Index(spec, ":"); idx != -1 {
+			part, spec = spec[:idx], spec[idx:]
+		} else {
+			part, spec = spec, ""
 		}
+		parts = append(parts, part)
 
-		remotePort, err := strconv.Atoi(parts[1])
-		if err != nil {
-			return 0, 0, fmt.Errorf("invalid remote port: %w", err)
+		if spec != "" {
+			spec = spec[1:]
+			if spec == "" {
+				return nil, fmt.Errorf("invalid port forward format: trailing ':'")
+			}
 		}
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty port forward")
+	}
+	return parts, nil
+}
 
-		return localPort, remotePort, nil
+// parseTunnelPort 解析1-65535范围内的端口
+func parseTunnelPort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port: %w", err)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port %d out of range", port)
+	}
+	return port, nil
+}
 
-	default:
-		return 0, 0, fmt.Errorf("invalid port forward format: %s", forward)
+// ParsePortForward 解析转发格式中的本地和远程端口，忽略绑定地址和远程主机
+func ParsePortForward(forward string) (localPort, remotePort int, err error) {
+	config, err := ParseTunnelSpec(forward)
+	if err != nil {
+		return 0, 0, err
 	}
+	return config.LocalPort, config.RemotePort, nil
 }
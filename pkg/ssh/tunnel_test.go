@@ -0,0 +1,118 @@
+package ssh_test
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	devssh "devssh/pkg/ssh"
+)
+
+// echoServer 在本机监听并原样返回收到的数据，返回端口
+func echoServer(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// freePort 返回当前未被占用的本地端口
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestTunnel(t *testing.T) {
+	srv := newServer(t)
+	client := connect(t, srv.Config())
+
+	config := &devssh.TunnelConfig{
+		LocalHost:  "127.0.0.1",
+		LocalPort:  freePort(t),
+		RemoteHost: "127.0.0.1",
+		RemotePort: echoServer(t),
+	}
+	tunnel := devssh.NewTunnel(client.GetClient(), config)
+	if err := tunnel.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer tunnel.Stop()
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(config.LocalHost, strconv.Itoa(config.LocalPort)))
+	if err != nil {
+		t.Fatalf("failed to connect to the tunnel: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := io.WriteString(conn, "ping"); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("failed to read through the tunnel: %v", err)
+	}
+	if string(reply) != "ping" {
+		t.Errorf("reply = %q, want %q", reply, "ping")
+	}
+
+	stats := tunnel.Stats()
+	if stats.TotalConnections != 1 || stats.ActiveConnections != 1 || stats.BytesOut != 4 || stats.BytesIn != 4 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if tunnel.IdleFor() != 0 {
+		t.Error("tunnel with an open connection is idle")
+	}
+
+	conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for tunnel.Stats().ActiveConnections != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("connection still active after the client closed it")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTunnelStop(t *testing.T) {
+	srv := newServer(t)
+	client := connect(t, srv.Config())
+
+	config := &devssh.TunnelConfig{LocalHost: "127.0.0.1", LocalPort: freePort(t), RemoteHost: "127.0.0.1", RemotePort: echoServer(t)}
+	tunnel := devssh.NewTunnel(client.GetClient(), config)
+	if err := tunnel.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := tunnel.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	if conn, err := net.Dial("tcp", net.JoinHostPort(config.LocalHost, strconv.Itoa(config.LocalPort))); err == nil {
+		conn.Close()
+		t.Error("tunnel still accepts connections after Stop")
+	}
+	if err := tunnel.Start(); err == nil {
+		t.Error("a stopped tunnel was started again")
+	}
+}
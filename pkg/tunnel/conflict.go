@@ -0,0 +1,158 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConflictPolicy 本地端口被占用时的处理方式
+type ConflictPolicy string
+
+const (
+	// ConflictIncrement 依次尝试后面的端口（默认）
+	ConflictIncrement ConflictPolicy = "increment"
+	// ConflictFail 直接报错，保证URL和OAuth回调地址不变
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictKill 确认后结束占用端口的本地进程
+	ConflictKill ConflictPolicy = "kill"
+)
+
+// ErrPortInUse 本地端口被占用且策略不允许换端口
+var ErrPortInUse = errors.New("local port is in use")
+
+// portReleaseTimeout 结束占用进程后等待端口释放的最长时间
+const portReleaseTimeout = 5 * time.Second
+
+// ParseConflictPolicy 解析端口冲突策略，空字符串返回空策略（使用默认）
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(strings.ToLower(value)); policy {
+	case "", ConflictIncrement, ConflictFail, ConflictKill:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown port conflict policy %q (use increment, fail or kill)", value)
+	}
+}
+
+// PortOwner 占用本地端口的进程
+type PortOwner struct {
+	PID  int
+	Name string
+}
+
+// String 返回进程的可读描述，如 "python3 (PID 1234)"
+func (o PortOwner) String() string {
+	if o.PID == 0 {
+		return "an unknown process"
+	}
+	if o.Name == "" {
+		return fmt.Sprintf("PID %d", o.PID)
+	}
+	return fmt.Sprintf("%s (PID %d)", o.Name, o.PID)
+}
+
+// FindLocalPortOwner 查找在本地TCP端口上监听的进程，优先使用lsof，Linux上没有lsof时读取/proc
+func FindLocalPortOwner(port int) (PortOwner, error) {
+	output, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fpc").Output()
+	if err == nil {
+		if owner, ok := parseLsofOwner(string(output)); ok {
+			return owner, nil
+		}
+	}
+
+	if runtime.GOOS == "linux" {
+		output, err := exec.Command("sh", "-c", procNetScript).Output()
+		if err != nil {
+			return PortOwner{}, fmt.Errorf("failed to read /proc: %w", err)
+		}
+		ports, err := parseProcNet(string(output))
+		if err != nil {
+			return PortOwner{}, err
+		}
+		for _, info := range ports {
+			if info.Port == port && info.PID != 0 {
+				return PortOwner{PID: info.PID, Name: info.Process}, nil
+			}
+		}
+	}
+
+	return PortOwner{}, fmt.Errorf("could not find the process listening on port %d", port)
+}
+
+// parseLsofOwner 解析 lsof -Fpc 输出中的第一个进程（p<pid> 与 c<command> 行）
+func parseLsofOwner(output string) (PortOwner, bool) {
+	var owner PortOwner
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		switch line[0] {
+		case 'p':
+			if owner.PID != 0 {
+				return owner, true
+			}
+			owner.PID, _ = strconv.Atoi(line[1:])
+		case 'c':
+			owner.Name = line[1:]
+		}
+	}
+	return owner, owner.PID != 0
+}
+
+// resolveLocalPort 按冲突策略确定实际监听的本地端口
+func (m *TunnelManager) resolveLocalPort(host string, port int, policy ConflictPolicy) (int, error) {
+	switch policy {
+	case ConflictFail:
+		if !IsAddressAvailable(host, port) {
+			return 0, fmt.Errorf("%w: %d (strict port policy)", ErrPortInUse, port)
+		}
+		return port, nil
+
+	case ConflictKill:
+		if IsAddressAvailable(host, port) {
+			return port, nil
+		}
+		owner, err := FindLocalPortOwner(port)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %d: %v", ErrPortInUse, port, err)
+		}
+
+		m.mu.RLock()
+		confirm := m.onConflict
+		m.mu.RUnlock()
+		if confirm == nil || !confirm(port, owner) {
+			return 0, fmt.Errorf("%w: %d is used by %s", ErrPortInUse, port, owner)
+		}
+
+		m.logger.Infof("Stopping %s to free local port %d", owner, port)
+		if err := terminateProcess(owner.PID); err != nil {
+			return 0, fmt.Errorf("failed to stop %s: %w", owner, err)
+		}
+		deadline := time.Now().Add(portReleaseTimeout)
+		for !IsAddressAvailable(host, port) {
+			if time.Now().After(deadline) {
+				return 0, fmt.Errorf("%w: %d is still in use after stopping %s", ErrPortInUse, port, owner)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		return port, nil
+
+	default:
+		logFunc := func(msg string) {
+			m.logger.Info(msg)
+		}
+		actualPort, err := FindAvailablePortOn(host, port, logFunc)
+		if err != nil {
+			return 0, err
+		}
+		// 如果端口有变化，记录最终结果
+		if actualPort != port {
+			m.logger.Infof("Local Port %d was occupied, automatically switch to port %d", port, actualPort)
+		}
+		return actualPort, nil
+	}
+}
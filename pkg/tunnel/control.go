@@ -0,0 +1,309 @@
+package tunnel
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+)
+
+// 会话控制请求的动作
+const (
+	ControlAdd    = "add"
+	ControlRemove = "remove"
+	ControlList   = "list"
+)
+
+// ControlRequest 发送给运行中会话的控制请求
+type ControlRequest struct {
+	Action     string `json:"action"`
+	Name       string `json:"name,omitempty"`
+	LocalHost  string `json:"local_host,omitempty"`
+	LocalPort  int    `json:"local_port,omitempty"`
+	RemoteHost string `json:"remote_host,omitempty"`
+	RemotePort int    `json:"remote_port,omitempty"`
+	// Conflict 本地端口被占用时的处理方式
+	Conflict ConflictPolicy `json:"conflict,omitempty"`
+	// Target remove时的目标：隧道名、远程端口或本地端口
+	Target string `json:"target,omitempty"`
+	// Forget remove时同时删除保存的命名转发
+	Forget bool `json:"forget,omitempty"`
+}
+
+// ControlResponse 控制请求的结果
+type ControlResponse struct {
+	Error    string          `json:"error,omitempty"`
+	Forwards []ForwardStatus `json:"forwards,omitempty"`
+}
+
+// ForwardStatus 一条端口转发的当前状态
+type ForwardStatus struct {
+	Name       string `json:"name"`
+	LocalHost  string `json:"local_host"`
+	LocalPort  int    `json:"local_port"`
+	RemoteHost string `json:"remote_host"`
+	RemotePort int    `json:"remote_port"`
+	ssh.TunnelStats
+}
+
+// String 返回转发的可读形式，如 "localhost:8080 -> remote:80"
+func (f ForwardStatus) String() string {
+	return FormatForward(f.LocalHost, f.LocalPort, f.RemoteHost, f.RemotePort)
+}
+
+// forwardStatus 由隧道信息构造转发状态
+func forwardStatus(name string, info TunnelInfo) ForwardStatus {
+	return ForwardStatus{
+		Name:        name,
+		LocalHost:   info.LocalHost,
+		LocalPort:   info.LocalPort,
+		RemoteHost:  info.RemoteHost,
+		RemotePort:  info.RemotePort,
+		TunnelStats: info.Stats,
+	}
+}
+
+// TunnelStore 保存命名转发，供下次连接同一主机时恢复
+type TunnelStore interface {
+	SaveTunnel(forward ForwardConfig) error
+	ForgetTunnel(name string) error
+}
+
+// Controller 在会话运行期间增删端口转发，供控制socket和交互命令使用
+type Controller struct {
+	client  *ssh.Client
+	manager *TunnelManager
+	logger  log.Logger
+	store   TunnelStore
+}
+
+// NewController 创建会话控制器
+func NewController(client *ssh.Client, manager *TunnelManager, logger log.Logger) *Controller {
+	if logger == nil {
+		logger = manager.logger
+	}
+
+	return &Controller{
+		client:  client,
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// SetStore 设置命名转发的保存位置，为nil时不保存
+func (c *Controller) SetStore(store TunnelStore) {
+	c.store = store
+}
+
+// Add 新增一条转发，同一远程地址已被转发时返回错误；命名的转发同时保存
+func (c *Controller) Add(forward ForwardConfig) (ForwardStatus, error) {
+	_, remoteHost := forward.hosts()
+	for name, info := range c.manager.ListTunnels() {
+		sameHost := info.RemoteHost == remoteHost || (isLoopbackHost(info.RemoteHost) && isLoopbackHost(remoteHost))
+		if info.RemotePort == forward.RemotePort && sameHost {
+			return ForwardStatus{}, fmt.Errorf("%s is already forwarded (%s: %s)", formatRemote(remoteHost, forward.RemotePort), name, info)
+		}
+	}
+
+	// 手动添加的端口不再被端口监视忽略
+	if isLoopbackHost(remoteHost) {
+		c.manager.IncludePort(forward.RemotePort)
+	}
+
+	name := forward.Name
+	switch {
+	case name != "":
+	case isLoopbackHost(remoteHost):
+		name = fmt.Sprintf("forward-%d", forward.RemotePort)
+	default:
+		name = fmt.Sprintf("forward-%s-%d", remoteHost, forward.RemotePort)
+	}
+	if _, err := c.manager.CreateForward(c.client, forward, name); err != nil {
+		return ForwardStatus{}, err
+	}
+
+	status := forwardStatus(name, c.manager.ListTunnels()[name])
+	c.logger.Infof("Forwarding %s", status)
+
+	if forward.Name != "" && c.store != nil {
+		if err := c.store.SaveTunnel(forward); err != nil {
+			c.logger.Warnf("Forward %s will not be restored next time: %v", name, err)
+		}
+	}
+	return status, nil
+}
+
+// Remove 按隧道名、远程端口或本地端口移除一条转发，forget为true时同时删除保存的命名转发
+func (c *Controller) Remove(target string, forget bool) (ForwardStatus, error) {
+	tunnels := c.manager.ListTunnels()
+
+	name := ""
+	if _, exists := tunnels[target]; exists {
+		name = target
+	} else if port, err := strconv.Atoi(target); err == nil {
+		// 远程端口优先于本地端口
+		for n, info := range tunnels {
+			if info.RemotePort == port {
+				name = n
+				break
+			}
+		}
+		if name == "" {
+			for n, info := range tunnels {
+				if info.LocalPort == port {
+					name = n
+					break
+				}
+			}
+		}
+	}
+	if name == "" {
+		return ForwardStatus{}, fmt.Errorf("no forward matches %q", target)
+	}
+
+	info := tunnels[name]
+	if err := c.manager.StopTunnel(name); err != nil {
+		return ForwardStatus{}, err
+	}
+	// 避免端口监视立即重新转发
+	if isLoopbackHost(info.RemoteHost) {
+		c.manager.ExcludePort(info.RemotePort)
+	}
+
+	c.logger.Infof("Stopped forwarding %s", info)
+
+	if forget && c.store != nil {
+		if err := c.store.ForgetTunnel(name); err != nil {
+			return ForwardStatus{}, fmt.Errorf("stopped %s but failed to forget it: %w", name, err)
+		}
+	}
+	return forwardStatus(name, info), nil
+}
+
+// List 返回按名称排序的当前转发
+func (c *Controller) List() []ForwardStatus {
+	var forwards []ForwardStatus
+	for name, info := range c.manager.ListTunnels() {
+		forwards = append(forwards, forwardStatus(name, info))
+	}
+	sort.Slice(forwards, func(i, j int) bool {
+		return forwards[i].Name < forwards[j].Name
+	})
+	return forwards
+}
+
+// Handle 执行一条控制请求
+func (c *Controller) Handle(req ControlRequest) ControlResponse {
+	var (
+		forward ForwardStatus
+		err     error
+	)
+
+	switch req.Action {
+	case ControlAdd:
+		localPort := req.LocalPort
+		if localPort == 0 {
+			localPort = req.RemotePort
+		}
+		forward, err = c.Add(ForwardConfig{
+			Name:       req.Name,
+			LocalHost:  req.LocalHost,
+			LocalPort:  localPort,
+			RemoteHost: req.RemoteHost,
+			RemotePort: req.RemotePort,
+			Conflict:   req.Conflict,
+		})
+	case ControlRemove:
+		forward, err = c.Remove(req.Target, req.Forget)
+	case ControlList:
+		return ControlResponse{Forwards: c.List()}
+	default:
+		err = fmt.Errorf("unknown action %q", req.Action)
+	}
+
+	if err != nil {
+		return ControlResponse{Error: err.Error()}
+	}
+	return ControlResponse{Forwards: []ForwardStatus{forward}}
+}
+
+// Listen 在unix socket上接受控制请求，返回的listener关闭后停止服务
+func (c *Controller) Listen(socketPath string) (net.Listener, error) {
+	// 清理上次异常退出留下的socket文件
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set control socket permissions: %w", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					c.logger.Debugf("Control socket accept failed: %v", err)
+				}
+				return
+			}
+			go c.serve(conn)
+		}
+	}()
+
+	return listener, nil
+}
+
+// serve 处理一个连接上的单条请求
+func (c *Controller) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	var req ControlRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		c.logger.Debugf("Invalid control request: %v", err)
+		return
+	}
+	c.logger.Debugf("Control request: %s", req.Action)
+
+	if err := json.NewEncoder(conn).Encode(c.Handle(req)); err != nil {
+		c.logger.Debugf("Failed to send control response: %v", err)
+	}
+}
+
+// SendControl 向运行中会话的控制socket发送请求
+func SendControl(socketPath string, req ControlRequest) ([]ForwardStatus, error) {
+	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach session (is it still running?): %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp ControlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return resp.Forwards, nil
+}
@@ -0,0 +1,196 @@
+package tunnel
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// probeMarker 分隔每个端口的HTTP探测输出
+const probeMarker = "--devssh-probe"
+
+// probeBodyLimit 每个端口读取的最大字节数，足够包含响应头和<title>
+const probeBodyLimit = 16384
+
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// knownApps 命令行中的可执行文件或模块名到应用名的映射，按顺序匹配
+var knownApps = []struct {
+	token string
+	name  string
+}{
+	{"vite", "vite"},
+	{"next", "next.js"},
+	{"next-server", "next.js"},
+	{"nuxt", "nuxt"},
+	{"astro", "astro"},
+	{"remix", "remix"},
+	{"react-scripts", "create-react-app"},
+	{"webpack", "webpack"},
+	{"webpack-dev-server", "webpack"},
+	{"ng", "angular"},
+	{"jupyter-lab", "jupyter lab"},
+	{"jupyter-notebook", "jupyter notebook"},
+	{"jupyter", "jupyter"},
+	{"tensorboard", "tensorboard"},
+	{"streamlit", "streamlit"},
+	{"gradio", "gradio"},
+	{"mlflow", "mlflow"},
+	{"uvicorn", "uvicorn"},
+	{"gunicorn", "gunicorn"},
+	{"flask", "flask"},
+	{"manage", "django"},
+	{"http.server", "python http.server"},
+	{"rails", "rails"},
+	{"puma", "puma"},
+	{"hugo", "hugo"},
+	{"jekyll", "jekyll"},
+	{"code-server", "code-server"},
+	{"openvscode-server", "openvscode-server"},
+	{"grafana-server", "grafana"},
+	{"nginx", "nginx"},
+	{"caddy", "caddy"},
+	{"httpd", "apache"},
+	{"apache2", "apache"},
+}
+
+// Identify 对给定端口做一次HTTP探测（一次SSH往返），填充Server响应头和页面标题
+func (s *PortScanner) Identify(ports []PortInfo) {
+	if len(ports) == 0 {
+		return
+	}
+
+	var script strings.Builder
+	for _, port := range ports {
+		scheme := "http"
+		if port.Port == 443 {
+			scheme = "https"
+		}
+		url := fmt.Sprintf("%s://%s:%d/", scheme, probeHost(port.Address), port.Port)
+		fmt.Fprintf(&script, "echo '%s %d'\n", probeMarker, port.Port)
+		fmt.Fprintf(&script, "if command -v curl >/dev/null 2>&1; then curl -s -k -i -m 2 '%s' 2>/dev/null | head -c %d; ", url, probeBodyLimit)
+		fmt.Fprintf(&script, "else timeout 2 bash -c 'exec 3<>/dev/tcp/%s/%d && printf \"GET / HTTP/1.0\\r\\nHost: localhost\\r\\n\\r\\n\" >&3 && head -c %d <&3' 2>/dev/null; fi\n",
+			strings.Trim(probeHost(port.Address), "[]"), port.Port, probeBodyLimit)
+		script.WriteString("echo\n")
+	}
+
+	output, err := s.sshClient.RunCommand(script.String())
+	if err != nil && output == "" {
+		s.logger.Debugf("HTTP probe failed: %v", err)
+		return
+	}
+
+	responses := splitProbeOutput(output)
+	for i := range ports {
+		if response, ok := responses[ports[i].Port]; ok {
+			ports[i].HTTPServer, ports[i].HTTPTitle = parseHTTPResponse(response)
+		}
+	}
+}
+
+// probeHost 返回探测时连接的地址，监听所有地址时使用回环地址
+func probeHost(address string) string {
+	switch {
+	case address == "" || address == "0.0.0.0" || address == "::":
+		return "127.0.0.1"
+	case strings.Contains(address, ":"):
+		return "[" + address + "]"
+	default:
+		return address
+	}
+}
+
+// splitProbeOutput 按端口拆分探测输出
+func splitProbeOutput(output string) map[int]string {
+	responses := make(map[int]string)
+	for _, part := range strings.Split(output, probeMarker+" ")[1:] {
+		var port int
+		if _, err := fmt.Sscanf(part, "%d", &port); err != nil {
+			continue
+		}
+		if idx := strings.Index(part, "\n"); idx != -1 {
+			responses[port] = part[idx+1:]
+		}
+	}
+	return responses
+}
+
+// parseHTTPResponse 从HTTP响应中取出Server头和<title>
+func parseHTTPResponse(response string) (server, title string) {
+	if !strings.HasPrefix(response, "HTTP/") {
+		return "", ""
+	}
+
+	header, body, _ := strings.Cut(strings.ReplaceAll(response, "\r\n", "\n"), "\n\n")
+	for _, line := range strings.Split(header, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "server") {
+			server = strings.TrimSpace(value)
+		}
+	}
+
+	if match := titlePattern.FindStringSubmatch(body); match != nil {
+		title = strings.Join(strings.Fields(html.UnescapeString(match[1])), " ")
+		if len(title) > 60 {
+			title = title[:57] + "..."
+		}
+	}
+	return server, title
+}
+
+// identifyApp 根据命令行识别常见的开发服务器和Web应用
+func identifyApp(command string) string {
+	fields := strings.Fields(command)
+	for _, known := range knownApps {
+		for _, field := range fields {
+			if strings.HasPrefix(field, "-") {
+				continue
+			}
+			base := path.Base(field)
+			base = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(base, ".js"), ".mjs"), ".py")
+			if base == known.token {
+				return known.name
+			}
+		}
+	}
+	return ""
+}
+
+// Describe 返回端口上服务的可读描述，如 "vite (node, project frontend)"
+func (p PortInfo) Describe() string {
+	name := identifyApp(p.Command)
+	switch {
+	case name != "":
+	case p.HTTPTitle != "":
+		name = fmt.Sprintf("%q", p.HTTPTitle)
+	case p.HTTPServer != "":
+		name = p.HTTPServer
+	case p.Service != "" && p.Service != "Unknown":
+		name = p.Service
+	case p.Process != "":
+		name = p.Process
+	default:
+		name = "Unknown"
+	}
+
+	var details []string
+	if p.Process != "" && p.Process != name {
+		details = append(details, p.Process)
+	}
+	if p.Cwd != "" && p.Cwd != "/" {
+		details = append(details, "project "+path.Base(p.Cwd))
+	}
+	if p.Container != "" {
+		details = append(details, "container "+p.Container)
+	}
+	if p.HTTPTitle != "" && !strings.Contains(name, p.HTTPTitle) {
+		details = append(details, fmt.Sprintf("%q", p.HTTPTitle))
+	}
+
+	if len(details) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(details, ", "))
+}
@@ -1,78 +1,187 @@
 package tunnel
 
 import (
+	"context"
 	"fmt"
-	"io"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"devssh/pkg/logging"
 	"devssh/pkg/ssh"
 	"github.com/loft-sh/log"
-	"github.com/sirupsen/logrus"
 )
 
 type TunnelManager struct {
-	tunnels map[string]*ssh.Tunnel
-	mu      sync.RWMutex
-	logger  log.Logger
+	tunnels  map[string]*ssh.Tunnel
+	mu       sync.RWMutex
+	logger   log.Logger
+	onChange func()
+	// excluded 被用户手动移除的远程端口，端口监视不再自动转发
+	excluded map[int]bool
+	// conflictPolicy 转发未指定策略时的本地端口冲突处理方式
+	conflictPolicy ConflictPolicy
+	onConflict     func(port int, owner PortOwner) bool
+	// portFilter 配置的自动检测端口增减
+	portFilter *PortFilter
 }
 
+// NewTunnelManager 创建隧道管理器，使用全局logger
 func NewTunnelManager() *TunnelManager {
-	// 创建一个不输出任何内容的logger
-	logger := log.NewStreamLogger(io.Discard, io.Discard, logrus.InfoLevel)
-	return &TunnelManager{
-		tunnels: make(map[string]*ssh.Tunnel),
-		logger:  logger,
-	}
+	return NewTunnelManagerWithLogger(logging.GetGlobalLogger())
 }
 
 func NewTunnelManagerWithLogger(logger log.Logger) *TunnelManager {
 	return &TunnelManager{
-		tunnels: make(map[string]*ssh.Tunnel),
-		logger:  logger,
+		tunnels:  make(map[string]*ssh.Tunnel),
+		logger:   logger,
+		excluded: make(map[int]bool),
 	}
 }
 
-func (m *TunnelManager) CreateTunnel(client *ssh.Client, localPort, remotePort int, name string) (int, error) {
+// OnChange 设置隧道新增或移除后的回调，StopAllTunnels不触发
+func (m *TunnelManager) OnChange(handler func()) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.onChange = handler
+}
 
-	if _, exists := m.tunnels[name]; exists {
+// SetConflictPolicy 设置本地端口被占用时的默认处理方式
+func (m *TunnelManager) SetConflictPolicy(policy ConflictPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conflictPolicy = policy
+}
+
+// OnConflict 设置kill策略下的确认回调，返回true时结束占用端口的进程；未设置时视为拒绝
+func (m *TunnelManager) OnConflict(handler func(port int, owner PortOwner) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onConflict = handler
+}
+
+// SetPortFilter 设置自动检测转发时额外包含和排除的端口
+func (m *TunnelManager) SetPortFilter(filter *PortFilter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.portFilter = filter
+}
+
+// newScanner 创建使用管理器端口过滤的扫描器
+func (m *TunnelManager) newScanner(client *ssh.Client, logger log.Logger) *PortScanner {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	scanner := NewPortScannerWithLogger(client, logger)
+	scanner.SetFilter(m.portFilter)
+	return scanner
+}
+
+// ExcludePort 不再自动转发该远程端口
+func (m *TunnelManager) ExcludePort(port int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.excluded[port] = true
+}
+
+// IncludePort 取消 ExcludePort
+func (m *TunnelManager) IncludePort(port int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.excluded, port)
+}
+
+// IsPortExcluded 判断远程端口是否被排除在自动转发之外
+func (m *TunnelManager) IsPortExcluded(port int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.excluded[port]
+}
+
+// notifyChange 在不持有锁时调用回调
+func (m *TunnelManager) notifyChange() {
+	m.mu.RLock()
+	handler := m.onChange
+	m.mu.RUnlock()
+
+	if handler != nil {
+		handler()
+	}
+}
+
+func (m *TunnelManager) CreateTunnel(client *ssh.Client, localPort, remotePort int, name string) (int, error) {
+	return m.CreateForward(client, ForwardConfig{LocalPort: localPort, RemotePort: remotePort}, name)
+}
+
+// CreateForward 按转发配置创建隧道，未指定的绑定地址和远程主机默认为127.0.0.1，返回实际使用的本地端口
+func (m *TunnelManager) CreateForward(client *ssh.Client, forward ForwardConfig, name string) (int, error) {
+	actualPort, err := m.createTunnel(client, forward, name)
+	if err == nil {
+		m.notifyChange()
+	}
+	return actualPort, err
+}
+
+func (m *TunnelManager) createTunnel(client *ssh.Client, forward ForwardConfig, name string) (int, error) {
+	if m.HasTunnel(name) {
 		return 0, fmt.Errorf("tunnel %s already exists", name)
 	}
+	if !client.IsConnected() {
+		return 0, fmt.Errorf("cannot create tunnel %s: SSH connection is closed", name)
+	}
 
-	// 记录日志的函数
-	logFunc := func(msg string) {
-		m.logger.Info(msg)
+	localHost, remoteHost := forward.hosts()
+
+	policy := forward.Conflict
+	if policy == "" {
+		m.mu.RLock()
+		policy = m.conflictPolicy
+		m.mu.RUnlock()
 	}
 
-	// 查找可用端口
-	actualPort, err := FindAvailablePort(localPort, logFunc)
+	// 确定本地端口时可能等待用户确认，不持有锁
+	actualPort, err := m.resolveLocalPort(localHost, forward.LocalPort, policy)
 	if err != nil {
 		return 0, fmt.Errorf("failed to find available port for tunnel %s: %w", name, err)
 	}
 
-	// 如果端口有变化，记录最终结果
-	if actualPort != localPort {
-		m.logger.Infof("Local Port %d was occupied, automatically switch to port %d", localPort, actualPort)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.tunnels[name]; exists {
+		return 0, fmt.Errorf("tunnel %s already exists", name)
 	}
 
 	config := &ssh.TunnelConfig{
-		LocalHost:  "127.0.0.1",
+		LocalHost:  localHost,
 		LocalPort:  actualPort,
-		RemoteHost: "127.0.0.1",
-		RemotePort: remotePort,
+		RemoteHost: remoteHost,
+		RemotePort: forward.RemotePort,
 	}
 
-	tunnel := ssh.NewTunnel(client.GetClient(), config)
+	tunnel := ssh.NewTunnelWithLogger(client.GetClient(), config, m.logger)
 	if err := tunnel.Start(); err != nil {
 		return 0, fmt.Errorf("failed to start tunnel on port %d: %w", actualPort, err)
 	}
 
+	if !isLoopbackHost(localHost) {
+		m.logger.Warnf("Forward %s listens on %s and is reachable from other machines", name, net.JoinHostPort(localHost, strconv.Itoa(actualPort)))
+	}
+
 	m.tunnels[name] = tunnel
 	return actualPort, nil
 }
 
 func (m *TunnelManager) StopTunnel(name string) error {
+	if err := m.stopTunnel(name); err != nil {
+		return err
+	}
+	m.notifyChange()
+	return nil
+}
+
+func (m *TunnelManager) stopTunnel(name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -109,32 +218,117 @@ func (m *TunnelManager) StopAllTunnels() error {
 	return nil
 }
 
-func (m *TunnelManager) ListTunnels() map[string]struct {
+// Follow 在client的连接断开时关闭所有隧道并释放本地端口，否则隧道仍接受本地连接但无法转发；
+// 返回停止跟随的函数
+func (m *TunnelManager) Follow(client *ssh.Client) func() {
+	return client.OnStateChange(func(state ssh.ConnectionState) {
+		if state != ssh.StateDisconnected {
+			return
+		}
+		m.mu.RLock()
+		count := len(m.tunnels)
+		m.mu.RUnlock()
+		if count == 0 {
+			return
+		}
+
+		m.logger.Warnf("SSH connection lost, closing %d port forward(s)", count)
+		if err := m.StopAllTunnels(); err != nil {
+			m.logger.Warnf("Failed to stop port forwards: %v", err)
+		}
+		m.notifyChange()
+	})
+}
+
+// TunnelInfo 隧道的端口和流量统计
+type TunnelInfo struct {
+	LocalHost  string
 	LocalPort  int
+	RemoteHost string
 	RemotePort int
-} {
+	Stats      ssh.TunnelStats
+}
+
+// String 返回转发的可读形式，如 "localhost:8080 -> remote:80"
+func (i TunnelInfo) String() string {
+	return FormatForward(i.LocalHost, i.LocalPort, i.RemoteHost, i.RemotePort)
+}
+
+func (m *TunnelManager) ListTunnels() map[string]TunnelInfo {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	result := make(map[string]struct {
-		LocalPort  int
-		RemotePort int
-	})
+	result := make(map[string]TunnelInfo)
 
 	for name, tunnel := range m.tunnels {
 		config := tunnel.GetConfig()
-		result[name] = struct {
-			LocalPort  int
-			RemotePort int
-		}{
+		result[name] = TunnelInfo{
+			LocalHost:  config.LocalHost,
 			LocalPort:  config.LocalPort,
+			RemoteHost: config.RemoteHost,
 			RemotePort: config.RemotePort,
+			Stats:      tunnel.Stats(),
 		}
 	}
 
 	return result
 }
 
+// CloseIdleTunnels 关闭空闲超过timeout的隧道（keep返回true的除外），返回被关闭的隧道。
+// 被关闭隧道的远程端口不再被端口监视自动转发
+func (m *TunnelManager) CloseIdleTunnels(timeout time.Duration, keep func(name string, info TunnelInfo) bool) map[string]TunnelInfo {
+	m.mu.RLock()
+	var idle []string
+	for name, tunnel := range m.tunnels {
+		if tunnel.IdleFor() >= timeout {
+			idle = append(idle, name)
+		}
+	}
+	m.mu.RUnlock()
+
+	closed := make(map[string]TunnelInfo)
+	tunnels := m.ListTunnels()
+	for _, name := range idle {
+		info, exists := tunnels[name]
+		if !exists || (keep != nil && keep(name, info)) {
+			continue
+		}
+		if err := m.StopTunnel(name); err != nil {
+			m.logger.Debugf("Failed to close idle tunnel %s: %v", name, err)
+			continue
+		}
+		m.ExcludePort(info.RemotePort)
+		closed[name] = info
+	}
+	return closed
+}
+
+// RunIdleReaper 定期关闭空闲超过timeout的隧道，直到ctx被取消
+func (m *TunnelManager) RunIdleReaper(ctx context.Context, timeout time.Duration, keep func(name string, info TunnelInfo) bool) {
+	interval := timeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for name, info := range m.CloseIdleTunnels(timeout, keep) {
+			m.logger.Infof("Closed %s (%s) after %v without traffic", name, info, timeout)
+		}
+	}
+}
+
 func (m *TunnelManager) HasTunnel(name string) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -152,9 +346,116 @@ func (m *TunnelManager) GetTunnel(name string) (*ssh.Tunnel, bool) {
 }
 
 type ForwardConfig struct {
-	LocalPort  int
+	// Name 用户指定的隧道名，命名的转发会按主机保存，下次连接时恢复
+	Name string
+	// LocalHost 本地监听地址，为空时为127.0.0.1
+	LocalHost string
+	LocalPort int
+	// RemoteHost 从开发机访问的目标主机，为空时为开发机自身
+	RemoteHost string
 	RemotePort int
 	AutoDetect bool
+	// Conflict 本地端口被占用时的处理方式，为空时使用管理器的默认策略
+	Conflict ConflictPolicy
+}
+
+// ForwardFromSpec 解析 [NAME=][BIND:]LOCAL:[HOST:]REMOTE[/POLICY] 格式的转发，POLICY为端口冲突策略
+func ForwardFromSpec(spec string) (ForwardConfig, error) {
+	name, forward, named := strings.Cut(spec, "=")
+	if !named {
+		name, forward = "", spec
+	} else if err := ValidateTunnelName(name); err != nil {
+		return ForwardConfig{}, fmt.Errorf("invalid port forward %s: %w", spec, err)
+	}
+
+	address, policyName, _ := strings.Cut(forward, "/")
+	policy, err := ParseConflictPolicy(policyName)
+	if err != nil {
+		return ForwardConfig{}, fmt.Errorf("invalid port forward %s: %w", spec, err)
+	}
+
+	config, err := ssh.ParseTunnelSpec(address)
+	if err != nil {
+		return ForwardConfig{}, err
+	}
+	return ForwardConfig{
+		Name:       name,
+		LocalHost:  config.LocalHost,
+		LocalPort:  config.LocalPort,
+		RemoteHost: config.RemoteHost,
+		RemotePort: config.RemotePort,
+		Conflict:   policy,
+	}, nil
+}
+
+// Spec 返回可由 ForwardFromSpec 解析的转发格式（不含名称），默认地址省略
+func (f ForwardConfig) Spec() string {
+	localHost, remoteHost := f.hosts()
+
+	var spec strings.Builder
+	if localHost != ssh.DefaultTunnelHost {
+		spec.WriteString(specHost(localHost) + ":")
+	}
+	spec.WriteString(strconv.Itoa(f.LocalPort) + ":")
+	if remoteHost != ssh.DefaultTunnelHost {
+		spec.WriteString(specHost(remoteHost) + ":")
+	}
+	spec.WriteString(strconv.Itoa(f.RemotePort))
+	if f.Conflict != "" {
+		spec.WriteString("/" + string(f.Conflict))
+	}
+	return spec.String()
+}
+
+// specHost IPv6地址加方括号
+func specHost(host string) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// reservedTunnelPrefixes 自动生成的隧道名前缀，用户命名时不可使用
+var reservedTunnelPrefixes = []string{autoTunnelPrefix, "tunnel-", "forward-"}
+
+// ValidateTunnelName 检查用户指定的隧道名：字母开头，只含字母、数字、'-'、'_'、'.'，且不与自动生成的名称冲突
+func ValidateTunnelName(name string) error {
+	if name == "" {
+		return fmt.Errorf("tunnel name is empty")
+	}
+	for i, r := range name {
+		letter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if i == 0 && !letter {
+			return fmt.Errorf("tunnel name %q must start with a letter", name)
+		}
+		if !letter && !(r >= '0' && r <= '9') && r != '-' && r != '_' && r != '.' {
+			return fmt.Errorf("tunnel name %q contains invalid character %q", name, r)
+		}
+	}
+	for _, prefix := range reservedTunnelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("tunnel name %q uses the reserved prefix %q", name, prefix)
+		}
+	}
+	return nil
+}
+
+// hosts 返回补全默认值后的本地监听地址和远程主机
+func (f ForwardConfig) hosts() (localHost, remoteHost string) {
+	localHost, remoteHost = f.LocalHost, f.RemoteHost
+	if localHost == "" {
+		localHost = ssh.DefaultTunnelHost
+	}
+	if remoteHost == "" {
+		remoteHost = ssh.DefaultTunnelHost
+	}
+	return localHost, remoteHost
+}
+
+// String 返回转发的可读形式，如 "localhost:8080 -> remote:80"
+func (f ForwardConfig) String() string {
+	localHost, remoteHost := f.hosts()
+	return FormatForward(localHost, f.LocalPort, remoteHost, f.RemotePort)
 }
 
 type PortForwardResult struct {
@@ -162,44 +463,53 @@ type PortForwardResult struct {
 	LocalPort  int
 	RemotePort int
 	ActualPort int
+	// Service 自动检测到的服务，手动转发时为零值
+	Service PortInfo
 }
 
 func CreatePortForwards(client *ssh.Client, configs []ForwardConfig, manager *TunnelManager) ([]PortForwardResult, error) {
 	var results []PortForwardResult
 
 	for i, config := range configs {
-		name := fmt.Sprintf("tunnel-%d", i)
+		name := config.Name
+		if name == "" {
+			name = fmt.Sprintf("tunnel-%d", i)
+		}
 
 		if config.AutoDetect {
 			// 自动检测并转发端口
-			scanner := NewPortScanner(client)
+			scanner := manager.newScanner(client, manager.logger)
 			ports, err := scanner.DetectWebServices()
 			if err != nil {
 				return nil, fmt.Errorf("failed to detect web services: %w", err)
 			}
+			scanner.Identify(ports)
 
 			for _, portInfo := range ports {
-				tunnelName := fmt.Sprintf("auto-%d", portInfo.Port)
+				tunnelName := AutoTunnelName(portInfo.Port)
 				actualPort, err := manager.CreateTunnel(client, portInfo.Port, portInfo.Port, tunnelName)
 				if err != nil {
 					return nil, fmt.Errorf("failed to create auto tunnel for port %d: %w", portInfo.Port, err)
 				}
-				manager.logger.Infof("Auto-forwarding port %d (%s)", portInfo.Port, portInfo.Service)
+				manager.logger.Infof("Auto-forwarding port %d: %s", portInfo.Port, portInfo.Describe())
 
 				results = append(results, PortForwardResult{
 					Name:       tunnelName,
 					LocalPort:  portInfo.Port,
 					RemotePort: portInfo.Port,
 					ActualPort: actualPort,
+					Service:    portInfo,
 				})
 			}
 		} else {
 			// 手动指定端口转发
-			actualPort, err := manager.CreateTunnel(client, config.LocalPort, config.RemotePort, name)
+			actualPort, err := manager.CreateForward(client, config, name)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create tunnel for port %d->%d: %w", config.LocalPort, config.RemotePort, err)
+				return nil, fmt.Errorf("failed to create tunnel for %s: %w", config, err)
 			}
-			manager.logger.Infof("Forwarding local port %d to remote port %d", config.LocalPort, config.RemotePort)
+			actual := config
+			actual.LocalPort = actualPort
+			manager.logger.Infof("Forwarding %s", actual)
 
 			results = append(results, PortForwardResult{
 				Name:       name,
@@ -1,8 +1,12 @@
 package tunnel
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"net/http"
+	"strconv"
+	"time"
 )
 
 const (
@@ -13,12 +17,16 @@ const (
 
 // IsPortAvailable 检查端口是否可用
 func IsPortAvailable(port int) bool {
+	return IsAddressAvailable("", port)
+}
+
+// IsAddressAvailable 检查能否在指定地址的端口上监听，host为空时检查所有地址
+func IsAddressAvailable(host string, port int) bool {
 	if port < 1 || port > MaxPort {
 		return false
 	}
 
-	addr := fmt.Sprintf(":%d", port)
-	listener, err := net.Listen("tcp", addr)
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
 		return false
 	}
@@ -28,6 +36,11 @@ func IsPortAvailable(port int) bool {
 
 // FindAvailablePort 寻找可用端口，记录重试过程
 func FindAvailablePort(startPort int, logFunc func(string)) (int, error) {
+	return FindAvailablePortOn("", startPort, logFunc)
+}
+
+// FindAvailablePortOn 在指定地址上寻找可用端口，host为空时检查所有地址
+func FindAvailablePortOn(host string, startPort int, logFunc func(string)) (int, error) {
 	// 确保起始端口不小于系统端口最小值
 	if startPort < SystemPortMin {
 		startPort = SystemPortMin
@@ -42,7 +55,7 @@ func FindAvailablePort(startPort int, logFunc func(string)) (int, error) {
 			return 0, fmt.Errorf("port %d exceeds maximum port %d", currentPort, MaxPort)
 		}
 
-		if IsPortAvailable(currentPort) {
+		if IsAddressAvailable(host, currentPort) {
 			if attempts > 0 {
 				logFunc(fmt.Sprintf("Found available port %d after %d attempts", currentPort, attempts))
 			}
@@ -61,3 +74,34 @@ func FindAvailablePort(startPort int, logFunc func(string)) (int, error) {
 
 	return 0, fmt.Errorf("no available port found after %d attempts (starting from %d)", MaxPortRetries, startPort)
 }
+
+// WaitForHTTP 等待URL返回HTTP 200，直到ctx取消或超时
+func WaitForHTTP(ctx context.Context, url string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s: %w", url, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package tunnel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortRange 闭区间端口范围，单个端口时Start等于End
+type PortRange struct {
+	Start int
+	End   int
+}
+
+// ParsePortRange 解析 PORT 或 START-END 格式的端口范围
+func ParsePortRange(spec string) (PortRange, error) {
+	spec = strings.TrimSpace(spec)
+	startSpec, endSpec, isRange := strings.Cut(spec, "-")
+	if !isRange {
+		endSpec = startSpec
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(startSpec))
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q", spec)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endSpec))
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q", spec)
+	}
+	if start < 1 || end > 65535 || start > end {
+		return PortRange{}, fmt.Errorf("invalid port range %q: ports must be between 1 and 65535 and start <= end", spec)
+	}
+	return PortRange{Start: start, End: end}, nil
+}
+
+// Contains 判断端口是否在范围内
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Start && port <= r.End
+}
+
+func (r PortRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// PortFilter 调整自动检测转发的端口：Include 在内置Web端口之外额外转发，Exclude 永不自动转发，优先于 Include
+type PortFilter struct {
+	Include []PortRange
+	Exclude []PortRange
+}
+
+// NewPortFilter 由端口或端口范围列表（如 9000、9000-9100）创建过滤器
+func NewPortFilter(include, exclude []string) (*PortFilter, error) {
+	filter := &PortFilter{}
+	for _, spec := range include {
+		r, err := ParsePortRange(spec)
+		if err != nil {
+			return nil, fmt.Errorf("auto-detect include: %w", err)
+		}
+		filter.Include = append(filter.Include, r)
+	}
+	for _, spec := range exclude {
+		r, err := ParsePortRange(spec)
+		if err != nil {
+			return nil, fmt.Errorf("auto-detect exclude: %w", err)
+		}
+		filter.Exclude = append(filter.Exclude, r)
+	}
+	return filter, nil
+}
+
+// Allows 判断是否自动转发端口，builtin 为端口是否属于内置Web端口；nil过滤器只允许内置端口
+func (f *PortFilter) Allows(port int, builtin bool) bool {
+	if f == nil {
+		return builtin
+	}
+	if containsPort(f.Exclude, port) {
+		return false
+	}
+	return builtin || containsPort(f.Include, port)
+}
+
+func containsPort(ranges []PortRange, port int) bool {
+	for _, r := range ranges {
+		if r.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
 )
 
 type PortInfo struct {
@@ -13,18 +15,45 @@ type PortInfo struct {
 	Protocol string
 	Service  string
 	Process  string
+	// Address 监听地址，如 0.0.0.0、127.0.0.1、::
+	Address string
+	// PID 监听进程ID，无权限读取时为0
+	PID int
+	// Command 监听进程的完整命令行
+	Command string
+	// Cwd 监听进程的工作目录
+	Cwd string
+	// Container 进程所在容器的短ID，或发布该端口的docker容器名
+	Container string
+	// HTTPServer 和 HTTPTitle 来自HTTP探测（Identify）
+	HTTPServer string
+	HTTPTitle  string
 }
 
 type PortScanner struct {
 	sshClient *ssh.Client
+	logger    log.Logger
+	// filter 自动检测的端口增减，为nil时只检测内置Web端口
+	filter *PortFilter
 }
 
 func NewPortScanner(sshClient *ssh.Client) *PortScanner {
+	return NewPortScannerWithLogger(sshClient, log.Discard)
+}
+
+// NewPortScannerWithLogger 创建带logger的端口扫描器
+func NewPortScannerWithLogger(sshClient *ssh.Client, logger log.Logger) *PortScanner {
 	return &PortScanner{
 		sshClient: sshClient,
+		logger:    logger,
 	}
 }
 
+// SetFilter 设置 DetectWebServices 额外包含和排除的端口
+func (s *PortScanner) SetFilter(filter *PortFilter) {
+	s.filter = filter
+}
+
 func (s *PortScanner) ScanCommonPorts() ([]PortInfo, error) {
 	commonPorts := []int{
 		// Web servers
@@ -59,6 +88,15 @@ func (s *PortScanner) ScanCommonPorts() ([]PortInfo, error) {
 		53, // DNS
 	}
 
+	// 优先用一次监听端口查询代替逐个端口探测
+	if listening, err := s.GetListeningPorts(); err == nil {
+		wanted := make(map[int]bool, len(commonPorts))
+		for _, port := range commonPorts {
+			wanted[port] = true
+		}
+		return filterPorts(listening, func(port int) bool { return wanted[port] }), nil
+	}
+
 	var openPorts []PortInfo
 
 	for _, port := range commonPorts {
@@ -76,6 +114,10 @@ func (s *PortScanner) ScanCommonPorts() ([]PortInfo, error) {
 }
 
 func (s *PortScanner) ScanPortRange(start, end int) ([]PortInfo, error) {
+	if listening, err := s.GetListeningPorts(); err == nil {
+		return filterPorts(listening, func(port int) bool { return port >= start && port <= end }), nil
+	}
+
 	var openPorts []PortInfo
 
 	for port := start; port <= end; port++ {
@@ -93,20 +135,32 @@ func (s *PortScanner) ScanPortRange(start, end int) ([]PortInfo, error) {
 }
 
 func (s *PortScanner) GetListeningPorts() ([]PortInfo, error) {
+	// 优先直接读取 /proc/net，一次往返同时拿到进程信息
+	output, err := s.sshClient.RunCommand(procNetScript)
+	if err == nil {
+		ports, parseErr := parseProcNet(output)
+		if parseErr == nil {
+			for i := range ports {
+				ports[i].Service = s.guessService(ports[i].Port)
+			}
+			return ports, nil
+		}
+		err = parseErr
+	}
+	s.logger.Debugf("Reading /proc/net failed, falling back to ss/netstat: %v", err)
+
 	// 使用 netstat 或 ss 命令获取监听端口
 	commands := []string{
 		"ss -tuln 2>/dev/null",
 		"netstat -tuln 2>/dev/null",
 	}
 
-	var output string
-	var err error
-
 	for _, cmd := range commands {
 		output, err = s.sshClient.RunCommand(cmd)
 		if err == nil && output != "" {
 			break
 		}
+		s.logger.Debugf("Listening port query %q failed: %v", cmd, err)
 	}
 
 	if err != nil || output == "" {
@@ -150,7 +204,11 @@ func (s *PortScanner) parseNetstatOutput(output string) []PortInfo {
 		}
 
 		// 解析地址字段 (格式: 0.0.0.0:8080 或 :::8080)
+		// ss 的第二列是状态，本地地址在第五列；netstat 在第四列
 		localAddr := fields[3]
+		if state := strings.ToUpper(fields[1]); (state == "LISTEN" || state == "UNCONN") && len(fields) > 4 {
+			localAddr = fields[4]
+		}
 		portStr := ""
 
 		if idx := strings.LastIndex(localAddr, ":"); idx != -1 {
@@ -186,12 +244,24 @@ func (s *PortScanner) parseNetstatOutput(output string) []PortInfo {
 			Protocol: protocol,
 			Service:  s.guessService(port),
 			Process:  process,
+			Address:  strings.Trim(localAddr[:strings.LastIndex(localAddr, ":")], "[]"),
 		}
 
 		ports = append(ports, info)
 	}
 
-	return ports
+	return uniquePorts(ports)
+}
+
+// filterPorts 返回端口号满足条件的TCP端口
+func filterPorts(ports []PortInfo, keep func(port int) bool) []PortInfo {
+	var filtered []PortInfo
+	for _, port := range ports {
+		if port.Protocol == "tcp" && keep(port.Port) {
+			filtered = append(filtered, port)
+		}
+	}
+	return filtered
 }
 
 func (s *PortScanner) guessService(port int) string {
@@ -205,6 +275,8 @@ func (s *PortScanner) guessService(port int) string {
 		5000:  "Flask",
 		5173:  "Vite",
 		6000:  "X11",
+		6006:  "TensorBoard",
+		8265:  "Ray Dashboard",
 		8080:  "HTTP Proxy/Web IDE",
 		8000:  "Django/Flask",
 		8888:  "Jupyter",
@@ -232,7 +304,6 @@ func (s *PortScanner) DetectWebServices() ([]PortInfo, error) {
 		return nil, err
 	}
 
-	var webPorts []PortInfo
 	webPortNumbers := map[int]bool{
 		80:   true,
 		443:  true,
@@ -246,13 +317,7 @@ func (s *PortScanner) DetectWebServices() ([]PortInfo, error) {
 		8888: true,
 	}
 
-	for _, port := range allPorts {
-		if webPortNumbers[port.Port] {
-			webPorts = append(webPorts, port)
-		}
-	}
-
-	return webPorts, nil
+	return filterPorts(allPorts, func(port int) bool { return s.filter.Allows(port, webPortNumbers[port]) }), nil
 }
 
 func (s *PortScanner) CheckServiceHealth(port int) (bool, error) {
@@ -0,0 +1,197 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+)
+
+const (
+	DefaultWatchInterval = 5 * time.Second // 默认端口轮询间隔
+	// 端口连续消失这么多次后才移除转发，避免开发服务器重启时反复创建
+	watchMissingThreshold = 2
+)
+
+// PortEventType 端口监视事件类型
+type PortEventType string
+
+const (
+	PortForwarded     PortEventType = "port_forwarded"
+	PortRemoved       PortEventType = "port_removed"
+	PortForwardFailed PortEventType = "port_forward_failed"
+)
+
+// PortEvent 端口监视事件
+type PortEvent struct {
+	Type       PortEventType
+	RemotePort int
+	LocalPort  int
+	Service    string
+	Err        error
+	Time       time.Time
+	// Info 新转发端口的检测信息，仅PortForwarded事件有值
+	Info PortInfo
+}
+
+// PortWatcher 定期扫描远程监听端口，为新出现的Web服务自动创建转发，服务消失后移除转发
+type PortWatcher struct {
+	client   *ssh.Client
+	scanner  *PortScanner
+	manager  *TunnelManager
+	interval time.Duration
+	logger   log.Logger
+	onEvent  func(PortEvent)
+	missing  map[int]int
+}
+
+// NewPortWatcher 创建端口监视器
+func NewPortWatcher(client *ssh.Client, manager *TunnelManager, logger log.Logger) *PortWatcher {
+	if logger == nil {
+		logger = manager.logger
+	}
+
+	return &PortWatcher{
+		client:   client,
+		scanner:  manager.newScanner(client, logger),
+		manager:  manager,
+		interval: DefaultWatchInterval,
+		logger:   logger,
+		missing:  make(map[int]int),
+	}
+}
+
+// SetInterval 设置轮询间隔
+func (w *PortWatcher) SetInterval(interval time.Duration) {
+	if interval > 0 {
+		w.interval = interval
+	}
+}
+
+// OnEvent 设置事件回调，用于把转发变化通知给CLI
+func (w *PortWatcher) OnEvent(handler func(PortEvent)) {
+	w.onEvent = handler
+}
+
+// Run 运行监视循环，直到ctx被取消
+func (w *PortWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.logger.Debugf("Watching remote ports every %v", w.interval)
+	for {
+		w.poll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll 扫描一次并同步自动转发
+func (w *PortWatcher) poll() {
+	ports, err := w.scanner.DetectWebServices()
+	if err != nil {
+		w.logger.Debugf("Port scan failed: %v", err)
+		return
+	}
+
+	listening := make(map[int]PortInfo, len(ports))
+	for _, port := range ports {
+		listening[port.Port] = port
+	}
+
+	// 已被手动转发或IDE转发占用、或被用户移除的远程端口不再转发
+	forwarded := make(map[int]bool)
+	for _, info := range w.manager.ListTunnels() {
+		forwarded[info.RemotePort] = true
+	}
+
+	var added []PortInfo
+	for _, info := range ports {
+		delete(w.missing, info.Port)
+		if !forwarded[info.Port] && !w.manager.IsPortExcluded(info.Port) {
+			added = append(added, info)
+		}
+	}
+	// 只识别新出现的端口，避免每次轮询都发HTTP请求
+	w.scanner.Identify(added)
+
+	for _, info := range added {
+		port := info.Port
+		service := info.Describe()
+		actualPort, err := w.manager.CreateTunnel(w.client, port, port, AutoTunnelName(port))
+		if err != nil {
+			w.logger.Warnf("Failed to forward new port %d: %v", port, err)
+			w.emit(PortEvent{Type: PortForwardFailed, RemotePort: port, Service: service, Err: err})
+			continue
+		}
+		w.logger.Infof("Detected %s on remote port %d, forwarding to localhost:%d", service, port, actualPort)
+		w.emit(PortEvent{Type: PortForwarded, RemotePort: port, LocalPort: actualPort, Service: service, Info: info})
+	}
+
+	// 只移除自动创建的转发
+	for name, info := range w.manager.ListTunnels() {
+		if !IsAutoTunnel(name) {
+			continue
+		}
+		if _, ok := listening[info.RemotePort]; ok {
+			continue
+		}
+
+		w.missing[info.RemotePort]++
+		if w.missing[info.RemotePort] < watchMissingThreshold {
+			continue
+		}
+		delete(w.missing, info.RemotePort)
+
+		if err := w.manager.StopTunnel(name); err != nil {
+			w.logger.Warnf("Failed to remove forward for port %d: %v", info.RemotePort, err)
+			continue
+		}
+		w.logger.Infof("Remote port %d is no longer listening, removed forward from localhost:%d", info.RemotePort, info.LocalPort)
+		w.emit(PortEvent{Type: PortRemoved, RemotePort: info.RemotePort, LocalPort: info.LocalPort})
+	}
+}
+
+func (w *PortWatcher) emit(event PortEvent) {
+	if w.onEvent == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	w.onEvent(event)
+}
+
+// String 返回事件的可读描述
+func (e PortEvent) String() string {
+	switch e.Type {
+	case PortForwarded:
+		return fmt.Sprintf("%s on remote port %d is available at localhost:%d", e.Service, e.RemotePort, e.LocalPort)
+	case PortRemoved:
+		return fmt.Sprintf("remote port %d closed, forward from localhost:%d removed", e.RemotePort, e.LocalPort)
+	case PortForwardFailed:
+		return fmt.Sprintf("failed to forward remote port %d: %v", e.RemotePort, e.Err)
+	default:
+		return string(e.Type)
+	}
+}
+
+// autoTunnelPrefix 自动检测创建的隧道名前缀
+const autoTunnelPrefix = "auto-"
+
+// AutoTunnelName 返回自动转发端口使用的隧道名
+func AutoTunnelName(port int) string {
+	return fmt.Sprintf("%s%d", autoTunnelPrefix, port)
+}
+
+// IsAutoTunnel 判断隧道是否由自动检测创建
+func IsAutoTunnel(name string) bool {
+	return strings.HasPrefix(name, autoTunnelPrefix)
+}
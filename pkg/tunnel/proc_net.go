@@ -0,0 +1,258 @@
+package tunnel
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// procNetScript 一次性读取监听套接字、套接字所属进程的命令行/工作目录/容器ID以及docker端口映射，
+// 各部分以 #devssh-tcp、#devssh-sockets、#devssh-process、#devssh-docker 分隔，命令行中的换行替换为空格。没有GNU find时逐个readlink
+const procNetScript = `[ -r /proc/net/tcp ] || exit 1
+echo '#devssh-tcp'
+cat /proc/net/tcp /proc/net/tcp6 2>/dev/null
+echo '#devssh-sockets'
+if find --version >/dev/null 2>&1; then
+  s=$(find /proc/[0-9]*/fd -lname 'socket:*' -printf '%h %l\n' 2>/dev/null)
+else
+  s=$(for fd in /proc/[0-9]*/fd/*; do l=$(readlink "$fd" 2>/dev/null) && case "$l" in socket:*) echo "${fd%/*} $l";; esac; done)
+fi
+echo "$s"
+echo '#devssh-process'
+for p in $(echo "$s" | cut -d/ -f3 | sort -u); do
+  printf 'cmd %s ' "$p"; tr '\0\n' '  ' < "/proc/$p/cmdline" 2>/dev/null; echo
+  printf 'cwd %s %s\n' "$p" "$(readlink "/proc/$p/cwd" 2>/dev/null)"
+  printf 'ctr %s %s\n' "$p" "$(grep -oE '[0-9a-f]{64}' "/proc/$p/cgroup" 2>/dev/null | head -n 1)"
+done
+echo '#devssh-docker'
+docker ps --format '{{.ID}} {{.Names}} {{.Ports}}' 2>/dev/null || true`
+
+// tcpListenState /proc/net/tcp 中 LISTEN 状态的编码
+const tcpListenState = "0A"
+
+// parseProcNet 解析 procNetScript 的输出，返回去重后的TCP监听端口
+func parseProcNet(output string) ([]PortInfo, error) {
+	type socket struct {
+		port    int
+		address string
+	}
+
+	var (
+		section   string
+		listening = make(map[string]socket) // inode -> socket
+		order     []string
+		owners    = make(map[string]int)        // inode -> pid
+		processes = make(map[int]*processInfo)  // pid -> 进程信息
+		published = make(map[int]dockerMapping) // 宿主机端口 -> 容器
+	)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#devssh-") {
+			section = line
+			continue
+		}
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch section {
+		case "#devssh-tcp":
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			if len(fields) < 10 || fields[3] != tcpListenState {
+				continue
+			}
+			address, port, err := parseProcAddress(fields[1])
+			if err != nil {
+				continue
+			}
+			inode := fields[9]
+			if _, exists := listening[inode]; !exists {
+				order = append(order, inode)
+			}
+			listening[inode] = socket{port: port, address: address}
+		case "#devssh-sockets":
+			// /proc/<pid>/fd socket:[inode]
+			if len(fields) != 2 {
+				continue
+			}
+			parts := strings.Split(fields[0], "/")
+			if len(parts) < 3 {
+				continue
+			}
+			pid, err := strconv.Atoi(parts[2])
+			if err != nil {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(fields[1], "socket:["), "]")
+			if _, exists := owners[inode]; !exists {
+				owners[inode] = pid
+			}
+		case "#devssh-process":
+			// cmd|cwd|ctr <pid> <value>
+			if len(fields) < 3 {
+				continue
+			}
+			pid, err := strconv.Atoi(fields[1])
+			if err != nil {
+				continue
+			}
+			proc, ok := processes[pid]
+			if !ok {
+				proc = &processInfo{}
+				processes[pid] = proc
+			}
+			value := strings.Join(fields[2:], " ")
+			switch fields[0] {
+			case "cmd":
+				proc.command = value
+			case "cwd":
+				proc.cwd = value
+			case "ctr":
+				proc.container = shortContainerID(value)
+			}
+		case "#devssh-docker":
+			for port, mapping := range parseDockerPorts(fields) {
+				published[port] = mapping
+			}
+		}
+	}
+
+	if section == "" {
+		return nil, fmt.Errorf("unexpected /proc/net output")
+	}
+
+	var ports []PortInfo
+	for _, inode := range order {
+		sock := listening[inode]
+		info := PortInfo{
+			Port:     sock.port,
+			Protocol: "tcp",
+			Address:  sock.address,
+		}
+		if pid, ok := owners[inode]; ok {
+			info.PID = pid
+			if proc, ok := processes[pid]; ok {
+				info.Process = processName(proc.command)
+				info.Command = proc.command
+				info.Cwd = proc.cwd
+				info.Container = proc.container
+			}
+		}
+		// docker发布的端口由docker-proxy监听，以容器名标识
+		if mapping, ok := published[sock.port]; ok && info.Container == "" {
+			info.Container = mapping.name
+		}
+		ports = append(ports, info)
+	}
+
+	return uniquePorts(ports), nil
+}
+
+// parseProcAddress 解析 /proc/net/tcp 的十六进制地址，如 0100007F:1F90 或32位十六进制的IPv6地址
+func parseProcAddress(value string) (string, int, error) {
+	hostHex, portHex, ok := strings.Cut(value, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid address %q", value)
+	}
+
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in %q: %w", value, err)
+	}
+
+	raw, err := hex.DecodeString(hostHex)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", 0, fmt.Errorf("invalid host in %q", value)
+	}
+
+	// 地址按32位字以主机字节序（小端）存储
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+
+	return ip.String(), int(port), nil
+}
+
+// processInfo 监听进程的详细信息
+type processInfo struct {
+	command   string
+	cwd       string
+	container string
+}
+
+// dockerMapping docker发布到宿主机的端口
+type dockerMapping struct {
+	id   string
+	name string
+}
+
+// parseDockerPorts 解析一行 "ID NAME 0.0.0.0:8080->80/tcp, :::8080->80/tcp"，返回宿主机端口到容器的映射
+func parseDockerPorts(fields []string) map[int]dockerMapping {
+	if len(fields) < 3 {
+		return nil
+	}
+
+	mapping := dockerMapping{id: fields[0], name: fields[1]}
+	ports := make(map[int]dockerMapping)
+	for _, field := range fields[2:] {
+		hostPart, _, ok := strings.Cut(strings.TrimSuffix(field, ","), "->")
+		if !ok {
+			continue
+		}
+		idx := strings.LastIndex(hostPart, ":")
+		if idx == -1 {
+			continue
+		}
+		// 端口范围（8000-8010）不处理
+		if port, err := strconv.Atoi(hostPart[idx+1:]); err == nil {
+			ports[port] = mapping
+		}
+	}
+	return ports
+}
+
+// shortContainerID 截取容器ID的前12位，与docker ps一致
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// processName 从命令行中取出可执行文件名
+func processName(cmdline string) string {
+	fields := strings.Fields(cmdline)
+	if len(fields) == 0 {
+		return ""
+	}
+	return path.Base(fields[0])
+}
+
+// uniquePorts 按端口去重（同一服务常同时监听IPv4和IPv6），优先保留带进程信息的条目
+func uniquePorts(ports []PortInfo) []PortInfo {
+	index := make(map[string]int)
+	var unique []PortInfo
+
+	for _, port := range ports {
+		key := fmt.Sprintf("%s/%d", port.Protocol, port.Port)
+		i, exists := index[key]
+		if !exists {
+			index[key] = len(unique)
+			unique = append(unique, port)
+			continue
+		}
+		if unique[i].Process == "" && port.Process != "" {
+			address := unique[i].Address
+			unique[i] = port
+			unique[i].Address = address
+		}
+	}
+
+	return unique
+}
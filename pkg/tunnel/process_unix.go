@@ -0,0 +1,17 @@
+//go:build !windows
+
+package tunnel
+
+import (
+	"os"
+	"syscall"
+)
+
+// terminateProcess 发送SIGTERM，让进程有序退出
+func terminateProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(syscall.SIGTERM)
+}
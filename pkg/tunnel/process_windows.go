@@ -0,0 +1,14 @@
+//go:build windows
+
+package tunnel
+
+import "os"
+
+// terminateProcess Windows不支持SIGTERM，直接结束进程
+func terminateProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
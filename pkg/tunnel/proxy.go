@@ -0,0 +1,305 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"devssh/pkg/ssh"
+
+	"github.com/loft-sh/log"
+)
+
+// DefaultProxyPort 本地反向代理的默认端口
+const DefaultProxyPort = 7000
+
+// ProxyRoute 反向代理的一条路由
+type ProxyRoute struct {
+	Name       string `json:"name"`
+	RemotePort int    `json:"remote_port"`
+}
+
+// ReverseProxy 在一个本地端口上按主机名（NAME.localhost）或路径前缀（/NAME/）把HTTP请求代理到不同的远程端口，
+// 请求直接经SSH连接转发，不需要为每个服务占用本地端口
+type ReverseProxy struct {
+	client    *ssh.Client
+	logger    log.Logger
+	transport *http.Transport
+
+	mu       sync.RWMutex
+	routes   map[string]int // 名称 -> 远程端口
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewReverseProxy 创建反向代理
+func NewReverseProxy(client *ssh.Client, logger log.Logger) *ReverseProxy {
+	p := &ReverseProxy{
+		client: client,
+		logger: logger,
+		routes: make(map[string]int),
+	}
+	p.transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			// 重连后底层连接会变化，每次取当前连接
+			conn := client.GetClient()
+			if conn == nil {
+				return nil, fmt.Errorf("SSH connection is not available")
+			}
+			return conn.Dial(network, addr)
+		},
+		MaxIdleConnsPerHost: 8,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return p
+}
+
+// Register 注册一条路由，名称被其他端口占用时追加端口号，返回实际使用的名称
+func (p *ReverseProxy) Register(name string, remotePort int) string {
+	name = proxyLabel(name)
+	if name == "" {
+		name = strconv.Itoa(remotePort)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if port, exists := p.routes[name]; exists && port != remotePort {
+		name = fmt.Sprintf("%s-%d", name, remotePort)
+	}
+	p.routes[name] = remotePort
+	return name
+}
+
+// Unregister 移除指向该远程端口的全部路由
+func (p *ReverseProxy) Unregister(remotePort int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name, port := range p.routes {
+		if port == remotePort {
+			delete(p.routes, name)
+		}
+	}
+}
+
+// Routes 返回按名称排序的路由
+func (p *ReverseProxy) Routes() []ProxyRoute {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	routes := make([]ProxyRoute, 0, len(p.routes))
+	for name, port := range p.routes {
+		routes = append(routes, ProxyRoute{Name: name, RemotePort: port})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Name < routes[j].Name
+	})
+	return routes
+}
+
+// Start 在本地地址上开始提供代理，如 127.0.0.1:7000
+func (p *ReverseProxy) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	p.mu.Lock()
+	p.listener = listener
+	p.server = server
+	p.mu.Unlock()
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.logger.Warnf("Reverse proxy stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Port 返回代理实际监听的本地端口，未启动时返回0
+func (p *ReverseProxy) Port() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.listener == nil {
+		return 0
+	}
+	return p.listener.Addr().(*net.TCPAddr).Port
+}
+
+// URL 返回路由的访问地址，如 http://app.localhost:7000/
+func (p *ReverseProxy) URL(name string) string {
+	return fmt.Sprintf("http://%s.localhost:%d/", name, p.Port())
+}
+
+// Close 停止代理
+func (p *ReverseProxy) Close() error {
+	p.mu.Lock()
+	server := p.server
+	p.server = nil
+	p.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	p.transport.CloseIdleConnections()
+	return server.Close()
+}
+
+// ServeHTTP 按主机名或路径前缀选择远程端口并代理请求
+func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, port, prefix, ok := p.match(r)
+	if !ok {
+		// 未知的 NAME.localhost 返回404，只在代理自身地址上显示服务列表
+		if _, named := hostLabel(r.Host); r.URL.Path == "/" && !named {
+			p.serveIndex(w)
+			return
+		}
+		http.Error(w, fmt.Sprintf("devssh: no service is registered for %s%s", r.Host, r.URL.Path), http.StatusNotFound)
+		return
+	}
+
+	// 路径模式下 /NAME 重定向到 /NAME/，使相对路径的资源可以加载
+	if prefix != "" && r.URL.Path == prefix {
+		http.Redirect(w, r, prefix+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(&url.URL{Scheme: "http", Host: net.JoinHostPort(ssh.DefaultTunnelHost, strconv.Itoa(port))})
+			if prefix != "" {
+				pr.Out.URL.Path = strings.TrimPrefix(pr.In.URL.Path, prefix)
+				pr.Out.URL.RawPath = ""
+				pr.Out.Header.Set("X-Forwarded-Prefix", prefix)
+			}
+			pr.SetXForwarded()
+		},
+		Transport: p.transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			p.logger.Debugf("Reverse proxy request for %s failed: %v", name, err)
+			http.Error(w, fmt.Sprintf("devssh: %s (remote port %d) is not reachable: %v", name, port, err), http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// match 先按 NAME.localhost 主机名匹配，再按 /NAME/ 路径前缀匹配；NAME也可以是已注册的远程端口号
+func (p *ReverseProxy) match(r *http.Request) (name string, port int, prefix string, ok bool) {
+	if label, named := hostLabel(r.Host); named {
+		if port, ok := p.lookup(label); ok {
+			return label, port, "", true
+		}
+		return "", 0, "", false
+	}
+
+	segment, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if port, ok := p.lookup(segment); ok {
+		return segment, port, "/" + segment, true
+	}
+	return "", 0, "", false
+}
+
+// hostLabel 取出 NAME.localhost 中的NAME，a.b.localhost 与 a.localhost 相同
+func hostLabel(host string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	label, found := strings.CutSuffix(strings.ToLower(host), ".localhost")
+	if !found {
+		return "", false
+	}
+	label, _, _ = strings.Cut(label, ".")
+	return label, true
+}
+
+// lookup 按名称或已注册的端口号查找远程端口
+func (p *ReverseProxy) lookup(name string) (int, bool) {
+	if name == "" {
+		return 0, false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if port, ok := p.routes[name]; ok {
+		return port, true
+	}
+	if port, err := strconv.Atoi(name); err == nil {
+		for _, registered := range p.routes {
+			if registered == port {
+				return port, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// serveIndex 列出全部路由
+func (p *ReverseProxy) serveIndex(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	var body strings.Builder
+	body.WriteString("<!DOCTYPE html><html><head><title>devssh services</title></head><body><h1>devssh services</h1>")
+	routes := p.Routes()
+	if len(routes) == 0 {
+		body.WriteString("<p>No services registered yet.</p>")
+	} else {
+		body.WriteString("<ul>")
+		for _, route := range routes {
+			link := html.EscapeString(p.URL(route.Name))
+			fmt.Fprintf(&body, `<li><a href="%s">%s</a> (remote port %d, also at <a href="/%s/">/%s/</a>)</li>`,
+				link, link, route.RemotePort, html.EscapeString(route.Name), html.EscapeString(route.Name))
+		}
+		body.WriteString("</ul>")
+	}
+	body.WriteString("</body></html>")
+	fmt.Fprint(w, body.String())
+}
+
+// ProxyName 为自动检测到的服务选择路由名称：已知应用名、进程名，否则为端口号
+func ProxyName(info PortInfo) string {
+	for _, candidate := range []string{identifyApp(info.Command), info.Process} {
+		if label := proxyLabel(candidate); label != "" {
+			return label
+		}
+	}
+	return strconv.Itoa(info.Port)
+}
+
+// proxyLabel 把名称转换为合法的DNS标签，如 "next.js" -> "next-js"
+func proxyLabel(name string) string {
+	var label strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			label.WriteRune(r)
+			dash = false
+		} else if !dash && label.Len() > 0 {
+			label.WriteByte('-')
+			dash = true
+		}
+	}
+	result := strings.TrimSuffix(label.String(), "-")
+	if len(result) > 63 {
+		result = strings.TrimSuffix(result[:63], "-")
+	}
+	return result
+}
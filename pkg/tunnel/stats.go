@@ -0,0 +1,61 @@
+package tunnel
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"devssh/pkg/ssh"
+)
+
+// FormatBytes 以1024为进制显示字节数，如 1.5 MB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatStats 以简短形式显示隧道流量，如 "in 1.2 MB, out 3.4 KB, 5 connections, 2 active"
+func FormatStats(stats ssh.TunnelStats) string {
+	activity := fmt.Sprintf("%d active", stats.ActiveConnections)
+	if stats.ActiveConnections == 0 {
+		activity = "idle " + time.Since(stats.LastActivity).Truncate(time.Second).String()
+	}
+	return fmt.Sprintf("in %s, out %s, %d connections, %s",
+		FormatBytes(stats.BytesIn), FormatBytes(stats.BytesOut), stats.TotalConnections, activity)
+}
+
+// FormatForward 以 "localhost:8080 -> remote:80" 的形式显示转发，非默认地址时显示实际地址
+func FormatForward(localHost string, localPort int, remoteHost string, remotePort int) string {
+	local := "localhost:" + strconv.Itoa(localPort)
+	if !isLoopbackHost(localHost) {
+		local = net.JoinHostPort(localHost, strconv.Itoa(localPort))
+	}
+	return local + " -> " + formatRemote(remoteHost, remotePort)
+}
+
+// formatRemote 显示转发目标，开发机自身显示为 remote:PORT
+func formatRemote(host string, port int) string {
+	if isLoopbackHost(host) {
+		return "remote:" + strconv.Itoa(port)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// isLoopbackHost 判断地址是否为空或回环地址
+func isLoopbackHost(host string) bool {
+	if host == "" || host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
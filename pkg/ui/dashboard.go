@@ -0,0 +1,387 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"devssh/pkg/config"
+	"devssh/pkg/progress"
+	"devssh/pkg/ssh"
+	"devssh/pkg/tunnel"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/skratchdot/open-golang/open"
+)
+
+const refreshInterval = 2 * time.Second
+
+// progressTTL 超过这个时间没有新进度的连接不再显示
+const progressTTL = 10 * time.Minute
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	headerStyle   = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	healthyStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	downStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+)
+
+// row 仪表盘中的一行：已有会话或可连接的主机
+type row struct {
+	host    string
+	session *config.ConnectionConfig
+	healthy bool
+	// forwards 从会话控制socket获取的实时转发和流量，获取失败时为nil
+	forwards []tunnel.ForwardStatus
+	// progress 从仪表盘启动、尚未建立会话的up的最新进度
+	progress *progress.Event
+}
+
+type refreshMsg struct {
+	rows []row
+	err  error
+}
+
+type tickMsg time.Time
+
+type statusMsg string
+
+// Dashboard 终端仪表盘，展示主机、会话、端口转发及IDE健康状态
+type Dashboard struct {
+	executable string
+	rows       []row
+	cursor     int
+	status     string
+	err        error
+}
+
+// NewDashboard 创建仪表盘，executable用于在后台启动 `devssh up`
+func NewDashboard(executable string) *Dashboard {
+	return &Dashboard{executable: executable}
+}
+
+// Run 运行仪表盘直到用户退出
+func (d *Dashboard) Run() error {
+	_, err := tea.NewProgram(d, tea.WithAltScreen()).Run()
+	return err
+}
+
+func (d *Dashboard) Init() tea.Cmd {
+	return tea.Batch(refresh, tick())
+}
+
+func (d *Dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return d.handleKey(msg)
+	case tickMsg:
+		return d, tea.Batch(refresh, tick())
+	case refreshMsg:
+		d.rows = msg.rows
+		d.err = msg.err
+		if d.cursor >= len(d.rows) {
+			d.cursor = max(len(d.rows)-1, 0)
+		}
+	case statusMsg:
+		d.status = string(msg)
+	}
+	return d, nil
+}
+
+func (d *Dashboard) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c", "esc":
+		return d, tea.Quit
+	case "up", "k":
+		if d.cursor > 0 {
+			d.cursor--
+		}
+	case "down", "j":
+		if d.cursor < len(d.rows)-1 {
+			d.cursor++
+		}
+	case "r":
+		return d, refresh
+	}
+
+	if len(d.rows) == 0 {
+		return d, nil
+	}
+	selected := d.rows[d.cursor]
+
+	switch msg.String() {
+	case "enter", "c":
+		if selected.session != nil {
+			return d, status(fmt.Sprintf("%s already has an active session", selected.host))
+		}
+		return d, d.connect(selected.host)
+	case "s":
+		if selected.session == nil {
+			return d, status("no session to stop")
+		}
+		return d, stop(*selected.session)
+	case "o":
+		if selected.session == nil || selected.session.LocalPort == 0 {
+			return d, status("no IDE to open")
+		}
+		return d, openBrowser(selected.session.LocalPort)
+	}
+
+	return d, nil
+}
+
+func (d *Dashboard) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("DevSSH dashboard"))
+	b.WriteString("\n\n")
+	b.WriteString(headerStyle.Render(fmt.Sprintf("  %-24s %-10s %-8s %-28s %s", "HOST", "SESSION", "IDE", "URL", "FORWARDS")))
+	b.WriteString("\n")
+
+	if len(d.rows) == 0 {
+		b.WriteString(dimStyle.Render("  no hosts or sessions found"))
+		b.WriteString("\n")
+	}
+
+	for i, r := range d.rows {
+		line := formatRow(r)
+		if i == d.cursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	if d.err != nil {
+		b.WriteString(downStyle.Render(d.err.Error()))
+		b.WriteString("\n")
+	}
+	if d.status != "" {
+		b.WriteString(d.status)
+		b.WriteString("\n")
+	}
+	b.WriteString(dimStyle.Render("↑/↓ select • enter connect • s stop • o open in browser • r refresh • q quit"))
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+func formatRow(r row) string {
+	if r.session == nil {
+		line := fmt.Sprintf("  %-24s %-10s %-8s %-28s ", r.host, "-", "-", "-")
+		switch {
+		case r.progress == nil:
+			return line + "-"
+		case r.progress.Status == progress.StatusFailed:
+			return line + downStyle.Render(fmt.Sprintf("%s failed: %s", r.progress.Phase, r.progress.Error))
+		default:
+			return line + dimStyle.Render(fmt.Sprintf("[%d/%d] %s (%d%%)", r.progress.Step, r.progress.Steps, r.progress.Message, r.progress.Percent))
+		}
+	}
+
+	s := r.session
+	health := downStyle.Render("down")
+	if r.healthy {
+		health = healthyStyle.Render("up")
+	}
+
+	ideName, url := "-", "-"
+	if s.IDE != "" {
+		ideName = s.IDE
+		url = fmt.Sprintf("http://localhost:%d", s.LocalPort)
+	}
+
+	var forwards []string
+	if r.forwards != nil {
+		for _, f := range r.forwards {
+			forwards = append(forwards, fmt.Sprintf("%d->%d (↓%s ↑%s)", f.LocalPort, f.RemotePort,
+				tunnel.FormatBytes(f.BytesIn), tunnel.FormatBytes(f.BytesOut)))
+		}
+	} else {
+		for _, f := range s.Forwards {
+			forwards = append(forwards, fmt.Sprintf("%d->%d", f.LocalPort, f.RemotePort))
+		}
+	}
+
+	line := fmt.Sprintf("  %-24s %-10s %-8s %-28s %s", r.host, s.ID, ideName, url, strings.Join(forwards, ","))
+	if s.IDE != "" {
+		line += " " + health
+	}
+	return line
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+func status(text string) tea.Cmd {
+	return func() tea.Msg {
+		return statusMsg(text)
+	}
+}
+
+// refresh 重新加载会话与主机列表，并检查IDE是否响应
+func refresh() tea.Msg {
+	cfg, err := config.Load()
+	if err != nil {
+		return refreshMsg{err: err}
+	}
+	if _, err := cfg.PruneStaleConnections(); err != nil {
+		return refreshMsg{err: err}
+	}
+
+	var rows []row
+	seen := make(map[string]bool)
+
+	sessions := cfg.ListConnections()
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+	})
+	for i := range sessions {
+		s := sessions[i]
+		r := row{host: s.Host, session: &s}
+		if s.IDE != "" && s.LocalPort > 0 {
+			r.healthy = checkHTTP(s.LocalPort)
+		}
+		if socketPath, err := config.SessionSocketPath(s.ID); err == nil {
+			if forwards, err := tunnel.SendControl(socketPath, tunnel.ControlRequest{Action: tunnel.ControlList}); err == nil {
+				r.forwards = append([]tunnel.ForwardStatus{}, forwards...)
+			}
+		}
+		rows = append(rows, r)
+		seen[s.Host] = true
+	}
+
+	hosts, err := ssh.NewSSHConfigParser().ListHosts()
+	if err != nil {
+		return refreshMsg{rows: rows, err: err}
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if !seen[host] {
+			rows = append(rows, row{host: host, progress: lastProgress(host)})
+		}
+	}
+
+	return refreshMsg{rows: rows}
+}
+
+// checkHTTP 检查本地转发端口上的IDE是否响应
+func checkHTTP(port int) bool {
+	client := &http.Client{Timeout: 500 * time.Millisecond}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d", port))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < 500
+}
+
+// progressPath 从仪表盘启动的up写入进度事件的文件
+func progressPath(host string) (string, error) {
+	stateDir, err := config.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, fmt.Sprintf("ui-%s.progress", host)), nil
+}
+
+// lastProgress 读取该主机最近一次连接的最新进度，没有或已过期时返回nil
+func lastProgress(host string) *progress.Event {
+	path, err := progressPath(host)
+	if err != nil {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var last *progress.Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event progress.Event
+		if json.Unmarshal(scanner.Bytes(), &event) == nil && event.Phase != "" {
+			last = &event
+		}
+	}
+	// 就绪后由会话行显示
+	if last == nil || last.Phase == progress.PhaseReady || time.Since(last.Time) > progressTTL {
+		return nil
+	}
+	return last
+}
+
+// connect 在后台启动 `devssh up -o json <host>`，进度事件写入进度文件，日志写入状态目录下的日志文件
+func (d *Dashboard) connect(host string) tea.Cmd {
+	return func() tea.Msg {
+		stateDir, err := config.GetStateDir()
+		if err != nil {
+			return statusMsg(err.Error())
+		}
+		if err := os.MkdirAll(stateDir, 0755); err != nil {
+			return statusMsg(err.Error())
+		}
+
+		logPath := filepath.Join(stateDir, fmt.Sprintf("ui-%s.log", host))
+		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return statusMsg(fmt.Sprintf("failed to open log file: %v", err))
+		}
+		defer logFile.Close()
+
+		path, err := progressPath(host)
+		if err != nil {
+			return statusMsg(err.Error())
+		}
+		progressFile, err := os.Create(path)
+		if err != nil {
+			return statusMsg(fmt.Sprintf("failed to open progress file: %v", err))
+		}
+		defer progressFile.Close()
+
+		// -o json时日志写入stderr，stdout只有进度事件
+		cmd := exec.Command(d.executable, "up", "-o", "json", host)
+		cmd.Stdout = progressFile
+		cmd.Stderr = logFile
+		if err := cmd.Start(); err != nil {
+			return statusMsg(fmt.Sprintf("failed to start session: %v", err))
+		}
+		cmd.Process.Release()
+
+		return statusMsg(fmt.Sprintf("connecting to %s (log: %s)", host, logPath))
+	}
+}
+
+func stop(session config.ConnectionConfig) tea.Cmd {
+	return func() tea.Msg {
+		if err := session.Terminate(); err != nil {
+			return statusMsg(fmt.Sprintf("failed to stop session %s: %v", session.ID, err))
+		}
+		return statusMsg(fmt.Sprintf("stopping session %s", session.ID))
+	}
+}
+
+func openBrowser(port int) tea.Cmd {
+	return func() tea.Msg {
+		url := fmt.Sprintf("http://localhost:%d", port)
+		if err := open.Start(url); err != nil {
+			return statusMsg(fmt.Sprintf("failed to open %s: %v", url, err))
+		}
+		return statusMsg(fmt.Sprintf("opened %s", url))
+	}
+}
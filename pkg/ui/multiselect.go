@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ErrCanceled 用户取消选择
+var ErrCanceled = fmt.Errorf("selection canceled")
+
+// MultiSelect 终端多选列表
+type MultiSelect struct {
+	title    string
+	items    []string
+	selected []bool
+	cursor   int
+	canceled bool
+}
+
+// NewMultiSelect 创建多选列表，默认全部选中
+func NewMultiSelect(title string, items []string) *MultiSelect {
+	selected := make([]bool, len(items))
+	for i := range selected {
+		selected[i] = true
+	}
+	return &MultiSelect{
+		title:    title,
+		items:    items,
+		selected: selected,
+	}
+}
+
+// Run 显示列表直到用户确认，返回选中项的下标
+func (m *MultiSelect) Run() ([]int, error) {
+	if _, err := tea.NewProgram(m).Run(); err != nil {
+		return nil, err
+	}
+	if m.canceled {
+		return nil, ErrCanceled
+	}
+
+	var indexes []int
+	for i, selected := range m.selected {
+		if selected {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+func (m *MultiSelect) Init() tea.Cmd {
+	return nil
+}
+
+func (m *MultiSelect) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch key.String() {
+	case "q", "ctrl+c", "esc":
+		m.canceled = true
+		return m, tea.Quit
+	case "enter":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.items)-1 {
+			m.cursor++
+		}
+	case " ", "x":
+		if len(m.items) > 0 {
+			m.selected[m.cursor] = !m.selected[m.cursor]
+		}
+	case "a":
+		// 全部选中时取消全部，否则全选
+		all := true
+		for _, selected := range m.selected {
+			all = all && selected
+		}
+		for i := range m.selected {
+			m.selected[i] = !all
+		}
+	}
+	return m, nil
+}
+
+func (m *MultiSelect) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render(m.title))
+	b.WriteString("\n\n")
+
+	count := 0
+	for i, item := range m.items {
+		mark := "[ ]"
+		if m.selected[i] {
+			mark = "[x]"
+			count++
+		}
+		line := fmt.Sprintf("  %s %s", mark, item)
+		if i == m.cursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(dimStyle.Render(fmt.Sprintf("%d of %d selected • ↑/↓ move • space toggle • a all/none • enter confirm • q cancel", count, len(m.items))))
+	b.WriteString("\n")
+
+	return b.String()
+}